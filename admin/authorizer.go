@@ -0,0 +1,133 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+// Package admin authorizes requests to the administrative APIs, which are served on the admin port. Requests are
+// authorized by one or more Authorizer implementations, for example by introspecting the access token of the request
+// or by checking a static API key.
+package admin
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+
+	"github.com/ory/fosite"
+	"github.com/ory/go-convenience/stringslice"
+	"github.com/ory/hydra/oauth2"
+	"github.com/pkg/errors"
+)
+
+// Authorizer decides whether a request to the administrative APIs may be served.
+type Authorizer interface {
+	// Authorize returns nil if the request may access an endpoint which requires scope. Otherwise it returns
+	// fosite.ErrRequestUnauthorized if the request could not be authenticated or fosite.ErrRequestForbidden if it lacks
+	// the required permissions.
+	Authorize(r *http.Request, scope string) error
+}
+
+// Authorizers allows a request if any of its authorizers allows it. Otherwise, the error of the first authorizer is
+// returned.
+type Authorizers []Authorizer
+
+func (a Authorizers) Authorize(r *http.Request, scope string) error {
+	var first error
+	for _, authorizer := range a {
+		err := authorizer.Authorize(r, scope)
+		if err == nil {
+			return nil
+		} else if first == nil {
+			first = err
+		}
+	}
+
+	if first == nil {
+		return errors.WithStack(fosite.ErrRequestUnauthorized.WithDebug("No authorizer is configured"))
+	}
+	return first
+}
+
+// IntrospectionAuthorizer authorizes requests which carry an OAuth 2.0 access token issued by this server. The access
+// token must have been issued to one of Clients and must have been granted the scope required by the endpoint, for
+// example `hydra.clients`.
+type IntrospectionAuthorizer struct {
+	OAuth2  fosite.OAuth2Provider
+	Clients []string
+}
+
+func (a *IntrospectionAuthorizer) Authorize(r *http.Request, scope string) error {
+	token := fosite.AccessTokenFromRequest(r)
+	if token == "" {
+		return errors.WithStack(fosite.ErrRequestUnauthorized.WithDebug("The request is missing an access token in the Authorization header"))
+	}
+
+	tokenType, ar, err := a.OAuth2.IntrospectToken(r.Context(), token, fosite.AccessToken, oauth2.NewSession(""), scope)
+	if e, ok := errors.Cause(err).(*fosite.RFC6749Error); ok && e.Name == fosite.ErrInvalidScope.Name {
+		return errors.WithStack(fosite.ErrRequestForbidden.WithDebug(fmt.Sprintf("The access token was not granted scope %s", scope)))
+	} else if err != nil {
+		return errors.WithStack(fosite.ErrRequestUnauthorized.WithDebug("The access token is invalid, expired or has been revoked"))
+	} else if tokenType != fosite.AccessToken {
+		return errors.WithStack(fosite.ErrRequestUnauthorized.WithDebug("Only access tokens are allowed in the Authorization header"))
+	} else if !stringslice.Has(a.Clients, ar.GetClient().GetID()) {
+		return errors.WithStack(fosite.ErrRequestForbidden.WithDebug(fmt.Sprintf("OAuth 2.0 Client %s is not allowed to access the administrative APIs", ar.GetClient().GetID())))
+	}
+
+	return nil
+}
+
+// APIKeyAuthorizer authorizes requests which carry one of Keys as bearer token in the Authorization header. A valid
+// API key grants access to all administrative APIs.
+type APIKeyAuthorizer struct {
+	Keys []string
+}
+
+func (a *APIKeyAuthorizer) Authorize(r *http.Request, _ string) error {
+	token := fosite.AccessTokenFromRequest(r)
+	if token == "" {
+		return errors.WithStack(fosite.ErrRequestUnauthorized.WithDebug("The request is missing an API key in the Authorization header"))
+	}
+
+	for _, key := range a.Keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+			return nil
+		}
+	}
+
+	return errors.WithStack(fosite.ErrRequestUnauthorized.WithDebug("The API key is invalid"))
+}
+
+// CertificateAuthorizer authorizes requests which present a TLS client certificate that was verified against the
+// client certificate authorities of the admin port. If Subjects is not empty, the common name of the certificate must
+// be one of them. A valid certificate grants access to all administrative APIs.
+type CertificateAuthorizer struct {
+	Subjects []string
+}
+
+func (a *CertificateAuthorizer) Authorize(r *http.Request, _ string) error {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return errors.WithStack(fosite.ErrRequestUnauthorized.WithDebug("The request did not present a trusted TLS client certificate"))
+	}
+
+	subject := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if len(a.Subjects) > 0 && !stringslice.Has(a.Subjects, subject) {
+		return errors.WithStack(fosite.ErrRequestForbidden.WithDebug(fmt.Sprintf("TLS client certificates issued to %s are not allowed", subject)))
+	}
+
+	return nil
+}
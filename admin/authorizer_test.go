@@ -0,0 +1,151 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package admin_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	. "github.com/ory/hydra/admin"
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/oauth2"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func errorName(err error) string {
+	if e, ok := errors.Cause(err).(*fosite.RFC6749Error); ok {
+		return e.Name
+	}
+	return ""
+}
+
+func bearer(token string) *http.Request {
+	r := httptest.NewRequest("GET", "/clients", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestIntrospectionAuthorizer(t *testing.T) {
+	hasher := &fosite.BCrypt{WorkFactor: 4}
+	store := oauth2.NewFositeMemoryStore(client.NewMemoryManager(hasher), time.Hour)
+	config := &compose.Config{AccessTokenLifespan: time.Hour, ScopeStrategy: fosite.WildcardScopeStrategy}
+	strategy := compose.NewOAuth2HMACStrategy(config, []byte("some super secret secret secret secret"))
+	a := &IntrospectionAuthorizer{
+		OAuth2:  compose.Compose(config, store, strategy, hasher, compose.OAuth2TokenIntrospectionFactory),
+		Clients: []string{"admin-client"},
+	}
+
+	issue := func(t *testing.T, clientID string, scopes ...string) (string, string) {
+		ctx := context.Background()
+		req := &fosite.Request{
+			ID:            uuid.New(),
+			RequestedAt:   time.Now().UTC(),
+			Client:        &client.Client{ID: clientID},
+			GrantedScopes: fosite.Arguments(scopes),
+			Form:          url.Values{},
+			Session:       oauth2.NewSession(clientID),
+		}
+
+		access, signature, err := strategy.GenerateAccessToken(ctx, req)
+		require.NoError(t, err)
+		require.NoError(t, store.CreateAccessTokenSession(ctx, signature, req))
+
+		refresh, signature, err := strategy.GenerateRefreshToken(ctx, req)
+		require.NoError(t, err)
+		require.NoError(t, store.CreateRefreshTokenSession(ctx, signature, req))
+		return access, refresh
+	}
+
+	access, refresh := issue(t, "admin-client", "hydra.clients")
+	wildcard, _ := issue(t, "admin-client", "hydra.*")
+	other, _ := issue(t, "other-client", "hydra.*")
+
+	for k, tc := range []struct {
+		r     *http.Request
+		scope string
+		error string
+	}{
+		{r: bearer(""), scope: ScopeClients, error: fosite.ErrRequestUnauthorized.Name},
+		{r: bearer("foo.bar"), scope: ScopeClients, error: fosite.ErrRequestUnauthorized.Name},
+		{r: bearer(refresh), scope: ScopeClients, error: fosite.ErrRequestUnauthorized.Name},
+		{r: bearer(access), scope: ScopeKeys, error: fosite.ErrRequestForbidden.Name},
+		{r: bearer(access), scope: ScopeClients},
+		{r: bearer(wildcard), scope: ScopeKeys},
+		{r: bearer(other), scope: ScopeKeys, error: fosite.ErrRequestForbidden.Name},
+	} {
+		err := a.Authorize(tc.r, tc.scope)
+		if tc.error == "" {
+			assert.NoError(t, err, "%d", k)
+		} else {
+			assert.Equal(t, tc.error, errorName(err), "%d: %+v", k, err)
+		}
+	}
+}
+
+func TestAPIKeyAuthorizer(t *testing.T) {
+	a := &APIKeyAuthorizer{Keys: []string{"foo", "bar"}}
+	assert.NoError(t, a.Authorize(bearer("foo"), ScopeClients))
+	assert.NoError(t, a.Authorize(bearer("bar"), ScopeKeys))
+	assert.Equal(t, fosite.ErrRequestUnauthorized.Name, errorName(a.Authorize(bearer("baz"), ScopeClients)))
+	assert.Equal(t, fosite.ErrRequestUnauthorized.Name, errorName(a.Authorize(bearer(""), ScopeClients)))
+	assert.Error(t, (&APIKeyAuthorizer{}).Authorize(bearer(""), ScopeClients))
+}
+
+func TestCertificateAuthorizer(t *testing.T) {
+	withCertificate := func(cn string) *http.Request {
+		r := bearer("")
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}}}
+		return r
+	}
+
+	a := &CertificateAuthorizer{}
+	assert.NoError(t, a.Authorize(withCertificate("foo"), ScopeClients))
+	assert.Equal(t, fosite.ErrRequestUnauthorized.Name, errorName(a.Authorize(bearer(""), ScopeClients)))
+
+	r := bearer("")
+	r.TLS = &tls.ConnectionState{}
+	assert.Equal(t, fosite.ErrRequestUnauthorized.Name, errorName(a.Authorize(r, ScopeClients)))
+
+	a = &CertificateAuthorizer{Subjects: []string{"foo"}}
+	assert.NoError(t, a.Authorize(withCertificate("foo"), ScopeClients))
+	assert.Equal(t, fosite.ErrRequestForbidden.Name, errorName(a.Authorize(withCertificate("bar"), ScopeClients)))
+}
+
+func TestAuthorizers(t *testing.T) {
+	a := Authorizers{&APIKeyAuthorizer{Keys: []string{"foo"}}, &CertificateAuthorizer{}}
+	assert.NoError(t, a.Authorize(bearer("foo"), ScopeClients))
+	assert.Error(t, a.Authorize(bearer("bar"), ScopeClients))
+	assert.Error(t, Authorizers{}.Authorize(bearer("foo"), ScopeClients))
+}
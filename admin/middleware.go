@@ -0,0 +1,101 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ory/herodot"
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/health"
+	"github.com/ory/hydra/jwk"
+	"github.com/ory/hydra/oauth2"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ScopeClients is required to manage OAuth 2.0 clients.
+	ScopeClients = "hydra.clients"
+
+	// ScopeKeys is required to manage JSON Web Keys.
+	ScopeKeys = "hydra.keys"
+
+	// ScopeConsent is required to handle login and consent requests and to manage login and consent sessions.
+	ScopeConsent = "hydra.consent"
+
+	// ScopeFlush is required to flush inactive access tokens.
+	ScopeFlush = "hydra.flush"
+
+	// ScopeAdmin is required by administrative APIs which are not covered by any other scope.
+	ScopeAdmin = "hydra"
+)
+
+var scopes = []struct {
+	prefix string
+	scope  string
+}{
+	{prefix: client.ClientsHandlerPath, scope: ScopeClients},
+	{prefix: jwk.KeyHandlerPath, scope: ScopeKeys},
+	{prefix: "/oauth2/auth/requests", scope: ScopeConsent},
+	{prefix: "/oauth2/auth/sessions", scope: ScopeConsent},
+	{prefix: oauth2.FlushPath, scope: ScopeFlush},
+}
+
+// RequiredScope returns the scope a request must be authorized for. It returns false if the request may be served
+// without authorization, which is the case for the health endpoints.
+func RequiredScope(r *http.Request) (string, bool) {
+	switch r.URL.Path {
+	case health.AliveCheckPath, health.ReadyCheckPath, health.VersionPath, health.MetricsPath, health.MetricsPrometheusPath, "/health/status":
+		return "", false
+	}
+
+	for _, s := range scopes {
+		if r.URL.Path == s.prefix || strings.HasPrefix(r.URL.Path, s.prefix+"/") {
+			return s.scope, true
+		}
+	}
+
+	return ScopeAdmin, true
+}
+
+// Middleware is a negroni middleware which rejects requests to the administrative APIs unless Authorizer allows them.
+type Middleware struct {
+	Authorizer Authorizer
+	H          herodot.Writer
+	L          logrus.FieldLogger
+}
+
+func (m *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	scope, ok := RequiredScope(r)
+	if !ok {
+		next(rw, r)
+		return
+	}
+
+	if err := m.Authorizer.Authorize(r, scope); err != nil {
+		m.L.WithError(err).WithField("scope", scope).Infof("Rejected unauthorized request to %s", r.URL.Path)
+		m.H.WriteError(rw, r, err)
+		return
+	}
+
+	next(rw, r)
+}
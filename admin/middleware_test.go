@@ -0,0 +1,97 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package admin_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/herodot"
+	. "github.com/ory/hydra/admin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/negroni"
+)
+
+func TestRequiredScope(t *testing.T) {
+	for k, tc := range []struct {
+		path     string
+		scope    string
+		required bool
+	}{
+		{path: "/clients", scope: ScopeClients, required: true},
+		{path: "/clients/foo", scope: ScopeClients, required: true},
+		{path: "/clientsfoo", scope: ScopeAdmin, required: true},
+		{path: "/keys/foo/bar", scope: ScopeKeys, required: true},
+		{path: "/oauth2/auth/requests/login/foo", scope: ScopeConsent, required: true},
+		{path: "/oauth2/auth/sessions/consent/foo", scope: ScopeConsent, required: true},
+		{path: "/oauth2/flush", scope: ScopeFlush, required: true},
+		{path: "/does-not-exist", scope: ScopeAdmin, required: true},
+		{path: "/health/alive"},
+		{path: "/health/ready"},
+		{path: "/health/status"},
+		{path: "/version"},
+	} {
+		scope, required := RequiredScope(httptest.NewRequest("GET", tc.path, nil))
+		assert.Equal(t, tc.required, required, "%d", k)
+		assert.Equal(t, tc.scope, scope, "%d", k)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	router := httprouter.New()
+	router.GET("/clients", func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router.GET("/health/alive", func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	l := logrus.New()
+	n := negroni.New()
+	n.Use(&Middleware{
+		Authorizer: &APIKeyAuthorizer{Keys: []string{"foo"}},
+		H:          herodot.NewJSONWriter(l),
+		L:          l,
+	})
+	n.UseHandler(router)
+
+	for k, tc := range []struct {
+		path   string
+		key    string
+		status int
+	}{
+		{path: "/clients", status: http.StatusUnauthorized},
+		{path: "/clients", key: "bar", status: http.StatusUnauthorized},
+		{path: "/clients", key: "foo", status: http.StatusOK},
+		{path: "/health/alive", status: http.StatusOK},
+	} {
+		r := httptest.NewRequest("GET", tc.path, nil)
+		if tc.key != "" {
+			r.Header.Set("Authorization", "Bearer "+tc.key)
+		}
+		rw := httptest.NewRecorder()
+		n.ServeHTTP(rw, r)
+		assert.Equal(t, tc.status, rw.Code, "%d: %s", k, rw.Body.String())
+	}
+}
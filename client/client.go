@@ -24,6 +24,24 @@ import (
 	"strings"
 
 	"github.com/ory/fosite"
+	"github.com/square/go-jose"
+)
+
+const (
+	// TokenEndpointAuthMethodClientSecretBasic authenticates the client using HTTP Basic Authorization.
+	TokenEndpointAuthMethodClientSecretBasic = "client_secret_basic"
+
+	// TokenEndpointAuthMethodClientSecretPost authenticates the client using the request body.
+	TokenEndpointAuthMethodClientSecretPost = "client_secret_post"
+
+	// TokenEndpointAuthMethodClientSecretJWT authenticates the client using a JWT signed with the client secret.
+	TokenEndpointAuthMethodClientSecretJWT = "client_secret_jwt"
+
+	// TokenEndpointAuthMethodPrivateKeyJWT authenticates the client using a JWT signed with one of its private keys.
+	TokenEndpointAuthMethodPrivateKeyJWT = "private_key_jwt"
+
+	// TokenEndpointAuthMethodNone is used by public clients which do not authenticate at the token endpoint.
+	TokenEndpointAuthMethodNone = "none"
 )
 
 // Client represents an OAuth 2.0 Client.
@@ -98,6 +116,91 @@ type Client struct {
 	// represented as the number of seconds from 1970-01-01T00:00:00Z as
 	// measured in UTC until the date/time of expiration.
 	SecretExpiresAt int `json:"client_secret_expires_at" gorethink:"client_secret_expires_at"`
+
+	// SubjectType requested for responses to this Client. The subject_types_supported Discovery parameter contains a
+	// list of the supported subject_type values for this server. Valid types include `pairwise` and `public`.
+	// If empty, `public` is used.
+	SubjectType string `json:"subject_type" gorethink:"subject_type"`
+
+	// URL using the https scheme to be used in calculating Pseudonymous Identifiers by the OP. The URL references a
+	// file with a single JSON array of redirect_uri values.
+	SectorIdentifierURI string `json:"sector_identifier_uri" gorethink:"sector_identifier_uri"`
+
+	// RegistrationAccessTokenSignature is the signature of the registration access token which was issued when the
+	// client registered itself using OAuth 2.0 Dynamic Client Registration. It is never exposed through the API.
+	RegistrationAccessTokenSignature string `json:"-" gorethink:"registration_access_token_signature"`
+
+	// Requested Client Authentication method for the Token Endpoint. The options are client_secret_post,
+	// client_secret_basic, client_secret_jwt, private_key_jwt, and none. If empty, client_secret_basic is used.
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method" gorethink:"token_endpoint_auth_method"`
+
+	// URL for the Client's JSON Web Key Set [JWK] document. If the Client signs requests to the Server, it contains
+	// the signing key(s) the Server uses to validate signatures from the Client. The JWK Set MAY also contain the
+	// Client's encryption keys(s), which are used by the Server to encrypt responses to the Client. Use either jwks_uri
+	// or jwks, but not both.
+	JSONWebKeysURI string `json:"jwks_uri" gorethink:"jwks_uri"`
+
+	// Client's JSON Web Key Set [JWK] document, passed by value. The semantics of the jwks parameter are the same as
+	// the jwks_uri parameter, other than that the JWK Set is passed by value, rather than by reference. Use either
+	// jwks_uri or jwks, but not both.
+	JSONWebKeys *jose.JSONWebKeySet `json:"jwks,omitempty" gorethink:"jwks"`
+
+	// EncryptedSecret is the client secret, encrypted using the system secret. It is only stored for clients using
+	// client_secret_jwt, because verifying their assertions requires the plaintext secret. It is never exposed
+	// through the API.
+	EncryptedSecret string `json:"-" gorethink:"client_secret_encrypted"`
+
+	// Array of URLs supplied by the RP to which it MAY request that the End-User's User Agent be redirected using the
+	// post_logout_redirect_uri parameter after a logout has been performed.
+	PostLogoutRedirectURIs []string `json:"post_logout_redirect_uris" gorethink:"post_logout_redirect_uris"`
+
+	// RP URL that will cause the RP to log itself out when sent a Logout Token by the OP.
+	BackChannelLogoutURI string `json:"backchannel_logout_uri" gorethink:"backchannel_logout_uri"`
+
+	// Boolean value specifying whether the RP requires that a sid (session ID) Claim be included in the Logout
+	// Token to identify the RP session with the OP when the backchannel_logout_uri is used.
+	BackChannelLogoutSessionRequired bool `json:"backchannel_logout_session_required" gorethink:"backchannel_logout_session_required"`
+
+	// RP URL that will cause the RP to log itself out when rendered in an iframe by the OP.
+	FrontChannelLogoutURI string `json:"frontchannel_logout_uri" gorethink:"frontchannel_logout_uri"`
+
+	// Boolean value specifying whether the RP requires that iss (issuer) and sid (session ID) query parameters be
+	// included to identify the RP session with the OP when the frontchannel_logout_uri is used.
+	FrontChannelLogoutSessionRequired bool `json:"frontchannel_logout_session_required" gorethink:"frontchannel_logout_session_required"`
+
+	// TokenExchangeAudiences is an array of audiences the client may request tokens for using OAuth 2.0 Token
+	// Exchange.
+	TokenExchangeAudiences []string `json:"token_exchange_audiences" gorethink:"token_exchange_audiences"`
+
+	// TokenExchangeScope is a string containing a space-separated list of scope values the client may request using
+	// OAuth 2.0 Token Exchange. Exchanged tokens never carry scopes which were not granted to the subject token.
+	TokenExchangeScope string `json:"token_exchange_scope" gorethink:"token_exchange_scope"`
+
+	// RefreshTokenGracePeriod is the number of seconds during which a refresh token which has just been rotated may
+	// be used again, for example by concurrent requests of a mobile app. Outside of this window, using a rotated
+	// refresh token revokes all tokens issued from the same authorization. If 0, rotated refresh tokens can not be
+	// used again.
+	RefreshTokenGracePeriod int `json:"refresh_token_grace_period" gorethink:"refresh_token_grace_period"`
+
+	// JWS alg algorithm [JWA] REQUIRED for signing UserInfo Responses. If this is specified, the response will be JWT
+	// [JWT] serialized, and signed using JWS. If omitted, the UserInfo Response will return the Claims as a UTF-8
+	// encoded JSON object using the application/json content-type.
+	UserinfoSignedResponseAlg string `json:"userinfo_signed_response_alg" gorethink:"userinfo_signed_response_alg"`
+
+	// JWE alg algorithm [JWA] REQUIRED for encrypting UserInfo Responses. If both signing and encryption are
+	// requested, the response will be signed then encrypted, with the result being a Nested JWT. The response is
+	// encrypted to a key of the client's JSON Web Key Set.
+	UserinfoEncryptedResponseAlg string `json:"userinfo_encrypted_response_alg" gorethink:"userinfo_encrypted_response_alg"`
+
+	// JWE enc algorithm [JWA] REQUIRED for encrypting UserInfo Responses. If userinfo_encrypted_response_alg is
+	// specified, the default for this value is A128CBC-HS256. When userinfo_encrypted_response_enc is included,
+	// userinfo_encrypted_response_alg MUST also be provided.
+	UserinfoEncryptedResponseEnc string `json:"userinfo_encrypted_response_enc" gorethink:"userinfo_encrypted_response_enc"`
+
+	// JWS alg algorithm [JWA] REQUIRED for signing the ID Token issued to this Client. If omitted, ID Tokens are
+	// signed using the default algorithm of the server. The algorithm must be one of the
+	// id_token_signing_alg_values_supported advertised by OpenID Connect Discovery.
+	IDTokenSignedResponseAlg string `json:"id_token_signed_response_alg" gorethink:"id_token_signed_response_alg"`
 }
 
 func (c *Client) GetID() string {
@@ -116,6 +219,10 @@ func (c *Client) GetScopes() fosite.Arguments {
 	return fosite.Arguments(strings.Fields(c.Scope))
 }
 
+func (c *Client) GetTokenExchangeScopes() fosite.Arguments {
+	return fosite.Arguments(strings.Fields(c.TokenExchangeScope))
+}
+
 func (c *Client) GetGrantTypes() fosite.Arguments {
 	// https://openid.net/specs/openid-connect-registration-1_0.html#ClientMetadata
 	//
@@ -147,3 +254,13 @@ func (c *Client) GetOwner() string {
 func (c *Client) IsPublic() bool {
 	return c.Public
 }
+
+func (c *Client) GetTokenEndpointAuthMethod() string {
+	if c.TokenEndpointAuthMethod == "" {
+		if c.Public {
+			return TokenEndpointAuthMethodNone
+		}
+		return TokenEndpointAuthMethodClientSecretBasic
+	}
+	return c.TokenEndpointAuthMethod
+}
@@ -74,3 +74,32 @@ type swaggerQueryClientPayload struct {
 	// in: path
 	ID string `json:"id"`
 }
+
+// swagger:parameters registerOAuth2Client
+type swaggerRegisterClientPayload struct {
+	// in: body
+	// required: true
+	Body Client
+}
+
+// swagger:parameters updateOAuth2ClientRegistration
+type swaggerUpdateClientRegistrationPayload struct {
+	// The id of the OAuth 2.0 Client.
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	// required: true
+	Body Registration
+}
+
+// swagger:parameters getOAuth2ClientRegistration deleteOAuth2ClientRegistration
+type swaggerQueryClientRegistrationPayload struct {
+	// The id of the OAuth 2.0 Client.
+	//
+	// unique: true
+	// in: path
+	ID string `json:"id"`
+}
@@ -26,14 +26,19 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory/herodot"
+	"github.com/ory/hydra/jwk"
 	"github.com/ory/hydra/rand/sequence"
 	"github.com/ory/pagination"
 	"github.com/pkg/errors"
 )
 
 type Handler struct {
-	Manager Manager
-	H       herodot.Writer
+	Manager   Manager
+	H         herodot.Writer
+	Validator *Validator
+
+	// Cipher encrypts the secrets of clients using client_secret_jwt.
+	Cipher *jwk.AEAD
 }
 
 const (
@@ -90,9 +95,19 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 		return
 	}
 
+	if err := h.Validator.Validate(&c); err != nil {
+		h.H.WriteErrorCode(w, r, http.StatusBadRequest, err)
+		return
+	}
+
 	// has to be 0 because it is not supposed to be set
 	c.SecretExpiresAt = 0
 
+	if err := encryptSecret(h.Cipher, &c, c.Secret); err != nil {
+		h.H.WriteError(w, r, err)
+		return
+	}
+
 	secret := c.Secret
 	if err := h.Manager.CreateClient(&c); err != nil {
 		h.H.WriteError(w, r, err)
@@ -147,9 +162,29 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request, ps httprouter.P
 
 	c.ID = ps.ByName("id")
 
+	if err := h.Validator.Validate(&c); err != nil {
+		h.H.WriteErrorCode(w, r, http.StatusBadRequest, err)
+		return
+	}
+
 	// has to be 0 because it is not supposed to be set
 	c.SecretExpiresAt = 0
 
+	if c.TokenEndpointAuthMethod == TokenEndpointAuthMethodClientSecretJWT && secret == "" {
+		if o, err := h.Manager.GetConcreteClient(c.ID); err != nil {
+			h.H.WriteError(w, r, err)
+			return
+		} else if o.EncryptedSecret == "" {
+			h.H.WriteErrorCode(w, r, http.StatusBadRequest, errors.New("The client secret must be set when switching to token endpoint authentication method client_secret_jwt"))
+			return
+		}
+	}
+
+	if err := encryptSecret(h.Cipher, &c, secret); err != nil {
+		h.H.WriteError(w, r, err)
+		return
+	}
+
 	if err := h.Manager.UpdateClient(&c); err != nil {
 		h.H.WriteError(w, r, err)
 		return
@@ -159,6 +194,22 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request, ps httprouter.P
 	h.H.WriteCreated(w, r, ClientsHandlerPath+"/"+c.GetID(), &c)
 }
 
+// encryptSecret stores the encrypted secret of clients which use client_secret_jwt, as their assertions are signed
+// with the plaintext secret.
+func encryptSecret(cipher *jwk.AEAD, c *Client, secret string) error {
+	if c.TokenEndpointAuthMethod != TokenEndpointAuthMethodClientSecretJWT || secret == "" {
+		return nil
+	}
+
+	encrypted, err := cipher.Encrypt([]byte(secret))
+	if err != nil {
+		return err
+	}
+
+	c.EncryptedSecret = encrypted
+	return nil
+}
+
 // swagger:route GET /clients oAuth2 listOAuth2Clients
 //
 // List OAuth 2.0 Clients
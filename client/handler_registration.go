@@ -0,0 +1,348 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package client
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/fosite"
+	"github.com/ory/herodot"
+	"github.com/ory/hydra/jwk"
+	"github.com/ory/hydra/pkg"
+	"github.com/pkg/errors"
+)
+
+const (
+	RegistrationPath = "/oauth2/register"
+)
+
+// RegistrationHandler implements OAuth 2.0 Dynamic Client Registration (RFC 7591) and the OAuth 2.0 Dynamic Client
+// Registration Management Protocol (RFC 7592).
+type RegistrationHandler struct {
+	Manager   Manager
+	H         herodot.Writer
+	Validator *Validator
+
+	// Cipher encrypts the secrets of clients using client_secret_jwt.
+	Cipher *jwk.AEAD
+
+	IssuerURL string
+
+	// InitialAccessToken, if set, must be presented as a bearer token when registering a client.
+	InitialAccessToken string
+}
+
+func (h *RegistrationHandler) SetRoutes(r *httprouter.Router) {
+	r.POST(RegistrationPath, h.Register)
+	r.GET(RegistrationPath+"/:id", h.Get)
+	r.PUT(RegistrationPath+"/:id", h.Update)
+	r.DELETE(RegistrationPath+"/:id", h.Delete)
+}
+
+// swagger:route POST /oauth2/register oAuth2 registerOAuth2Client
+//
+// Register an OAuth 2.0 client
+//
+// This endpoint implements OAuth 2.0 Dynamic Client Registration (https://tools.ietf.org/html/rfc7591). The client
+// identifier and secret are always generated by the server. The response contains a `registration_access_token` and
+// a `registration_client_uri` which the client uses to read, update and delete its registration. Write the secret
+// and the registration access token down, they will not be returned again.
+//
+// Metadata which can only be set by an administrator (`owner`, `client_secret_expires_at`, `token_exchange_audiences`,
+// `token_exchange_scope` and `refresh_token_grace_period`) is ignored. Scopes of the administrative endpoints (`hydra`
+// and `hydra.*`) and grant types other than `authorization_code`, `implicit`, `refresh_token`, `client_credentials` and
+// the device code grant are rejected.
+//
+// This endpoint is only available if dynamic client registration is enabled. If an initial access token is configured,
+// it must be sent as a bearer token in the Authorization header.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       201: oAuth2ClientRegistration
+//       400: registrationError
+//       401: registrationError
+//       500: genericError
+func (h *RegistrationHandler) Register(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if h.InitialAccessToken != "" && subtle.ConstantTimeCompare([]byte(fosite.AccessTokenFromRequest(r)), []byte(h.InitialAccessToken)) != 1 {
+		h.writeError(w, r, newRegistrationError(ErrorInvalidToken, http.StatusUnauthorized, "The initial access token is missing or invalid"))
+		return
+	}
+
+	var c Client
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		h.writeError(w, r, newRegistrationError(ErrorInvalidClientMetadata, http.StatusBadRequest, "Unable to decode the request body: %s", err))
+		return
+	}
+
+	// The client identifier and secret are always issued by the server.
+	c.ID = ""
+	c.Secret = ""
+	copyAdministrativeMetadata(&c, new(Client))
+	if c.TokenEndpointAuthMethod == TokenEndpointAuthMethodNone {
+		c.Public = true
+	}
+
+	if err := h.Validator.ValidateRegistration(&c); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	secret, err := pkg.GenerateSecret(26)
+	if err != nil {
+		h.H.WriteError(w, r, errors.WithStack(err))
+		return
+	}
+	c.Secret = string(secret)
+
+	if err := encryptSecret(h.Cipher, &c, c.Secret); err != nil {
+		h.H.WriteError(w, r, err)
+		return
+	}
+
+	token, err := pkg.GenerateSecret(32)
+	if err != nil {
+		h.H.WriteError(w, r, errors.WithStack(err))
+		return
+	}
+	c.RegistrationAccessTokenSignature = registrationAccessTokenSignature(string(token))
+
+	if err := h.Manager.CreateClient(&c); err != nil {
+		h.H.WriteError(w, r, err)
+		return
+	}
+
+	c.Secret = ""
+	if !c.Public {
+		c.Secret = string(secret)
+	}
+
+	h.H.WriteCreated(w, r, h.registrationClientURI(c.ID), &Registration{
+		Client:                  &c,
+		ClientID:                c.ID,
+		RegistrationAccessToken: string(token),
+		RegistrationClientURI:   h.registrationClientURI(c.ID),
+	})
+}
+
+// swagger:route GET /oauth2/register/{id} oAuth2 getOAuth2ClientRegistration
+//
+// Read an OAuth 2.0 client registration
+//
+// This endpoint implements the client read request of the OAuth 2.0 Dynamic Client Registration Management Protocol
+// (https://tools.ietf.org/html/rfc7592). The registration access token must be sent as a bearer token in the
+// Authorization header. The client secret is never returned.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: oAuth2ClientRegistration
+//       401: registrationError
+//       500: genericError
+func (h *RegistrationHandler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	c, err := h.authenticate(r, ps.ByName("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	c.Secret = ""
+	h.H.Write(w, r, &Registration{
+		Client:                c,
+		ClientID:              c.ID,
+		RegistrationClientURI: h.registrationClientURI(c.ID),
+	})
+}
+
+// swagger:route PUT /oauth2/register/{id} oAuth2 updateOAuth2ClientRegistration
+//
+// Update an OAuth 2.0 client registration
+//
+// This endpoint implements the client update request of the OAuth 2.0 Dynamic Client Registration Management Protocol
+// (https://tools.ietf.org/html/rfc7592). The registration access token must be sent as a bearer token in the
+// Authorization header. The request body must contain the `client_id` and may contain the current `client_secret`,
+// the client secret itself can not be changed using this endpoint.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: oAuth2ClientRegistration
+//       400: registrationError
+//       401: registrationError
+//       500: genericError
+func (h *RegistrationHandler) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	o, err := h.authenticate(r, ps.ByName("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	req := Registration{Client: new(Client)}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, r, newRegistrationError(ErrorInvalidClientMetadata, http.StatusBadRequest, "Unable to decode the request body: %s", err))
+		return
+	}
+
+	if req.ClientID != o.ID {
+		h.writeError(w, r, newRegistrationError(ErrorInvalidClientMetadata, http.StatusBadRequest, "The client_id in the request body does not match the client being updated"))
+		return
+	}
+
+	if req.RegistrationAccessToken != "" || req.RegistrationClientURI != "" {
+		h.writeError(w, r, newRegistrationError(ErrorInvalidClientMetadata, http.StatusBadRequest, "The fields registration_access_token and registration_client_uri must not be included in the request body"))
+		return
+	}
+
+	c := req.Client
+	secret := c.Secret
+	if secret != "" {
+		if _, err := h.Manager.Authenticate(o.ID, []byte(secret)); err != nil {
+			h.writeError(w, r, newRegistrationError(ErrorInvalidClientMetadata, http.StatusBadRequest, "The client_secret in the request body does not match the current client secret"))
+			return
+		}
+	}
+
+	c.ID = o.ID
+	c.Secret = ""
+	c.RegistrationAccessTokenSignature = o.RegistrationAccessTokenSignature
+	copyAdministrativeMetadata(c, o)
+	if c.TokenEndpointAuthMethod == TokenEndpointAuthMethodNone {
+		c.Public = true
+	}
+
+	if err := h.Validator.ValidateRegistration(c); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if c.TokenEndpointAuthMethod == TokenEndpointAuthMethodClientSecretJWT && o.EncryptedSecret == "" {
+		if secret == "" {
+			h.writeError(w, r, newRegistrationError(ErrorInvalidClientMetadata, http.StatusBadRequest, "The current client_secret must be included in the request body when switching to token endpoint authentication method client_secret_jwt"))
+			return
+		} else if err := encryptSecret(h.Cipher, c, secret); err != nil {
+			h.H.WriteError(w, r, err)
+			return
+		}
+	}
+
+	if err := h.Manager.UpdateClient(c); err != nil {
+		h.H.WriteError(w, r, err)
+		return
+	}
+
+	c.Secret = ""
+	h.H.Write(w, r, &Registration{
+		Client:                c,
+		ClientID:              c.ID,
+		RegistrationClientURI: h.registrationClientURI(c.ID),
+	})
+}
+
+// swagger:route DELETE /oauth2/register/{id} oAuth2 deleteOAuth2ClientRegistration
+//
+// Delete an OAuth 2.0 client registration
+//
+// This endpoint implements the client delete request of the OAuth 2.0 Dynamic Client Registration Management Protocol
+// (https://tools.ietf.org/html/rfc7592). The registration access token must be sent as a bearer token in the
+// Authorization header.
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       401: registrationError
+//       500: genericError
+func (h *RegistrationHandler) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	c, err := h.authenticate(r, ps.ByName("id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	if err := h.Manager.DeleteClient(c.ID); err != nil {
+		h.H.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authenticate returns the client if the request carries its registration access token. Unknown clients are
+// indistinguishable from invalid tokens, as recommended by RFC 7592.
+func (h *RegistrationHandler) authenticate(r *http.Request, id string) (*Client, error) {
+	token := fosite.AccessTokenFromRequest(r)
+	if token == "" {
+		return nil, newRegistrationError(ErrorInvalidToken, http.StatusUnauthorized, "The registration access token is missing")
+	}
+
+	c, err := h.Manager.GetConcreteClient(id)
+	if err != nil || c.RegistrationAccessTokenSignature == "" ||
+		subtle.ConstantTimeCompare([]byte(c.RegistrationAccessTokenSignature), []byte(registrationAccessTokenSignature(token))) != 1 {
+		return nil, newRegistrationError(ErrorInvalidToken, http.StatusUnauthorized, "The registration access token is invalid")
+	}
+
+	return c, nil
+}
+
+// copyAdministrativeMetadata overwrites the metadata of c which can only be set by an administrator with the values of o.
+func copyAdministrativeMetadata(c, o *Client) {
+	c.Owner = o.Owner
+	c.SecretExpiresAt = o.SecretExpiresAt
+	c.TokenExchangeAudiences = o.TokenExchangeAudiences
+	c.TokenExchangeScope = o.TokenExchangeScope
+	c.RefreshTokenGracePeriod = o.RefreshTokenGracePeriod
+}
+
+func (h *RegistrationHandler) registrationClientURI(id string) string {
+	return strings.TrimRight(h.IssuerURL, "/") + RegistrationPath + "/" + id
+}
+
+func (h *RegistrationHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e, ok := errors.Cause(err).(*RegistrationError)
+	if !ok {
+		h.H.WriteError(w, r, err)
+		return
+	}
+
+	if e.Name == ErrorInvalidToken {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	h.H.WriteCode(w, r, e.StatusCode, e)
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/herodot"
+	"github.com/ory/hydra/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistrationHandler(t *testing.T) {
+	h := &RegistrationHandler{
+		Manager:            NewMemoryManager(nil),
+		H:                  herodot.NewJSONWriter(nil),
+		Validator:          NewValidator([]string{"public"}),
+		Cipher:             &jwk.AEAD{Key: []byte("some-secret-thats-random-and-long")},
+		IssuerURL:          "https://hydra.localhost/",
+		InitialAccessToken: "initial-token",
+	}
+
+	router := httprouter.New()
+	h.SetRoutes(router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	do := func(method, path, token string, body interface{}) (*http.Response, map[string]interface{}) {
+		var b bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&b).Encode(body))
+		}
+
+		req, err := http.NewRequest(method, ts.URL+path, &b)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		var out map[string]interface{}
+		json.NewDecoder(res.Body).Decode(&out)
+		return res, out
+	}
+
+	metadata := map[string]interface{}{
+		"client_name":   "my app",
+		"redirect_uris": []string{"https://app.localhost/cb"},
+		"id":            "attempt-to-set-id",
+		"owner":         "attempt-to-set-owner",
+
+		"token_exchange_audiences":   []string{"https://api.localhost/"},
+		"refresh_token_grace_period": 3600,
+	}
+
+	t.Run("case=registration requires the initial access token", func(t *testing.T) {
+		res, body := do("POST", RegistrationPath, "", metadata)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		assert.Equal(t, ErrorInvalidToken, body["error"])
+
+		res, _ = do("POST", RegistrationPath, "not-the-initial-token", metadata)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("case=invalid metadata is rejected", func(t *testing.T) {
+		for k, tc := range []struct {
+			metadata map[string]interface{}
+			error    string
+		}{
+			{metadata: map[string]interface{}{}, error: ErrorInvalidRedirectURI},
+			{metadata: map[string]interface{}{"redirect_uris": []string{"/relative"}}, error: ErrorInvalidRedirectURI},
+			{metadata: map[string]interface{}{"redirect_uris": []string{"https://app.localhost/cb#fragment"}}, error: ErrorInvalidRedirectURI},
+			{metadata: map[string]interface{}{"redirect_uris": []string{"https://app.localhost/cb"}, "response_types": []string{"token"}}, error: ErrorInvalidClientMetadata},
+			{metadata: map[string]interface{}{"redirect_uris": []string{"https://app.localhost/cb"}, "subject_type": "pairwise"}, error: ErrorInvalidClientMetadata},
+			{metadata: map[string]interface{}{"redirect_uris": []string{"https://app.localhost/cb"}, "logo_uri": "not-a-url"}, error: ErrorInvalidClientMetadata},
+			{metadata: map[string]interface{}{"redirect_uris": []string{"https://app.localhost/cb"}, "scope": "openid hydra.clients"}, error: ErrorInvalidClientMetadata},
+			{metadata: map[string]interface{}{"redirect_uris": []string{"https://app.localhost/cb"}, "scope": "hydra"}, error: ErrorInvalidClientMetadata},
+			{metadata: map[string]interface{}{"redirect_uris": []string{"https://app.localhost/cb"}, "scope": "*"}, error: ErrorInvalidClientMetadata},
+			{metadata: map[string]interface{}{"grant_types": []string{"urn:ietf:params:oauth:grant-type:token-exchange"}}, error: ErrorInvalidClientMetadata},
+			{metadata: map[string]interface{}{"grant_types": []string{"password"}}, error: ErrorInvalidClientMetadata},
+		} {
+			t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+				res, body := do("POST", RegistrationPath, "initial-token", tc.metadata)
+				assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+				assert.Equal(t, tc.error, body["error"])
+			})
+		}
+	})
+
+	res, registered := do("POST", RegistrationPath, "initial-token", metadata)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	id, _ := registered["client_id"].(string)
+	token, _ := registered["registration_access_token"].(string)
+	require.NotEmpty(t, id)
+	require.NotEmpty(t, token)
+	assert.NotEqual(t, "attempt-to-set-id", id)
+	assert.Empty(t, registered["owner"])
+	assert.Empty(t, registered["token_exchange_audiences"])
+	assert.EqualValues(t, 0, registered["refresh_token_grace_period"])
+	assert.NotEmpty(t, registered["client_secret"])
+	assert.Equal(t, "https://hydra.localhost/oauth2/register/"+id, registered["registration_client_uri"])
+
+	t.Run("case=the registration access token is required", func(t *testing.T) {
+		for _, method := range []string{"GET", "PUT", "DELETE"} {
+			res, body := do(method, RegistrationPath+"/"+id, "", nil)
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+			assert.Equal(t, ErrorInvalidToken, body["error"])
+
+			res, _ = do(method, RegistrationPath+"/"+id, "invalid-token", nil)
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+			assert.Contains(t, res.Header.Get("WWW-Authenticate"), "invalid_token")
+		}
+
+		res, _ := do("GET", RegistrationPath+"/does-not-exist", token, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("case=client reads its registration", func(t *testing.T) {
+		res, body := do("GET", RegistrationPath+"/"+id, token, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, id, body["client_id"])
+		assert.Equal(t, "my app", body["client_name"])
+		assert.Empty(t, body["client_secret"])
+		assert.Empty(t, body["registration_access_token"])
+	})
+
+	t.Run("case=client updates its registration", func(t *testing.T) {
+		res, _ := do("PUT", RegistrationPath+"/"+id, token, map[string]interface{}{
+			"client_id":     "some-other-client",
+			"redirect_uris": []string{"https://app.localhost/cb"},
+		})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		res, _ = do("PUT", RegistrationPath+"/"+id, token, map[string]interface{}{
+			"client_id":     id,
+			"client_secret": "not-the-secret",
+			"redirect_uris": []string{"https://app.localhost/cb"},
+		})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		res, body := do("PUT", RegistrationPath+"/"+id, token, map[string]interface{}{
+			"client_id":     id,
+			"client_secret": registered["client_secret"],
+			"client_name":   "my new app",
+			"redirect_uris": []string{"https://app.localhost/new-cb"},
+
+			"token_exchange_scope": "offline",
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "my new app", body["client_name"])
+		assert.Empty(t, body["client_secret"])
+
+		c, err := h.Manager.Authenticate(id, []byte(registered["client_secret"].(string)))
+		require.NoError(t, err)
+		assert.EqualValues(t, []string{"https://app.localhost/new-cb"}, c.RedirectURIs)
+		assert.Empty(t, c.TokenExchangeScope)
+	})
+
+	t.Run("case=client deletes its registration", func(t *testing.T) {
+		res, _ := do("DELETE", RegistrationPath+"/"+id, token, nil)
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		_, err := h.Manager.GetConcreteClient(id)
+		assert.Error(t, err)
+
+		res, _ = do("GET", RegistrationPath+"/"+id, token, nil)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/pkg"
+	"github.com/ory/pagination"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+const boltBucketClients = "hydra_client"
+
+// BoltManager stores clients in an embedded BoltDB database. Clients are stored by ID using the same encoding as rows
+// of the SQL manager.
+type BoltManager struct {
+	Hasher fosite.Hasher
+	DB     *bolt.DB
+}
+
+func (m *BoltManager) GetConcreteClient(id string) (*Client, error) {
+	var d sqlData
+	if err := m.DB.View(func(tx *bolt.Tx) error {
+		return pkg.BoltGet(tx, boltBucketClients, id, &d)
+	}); err != nil {
+		return nil, err
+	}
+
+	return d.ToClient()
+}
+
+func (m *BoltManager) GetClient(_ context.Context, id string) (fosite.Client, error) {
+	return m.GetConcreteClient(id)
+}
+
+func (m *BoltManager) UpdateClient(c *Client) error {
+	o, err := m.GetConcreteClient(c.ID)
+	if err != nil {
+		return err
+	}
+
+	if c.RegistrationAccessTokenSignature == "" {
+		c.RegistrationAccessTokenSignature = o.RegistrationAccessTokenSignature
+	}
+
+	if c.Secret == "" {
+		c.Secret = string(o.GetHashedSecret())
+		if c.EncryptedSecret == "" {
+			c.EncryptedSecret = o.EncryptedSecret
+		}
+	} else {
+		h, err := m.Hasher.Hash([]byte(c.Secret))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		c.Secret = string(h)
+	}
+
+	d, err := sqlDataFromClient(c)
+	if err != nil {
+		return err
+	}
+
+	return m.DB.Update(func(tx *bolt.Tx) error {
+		return pkg.BoltPut(tx, boltBucketClients, c.ID, d)
+	})
+}
+
+func (m *BoltManager) Authenticate(id string, secret []byte) (*Client, error) {
+	c, err := m.GetConcreteClient(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.Hasher.Compare(c.GetHashedSecret(), secret); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return c, nil
+}
+
+func (m *BoltManager) CreateClient(c *Client) error {
+	if c.ID == "" {
+		c.ID = uuid.New()
+	}
+
+	h, err := m.Hasher.Hash([]byte(c.Secret))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c.Secret = string(h)
+
+	d, err := sqlDataFromClient(c)
+	if err != nil {
+		return err
+	}
+
+	return m.DB.Update(func(tx *bolt.Tx) error {
+		var existing sqlData
+		if err := pkg.BoltGet(tx, boltBucketClients, c.ID, &existing); err == nil {
+			return errors.Errorf("Client %s already exists", c.ID)
+		} else if errors.Cause(err) != pkg.ErrNotFound {
+			return err
+		}
+
+		return pkg.BoltPut(tx, boltBucketClients, c.ID, d)
+	})
+}
+
+func (m *BoltManager) DeleteClient(id string) error {
+	return m.DB.Update(func(tx *bolt.Tx) error {
+		return pkg.BoltDelete(tx, boltBucketClients, id)
+	})
+}
+
+func (m *BoltManager) GetClients(limit, offset int) (map[string]Client, error) {
+	var ds []sqlData
+	if err := m.DB.View(func(tx *bolt.Tx) error {
+		// Bolt iterates in key order, which orders clients by ID just like the SQL manager.
+		return pkg.BoltForEach(tx, boltBucketClients, func(_ string, raw []byte) error {
+			var d sqlData
+			if err := json.Unmarshal(raw, &d); err != nil {
+				return errors.WithStack(err)
+			}
+			ds = append(ds, d)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	clients := make(map[string]Client)
+	start, end := pagination.Index(limit, offset, len(ds))
+	for _, d := range ds[start:end] {
+		c, err := d.ToClient()
+		if err != nil {
+			return nil, err
+		}
+		clients[d.ID] = *c
+	}
+	return clients, nil
+}
@@ -23,16 +23,20 @@ package client
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/ory/fosite"
 	"github.com/ory/go-convenience/stringsx"
+	"github.com/ory/hydra/jwk"
+	"github.com/ory/hydra/pkg"
 	"github.com/ory/sqlcon"
 	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
 	"github.com/rubenv/sql-migrate"
+	"github.com/square/go-jose"
 )
 
 var migrations = &migrate.MemoryMigrationSource{
@@ -68,6 +72,120 @@ var migrations = &migrate.MemoryMigrationSource{
 				`ALTER TABLE hydra_client DROP COLUMN client_secret_expires_at`,
 			},
 		},
+		{
+			Id: "3",
+			Up: []string{
+				`ALTER TABLE hydra_client ADD subject_type VARCHAR(15) NOT NULL DEFAULT ''`,
+				`ALTER TABLE hydra_client ADD sector_identifier_uri TEXT`,
+				`UPDATE hydra_client SET sector_identifier_uri=''`,
+			},
+			Down: []string{
+				`ALTER TABLE hydra_client DROP COLUMN subject_type`,
+				`ALTER TABLE hydra_client DROP COLUMN sector_identifier_uri`,
+			},
+		},
+		{
+			Id: "4",
+			Up: []string{
+				`ALTER TABLE hydra_client ADD registration_access_token_signature VARCHAR(128) NOT NULL DEFAULT ''`,
+			},
+			Down: []string{
+				`ALTER TABLE hydra_client DROP COLUMN registration_access_token_signature`,
+			},
+		},
+		{
+			Id: "5",
+			Up: []string{
+				`ALTER TABLE hydra_client ADD token_endpoint_auth_method VARCHAR(25) NOT NULL DEFAULT ''`,
+				`ALTER TABLE hydra_client ADD jwks TEXT`,
+				`ALTER TABLE hydra_client ADD jwks_uri TEXT`,
+				`ALTER TABLE hydra_client ADD client_secret_encrypted TEXT`,
+				`UPDATE hydra_client SET jwks='', jwks_uri='', client_secret_encrypted=''`,
+			},
+			Down: []string{
+				`ALTER TABLE hydra_client DROP COLUMN token_endpoint_auth_method`,
+				`ALTER TABLE hydra_client DROP COLUMN jwks`,
+				`ALTER TABLE hydra_client DROP COLUMN jwks_uri`,
+				`ALTER TABLE hydra_client DROP COLUMN client_secret_encrypted`,
+			},
+		},
+		{
+			Id: "6",
+			Up: []string{
+				`ALTER TABLE hydra_client ADD post_logout_redirect_uris TEXT`,
+				`UPDATE hydra_client SET post_logout_redirect_uris=''`,
+			},
+			Down: []string{
+				`ALTER TABLE hydra_client DROP COLUMN post_logout_redirect_uris`,
+			},
+		},
+		{
+			Id: "7",
+			Up: []string{
+				`ALTER TABLE hydra_client ADD backchannel_logout_uri TEXT`,
+				`ALTER TABLE hydra_client ADD backchannel_logout_session_required BOOL NOT NULL DEFAULT FALSE`,
+				`UPDATE hydra_client SET backchannel_logout_uri=''`,
+			},
+			Down: []string{
+				`ALTER TABLE hydra_client DROP COLUMN backchannel_logout_uri`,
+				`ALTER TABLE hydra_client DROP COLUMN backchannel_logout_session_required`,
+			},
+		},
+		{
+			Id: "8",
+			Up: []string{
+				`ALTER TABLE hydra_client ADD frontchannel_logout_uri TEXT`,
+				`ALTER TABLE hydra_client ADD frontchannel_logout_session_required BOOL NOT NULL DEFAULT FALSE`,
+				`UPDATE hydra_client SET frontchannel_logout_uri=''`,
+			},
+			Down: []string{
+				`ALTER TABLE hydra_client DROP COLUMN frontchannel_logout_uri`,
+				`ALTER TABLE hydra_client DROP COLUMN frontchannel_logout_session_required`,
+			},
+		},
+		{
+			Id: "9",
+			Up: []string{
+				`ALTER TABLE hydra_client ADD token_exchange_audiences TEXT`,
+				`ALTER TABLE hydra_client ADD token_exchange_scope TEXT`,
+				`UPDATE hydra_client SET token_exchange_audiences='', token_exchange_scope=''`,
+			},
+			Down: []string{
+				`ALTER TABLE hydra_client DROP COLUMN token_exchange_audiences`,
+				`ALTER TABLE hydra_client DROP COLUMN token_exchange_scope`,
+			},
+		},
+		{
+			Id: "10",
+			Up: []string{
+				`ALTER TABLE hydra_client ADD refresh_token_grace_period INTEGER NOT NULL DEFAULT 0`,
+			},
+			Down: []string{
+				`ALTER TABLE hydra_client DROP COLUMN refresh_token_grace_period`,
+			},
+		},
+		{
+			Id: "11",
+			Up: []string{
+				`ALTER TABLE hydra_client ADD userinfo_signed_response_alg VARCHAR(32) NOT NULL DEFAULT ''`,
+				`ALTER TABLE hydra_client ADD userinfo_encrypted_response_alg VARCHAR(32) NOT NULL DEFAULT ''`,
+				`ALTER TABLE hydra_client ADD userinfo_encrypted_response_enc VARCHAR(32) NOT NULL DEFAULT ''`,
+			},
+			Down: []string{
+				`ALTER TABLE hydra_client DROP COLUMN userinfo_signed_response_alg`,
+				`ALTER TABLE hydra_client DROP COLUMN userinfo_encrypted_response_alg`,
+				`ALTER TABLE hydra_client DROP COLUMN userinfo_encrypted_response_enc`,
+			},
+		},
+		{
+			Id: "12",
+			Up: []string{
+				`ALTER TABLE hydra_client ADD id_token_signed_response_alg VARCHAR(32) NOT NULL DEFAULT ''`,
+			},
+			Down: []string{
+				`ALTER TABLE hydra_client DROP COLUMN id_token_signed_response_alg`,
+			},
+		},
 	},
 }
 
@@ -77,21 +195,40 @@ type SQLManager struct {
 }
 
 type sqlData struct {
-	ID                string `db:"id"`
-	Name              string `db:"client_name"`
-	Secret            string `db:"client_secret"`
-	RedirectURIs      string `db:"redirect_uris"`
-	GrantTypes        string `db:"grant_types"`
-	ResponseTypes     string `db:"response_types"`
-	Scope             string `db:"scope"`
-	Owner             string `db:"owner"`
-	PolicyURI         string `db:"policy_uri"`
-	TermsOfServiceURI string `db:"tos_uri"`
-	ClientURI         string `db:"client_uri"`
-	LogoURI           string `db:"logo_uri"`
-	Contacts          string `db:"contacts"`
-	Public            bool   `db:"public"`
-	SecretExpiresAt   int    `db:"client_secret_expires_at"`
+	ID                                string `db:"id"`
+	Name                              string `db:"client_name"`
+	Secret                            string `db:"client_secret"`
+	RedirectURIs                      string `db:"redirect_uris"`
+	GrantTypes                        string `db:"grant_types"`
+	ResponseTypes                     string `db:"response_types"`
+	Scope                             string `db:"scope"`
+	Owner                             string `db:"owner"`
+	PolicyURI                         string `db:"policy_uri"`
+	TermsOfServiceURI                 string `db:"tos_uri"`
+	ClientURI                         string `db:"client_uri"`
+	LogoURI                           string `db:"logo_uri"`
+	Contacts                          string `db:"contacts"`
+	Public                            bool   `db:"public"`
+	SecretExpiresAt                   int    `db:"client_secret_expires_at"`
+	SubjectType                       string `db:"subject_type"`
+	SectorIdentifierURI               string `db:"sector_identifier_uri"`
+	RegistrationAccessTokenSignature  string `db:"registration_access_token_signature"`
+	TokenEndpointAuthMethod           string `db:"token_endpoint_auth_method"`
+	JSONWebKeys                       string `db:"jwks"`
+	JSONWebKeysURI                    string `db:"jwks_uri"`
+	EncryptedSecret                   string `db:"client_secret_encrypted"`
+	PostLogoutRedirectURIs            string `db:"post_logout_redirect_uris"`
+	BackChannelLogoutURI              string `db:"backchannel_logout_uri"`
+	BackChannelLogoutSessionRequired  bool   `db:"backchannel_logout_session_required"`
+	FrontChannelLogoutURI             string `db:"frontchannel_logout_uri"`
+	FrontChannelLogoutSessionRequired bool   `db:"frontchannel_logout_session_required"`
+	TokenExchangeAudiences            string `db:"token_exchange_audiences"`
+	TokenExchangeScope                string `db:"token_exchange_scope"`
+	RefreshTokenGracePeriod           int    `db:"refresh_token_grace_period"`
+	UserinfoSignedResponseAlg         string `db:"userinfo_signed_response_alg"`
+	UserinfoEncryptedResponseAlg      string `db:"userinfo_encrypted_response_alg"`
+	UserinfoEncryptedResponseEnc      string `db:"userinfo_encrypted_response_enc"`
+	IDTokenSignedResponseAlg          string `db:"id_token_signed_response_alg"`
 }
 
 var sqlParams = []string{
@@ -110,51 +247,125 @@ var sqlParams = []string{
 	"contacts",
 	"public",
 	"client_secret_expires_at",
+	"subject_type",
+	"sector_identifier_uri",
+	"registration_access_token_signature",
+	"token_endpoint_auth_method",
+	"jwks",
+	"jwks_uri",
+	"client_secret_encrypted",
+	"post_logout_redirect_uris",
+	"backchannel_logout_uri",
+	"backchannel_logout_session_required",
+	"frontchannel_logout_uri",
+	"frontchannel_logout_session_required",
+	"token_exchange_audiences",
+	"token_exchange_scope",
+	"refresh_token_grace_period",
+	"userinfo_signed_response_alg",
+	"userinfo_encrypted_response_alg",
+	"userinfo_encrypted_response_enc",
+	"id_token_signed_response_alg",
 }
 
-func sqlDataFromClient(d *Client) *sqlData {
-	return &sqlData{
-		ID:                d.ID,
-		Name:              d.Name,
-		Secret:            d.Secret,
-		RedirectURIs:      strings.Join(d.RedirectURIs, "|"),
-		GrantTypes:        strings.Join(d.GrantTypes, "|"),
-		ResponseTypes:     strings.Join(d.ResponseTypes, "|"),
-		Scope:             d.Scope,
-		Owner:             d.Owner,
-		PolicyURI:         d.PolicyURI,
-		TermsOfServiceURI: d.TermsOfServiceURI,
-		ClientURI:         d.ClientURI,
-		LogoURI:           d.LogoURI,
-		Contacts:          strings.Join(d.Contacts, "|"),
-		Public:            d.Public,
-		SecretExpiresAt:   d.SecretExpiresAt,
+func sqlDataFromClient(d *Client) (*sqlData, error) {
+	jwks := ""
+	if d.JSONWebKeys != nil {
+		out, err := json.Marshal(d.JSONWebKeys)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		jwks = string(out)
 	}
+
+	return &sqlData{
+		ID:                                d.ID,
+		Name:                              d.Name,
+		Secret:                            d.Secret,
+		RedirectURIs:                      strings.Join(d.RedirectURIs, "|"),
+		GrantTypes:                        strings.Join(d.GrantTypes, "|"),
+		ResponseTypes:                     strings.Join(d.ResponseTypes, "|"),
+		Scope:                             d.Scope,
+		Owner:                             d.Owner,
+		PolicyURI:                         d.PolicyURI,
+		TermsOfServiceURI:                 d.TermsOfServiceURI,
+		ClientURI:                         d.ClientURI,
+		LogoURI:                           d.LogoURI,
+		Contacts:                          strings.Join(d.Contacts, "|"),
+		Public:                            d.Public,
+		SecretExpiresAt:                   d.SecretExpiresAt,
+		SubjectType:                       d.SubjectType,
+		SectorIdentifierURI:               d.SectorIdentifierURI,
+		RegistrationAccessTokenSignature:  d.RegistrationAccessTokenSignature,
+		TokenEndpointAuthMethod:           d.TokenEndpointAuthMethod,
+		JSONWebKeys:                       jwks,
+		JSONWebKeysURI:                    d.JSONWebKeysURI,
+		EncryptedSecret:                   d.EncryptedSecret,
+		PostLogoutRedirectURIs:            strings.Join(d.PostLogoutRedirectURIs, "|"),
+		BackChannelLogoutURI:              d.BackChannelLogoutURI,
+		BackChannelLogoutSessionRequired:  d.BackChannelLogoutSessionRequired,
+		FrontChannelLogoutURI:             d.FrontChannelLogoutURI,
+		FrontChannelLogoutSessionRequired: d.FrontChannelLogoutSessionRequired,
+		TokenExchangeAudiences:            strings.Join(d.TokenExchangeAudiences, "|"),
+		TokenExchangeScope:                d.TokenExchangeScope,
+		RefreshTokenGracePeriod:           d.RefreshTokenGracePeriod,
+		UserinfoSignedResponseAlg:         d.UserinfoSignedResponseAlg,
+		UserinfoEncryptedResponseAlg:      d.UserinfoEncryptedResponseAlg,
+		UserinfoEncryptedResponseEnc:      d.UserinfoEncryptedResponseEnc,
+		IDTokenSignedResponseAlg:          d.IDTokenSignedResponseAlg,
+	}, nil
 }
 
-func (d *sqlData) ToClient() *Client {
-	return &Client{
-		ID:                d.ID,
-		Name:              d.Name,
-		Secret:            d.Secret,
-		RedirectURIs:      stringsx.Splitx(d.RedirectURIs, "|"),
-		GrantTypes:        stringsx.Splitx(d.GrantTypes, "|"),
-		ResponseTypes:     stringsx.Splitx(d.ResponseTypes, "|"),
-		Scope:             d.Scope,
-		Owner:             d.Owner,
-		PolicyURI:         d.PolicyURI,
-		TermsOfServiceURI: d.TermsOfServiceURI,
-		ClientURI:         d.ClientURI,
-		LogoURI:           d.LogoURI,
-		Contacts:          stringsx.Splitx(d.Contacts, "|"),
-		Public:            d.Public,
-		SecretExpiresAt:   d.SecretExpiresAt,
+func (d *sqlData) ToClient() (*Client, error) {
+	c := &Client{
+		ID:                                d.ID,
+		Name:                              d.Name,
+		Secret:                            d.Secret,
+		RedirectURIs:                      stringsx.Splitx(d.RedirectURIs, "|"),
+		GrantTypes:                        stringsx.Splitx(d.GrantTypes, "|"),
+		ResponseTypes:                     stringsx.Splitx(d.ResponseTypes, "|"),
+		Scope:                             d.Scope,
+		Owner:                             d.Owner,
+		PolicyURI:                         d.PolicyURI,
+		TermsOfServiceURI:                 d.TermsOfServiceURI,
+		ClientURI:                         d.ClientURI,
+		LogoURI:                           d.LogoURI,
+		Contacts:                          stringsx.Splitx(d.Contacts, "|"),
+		Public:                            d.Public,
+		SecretExpiresAt:                   d.SecretExpiresAt,
+		SubjectType:                       d.SubjectType,
+		SectorIdentifierURI:               d.SectorIdentifierURI,
+		RegistrationAccessTokenSignature:  d.RegistrationAccessTokenSignature,
+		TokenEndpointAuthMethod:           d.TokenEndpointAuthMethod,
+		JSONWebKeysURI:                    d.JSONWebKeysURI,
+		EncryptedSecret:                   d.EncryptedSecret,
+		PostLogoutRedirectURIs:            stringsx.Splitx(d.PostLogoutRedirectURIs, "|"),
+		BackChannelLogoutURI:              d.BackChannelLogoutURI,
+		BackChannelLogoutSessionRequired:  d.BackChannelLogoutSessionRequired,
+		FrontChannelLogoutURI:             d.FrontChannelLogoutURI,
+		FrontChannelLogoutSessionRequired: d.FrontChannelLogoutSessionRequired,
+		TokenExchangeAudiences:            stringsx.Splitx(d.TokenExchangeAudiences, "|"),
+		TokenExchangeScope:                d.TokenExchangeScope,
+		RefreshTokenGracePeriod:           d.RefreshTokenGracePeriod,
+		UserinfoSignedResponseAlg:         d.UserinfoSignedResponseAlg,
+		UserinfoEncryptedResponseAlg:      d.UserinfoEncryptedResponseAlg,
+		UserinfoEncryptedResponseEnc:      d.UserinfoEncryptedResponseEnc,
+		IDTokenSignedResponseAlg:          d.IDTokenSignedResponseAlg,
+	}
+
+	if d.JSONWebKeys != "" {
+		c.JSONWebKeys = new(jose.JSONWebKeySet)
+		if err := json.Unmarshal([]byte(d.JSONWebKeys), c.JSONWebKeys); err != nil {
+			return nil, errors.WithStack(err)
+		}
 	}
+
+	return c, nil
 }
 
 func (s *SQLManager) CreateSchemas() (int, error) {
 	migrate.SetTable("hydra_client_migration")
-	n, err := migrate.Exec(s.DB.DB, s.DB.DriverName(), migrations, migrate.Up)
+	n, err := migrate.Exec(s.DB.DB, s.DB.DriverName(), pkg.SQLMigrations(migrations, s.DB.DriverName(), nil), migrate.Up)
 	if err != nil {
 		return 0, errors.Wrapf(err, "Could not migrate sql schema, applied %d migrations", n)
 	}
@@ -169,7 +380,7 @@ func (m *SQLManager) GetConcreteClient(id string) (*Client, error) {
 		return nil, errors.WithStack(err)
 	}
 
-	return d.ToClient(), nil
+	return d.ToClient()
 }
 
 func (m *SQLManager) GetClient(_ context.Context, id string) (fosite.Client, error) {
@@ -177,13 +388,20 @@ func (m *SQLManager) GetClient(_ context.Context, id string) (fosite.Client, err
 }
 
 func (m *SQLManager) UpdateClient(c *Client) error {
-	o, err := m.GetClient(context.Background(), c.ID)
+	o, err := m.GetConcreteClient(c.ID)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
+	if c.RegistrationAccessTokenSignature == "" {
+		c.RegistrationAccessTokenSignature = o.RegistrationAccessTokenSignature
+	}
+
 	if c.Secret == "" {
 		c.Secret = string(o.GetHashedSecret())
+		if c.EncryptedSecret == "" {
+			c.EncryptedSecret = o.EncryptedSecret
+		}
 	} else {
 		h, err := m.Hasher.Hash([]byte(c.Secret))
 		if err != nil {
@@ -192,7 +410,11 @@ func (m *SQLManager) UpdateClient(c *Client) error {
 		c.Secret = string(h)
 	}
 
-	s := sqlDataFromClient(c)
+	s, err := sqlDataFromClient(c)
+	if err != nil {
+		return err
+	}
+
 	var query []string
 	for _, param := range sqlParams {
 		query = append(query, fmt.Sprintf("%s=:%s", param, param))
@@ -228,7 +450,11 @@ func (m *SQLManager) CreateClient(c *Client) error {
 	}
 	c.Secret = string(h)
 
-	data := sqlDataFromClient(c)
+	data, err := sqlDataFromClient(c)
+	if err != nil {
+		return err
+	}
+
 	if _, err := m.DB.NamedExec(fmt.Sprintf(
 		"INSERT INTO hydra_client (%s) VALUES (%s)",
 		strings.Join(sqlParams, ", "),
@@ -256,7 +482,54 @@ func (m *SQLManager) GetClients(limit, offset int) (clients map[string]Client, e
 	}
 
 	for _, k := range d {
-		clients[k.ID] = *k.ToClient()
+		c, err := k.ToClient()
+		if err != nil {
+			return nil, err
+		}
+		clients[k.ID] = *c
 	}
 	return clients, nil
 }
+
+// ReEncryptSecrets encrypts the encrypted secrets of all clients again using the current key of the cipher, which
+// allows removing the rotated keys from the cipher afterwards. It returns the number of secrets which have been
+// encrypted again.
+func (m *SQLManager) ReEncryptSecrets(cipher *jwk.AEAD) (int, error) {
+	var ds []sqlData
+	if err := m.DB.Select(&ds, "SELECT id, client_secret_encrypted FROM hydra_client WHERE client_secret_encrypted <> ''"); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	rollback := func(err error) (int, error) {
+		if re := tx.Rollback(); re != nil {
+			return 0, errors.Wrap(err, re.Error())
+		}
+		return 0, err
+	}
+
+	for _, d := range ds {
+		secret, err := cipher.Decrypt(d.EncryptedSecret)
+		if err != nil {
+			return rollback(errors.Wrapf(err, "Could not decrypt the secret of client %s", d.ID))
+		}
+
+		encrypted, err := cipher.Encrypt(secret)
+		if err != nil {
+			return rollback(err)
+		}
+
+		if _, err := tx.Exec(tx.Rebind(`UPDATE hydra_client SET client_secret_encrypted=? WHERE id=?`), encrypted, d.ID); err != nil {
+			return rollback(errors.WithStack(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return rollback(errors.WithStack(err))
+	}
+	return len(ds), nil
+}
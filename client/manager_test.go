@@ -30,6 +30,7 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/ory/fosite"
 	. "github.com/ory/hydra/client"
+	"github.com/ory/hydra/pkg"
 	"github.com/ory/sqlcon/dockertest"
 )
 
@@ -37,12 +38,14 @@ var clientManagers = map[string]Manager{}
 
 func init() {
 	clientManagers["memory"] = NewMemoryManager(&fosite.BCrypt{})
+	clientManagers["bolt"] = &BoltManager{DB: pkg.BoltTestDatabase(), Hasher: &fosite.BCrypt{WorkFactor: 4}}
 }
 
 func TestMain(m *testing.M) {
 	runner := dockertest.Register()
 
 	flag.Parse()
+	connectToSQLite()
 	if !testing.Short() {
 		dockertest.Parallel([]func(){
 			connectToPG,
@@ -50,7 +53,9 @@ func TestMain(m *testing.M) {
 		})
 	}
 
-	runner.Exit(m.Run())
+	code := m.Run()
+	pkg.CleanupTestDatabases()
+	runner.Exit(code)
 }
 
 func connectToMySQL() {
@@ -67,6 +72,15 @@ func connectToMySQL() {
 	clientManagers["mysql"] = s
 }
 
+func connectToSQLite() {
+	s := &SQLManager{DB: pkg.SQLiteTestDatabase(), Hasher: &fosite.BCrypt{WorkFactor: 4}}
+	if _, err := s.CreateSchemas(); err != nil {
+		log.Fatalf("Could not create schema: %v", err)
+	}
+
+	clientManagers["sqlite"] = s
+}
+
 func connectToPG() {
 	db, err := dockertest.ConnectToTestPostgreSQL()
 	if err != nil {
@@ -81,12 +81,28 @@ func TestHelperCreateGetDeleteClient(k string, m Storage) func(t *testing.T) {
 		}
 
 		assert.NoError(t, m.CreateClient(&Client{
-			ID:                "2-1234",
-			Name:              "name",
-			Secret:            "secret",
-			RedirectURIs:      []string{"http://redirect"},
-			TermsOfServiceURI: "foo",
-			SecretExpiresAt:   1,
+			ID:                                "2-1234",
+			Name:                              "name",
+			Secret:                            "secret",
+			RedirectURIs:                      []string{"http://redirect"},
+			TermsOfServiceURI:                 "foo",
+			SecretExpiresAt:                   1,
+			SubjectType:                       "pairwise",
+			SectorIdentifierURI:               "https://sector/redirect_uris.json",
+			TokenEndpointAuthMethod:           TokenEndpointAuthMethodPrivateKeyJWT,
+			JSONWebKeysURI:                    "https://client/jwks.json",
+			PostLogoutRedirectURIs:            []string{"http://redirect/logout"},
+			BackChannelLogoutURI:              "http://redirect/backchannel-logout",
+			BackChannelLogoutSessionRequired:  true,
+			FrontChannelLogoutURI:             "http://redirect/frontchannel-logout",
+			FrontChannelLogoutSessionRequired: true,
+			TokenExchangeAudiences:            []string{"https://api.example.com"},
+			TokenExchangeScope:                "foo bar",
+			RefreshTokenGracePeriod:           30,
+			UserinfoSignedResponseAlg:         "RS256",
+			UserinfoEncryptedResponseAlg:      "RSA-OAEP",
+			UserinfoEncryptedResponseEnc:      "A128GCM",
+			IDTokenSignedResponseAlg:          "ES256",
 		}))
 
 		d, err := m.GetClient(nil, "1234")
@@ -105,6 +121,23 @@ func TestHelperCreateGetDeleteClient(k string, m Storage) func(t *testing.T) {
 		assert.Equal(t, ds["1234"].SecretExpiresAt, 0)
 		assert.Equal(t, ds["2-1234"].SecretExpiresAt, 1)
 
+		assert.Equal(t, "pairwise", ds["2-1234"].SubjectType)
+		assert.Equal(t, "https://sector/redirect_uris.json", ds["2-1234"].SectorIdentifierURI)
+		assert.Equal(t, TokenEndpointAuthMethodPrivateKeyJWT, ds["2-1234"].TokenEndpointAuthMethod)
+		assert.Equal(t, "https://client/jwks.json", ds["2-1234"].JSONWebKeysURI)
+		assert.EqualValues(t, []string{"http://redirect/logout"}, ds["2-1234"].PostLogoutRedirectURIs)
+		assert.Equal(t, "http://redirect/backchannel-logout", ds["2-1234"].BackChannelLogoutURI)
+		assert.True(t, ds["2-1234"].BackChannelLogoutSessionRequired)
+		assert.Equal(t, "http://redirect/frontchannel-logout", ds["2-1234"].FrontChannelLogoutURI)
+		assert.True(t, ds["2-1234"].FrontChannelLogoutSessionRequired)
+		assert.EqualValues(t, []string{"https://api.example.com"}, ds["2-1234"].TokenExchangeAudiences)
+		assert.Equal(t, "foo bar", ds["2-1234"].TokenExchangeScope)
+		assert.Equal(t, 30, ds["2-1234"].RefreshTokenGracePeriod)
+		assert.Equal(t, "RS256", ds["2-1234"].UserinfoSignedResponseAlg)
+		assert.Equal(t, "RSA-OAEP", ds["2-1234"].UserinfoEncryptedResponseAlg)
+		assert.Equal(t, "A128GCM", ds["2-1234"].UserinfoEncryptedResponseEnc)
+		assert.Equal(t, "ES256", ds["2-1234"].IDTokenSignedResponseAlg)
+
 		ds, err = m.GetClients(1, 0)
 		assert.NoError(t, err)
 		assert.Len(t, ds, 1)
@@ -0,0 +1,169 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package client
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ory/go-convenience/stringslice"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ErrorInvalidRedirectURI indicates that the value of one or more redirection URIs is invalid.
+	ErrorInvalidRedirectURI = "invalid_redirect_uri"
+
+	// ErrorInvalidClientMetadata indicates that the value of one of the client metadata fields is invalid.
+	ErrorInvalidClientMetadata = "invalid_client_metadata"
+
+	// ErrorInvalidToken indicates that the initial or registration access token is missing or invalid.
+	ErrorInvalidToken = "invalid_token"
+)
+
+// RegistrationError is an error response as defined in RFC 7591, section 3.2.2.
+//
+// swagger:model registrationError
+type RegistrationError struct {
+	// Name is a single ASCII error code.
+	Name string `json:"error"`
+
+	// Description is a human-readable description of the error.
+	Description string `json:"error_description,omitempty"`
+
+	// StatusCode is the HTTP status code which is used when the error is written.
+	StatusCode int `json:"-"`
+}
+
+func (e *RegistrationError) Error() string {
+	return e.Name + ": " + e.Description
+}
+
+func newRegistrationError(name string, code int, format string, args ...interface{}) error {
+	return errors.WithStack(&RegistrationError{
+		Name:        name,
+		Description: fmt.Sprintf(format, args...),
+		StatusCode:  code,
+	})
+}
+
+// registrationGrantTypes are the grant types a dynamically registered client may use. Other grant types, such as token
+// exchange, depend on metadata which can only be set by an administrator.
+var registrationGrantTypes = []string{
+	"authorization_code",
+	"implicit",
+	"refresh_token",
+	"client_credentials",
+	"urn:ietf:params:oauth:grant-type:device_code",
+}
+
+// Registration is the client information response of RFC 7591 and the client update request of RFC 7592.
+//
+// swagger:model oAuth2ClientRegistration
+type Registration struct {
+	*Client
+
+	// ClientID is the unique client identifier issued by the authorization server.
+	ClientID string `json:"client_id"`
+
+	// RegistrationAccessToken is used at the client configuration endpoint to read, update and delete the client.
+	// It is only returned once, when the client is registered.
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+
+	// RegistrationClientURI is the location of the client configuration endpoint.
+	RegistrationClientURI string `json:"registration_client_uri,omitempty"`
+}
+
+func registrationAccessTokenSignature(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
+
+// ValidateRegistration checks the metadata of a dynamically registered client as required by RFC 7591.
+func (v *Validator) ValidateRegistration(c *Client) error {
+	for _, r := range c.RedirectURIs {
+		u, err := url.Parse(r)
+		if err != nil {
+			return newRegistrationError(ErrorInvalidRedirectURI, http.StatusBadRequest, "Redirect URI %s could not be parsed: %s", r, err)
+		} else if !u.IsAbs() {
+			return newRegistrationError(ErrorInvalidRedirectURI, http.StatusBadRequest, "Redirect URI %s must be an absolute URI", r)
+		} else if u.Fragment != "" {
+			return newRegistrationError(ErrorInvalidRedirectURI, http.StatusBadRequest, "Redirect URI %s must not contain a fragment", r)
+		}
+	}
+
+	grantTypes := c.GetGrantTypes()
+	for _, gt := range grantTypes {
+		if !stringslice.Has(registrationGrantTypes, gt) {
+			return newRegistrationError(ErrorInvalidClientMetadata, http.StatusBadRequest, "Grant type %s is not allowed for dynamically registered clients, only %v are allowed", gt, registrationGrantTypes)
+		}
+	}
+
+	for _, scope := range strings.Fields(c.Scope) {
+		// Scopes of the administrative endpoints, including wildcards which would match them, can only be granted by
+		// an administrator.
+		if root := strings.SplitN(scope, ".", 2)[0]; root == "hydra" || root == "*" {
+			return newRegistrationError(ErrorInvalidClientMetadata, http.StatusBadRequest, "Scope %s is reserved and can not be requested by dynamically registered clients", scope)
+		}
+	}
+
+	if (grantTypes.Has("authorization_code") || grantTypes.Has("implicit")) && len(c.RedirectURIs) == 0 {
+		return newRegistrationError(ErrorInvalidRedirectURI, http.StatusBadRequest, "At least one redirect URI is required for grant types authorization_code and implicit")
+	}
+
+	for _, responseType := range c.GetResponseTypes() {
+		for _, rt := range strings.Fields(responseType) {
+			switch rt {
+			case "code":
+				if !grantTypes.Has("authorization_code") {
+					return newRegistrationError(ErrorInvalidClientMetadata, http.StatusBadRequest, "Response type code requires grant type authorization_code")
+				}
+			case "token", "id_token":
+				if !grantTypes.Has("implicit") {
+					return newRegistrationError(ErrorInvalidClientMetadata, http.StatusBadRequest, "Response type %s requires grant type implicit", rt)
+				}
+			}
+		}
+	}
+
+	for name, value := range map[string]string{
+		"client_uri": c.ClientURI,
+		"logo_uri":   c.LogoURI,
+		"policy_uri": c.PolicyURI,
+		"tos_uri":    c.TermsOfServiceURI,
+	} {
+		if value == "" {
+			continue
+		}
+
+		if u, err := url.Parse(value); err != nil || !u.IsAbs() {
+			return newRegistrationError(ErrorInvalidClientMetadata, http.StatusBadRequest, "Value of %s must be an absolute URI", name)
+		}
+	}
+
+	if err := v.Validate(c); err != nil {
+		return newRegistrationError(ErrorInvalidClientMetadata, http.StatusBadRequest, "%s", err)
+	}
+
+	return nil
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory/herodot"
 	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/jwk"
 	hydra "github.com/ory/hydra/sdk/go/hydra/swagger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -49,14 +50,17 @@ func createTestClient(prefix string) hydra.OAuth2Client {
 		ResponseTypes:         []string{prefix + "id_token", prefix + "code"},
 		RedirectUris:          []string{prefix + "redirect-url", prefix + "redirect-uri"},
 		ClientSecretExpiresAt: 0,
+		SubjectType:           "public",
 	}
 }
 
 func TestClientSDK(t *testing.T) {
 	manager := client.NewMemoryManager(nil)
 	handler := &client.Handler{
-		Manager: manager,
-		H:       herodot.NewJSONWriter(nil),
+		Manager:   manager,
+		H:         herodot.NewJSONWriter(nil),
+		Validator: client.NewValidator([]string{"public"}),
+		Cipher:    &jwk.AEAD{Key: []byte("some-secret-thats-random-and-long")},
 	}
 
 	router := httprouter.New()
@@ -0,0 +1,238 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ory/go-convenience/stringslice"
+	"github.com/pkg/errors"
+	"github.com/square/go-jose"
+)
+
+const subjectTypePairwise = "pairwise"
+
+// UserinfoEncryptionAlgorithms are the JWE alg values supported for encrypting userinfo responses.
+var UserinfoEncryptionAlgorithms = []string{
+	string(jose.RSA1_5),
+	string(jose.RSA_OAEP),
+	string(jose.RSA_OAEP_256),
+	string(jose.ECDH_ES),
+	string(jose.ECDH_ES_A128KW),
+	string(jose.ECDH_ES_A192KW),
+	string(jose.ECDH_ES_A256KW),
+}
+
+// UserinfoEncryptionEncodings are the JWE enc values supported for encrypting userinfo responses.
+var UserinfoEncryptionEncodings = []string{
+	string(jose.A128CBC_HS256),
+	string(jose.A192CBC_HS384),
+	string(jose.A256CBC_HS512),
+	string(jose.A128GCM),
+	string(jose.A192GCM),
+	string(jose.A256GCM),
+}
+
+// Validator checks the OpenID Connect related metadata of a client before it is persisted.
+type Validator struct {
+	SubjectTypes []string
+
+	// IDTokenSigningAlgorithms are the JWS alg values ID Tokens can be signed with, which depend on the OpenID Connect
+	// signing keys.
+	IDTokenSigningAlgorithms []string
+
+	// UserinfoSigningAlgorithms are the JWS alg values userinfo responses can be signed with, which depend on the
+	// OpenID Connect signing keys.
+	UserinfoSigningAlgorithms []string
+
+	c *http.Client
+}
+
+func NewValidator(subjectTypes []string) *Validator {
+	return &Validator{
+		SubjectTypes: subjectTypes,
+		c:            &http.Client{Timeout: time.Second * 10},
+	}
+}
+
+func (v *Validator) Validate(c *Client) error {
+	switch c.TokenEndpointAuthMethod {
+	case "", TokenEndpointAuthMethodClientSecretBasic, TokenEndpointAuthMethodClientSecretPost, TokenEndpointAuthMethodClientSecretJWT:
+		if c.Public && c.TokenEndpointAuthMethod != "" {
+			return errors.Errorf("Public clients must use token_endpoint_auth_method none, got %s", c.TokenEndpointAuthMethod)
+		}
+	case TokenEndpointAuthMethodPrivateKeyJWT:
+		if c.Public {
+			return errors.Errorf("Public clients must use token_endpoint_auth_method none, got %s", c.TokenEndpointAuthMethod)
+		} else if c.JSONWebKeys == nil && c.JSONWebKeysURI == "" {
+			return errors.New("When token_endpoint_auth_method is private_key_jwt, either jwks or jwks_uri must be set")
+		}
+	case TokenEndpointAuthMethodNone:
+		if !c.Public {
+			return errors.New("Only public clients may use token_endpoint_auth_method none")
+		}
+	default:
+		return errors.Errorf("Value %s of token_endpoint_auth_method is not supported", c.TokenEndpointAuthMethod)
+	}
+
+	if c.JSONWebKeys != nil && c.JSONWebKeysURI != "" {
+		return errors.New("Fields jwks and jwks_uri can not both be set")
+	}
+
+	if c.JSONWebKeysURI != "" {
+		if u, err := url.Parse(c.JSONWebKeysURI); err != nil || !u.IsAbs() {
+			return errors.Errorf("Value of jwks_uri must be an absolute URL")
+		}
+	}
+
+	if c.JSONWebKeys != nil {
+		for _, key := range c.JSONWebKeys.Keys {
+			switch key.Key.(type) {
+			case *rsa.PublicKey, *ecdsa.PublicKey:
+			default:
+				return errors.Errorf("Key %s in jwks must be a RSA or ECDSA public key", key.KeyID)
+			}
+		}
+	}
+
+	for _, r := range c.PostLogoutRedirectURIs {
+		if u, err := url.Parse(r); err != nil || !u.IsAbs() {
+			return errors.Errorf("Post logout redirect URI %s must be an absolute URL", r)
+		} else if u.Fragment != "" {
+			return errors.Errorf("Post logout redirect URI %s must not contain a fragment", r)
+		}
+	}
+
+	if c.BackChannelLogoutURI != "" {
+		if u, err := url.Parse(c.BackChannelLogoutURI); err != nil || !u.IsAbs() {
+			return errors.New("Value of backchannel_logout_uri must be an absolute URL")
+		} else if u.Fragment != "" {
+			return errors.New("Value of backchannel_logout_uri must not contain a fragment")
+		}
+	} else if c.BackChannelLogoutSessionRequired {
+		return errors.New("Field backchannel_logout_session_required can only be set together with backchannel_logout_uri")
+	}
+
+	if c.FrontChannelLogoutURI != "" {
+		if u, err := url.Parse(c.FrontChannelLogoutURI); err != nil || !u.IsAbs() {
+			return errors.New("Value of frontchannel_logout_uri must be an absolute URL")
+		} else if u.Fragment != "" {
+			return errors.New("Value of frontchannel_logout_uri must not contain a fragment")
+		}
+	} else if c.FrontChannelLogoutSessionRequired {
+		return errors.New("Field frontchannel_logout_session_required can only be set together with frontchannel_logout_uri")
+	}
+
+	if c.RefreshTokenGracePeriod < 0 {
+		return errors.New("Value of refresh_token_grace_period must not be negative")
+	}
+
+	if c.IDTokenSignedResponseAlg != "" && !stringslice.Has(v.IDTokenSigningAlgorithms, c.IDTokenSignedResponseAlg) {
+		return errors.Errorf("Value %s of id_token_signed_response_alg is not supported by this server, only %v are allowed", c.IDTokenSignedResponseAlg, v.IDTokenSigningAlgorithms)
+	}
+
+	if c.UserinfoSignedResponseAlg != "" && !stringslice.Has(v.UserinfoSigningAlgorithms, c.UserinfoSignedResponseAlg) {
+		return errors.Errorf("Value %s of userinfo_signed_response_alg is not supported by this server, only %v are allowed", c.UserinfoSignedResponseAlg, v.UserinfoSigningAlgorithms)
+	}
+
+	if c.UserinfoEncryptedResponseAlg != "" {
+		if !stringslice.Has(UserinfoEncryptionAlgorithms, c.UserinfoEncryptedResponseAlg) {
+			return errors.Errorf("Value %s of userinfo_encrypted_response_alg is not supported, only %v are allowed", c.UserinfoEncryptedResponseAlg, UserinfoEncryptionAlgorithms)
+		} else if c.JSONWebKeys == nil && c.JSONWebKeysURI == "" {
+			return errors.New("When userinfo_encrypted_response_alg is set, either jwks or jwks_uri must be set")
+		}
+	} else if c.UserinfoEncryptedResponseEnc != "" {
+		return errors.New("Field userinfo_encrypted_response_enc can only be set together with userinfo_encrypted_response_alg")
+	}
+
+	if c.UserinfoEncryptedResponseEnc != "" && !stringslice.Has(UserinfoEncryptionEncodings, c.UserinfoEncryptedResponseEnc) {
+		return errors.Errorf("Value %s of userinfo_encrypted_response_enc is not supported, only %v are allowed", c.UserinfoEncryptedResponseEnc, UserinfoEncryptionEncodings)
+	}
+
+	if c.SubjectType != "" && !stringslice.Has(v.SubjectTypes, c.SubjectType) {
+		return errors.Errorf("Subject type %s is not supported by this server, only %v are allowed", c.SubjectType, v.SubjectTypes)
+	}
+
+	if c.SectorIdentifierURI != "" {
+		if err := v.validateSectorIdentifierURI(c.SectorIdentifierURI, c.RedirectURIs); err != nil {
+			return err
+		}
+	} else if c.SubjectType == subjectTypePairwise {
+		// Without a sector identifier, the host of the redirect URIs is used as the sector and thus must be unique.
+		var host string
+		for _, r := range c.RedirectURIs {
+			u, err := url.Parse(r)
+			if err != nil {
+				return errors.Errorf("Redirect URI %s is not a valid URL: %s", r, err)
+			}
+
+			if host != "" && host != u.Host {
+				return errors.New("Clients with subject type pairwise must set sector_identifier_uri if their redirect URIs use more than one host")
+			}
+			host = u.Host
+		}
+	}
+
+	return nil
+}
+
+func (v *Validator) validateSectorIdentifierURI(location string, redirectURIs []string) error {
+	l, err := url.Parse(location)
+	if err != nil {
+		return errors.Errorf("Value of sector_identifier_uri could not be parsed: %s", err)
+	}
+
+	if l.Scheme != "https" {
+		return errors.Errorf("Value of sector_identifier_uri must use the https scheme, got %s", l.Scheme)
+	}
+
+	response, err := v.c.Get(location)
+	if err != nil {
+		return errors.Errorf("Unable to connect to sector_identifier_uri %s: %s", location, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return errors.Errorf("Expected sector_identifier_uri %s to respond with status code 200 but got %d", location, response.StatusCode)
+	}
+
+	var urls []string
+	if err := json.NewDecoder(response.Body).Decode(&urls); err != nil {
+		return errors.Errorf("Unable to decode response of sector_identifier_uri %s as a JSON array: %s", location, err)
+	}
+
+	if len(urls) == 0 {
+		return errors.Errorf("Array from sector_identifier_uri %s is empty", location)
+	}
+
+	for _, r := range redirectURIs {
+		if !stringslice.Has(urls, r) {
+			return errors.Errorf("Redirect URI %s is missing from the array at sector_identifier_uri %s", r, location)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package client
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]string{"https://foo/cb", "https://bar/cb"})
+	}))
+	defer ts.Close()
+
+	v := NewValidator([]string{"pairwise", "public"})
+	v.IDTokenSigningAlgorithms = []string{"RS256", "EdDSA"}
+	v.UserinfoSigningAlgorithms = []string{"RS256"}
+	v.c = ts.Client()
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	for k, tc := range []struct {
+		in        *Client
+		expectErr bool
+	}{
+		{in: &Client{RedirectURIs: []string{"https://foo/cb"}}},
+		{in: &Client{SubjectType: "public", RedirectURIs: []string{"https://foo/cb", "https://bar/cb"}}},
+		{in: &Client{SubjectType: "pairwise", RedirectURIs: []string{"https://foo/cb", "https://foo/other-cb"}}},
+		{in: &Client{SubjectType: "pairwise", RedirectURIs: []string{"https://foo/cb", "https://bar/cb"}}, expectErr: true},
+		{in: &Client{SubjectType: "pairwise", SectorIdentifierURI: ts.URL, RedirectURIs: []string{"https://foo/cb", "https://bar/cb"}}},
+		{in: &Client{SubjectType: "pairwise", SectorIdentifierURI: ts.URL, RedirectURIs: []string{"https://baz/cb"}}, expectErr: true},
+		{in: &Client{SubjectType: "pairwise", SectorIdentifierURI: "http://foo/sector.json"}, expectErr: true},
+		{in: &Client{SubjectType: "foo"}, expectErr: true},
+		{in: &Client{TokenEndpointAuthMethod: "foo"}, expectErr: true},
+		{in: &Client{TokenEndpointAuthMethod: "none"}, expectErr: true},
+		{in: &Client{TokenEndpointAuthMethod: "none", Public: true}},
+		{in: &Client{TokenEndpointAuthMethod: "client_secret_basic", Public: true}, expectErr: true},
+		{in: &Client{TokenEndpointAuthMethod: "client_secret_jwt"}},
+		{in: &Client{TokenEndpointAuthMethod: "private_key_jwt"}, expectErr: true},
+		{in: &Client{TokenEndpointAuthMethod: "private_key_jwt", JSONWebKeysURI: "https://foo/jwks.json"}},
+		{in: &Client{TokenEndpointAuthMethod: "private_key_jwt", JSONWebKeysURI: "/jwks.json"}, expectErr: true},
+		{in: &Client{TokenEndpointAuthMethod: "private_key_jwt", JSONWebKeys: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey}}}}},
+		{in: &Client{TokenEndpointAuthMethod: "private_key_jwt", JSONWebKeys: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key}}}}, expectErr: true},
+		{in: &Client{JSONWebKeysURI: "https://foo/jwks.json", JSONWebKeys: &jose.JSONWebKeySet{}}, expectErr: true},
+		{in: &Client{PostLogoutRedirectURIs: []string{"https://foo/logout"}}},
+		{in: &Client{PostLogoutRedirectURIs: []string{"/logout"}}, expectErr: true},
+		{in: &Client{PostLogoutRedirectURIs: []string{"https://foo/logout#bar"}}, expectErr: true},
+		{in: &Client{BackChannelLogoutURI: "https://foo/backchannel-logout", BackChannelLogoutSessionRequired: true}},
+		{in: &Client{BackChannelLogoutURI: "/backchannel-logout"}, expectErr: true},
+		{in: &Client{BackChannelLogoutSessionRequired: true}, expectErr: true},
+		{in: &Client{FrontChannelLogoutURI: "https://foo/frontchannel-logout", FrontChannelLogoutSessionRequired: true}},
+		{in: &Client{FrontChannelLogoutURI: "/frontchannel-logout"}, expectErr: true},
+		{in: &Client{FrontChannelLogoutSessionRequired: true}, expectErr: true},
+		{in: &Client{RefreshTokenGracePeriod: 30}},
+		{in: &Client{RefreshTokenGracePeriod: -1}, expectErr: true},
+		{in: &Client{IDTokenSignedResponseAlg: "EdDSA"}},
+		{in: &Client{IDTokenSignedResponseAlg: "ES256"}, expectErr: true},
+		{in: &Client{UserinfoSignedResponseAlg: "RS256"}},
+		{in: &Client{UserinfoSignedResponseAlg: "ES256"}, expectErr: true},
+		{in: &Client{UserinfoEncryptedResponseAlg: "RSA-OAEP", JSONWebKeysURI: "https://foo/jwks.json"}},
+		{in: &Client{UserinfoEncryptedResponseAlg: "RSA-OAEP", UserinfoEncryptedResponseEnc: "A256GCM", JSONWebKeysURI: "https://foo/jwks.json"}},
+		{in: &Client{UserinfoEncryptedResponseAlg: "RSA-OAEP"}, expectErr: true},
+		{in: &Client{UserinfoEncryptedResponseAlg: "dir", JSONWebKeysURI: "https://foo/jwks.json"}, expectErr: true},
+		{in: &Client{UserinfoEncryptedResponseAlg: "RSA-OAEP", UserinfoEncryptedResponseEnc: "foo", JSONWebKeysURI: "https://foo/jwks.json"}, expectErr: true},
+		{in: &Client{UserinfoEncryptedResponseEnc: "A256GCM"}, expectErr: true},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			err := v.Validate(tc.in)
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	assert.Error(t, NewValidator([]string{"public"}).Validate(&Client{SubjectType: "pairwise"}))
+}
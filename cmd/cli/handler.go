@@ -26,6 +26,7 @@ import (
 
 type Handler struct {
 	Clients       *ClientHandler
+	Consent       *ConsentHandler
 	Keys          *JWKHandler
 	Introspection *IntrospectionHandler
 	Token         *TokenHandler
@@ -35,6 +36,7 @@ type Handler struct {
 func NewHandler(c *config.Config) *Handler {
 	return &Handler{
 		Clients:       newClientHandler(c),
+		Consent:       newConsentHandler(c),
 		Keys:          newJWKHandler(c),
 		Introspection: newIntrospectionHandler(c),
 		Token:         newTokenHandler(c),
@@ -28,6 +28,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/ory/hydra/admin"
 	"github.com/ory/hydra/config"
 	"github.com/ory/hydra/pkg"
 	hydra "github.com/ory/hydra/sdk/go/hydra/swagger"
@@ -56,9 +57,7 @@ func (h *ClientHandler) newClientManager(cmd *cobra.Command) *hydra.OAuth2Api {
 		c.Configuration.DefaultHeader["X-Forwarded-Proto"] = "https"
 	}
 
-	if token, _ := cmd.Flags().GetString("access-token"); token != "" {
-		c.Configuration.DefaultHeader["Authorization"] = "Bearer " + token
-	}
+	configureAuthorization(cmd, c.Configuration, admin.ScopeClients)
 
 	return c
 }
@@ -95,6 +94,22 @@ func (h *ClientHandler) CreateClient(cmd *cobra.Command, args []string) {
 	secret, _ := cmd.Flags().GetString("secret")
 	id, _ := cmd.Flags().GetString("id")
 	public, _ := cmd.Flags().GetBool("is-public")
+	subjectType, _ := cmd.Flags().GetString("subject-type")
+	sectorIdentifierURI, _ := cmd.Flags().GetString("sector-identifier-uri")
+	tokenEndpointAuthMethod, _ := cmd.Flags().GetString("token-endpoint-auth-method")
+	jwksURI, _ := cmd.Flags().GetString("jwks-uri")
+	postLogoutCallbacks, _ := cmd.Flags().GetStringSlice("post-logout-callbacks")
+	backChannelLogoutCallback, _ := cmd.Flags().GetString("backchannel-logout-callback")
+	backChannelLogoutSessionRequired, _ := cmd.Flags().GetBool("backchannel-logout-session-required")
+	frontChannelLogoutCallback, _ := cmd.Flags().GetString("frontchannel-logout-callback")
+	frontChannelLogoutSessionRequired, _ := cmd.Flags().GetBool("frontchannel-logout-session-required")
+	tokenExchangeAudiences, _ := cmd.Flags().GetStringSlice("token-exchange-audiences")
+	tokenExchangeScope, _ := cmd.Flags().GetStringSlice("token-exchange-scope")
+	refreshTokenGracePeriod, _ := cmd.Flags().GetInt("refresh-token-grace-period")
+	userinfoSignedResponseAlg, _ := cmd.Flags().GetString("userinfo-signed-response-alg")
+	userinfoEncryptedResponseAlg, _ := cmd.Flags().GetString("userinfo-encrypted-response-alg")
+	userinfoEncryptedResponseEnc, _ := cmd.Flags().GetString("userinfo-encrypted-response-enc")
+	idTokenSignedResponseAlg, _ := cmd.Flags().GetString("id-token-signed-response-alg")
 
 	if secret == "" {
 		var secretb []byte
@@ -106,14 +121,30 @@ func (h *ClientHandler) CreateClient(cmd *cobra.Command, args []string) {
 	}
 
 	cc := hydra.OAuth2Client{
-		Id:            id,
-		ClientSecret:  secret,
-		ResponseTypes: responseTypes,
-		Scope:         strings.Join(allowedScopes, " "),
-		GrantTypes:    grantTypes,
-		RedirectUris:  callbacks,
-		ClientName:    name,
-		Public:        public,
+		Id:                                id,
+		ClientSecret:                      secret,
+		ResponseTypes:                     responseTypes,
+		Scope:                             strings.Join(allowedScopes, " "),
+		GrantTypes:                        grantTypes,
+		RedirectUris:                      callbacks,
+		ClientName:                        name,
+		Public:                            public,
+		SubjectType:                       subjectType,
+		SectorIdentifierUri:               sectorIdentifierURI,
+		TokenEndpointAuthMethod:           tokenEndpointAuthMethod,
+		JwksUri:                           jwksURI,
+		PostLogoutRedirectUris:            postLogoutCallbacks,
+		BackchannelLogoutUri:              backChannelLogoutCallback,
+		BackchannelLogoutSessionRequired:  backChannelLogoutSessionRequired,
+		FrontchannelLogoutUri:             frontChannelLogoutCallback,
+		FrontchannelLogoutSessionRequired: frontChannelLogoutSessionRequired,
+		TokenExchangeAudiences:            tokenExchangeAudiences,
+		TokenExchangeScope:                strings.Join(tokenExchangeScope, " "),
+		RefreshTokenGracePeriod:           int64(refreshTokenGracePeriod),
+		UserinfoSignedResponseAlg:         userinfoSignedResponseAlg,
+		UserinfoEncryptedResponseAlg:      userinfoEncryptedResponseAlg,
+		UserinfoEncryptedResponseEnc:      userinfoEncryptedResponseEnc,
+		IdTokenSignedResponseAlg:          idTokenSignedResponseAlg,
 	}
 
 	result, response, err := m.CreateOAuth2Client(cc)
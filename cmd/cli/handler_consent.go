@@ -0,0 +1,75 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package cli
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+
+	"github.com/ory/hydra/admin"
+	"github.com/ory/hydra/config"
+	hydra "github.com/ory/hydra/sdk/go/hydra/swagger"
+	"github.com/spf13/cobra"
+)
+
+type ConsentHandler struct {
+	Config *config.Config
+}
+
+func newConsentHandler(c *config.Config) *ConsentHandler {
+	return &ConsentHandler{
+		Config: c,
+	}
+}
+
+func (h *ConsentHandler) newConsentManager(cmd *cobra.Command) *hydra.OAuth2Api {
+	c := hydra.NewOAuth2ApiWithBasePath(h.Config.GetClusterURLWithoutTailingSlash(cmd))
+
+	skipTLSTermination, _ := cmd.Flags().GetBool("skip-tls-verify")
+	c.Configuration.Transport = &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: skipTLSTermination},
+	}
+
+	if term, _ := cmd.Flags().GetBool("fake-tls-termination"); term {
+		c.Configuration.DefaultHeader["X-Forwarded-Proto"] = "https"
+	}
+
+	configureAuthorization(cmd, c.Configuration, admin.ScopeConsent)
+
+	return c
+}
+
+func (h *ConsentHandler) ListConsentSessions(cmd *cobra.Command, args []string) {
+	m := h.newConsentManager(cmd)
+
+	if len(args) != 1 {
+		fmt.Print(cmd.UsageString())
+		return
+	}
+
+	limit, _ := cmd.Flags().GetInt("limit")
+	offset, _ := cmd.Flags().GetInt("offset")
+
+	sessions, response, err := m.ListUserConsentSessions(args[0], int64(limit), int64(offset))
+	checkResponse(response, err, http.StatusOK)
+	fmt.Printf("%s\n", formatResponse(sessions))
+}
@@ -21,14 +21,67 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/ory/hydra/pkg"
 	hydra "github.com/ory/hydra/sdk/go/hydra/swagger"
+	"github.com/spf13/cobra"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
 )
 
+// headerTransport adds headers to every request, for example X-Forwarded-Proto when faking TLS termination.
+type headerTransport struct {
+	http.RoundTripper
+	headers map[string]string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.RoundTripper.RoundTrip(req)
+}
+
+// configureAuthorization authorizes the requests of c with the access token set by --access-token. If no access token
+// is set but --client-id and --client-secret are, an access token with the given scope is requested from the token
+// endpoint set by --token-url using the OAuth 2.0 Client Credentials grant instead. The token endpoint is served on the
+// public port, not on the admin port the other requests are sent to.
+func configureAuthorization(cmd *cobra.Command, c *hydra.Configuration, scope string) {
+	if token, _ := cmd.Flags().GetString("access-token"); token != "" {
+		c.DefaultHeader["Authorization"] = "Bearer " + token
+		return
+	}
+
+	clientID, _ := cmd.Flags().GetString("client-id")
+	clientSecret, _ := cmd.Flags().GetString("client-secret")
+	if clientID == "" || clientSecret == "" {
+		return
+	}
+
+	tokenURL, _ := cmd.Flags().GetString("token-url")
+	if tokenURL == "" {
+		fmt.Fprintln(os.Stderr, "Please provide the URL of the OAuth 2.0 Token Endpoint, which is served on the public port, using flag --token-url or environment variable OAUTH2_TOKEN_URL.")
+		os.Exit(1)
+		return
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{
+		Transport: &headerTransport{RoundTripper: c.Transport, headers: c.DefaultHeader},
+	})
+	oauthConfig := &clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		TokenURL:     tokenURL,
+		Scopes:       []string{scope},
+	}
+	c.Transport = oauthConfig.Client(ctx).Transport
+}
+
 func checkResponse(response *hydra.APIResponse, err error, expectedStatusCode int) {
 	pkg.Must(err, "Command failed because error \"%s\" occurred.\n", err)
 
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/ory/hydra/admin"
 	"github.com/ory/hydra/config"
 	hydra "github.com/ory/hydra/sdk/go/hydra/swagger"
 	"github.com/spf13/cobra"
@@ -46,9 +47,7 @@ func (h *JWKHandler) newJwkManager(cmd *cobra.Command) *hydra.JsonWebKeyApi {
 		c.Configuration.DefaultHeader["X-Forwarded-Proto"] = "https"
 	}
 
-	if token, _ := cmd.Flags().GetString("access-token"); token != "" {
-		c.Configuration.DefaultHeader["Authorization"] = "Bearer " + token
-	}
+	configureAuthorization(cmd, c.Configuration, admin.ScopeKeys)
 
 	return c
 }
@@ -98,3 +97,15 @@ func (h *JWKHandler) DeleteKeys(cmd *cobra.Command, args []string) {
 	checkResponse(response, err, http.StatusNoContent)
 	fmt.Printf("Key set %s deleted.\n", args[0])
 }
+
+func (h *JWKHandler) RotateKeys(cmd *cobra.Command, args []string) {
+	m := h.newJwkManager(cmd)
+	if len(args) != 1 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	schedule, response, err := m.RotateJsonWebKeySet(args[0])
+	checkResponse(response, err, http.StatusOK)
+	fmt.Printf("%s\n", formatResponse(schedule))
+}
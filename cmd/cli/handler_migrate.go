@@ -58,6 +58,10 @@ func (h *MigrateHandler) connectToSql(dsn string) (*sqlx.DB, error) {
 		return nil, errors.Errorf("Could not parse DATABASE_URL: %s", err)
 	}
 
+	if u.Scheme == "sqlite" {
+		return pkg.OpenSQLite(dsn)
+	}
+
 	if err := pkg.Retry(h.c.GetLogger(), time.Second*15, time.Minute*2, func() error {
 		if u.Scheme == "mysql" {
 			dsn = strings.Replace(dsn, "mysql://", "", -1)
@@ -118,3 +122,41 @@ func (h *MigrateHandler) runMigrateSQL(db *sqlx.DB) error {
 	fmt.Printf("Migration successful! Applied a total of %d SQL migrations.\n", total)
 	return nil
 }
+
+func (h *MigrateHandler) MigrateSecrets(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		fmt.Println(cmd.UsageString())
+		return
+	}
+
+	if h.c.SystemSecret == "" {
+		fmt.Println("SYSTEM_SECRET must be set to the secret the data should be encrypted with.")
+		os.Exit(1)
+		return
+	}
+
+	db, err := h.connectToSql(args[0])
+	if err != nil {
+		fmt.Printf("An error occurred while connecting to SQL: %s", err)
+		os.Exit(1)
+		return
+	}
+
+	cipher := &jwk.AEAD{Key: h.c.GetSystemSecret(), RotatedKeys: h.c.GetRotatedSystemSecrets()}
+
+	keys, err := (&jwk.SQLManager{DB: db, Cipher: cipher}).ReEncrypt()
+	if err != nil {
+		fmt.Printf("An error occurred while encrypting the JSON Web Keys: %s", err)
+		os.Exit(1)
+		return
+	}
+	fmt.Printf("Encrypted %d JSON Web Keys using SYSTEM_SECRET.\n", keys)
+
+	secrets, err := (&client.SQLManager{DB: db}).ReEncryptSecrets(cipher)
+	if err != nil {
+		fmt.Printf("An error occurred while encrypting the client secrets: %s", err)
+		os.Exit(1)
+		return
+	}
+	fmt.Printf("Encrypted %d client secrets using SYSTEM_SECRET.\n", secrets)
+}
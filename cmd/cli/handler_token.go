@@ -26,6 +26,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/ory/hydra/admin"
 	"github.com/ory/hydra/config"
 	hydra "github.com/ory/hydra/sdk/go/hydra/swagger"
 	"github.com/spf13/cobra"
@@ -47,9 +48,7 @@ func (h *TokenHandler) newTokenManager(cmd *cobra.Command) *hydra.OAuth2Api {
 		c.Configuration.DefaultHeader["X-Forwarded-Proto"] = "https"
 	}
 
-	if token, _ := cmd.Flags().GetString("access-token"); token != "" {
-		c.Configuration.DefaultHeader["Authorization"] = "Bearer " + token
-	}
+	configureAuthorization(cmd, c.Configuration, admin.ScopeFlush)
 
 	return c
 }
@@ -46,4 +46,20 @@ func init() {
 	clientsCreateCmd.Flags().Bool("is-public", false, "Use this flag to create a public client")
 	clientsCreateCmd.Flags().String("secret", "", "Provide the client's secret")
 	clientsCreateCmd.Flags().StringP("name", "n", "", "The client's name")
+	clientsCreateCmd.Flags().String("subject-type", "public", "A subject type which will be used for this client, one of \"public\" or \"pairwise\"")
+	clientsCreateCmd.Flags().String("sector-identifier-uri", "", "An https URL referencing a JSON array of redirect URIs, used to compute pairwise subject identifiers")
+	clientsCreateCmd.Flags().String("token-endpoint-auth-method", "", "The method the client uses to authenticate at the token endpoint, one of \"client_secret_basic\", \"client_secret_post\", \"client_secret_jwt\", \"private_key_jwt\" or \"none\"")
+	clientsCreateCmd.Flags().StringSlice("post-logout-callbacks", []string{}, "A list of URLs the client may redirect to after an OpenID Connect logout")
+	clientsCreateCmd.Flags().String("backchannel-logout-callback", "", "The URL the client receives OpenID Connect Back-Channel Logout tokens at")
+	clientsCreateCmd.Flags().Bool("backchannel-logout-session-required", false, "Use this flag if the client requires the sid claim in logout tokens")
+	clientsCreateCmd.Flags().String("frontchannel-logout-callback", "", "The URL the client is logged out at by rendering it in an iframe during OpenID Connect logout")
+	clientsCreateCmd.Flags().Bool("frontchannel-logout-session-required", false, "Use this flag if the client requires the iss and sid query parameters in front-channel logout requests")
+	clientsCreateCmd.Flags().StringSlice("token-exchange-audiences", []string{}, "A list of audiences the client may request tokens for using OAuth 2.0 Token Exchange")
+	clientsCreateCmd.Flags().StringSlice("token-exchange-scope", []string{}, "The scope the client may request using OAuth 2.0 Token Exchange")
+	clientsCreateCmd.Flags().Int("refresh-token-grace-period", 0, "The number of seconds a rotated refresh token may be used again, for example by concurrent requests")
+	clientsCreateCmd.Flags().String("userinfo-signed-response-alg", "", "The JWS algorithm used to sign userinfo responses, for example \"RS256\". If empty, userinfo responses are plain JSON")
+	clientsCreateCmd.Flags().String("userinfo-encrypted-response-alg", "", "The JWE key management algorithm used to encrypt userinfo responses to the client's JSON Web Keys, for example \"RSA-OAEP\"")
+	clientsCreateCmd.Flags().String("userinfo-encrypted-response-enc", "", "The JWE content encryption algorithm used to encrypt userinfo responses, defaults to \"A128CBC-HS256\"")
+	clientsCreateCmd.Flags().String("id-token-signed-response-alg", "", "The JWS algorithm used to sign ID Tokens issued to the client, for example \"ES256\". If empty, the default algorithm of the server is used")
+	clientsCreateCmd.Flags().String("jwks-uri", "", "An URL referencing the client's JSON Web Key Set, required for \"private_key_jwt\" unless keys are registered by value")
 }
@@ -0,0 +1,43 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// consentCmd represents the consent command
+var consentCmd = &cobra.Command{
+	Use:   "consent <command>",
+	Short: "Manage the consent users have granted to OAuth2 clients",
+}
+
+func init() {
+	RootCmd.AddCommand(consentCmd)
+	consentCmd.PersistentFlags().Bool("fake-tls-termination", false, `Fake tls termination by adding "X-Forwarded-Proto: https" to http headers`)
+	consentCmd.PersistentFlags().String("access-token", os.Getenv("OAUTH2_ACCESS_TOKEN"), "Set an access token to be used in the Authorization header, defaults to environment variable ACCESS_TOKEN")
+	consentCmd.PersistentFlags().String("client-id", os.Getenv("OAUTH2_CLIENT_ID"), "Use the provided OAuth 2.0 Client ID to request an access token if no access token is set, defaults to environment variable OAUTH2_CLIENT_ID")
+	consentCmd.PersistentFlags().String("client-secret", os.Getenv("OAUTH2_CLIENT_SECRET"), "Use the provided OAuth 2.0 Client Secret to request an access token if no access token is set, defaults to environment variable OAUTH2_CLIENT_SECRET")
+	consentCmd.PersistentFlags().String("token-url", os.Getenv("OAUTH2_TOKEN_URL"), "Set the URL of the OAuth 2.0 Token Endpoint on the public port, which is used with --client-id and --client-secret, defaults to environment variable OAUTH2_TOKEN_URL")
+	consentCmd.PersistentFlags().String("endpoint", os.Getenv("HYDRA_URL"), "Set the URL where ORY Hydra is hosted, defaults to environment variable HYDRA_URL")
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// consentListCmd represents the list command
+var consentListCmd = &cobra.Command{
+	Use:   "list <user>",
+	Short: "List the consent a user has granted to OAuth2 clients",
+	Long: `Lists all OAuth2 clients a user has granted consent to and which ORY Hydra remembers, together with the granted
+scopes and when the consent expires.`,
+	Run: cmdHandler.Consent.ListConsentSessions,
+}
+
+func init() {
+	consentCmd.AddCommand(consentListCmd)
+	consentListCmd.Flags().Int("limit", 100, "The maximum amount of consent sessions returned")
+	consentListCmd.Flags().Int("offset", 0, "The offset from where to start looking")
+}
@@ -37,6 +37,9 @@ func init() {
 	//keysCmd.PersistentFlags().Bool("dry", false, "do not execute the command but show the corresponding curl command instead")
 	keysCmd.PersistentFlags().Bool("fake-tls-termination", false, `fake tls termination by adding "X-Forwarded-Proto: https" to http headers`)
 	keysCmd.PersistentFlags().String("access-token", os.Getenv("OAUTH2_ACCESS_TOKEN"), "Set an access token to be used in the Authorization header, defaults to environment variable ACCESS_TOKEN")
+	keysCmd.PersistentFlags().String("client-id", os.Getenv("OAUTH2_CLIENT_ID"), "Use the provided OAuth 2.0 Client ID to request an access token if no access token is set, defaults to environment variable OAUTH2_CLIENT_ID")
+	keysCmd.PersistentFlags().String("client-secret", os.Getenv("OAUTH2_CLIENT_SECRET"), "Use the provided OAuth 2.0 Client Secret to request an access token if no access token is set, defaults to environment variable OAUTH2_CLIENT_SECRET")
+	keysCmd.PersistentFlags().String("token-url", os.Getenv("OAUTH2_TOKEN_URL"), "Set the URL of the OAuth 2.0 Token Endpoint on the public port, which is used with --client-id and --client-secret, defaults to environment variable OAUTH2_TOKEN_URL")
 	keysCmd.PersistentFlags().String("endpoint", os.Getenv("HYDRA_URL"), "Set the URL where ORY Hydra is hosted, defaults to environment variable HYDRA_URL")
 
 	// Here you will define your flags and configuration settings.
@@ -0,0 +1,42 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// keysRotateCmd represents the rotate command
+var keysRotateCmd = &cobra.Command{
+	Use:   "rotate <set>",
+	Short: "Rotate a JSON Web Key Set",
+	Long: `Generates the next keys of a JSON Web Key Set, one for every algorithm used in the set. The new keys are
+published right away but only used for signing once JWK_ROTATION_ACTIVATION_DELAY has passed, the previous keys are
+removed JWK_ROTATION_RETIREMENT_DELAY later. Prints the rotation schedule of the keys of the set.
+
+Example:
+  hydra keys rotate hydra.openid.id-token`,
+	Run: cmdHandler.Keys.RotateKeys,
+}
+
+func init() {
+	keysCmd.AddCommand(keysRotateCmd)
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package cmd
+
+import "github.com/spf13/cobra"
+
+// migrateSecretsCmd represents the secrets command
+var migrateSecretsCmd = &cobra.Command{
+	Use:   "secrets <database-url>",
+	Short: "Encrypt stored keys and secrets using the current SYSTEM_SECRET",
+	Long: `Run this command after rotating SYSTEM_SECRET. It decrypts all JSON Web Keys and encrypted client secrets
+stored in the database using SYSTEM_SECRET or one of the ROTATED_SYSTEM_SECRETS, and encrypts them again using
+SYSTEM_SECRET. Afterwards, the rotated secrets are no longer required to decrypt the stored data, but they are still
+required to validate tokens issued before the secret was rotated.
+
+Example:
+  SYSTEM_SECRET=new-secret-at-least-16-characters ROTATED_SYSTEM_SECRETS=old-secret-at-least-16-characters hydra migrate secrets postgres://...
+
+### WARNING ###
+
+Before running this command on an existing database, create a back up!
+`,
+	Run: cmdHandler.Migration.MigrateSecrets,
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateSecretsCmd)
+}
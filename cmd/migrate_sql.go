@@ -34,7 +34,8 @@ This decreases risk of failure and decreases time required.
 
 ### WARNING ###
 
-Before running this command on an existing database, create a back up!
+Before running this command on an existing database, create a back up! Migrations of SQLite databases can not be
+reverted, restore the back up instead.
 `,
 	Run: cmdHandler.Migration.MigrateSQL,
 }
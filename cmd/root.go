@@ -104,6 +104,9 @@ func initConfig() {
 	viper.BindEnv("HOST")
 	viper.SetDefault("HOST", "")
 
+	viper.BindEnv("ADMIN_HOST")
+	viper.SetDefault("ADMIN_HOST", "")
+
 	viper.BindEnv("CLIENT_ID")
 	viper.SetDefault("CLIENT_ID", "")
 
@@ -116,27 +119,57 @@ func initConfig() {
 	viper.BindEnv("OAUTH2_ERROR_URL")
 	viper.SetDefault("OAUTH2_ERROR_URL", oauth2.DefaultErrorPath)
 
+	viper.BindEnv("OAUTH2_LOGOUT_REDIRECT_URL")
+	viper.SetDefault("OAUTH2_LOGOUT_REDIRECT_URL", oauth2.DefaultLogoutPath)
+
 	viper.BindEnv("DATABASE_PLUGIN")
 	viper.SetDefault("DATABASE_PLUGIN", "")
 
 	viper.BindEnv("DATABASE_URL")
 	viper.SetDefault("DATABASE_URL", "")
 
+	viper.BindEnv("EPHEMERAL_DATABASE_URL")
+	viper.SetDefault("EPHEMERAL_DATABASE_URL", "")
+
 	viper.BindEnv("SYSTEM_SECRET")
 	viper.SetDefault("SYSTEM_SECRET", "")
 
+	viper.BindEnv("ROTATED_SYSTEM_SECRETS")
+	viper.SetDefault("ROTATED_SYSTEM_SECRETS", "")
+
 	viper.BindEnv("CLIENT_SECRET")
 	viper.SetDefault("CLIENT_SECRET", "")
 
 	viper.BindEnv("HTTPS_ALLOW_TERMINATION_FROM")
 	viper.SetDefault("HTTPS_ALLOW_TERMINATION_FROM", "")
 
+	viper.BindEnv("ADMIN_HTTPS_ALLOW_TERMINATION_FROM")
+	viper.SetDefault("ADMIN_HTTPS_ALLOW_TERMINATION_FROM", "")
+
+	viper.BindEnv("ADMIN_HTTPS_CLIENT_CA_PATH")
+	viper.SetDefault("ADMIN_HTTPS_CLIENT_CA_PATH", "")
+
+	viper.BindEnv("ADMIN_HTTPS_CLIENT_SUBJECTS")
+	viper.SetDefault("ADMIN_HTTPS_CLIENT_SUBJECTS", "")
+
+	viper.BindEnv("ADMIN_AUTHORIZATION")
+	viper.SetDefault("ADMIN_AUTHORIZATION", "")
+
+	viper.BindEnv("ADMIN_API_KEYS")
+	viper.SetDefault("ADMIN_API_KEYS", "")
+
+	viper.BindEnv("ADMIN_CLIENTS")
+	viper.SetDefault("ADMIN_CLIENTS", "")
+
 	viper.BindEnv("CLUSTER_URL")
 	viper.SetDefault("CLUSTER_URL", "")
 
 	viper.BindEnv("PORT")
 	viper.SetDefault("PORT", 4444)
 
+	viper.BindEnv("ADMIN_PORT")
+	viper.SetDefault("ADMIN_PORT", 4445)
+
 	viper.BindEnv("OAUTH2_ISSUER_URL")
 	viper.SetDefault("OAUTH2_ISSUER_URL", "http://localhost:4444")
 
@@ -146,15 +179,52 @@ func initConfig() {
 	viper.BindEnv("OAUTH2_SHARE_ERROR_DEBUG")
 	viper.SetDefault("OAUTH2_SHARE_ERROR_DEBUG", false)
 
+	viper.BindEnv("OAUTH2_CLIENT_REGISTRATION_ENABLED")
+	viper.SetDefault("OAUTH2_CLIENT_REGISTRATION_ENABLED", false)
+
+	viper.BindEnv("OAUTH2_CLIENT_REGISTRATION_INITIAL_ACCESS_TOKEN")
+	viper.SetDefault("OAUTH2_CLIENT_REGISTRATION_INITIAL_ACCESS_TOKEN", "")
+
 	viper.BindEnv("ACCESS_TOKEN_LIFESPAN")
 	viper.SetDefault("ACCESS_TOKEN_LIFESPAN", "1h")
 
+	viper.BindEnv("OAUTH2_ACCESS_TOKEN_STRATEGY")
+	viper.SetDefault("OAUTH2_ACCESS_TOKEN_STRATEGY", "opaque")
+
+	viper.BindEnv("OAUTH2_ACCESS_TOKEN_JWT_ALGORITHM")
+	viper.SetDefault("OAUTH2_ACCESS_TOKEN_JWT_ALGORITHM", "RS256")
+
+	viper.BindEnv("OIDC_ID_TOKEN_SIGNING_ALGORITHMS")
+	viper.SetDefault("OIDC_ID_TOKEN_SIGNING_ALGORITHMS", "RS256")
+
+	viper.BindEnv("JWK_ROTATION_INTERVAL")
+
+	viper.BindEnv("JWK_ROTATION_ACTIVATION_DELAY")
+	viper.SetDefault("JWK_ROTATION_ACTIVATION_DELAY", "1h")
+
+	viper.BindEnv("JWK_ROTATION_RETIREMENT_DELAY")
+
+	viper.BindEnv("HSM_LIBRARY")
+
+	viper.BindEnv("HSM_TOKEN_LABEL")
+
+	viper.BindEnv("HSM_PIN")
+
+	viper.BindEnv("HSM_KEY_SETS")
+	viper.SetDefault("HSM_KEY_SETS", "hydra.openid.id-token,hydra.jwt.access-token")
+
 	viper.BindEnv("ID_TOKEN_LIFESPAN")
 	viper.SetDefault("ID_TOKEN_LIFESPAN", "1h")
 
+	viper.BindEnv("OIDC_ID_TOKEN_HINT_MAX_AGE")
+	viper.SetDefault("OIDC_ID_TOKEN_HINT_MAX_AGE", "24h")
+
 	viper.BindEnv("AUTH_CODE_LIFESPAN")
 	viper.SetDefault("AUTH_CODE_LIFESPAN", "10m")
 
+	viper.BindEnv("DEVICE_CODE_LIFESPAN")
+	viper.SetDefault("DEVICE_CODE_LIFESPAN", "10m")
+
 	viper.BindEnv("CHALLENGE_TOKEN_LIFESPAN")
 	viper.SetDefault("CHALLENGE_TOKEN_LIFESPAN", "10m")
 
@@ -176,6 +246,12 @@ func initConfig() {
 	viper.BindEnv("OIDC_DISCOVERY_USERINFO_ENDPOINT")
 	viper.SetDefault("OIDC_DISCOVERY_USERINFO_ENDPOINT", "")
 
+	viper.BindEnv("OIDC_SUBJECT_TYPES_SUPPORTED")
+	viper.SetDefault("OIDC_SUBJECT_TYPES_SUPPORTED", "public")
+
+	viper.BindEnv("OIDC_SUBJECT_TYPE_PAIRWISE_SALT")
+	viper.SetDefault("OIDC_SUBJECT_TYPE_PAIRWISE_SALT", "")
+
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err != nil {
 		fmt.Printf(`Config file not found because "%s"`, err)
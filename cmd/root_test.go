@@ -32,7 +32,10 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-var port int
+var port, adminPort int
+
+// apiKey bootstraps the administrative APIs, which are protected by the introspection and api_key authorizers.
+const apiKey = "bootstrap-api-key"
 
 func init() {
 	var err error
@@ -40,10 +43,18 @@ func init() {
 	if err != nil {
 		panic(err.Error())
 	}
+	adminPort, err = freeport.GetFreePort()
+	if err != nil {
+		panic(err.Error())
+	}
 	os.Setenv("PORT", fmt.Sprintf("%d", port))
+	os.Setenv("ADMIN_PORT", fmt.Sprintf("%d", adminPort))
 	os.Setenv("DATABASE_URL", "memory")
-	os.Setenv("HYDRA_URL", fmt.Sprintf("https://localhost:%d/", port))
+	os.Setenv("HYDRA_URL", fmt.Sprintf("https://localhost:%d/", adminPort))
 	os.Setenv("OAUTH2_ISSUER_URL", fmt.Sprintf("https://localhost:%d/", port))
+	os.Setenv("ADMIN_AUTHORIZATION", "introspection,api_key")
+	os.Setenv("ADMIN_API_KEYS", apiKey)
+	os.Setenv("ADMIN_CLIENTS", "foobarbaz")
 }
 
 func TestExecute(t *testing.T) {
@@ -51,6 +62,8 @@ func TestExecute(t *testing.T) {
 	copy(osArgs, os.Args)
 
 	endpoint := fmt.Sprintf("https://localhost:%d/", port)
+	adminEndpoint := fmt.Sprintf("https://localhost:%d/", adminPort)
+	tokenURL := fmt.Sprintf("https://localhost:%d/oauth2/token", port)
 
 	for _, c := range []struct {
 		args      []string
@@ -78,19 +91,29 @@ func TestExecute(t *testing.T) {
 				return err != nil
 			},
 		},
-		{args: []string{"clients", "create", "--endpoint", endpoint, "--id", "foobarbaz", "--secret", "foobar", "-g", "client_credentials"}},
-		{args: []string{"clients", "get", "--endpoint", endpoint, "foobarbaz"}},
-		{args: []string{"clients", "create", "--endpoint", endpoint, "--id", "public-foo", "--is-public"}},
-		{args: []string{"clients", "delete", "--endpoint", endpoint, "public-foo"}},
-		{args: []string{"keys", "create", "foo", "--endpoint", endpoint, "-a", "HS256"}},
-		{args: []string{"keys", "create", "foo", "--endpoint", endpoint, "-a", "HS256"}},
-		{args: []string{"keys", "get", "--endpoint", endpoint, "foo"}},
-		{args: []string{"keys", "delete", "--endpoint", endpoint, "foo"}},
+		{args: []string{"clients", "create", "--endpoint", adminEndpoint, "--access-token", apiKey, "--id", "foobarbaz", "--secret", "foobar", "-g", "client_credentials", "-a", "hydra.clients,hydra.keys,hydra.consent,hydra.flush"}},
+		{args: []string{"clients", "get", "--endpoint", adminEndpoint, "--access-token", apiKey, "foobarbaz"}},
+		{args: []string{"clients", "create", "--endpoint", adminEndpoint, "--access-token", apiKey, "--id", "public-foo", "--is-public"}},
+		{args: []string{"clients", "delete", "--endpoint", adminEndpoint, "--access-token", apiKey, "public-foo"}},
+		{args: []string{"consent", "list", "--endpoint", adminEndpoint, "--access-token", apiKey, "foo"}},
+		{args: []string{"keys", "create", "foo", "--endpoint", adminEndpoint, "--access-token", apiKey, "-a", "HS256"}},
+		{args: []string{"keys", "create", "foo", "--endpoint", adminEndpoint, "--access-token", apiKey, "-a", "HS256"}},
+		{args: []string{"keys", "get", "--endpoint", adminEndpoint, "--access-token", apiKey, "foo"}},
+		{args: []string{"keys", "delete", "--endpoint", adminEndpoint, "--access-token", apiKey, "foo"}},
+
+		// The introspection authorizer accepts the access tokens the CLI requests from the public port. Flags keep
+		// their values between executions, so the access token set above is reset.
+		{args: []string{"clients", "get", "--endpoint", adminEndpoint, "--access-token", "", "--client-id", "foobarbaz", "--client-secret", "foobar", "--token-url", tokenURL, "foobarbaz"}},
+		{args: []string{"consent", "list", "--endpoint", adminEndpoint, "--access-token", "", "--client-id", "foobarbaz", "--client-secret", "foobar", "--token-url", tokenURL, "foo"}},
+		{args: []string{"keys", "create", "bar", "--endpoint", adminEndpoint, "--access-token", "", "--client-id", "foobarbaz", "--client-secret", "foobar", "--token-url", tokenURL, "-a", "HS256"}},
+		{args: []string{"keys", "delete", "--endpoint", adminEndpoint, "--access-token", "", "--client-id", "foobarbaz", "--client-secret", "foobar", "--token-url", tokenURL, "bar"}},
+		{args: []string{"token", "flush", "--endpoint", adminEndpoint, "--access-token", "", "--client-id", "foobarbaz", "--client-secret", "foobar", "--token-url", tokenURL}},
+
 		{args: []string{"token", "revoke", "--endpoint", endpoint, "--client-secret", "foobar", "--client-id", "foobarbaz", "foo"}},
 		{args: []string{"token", "client", "--endpoint", endpoint, "--client-secret", "foobar", "--client-id", "foobarbaz"}},
 		{args: []string{"help", "migrate", "sql"}},
 		{args: []string{"version"}},
-		{args: []string{"token", "flush", "--endpoint", endpoint}},
+		{args: []string{"token", "flush", "--endpoint", adminEndpoint, "--access-token", apiKey}},
 	} {
 		c.args = append(c.args, []string{"--skip-tls-verify"}...)
 		RootCmd.SetArgs(c.args)
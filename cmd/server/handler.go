@@ -22,10 +22,13 @@ package server
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/gorilla/context"
 	"github.com/julienschmidt/httprouter"
@@ -33,12 +36,14 @@ import (
 	"github.com/ory/go-convenience/corsx"
 	"github.com/ory/graceful"
 	"github.com/ory/herodot"
+	"github.com/ory/hydra/admin"
 	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/config"
 	"github.com/ory/hydra/consent"
 	"github.com/ory/hydra/jwk"
 	"github.com/ory/hydra/oauth2"
 	"github.com/ory/hydra/pkg"
+	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
 	"github.com/rs/cors"
 	"github.com/spf13/cobra"
@@ -51,13 +56,14 @@ func RunHost(c *config.Config) func(cmd *cobra.Command, args []string) {
 	return func(cmd *cobra.Command, args []string) {
 		fmt.Println(banner)
 
-		router := httprouter.New()
+		frontend := httprouter.New()
+		backend := httprouter.New()
 		logger := c.GetLogger()
 		serverHandler := &Handler{
 			Config: c,
 			H:      herodot.NewJSONWriter(logger),
 		}
-		serverHandler.registerRoutes(router)
+		serverHandler.registerRoutes(frontend, backend)
 		c.ForceHTTP, _ = cmd.Flags().GetBool("dangerous-force-http")
 
 		if !c.ForceHTTP {
@@ -71,59 +77,146 @@ func RunHost(c *config.Config) func(cmd *cobra.Command, args []string) {
 			}
 		}
 
-		n := negroni.New()
-
+		var middlewares []negroni.Handler
 		if ok, _ := cmd.Flags().GetBool("disable-telemetry"); !ok && os.Getenv("DISABLE_TELEMETRY") != "1" {
 			telemetryMetrics := c.GetTelemetryMetrics()
 			go telemetryMetrics.RegisterSegment()
 			go telemetryMetrics.CommitMemoryStatistics()
-			n.Use(telemetryMetrics)
+			middlewares = append(middlewares, telemetryMetrics)
 		}
 
-		n.Use(c.GetPrometheusMetrics())
+		publicListener := &listener{
+			name:                 "public",
+			address:              c.GetAddress(),
+			router:               frontend,
+			certificate:          getOrCreateTLSCertificate(cmd, c),
+			tlsTermination:       c.AllowTLSTermination != "",
+			satisfiesTermination: c.DoesRequestSatisfyTermination,
+			cors:                 cors.New(corsx.ParseOptions()),
+		}
 
-		n.Use(negronilogrus.NewMiddlewareFromLogger(logger, c.Issuer))
-		n.UseFunc(serverHandler.rejectInsecureRequests)
-		n.UseHandler(router)
-		corsHandler := cors.New(corsx.ParseOptions()).Handler(n)
+		adminListener := &listener{
+			name:                 "admin",
+			address:              c.GetAdminAddress(),
+			router:               backend,
+			certificate:          getOrCreateAdminTLSCertificate(cmd, c),
+			tlsTermination:       c.GetAdminAllowTLSTermination() != "",
+			satisfiesTermination: c.DoesAdminRequestSatisfyTermination,
+		}
+		if options, ok := parseAdminCORSOptions(); ok {
+			adminListener.cors = cors.New(options)
+		}
+		if c.AdminClientCAPath != "" {
+			adminListener.clientCAs = loadClientCertificateAuthorities(c, c.AdminClientCAPath)
+		}
+		if serverHandler.Authorization != nil {
+			adminListener.middlewares = append(adminListener.middlewares, serverHandler.Authorization)
+		}
 
-		var srv = graceful.WithDefaults(&http.Server{
-			Addr:    c.GetAddress(),
-			Handler: context.ClearHandler(corsHandler),
-			TLSConfig: &tls.Config{
-				Certificates: []tls.Certificate{getOrCreateTLSCertificate(cmd, c)},
-			},
-		})
+		for _, rotation := range serverHandler.keyRotations {
+			go rotation.run(time.Minute)
+		}
 
-		err := graceful.Graceful(func() error {
-			var err error
-			logger.Infof("Setting up http server on %s", c.GetAddress())
-			if c.ForceHTTP {
-				logger.Warnln("HTTPS disabled. Never do this in production.")
-				err = srv.ListenAndServe()
-			} else if c.AllowTLSTermination != "" {
-				logger.Infoln("TLS termination enabled, disabling https.")
-				err = srv.ListenAndServe()
-			} else {
-				err = srv.ListenAndServeTLS("", "")
-			}
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go serverHandler.serve(publicListener, middlewares, &wg)
+		go serverHandler.serve(adminListener, middlewares, &wg)
+		wg.Wait()
+	}
+}
+
+// listener describes one of the HTTP servers started by RunHost. The public listener serves the OAuth 2.0 and OpenID
+// Connect endpoints, the admin listener serves the administrative APIs.
+type listener struct {
+	name                 string
+	address              string
+	router               *httprouter.Router
+	certificate          tls.Certificate
+	tlsTermination       bool
+	satisfiesTermination func(r *http.Request) error
+
+	// cors is nil if CORS is disabled for this listener.
+	cors *cors.Cors
+
+	// clientCAs are used to verify TLS client certificates. If nil, client certificates are not requested.
+	clientCAs *x509.CertPool
+
+	// middlewares are run after insecure requests have been rejected.
+	middlewares []negroni.Handler
+}
+
+func (h *Handler) serve(l *listener, middlewares []negroni.Handler, wg *sync.WaitGroup) {
+	defer wg.Done()
+	c := h.Config
+	logger := c.GetLogger()
+
+	n := negroni.New()
+	for _, m := range middlewares {
+		n.Use(m)
+	}
+
+	n.Use(c.GetPrometheusMetrics().WithRouter(l.router))
 
-			return err
-		}, srv.Shutdown)
-		logger.WithError(err).Fatal("Could not gracefully run server")
+	n.Use(negronilogrus.NewMiddlewareFromLogger(logger, c.Issuer))
+	n.UseFunc(h.rejectInsecureRequests(l.satisfiesTermination))
+	for _, m := range l.middlewares {
+		n.Use(m)
+	}
+	n.UseHandler(l.router)
+
+	var handler http.Handler = n
+	if l.cors != nil {
+		handler = l.cors.Handler(n)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{l.certificate},
+	}
+	if l.clientCAs != nil {
+		tlsConfig.ClientCAs = l.clientCAs
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	var srv = graceful.WithDefaults(&http.Server{
+		Addr:      l.address,
+		Handler:   context.ClearHandler(handler),
+		TLSConfig: tlsConfig,
+	})
+
+	if err := graceful.Graceful(func() error {
+		var err error
+		logger.Infof("Setting up %s http server on %s", l.name, l.address)
+		if c.ForceHTTP {
+			logger.Warnln("HTTPS disabled. Never do this in production.")
+			err = srv.ListenAndServe()
+		} else if l.tlsTermination {
+			logger.Infof("TLS termination enabled, disabling https on the %s http server.", l.name)
+			err = srv.ListenAndServe()
+		} else {
+			err = srv.ListenAndServeTLS("", "")
+		}
+
+		return err
+	}, srv.Shutdown); err != nil {
+		logger.WithError(err).Fatalf("Could not gracefully run %s http server", l.name)
 	}
 }
 
 type Handler struct {
-	Clients *client.Handler
-	Keys    *jwk.Handler
-	OAuth2  *oauth2.Handler
-	Consent *consent.Handler
-	Config  *config.Config
-	H       herodot.Writer
+	Authorization      *admin.Middleware
+	Clients            *client.Handler
+	ClientRegistration *client.RegistrationHandler
+	Keys               *jwk.Handler
+	OAuth2             *oauth2.Handler
+	Consent            *consent.Handler
+	Config             *config.Config
+	H                  herodot.Writer
+
+	keyRotations []*signingKeyRotation
 }
 
-func (h *Handler) registerRoutes(router *httprouter.Router) {
+// registerRoutes registers the public endpoints on frontend and the administrative APIs on backend.
+func (h *Handler) registerRoutes(frontend, backend *httprouter.Router) {
 	c := h.Config
 	ctx := c.Context()
 
@@ -133,28 +226,57 @@ func (h *Handler) registerRoutes(router *httprouter.Router) {
 	injectConsentManager(c, clientsManager)
 
 	injectFositeStore(c, clientsManager)
-	oauth2Provider, idTokenKeyID := newOAuth2Provider(c)
+	clientAssertionAuthenticator := newClientAssertionAuthenticator(c, clientsManager)
+	oauth2Provider, idTokenStrategy := newOAuth2Provider(c, clientAssertionAuthenticator)
+	h.Authorization = newAdminAuthorization(c, oauth2Provider)
 
 	// Set up handlers
-	h.Clients = newClientHandler(c, router, clientsManager)
-	h.Keys = newJWKHandler(c, router)
-	h.Consent = newConsentHandler(c, router)
-	h.OAuth2 = newOAuth2Handler(c, router, ctx.ConsentManager, oauth2Provider, idTokenKeyID)
-	_ = newHealthHandler(c, router)
-}
+	h.Clients = newClientHandler(c, backend, clientsManager)
+	h.ClientRegistration = newClientRegistrationHandler(c, frontend, clientsManager)
+	h.Keys = newJWKHandler(c, frontend, backend)
+	h.Consent = newConsentHandler(c, backend)
+	h.OAuth2 = newOAuth2Handler(c, frontend, backend, ctx.ConsentManager, oauth2Provider, idTokenStrategy, clientAssertionAuthenticator)
+	if h.Keys.Rotator != nil {
+		node := uuid.New()
+		h.keyRotations = append(h.keyRotations, &signingKeyRotation{
+			c:          c,
+			set:        oauth2.OpenIDConnectKeyName,
+			algorithms: c.GetIDTokenSigningAlgorithms(),
+			rotator:    h.Keys.Rotator,
+			strategies: []signingKeySetter{idTokenStrategy},
+			interval:   c.GetKeyRotationInterval(),
+			node:       node,
+		})
 
-func (h *Handler) rejectInsecureRequests(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-	if r.TLS != nil || h.Config.ForceHTTP {
-		next.ServeHTTP(rw, r)
-		return
+		if strategies := jwtAccessTokenStrategies(ctx.FositeStrategy); len(strategies) > 0 {
+			h.keyRotations = append(h.keyRotations, &signingKeyRotation{
+				c:          c,
+				set:        oauth2.AccessTokenKeyName,
+				algorithms: []string{c.GetAccessTokenJWTAlgorithm()},
+				rotator:    h.Keys.Rotator,
+				strategies: strategies,
+				interval:   c.GetKeyRotationInterval(),
+				node:       node,
+			})
+		}
 	}
+	_ = newHealthHandler(c, frontend, backend)
+}
 
-	if err := h.Config.DoesRequestSatisfyTermination(r); err == nil {
-		next.ServeHTTP(rw, r)
-		return
-	} else {
-		h.Config.GetLogger().WithError(err).Warnln("Could not serve http connection")
-	}
+func (h *Handler) rejectInsecureRequests(satisfiesTermination func(r *http.Request) error) negroni.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if r.TLS != nil || h.Config.ForceHTTP {
+			next.ServeHTTP(rw, r)
+			return
+		}
 
-	h.H.WriteErrorCode(rw, r, http.StatusBadGateway, errors.New("Can not serve request over insecure http"))
+		if err := satisfiesTermination(r); err == nil {
+			next.ServeHTTP(rw, r)
+			return
+		} else {
+			h.Config.GetLogger().WithError(err).Warnln("Could not serve http connection")
+		}
+
+		h.H.WriteErrorCode(rw, r, http.StatusBadGateway, errors.New("Can not serve request over insecure http"))
+	}
 }
@@ -0,0 +1,70 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package server
+
+import (
+	"github.com/ory/fosite"
+	"github.com/ory/herodot"
+	"github.com/ory/hydra/admin"
+	"github.com/ory/hydra/config"
+)
+
+// newAdminAuthorization returns the middleware which authorizes requests to the administrative APIs using the
+// authorizers listed in ADMIN_AUTHORIZATION, or nil if none are listed.
+func newAdminAuthorization(c *config.Config, o fosite.OAuth2Provider) *admin.Middleware {
+	logger := c.GetLogger()
+
+	var authorizers admin.Authorizers
+	for _, name := range c.GetAdminAuthorization() {
+		switch name {
+		case "introspection":
+			clients := c.GetAdminClients()
+			if len(clients) == 0 {
+				logger.Fatalln("ADMIN_CLIENTS must be set if ADMIN_AUTHORIZATION contains introspection.")
+			}
+			authorizers = append(authorizers, &admin.IntrospectionAuthorizer{OAuth2: o, Clients: clients})
+		case "api_key":
+			keys := c.GetAdminAPIKeys()
+			if len(keys) == 0 {
+				logger.Fatalln("ADMIN_API_KEYS must be set if ADMIN_AUTHORIZATION contains api_key.")
+			}
+			authorizers = append(authorizers, &admin.APIKeyAuthorizer{Keys: keys})
+		case "mtls":
+			if c.AdminClientCAPath == "" {
+				logger.Fatalln("ADMIN_HTTPS_CLIENT_CA_PATH must be set if ADMIN_AUTHORIZATION contains mtls.")
+			}
+			authorizers = append(authorizers, &admin.CertificateAuthorizer{Subjects: c.GetAdminClientSubjects()})
+		default:
+			logger.Fatalf("Unknown authorizer %s in ADMIN_AUTHORIZATION, expected one of introspection, api_key or mtls.", name)
+		}
+	}
+
+	if len(authorizers) == 0 {
+		logger.Warnln("ADMIN_AUTHORIZATION is not set, the administrative APIs are not protected. Never expose the admin port to untrusted networks.")
+		return nil
+	}
+
+	return &admin.Middleware{
+		Authorizer: authorizers,
+		H:          herodot.NewJSONWriter(logger),
+		L:          logger,
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/ory/herodot"
 	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/config"
+	"github.com/ory/hydra/jwk"
 	"github.com/ory/sqlcon"
 )
 
@@ -39,6 +40,16 @@ func newClientManager(c *config.Config) client.Manager {
 			DB:     con.GetDatabase(),
 			Hasher: ctx.Hasher,
 		}
+	case *config.SQLiteConnection:
+		return &client.SQLManager{
+			DB:     con.GetDatabase(),
+			Hasher: ctx.Hasher,
+		}
+	case *config.BoltConnection:
+		return &client.BoltManager{
+			DB:     con.GetDatabase(),
+			Hasher: ctx.Hasher,
+		}
 	case *config.PluginConnection:
 		if m, err := con.NewClientManager(); err != nil {
 			c.GetLogger().Fatalf("Could not load client manager plugin %s", err)
@@ -52,12 +63,39 @@ func newClientManager(c *config.Config) client.Manager {
 	return nil
 }
 
-func newClientHandler(c *config.Config, router *httprouter.Router, manager client.Manager) *client.Handler {
+func newClientValidator(c *config.Config) *client.Validator {
+	v := client.NewValidator(c.GetSubjectTypesSupported())
+	v.IDTokenSigningAlgorithms = c.GetIDTokenSigningAlgorithms()
+	v.UserinfoSigningAlgorithms = c.GetIDTokenSigningAlgorithms()
+	return v
+}
+
+func newClientHandler(c *config.Config, admin *httprouter.Router, manager client.Manager) *client.Handler {
 	h := &client.Handler{
-		H:       herodot.NewJSONWriter(c.GetLogger()),
-		Manager: manager,
+		H:         herodot.NewJSONWriter(c.GetLogger()),
+		Manager:   manager,
+		Validator: newClientValidator(c),
+		Cipher:    &jwk.AEAD{Key: c.GetSystemSecret(), RotatedKeys: c.GetRotatedSystemSecrets()},
+	}
+
+	h.SetRoutes(admin)
+	return h
+}
+
+func newClientRegistrationHandler(c *config.Config, public *httprouter.Router, manager client.Manager) *client.RegistrationHandler {
+	if !c.ClientRegistrationEnabled {
+		return nil
+	}
+
+	h := &client.RegistrationHandler{
+		H:                  herodot.NewJSONWriter(c.GetLogger()),
+		Manager:            manager,
+		Validator:          newClientValidator(c),
+		Cipher:             &jwk.AEAD{Key: c.GetSystemSecret(), RotatedKeys: c.GetRotatedSystemSecrets()},
+		IssuerURL:          c.Issuer,
+		InitialAccessToken: c.ClientRegistrationInitialToken,
 	}
 
-	h.SetRoutes(router)
+	h.SetRoutes(public)
 	return h
 }
@@ -43,6 +43,18 @@ func injectConsentManager(c *config.Config, cm client.Manager) {
 			cm,
 		)
 		break
+	case *config.SQLiteConnection:
+		manager = consent.NewSQLManager(
+			con.GetDatabase(),
+			cm,
+		)
+		break
+	case *config.BoltConnection:
+		manager = consent.NewBoltManager(
+			con.GetDatabase(),
+			cm,
+		)
+		break
 	case *config.PluginConnection:
 		var err error
 		if manager, err = con.NewConsentManager(); err != nil {
@@ -53,16 +65,22 @@ func injectConsentManager(c *config.Config, cm client.Manager) {
 		panic("Unknown connection type.")
 	}
 
+	if con := ctx.EphemeralConnection; con != nil {
+		manager = consent.NewRedisManager(manager, con.GetClient(), cm, consentRequestMaxAge)
+	}
+
 	ctx.ConsentManager = manager
 }
 
-func newConsentHandler(c *config.Config, router *httprouter.Router) *consent.Handler {
+func newConsentHandler(c *config.Config, admin *httprouter.Router) *consent.Handler {
 	var ctx = c.Context()
 	h := &consent.Handler{
-		H: herodot.NewJSONWriter(c.GetLogger()),
-		M: ctx.ConsentManager,
+		H:       herodot.NewJSONWriter(c.GetLogger()),
+		M:       ctx.ConsentManager,
+		Storage: ctx.FositeStore,
+		Metrics: c.GetPrometheusMetrics(),
 	}
 
-	h.SetRoutes(router)
+	h.SetRoutes(admin)
 	return h
 }
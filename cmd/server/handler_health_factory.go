@@ -28,7 +28,7 @@ import (
 	"github.com/ory/sqlcon"
 )
 
-func newHealthHandler(c *config.Config, router *httprouter.Router) *health.Handler {
+func newHealthHandler(c *config.Config, public, admin *httprouter.Router) *health.Handler {
 	ctx := c.Context()
 	var rc health.ReadyChecker
 
@@ -43,6 +43,16 @@ func newHealthHandler(c *config.Config, router *httprouter.Router) *health.Handl
 			return con.GetDatabase().Ping()
 		}
 		break
+	case *config.SQLiteConnection:
+		rc = func() error {
+			return con.Ping()
+		}
+		break
+	case *config.BoltConnection:
+		rc = func() error {
+			return con.Ping()
+		}
+		break
 	case *config.PluginConnection:
 		rc = func() error {
 			return con.Ping()
@@ -61,6 +71,11 @@ func newHealthHandler(c *config.Config, router *httprouter.Router) *health.Handl
 		},
 	}
 
-	h.SetRoutes(router)
+	if con := ctx.EphemeralConnection; con != nil {
+		h.ReadyChecks["ephemeral_database"] = con.Ping
+	}
+
+	h.SetRoutes(public)
+	h.SetRoutes(admin)
 	return h
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/ory/herodot"
 	"github.com/ory/hydra/config"
 	"github.com/ory/hydra/jwk"
+	"github.com/ory/hydra/oauth2"
 	"github.com/ory/sqlcon"
 )
 
@@ -39,7 +40,26 @@ func injectJWKManager(c *config.Config) {
 		ctx.KeyManager = &jwk.SQLManager{
 			DB: con.GetDatabase(),
 			Cipher: &jwk.AEAD{
-				Key: c.GetSystemSecret(),
+				Key:         c.GetSystemSecret(),
+				RotatedKeys: c.GetRotatedSystemSecrets(),
+			},
+		}
+		break
+	case *config.SQLiteConnection:
+		ctx.KeyManager = &jwk.SQLManager{
+			DB: con.GetDatabase(),
+			Cipher: &jwk.AEAD{
+				Key:         c.GetSystemSecret(),
+				RotatedKeys: c.GetRotatedSystemSecrets(),
+			},
+		}
+		break
+	case *config.BoltConnection:
+		ctx.KeyManager = &jwk.BoltManager{
+			DB: con.GetDatabase(),
+			Cipher: &jwk.AEAD{
+				Key:         c.GetSystemSecret(),
+				RotatedKeys: c.GetRotatedSystemSecrets(),
 			},
 		}
 		break
@@ -53,14 +73,26 @@ func injectJWKManager(c *config.Config) {
 	default:
 		c.GetLogger().Fatalf("Unknown connection type.")
 	}
+
+	if c.HSMLibrary != "" {
+		m, err := jwk.NewPKCS11Manager(c.HSMLibrary, c.HSMTokenLabel, c.HSMPin, c.GetHSMKeySets(), ctx.KeyManager)
+		if err != nil {
+			c.GetLogger().WithError(err).Fatalf("Could not open PKCS#11 token %s", c.HSMTokenLabel)
+		}
+		ctx.KeyManager = m
+	}
 }
 
-func newJWKHandler(c *config.Config, router *httprouter.Router) *jwk.Handler {
+func newJWKHandler(c *config.Config, public, admin *httprouter.Router) *jwk.Handler {
 	ctx := c.Context()
 	h := &jwk.Handler{
 		H:       herodot.NewJSONWriter(c.GetLogger()),
 		Manager: ctx.KeyManager,
+		Rotator: newKeyRotator(c),
+	}
+	if c.GetAccessTokenStrategy() == oauth2.AccessTokenStrategyJWT {
+		h.WellKnownKeys = append(h.WellKnownKeys, oauth2.AccessTokenKeyName)
 	}
-	h.SetRoutes(router)
+	h.SetRoutes(public, admin)
 	return h
 }
@@ -22,13 +22,16 @@ package server
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gorilla/sessions"
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory/fosite"
 	"github.com/ory/fosite/compose"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
 	"github.com/ory/fosite/handler/openid"
 	"github.com/ory/herodot"
 	"github.com/ory/hydra/client"
@@ -40,6 +43,9 @@ import (
 	"github.com/ory/sqlcon"
 )
 
+// consentRequestMaxAge is the time the user has to finish the login and consent flow of an authorization request.
+const consentRequestMaxAge = time.Minute * 15
+
 func injectFositeStore(c *config.Config, clients client.Manager) {
 	var ctx = c.Context()
 	var store pkg.FositeStorer
@@ -51,6 +57,12 @@ func injectFositeStore(c *config.Config, clients client.Manager) {
 	case *sqlcon.SQLConnection:
 		store = oauth2.NewFositeSQLStore(clients, con.GetDatabase(), c.GetLogger(), c.GetAccessTokenLifespan())
 		break
+	case *config.SQLiteConnection:
+		store = oauth2.NewFositeSQLStore(clients, con.GetDatabase(), c.GetLogger(), c.GetAccessTokenLifespan())
+		break
+	case *config.BoltConnection:
+		store = oauth2.NewFositeBoltStore(clients, con.GetDatabase(), c.GetLogger(), c.GetAccessTokenLifespan())
+		break
 	case *config.PluginConnection:
 		var err error
 		if store, err = con.NewOAuth2Manager(clients); err != nil {
@@ -61,21 +73,40 @@ func injectFositeStore(c *config.Config, clients client.Manager) {
 		panic("Unknown connection type.")
 	}
 
+	if con := ctx.EphemeralConnection; con != nil {
+		var err error
+		if store, err = oauth2.NewFositeRedisStore(store, clients, con.GetClient(), c.GetLogger(), c.GetAuthCodeLifespan()).WithCapabilities(); err != nil {
+			c.GetLogger().WithError(err).Fatalf("Could not use Redis for the OAuth 2.0 storage")
+		}
+	}
+
 	ctx.FositeStore = store
 }
 
-func newOAuth2Provider(c *config.Config) (fosite.OAuth2Provider, string) {
+func newClientAssertionAuthenticator(c *config.Config, clients client.Manager) *oauth2.ClientAssertionAuthenticator {
+	issuer := strings.TrimRight(c.Issuer, "/")
+	return oauth2.NewClientAssertionAuthenticator(
+		c.Context().Hasher,
+		clients,
+		c.Context().FositeStore,
+		&jwk.AEAD{Key: c.GetSystemSecret(), RotatedKeys: c.GetRotatedSystemSecrets()},
+		[]string{
+			issuer,
+			issuer + "/",
+			issuer + oauth2.TokenPath,
+			issuer + oauth2.IntrospectPath,
+			issuer + oauth2.RevocationPath,
+		},
+	)
+}
+
+func newOAuth2Provider(c *config.Config, hasher fosite.Hasher) (fosite.OAuth2Provider, *oauth2.OpenIDConnectStrategy) {
 	var ctx = c.Context()
 	var store = ctx.FositeStore
 
-	privateKey, err := createOrGetJWK(c, oauth2.OpenIDConnectKeyName, "private")
-	if err != nil {
-		c.GetLogger().WithError(err).Fatalf(`Could not fetch private signing key for OpenID Connect - did you forget to run "hydra migrate sql" or forget to set the SYSTEM_SECRET?`)
-	}
-
-	publicKey, err := createOrGetJWK(c, oauth2.OpenIDConnectKeyName, "public")
+	signingKeys, publishedKeys, err := createOrGetSigningKeys(c, oauth2.OpenIDConnectKeyName, c.GetIDTokenSigningAlgorithms())
 	if err != nil {
-		c.GetLogger().WithError(err).Fatalf(`Could not fetch public signing key for OpenID Connect - did you forget to run "hydra migrate sql" or forget to set the SYSTEM_SECRET?`)
+		c.GetLogger().WithError(err).Fatalf(`Could not fetch signing keys for OpenID Connect - did you forget to run "hydra migrate sql" or forget to set the SYSTEM_SECRET?`)
 	}
 
 	fc := &compose.Config{
@@ -89,20 +120,22 @@ func newOAuth2Provider(c *config.Config) (fosite.OAuth2Provider, string) {
 		EnablePKCEPlainChallengeMethod: false,
 	}
 
-	jwtStrategy := compose.NewOpenIDConnectStrategy(jwk.MustRSAPrivate(privateKey))
-	return compose.Compose(
-		fc,
-		store,
-		&compose.CommonStrategy{
-			CoreStrategy:               compose.NewOAuth2HMACStrategy(fc, c.GetSystemSecret()),
-			OpenIDConnectTokenStrategy: jwtStrategy,
-			JWTStrategy:                jwtStrategy,
-		},
-		nil,
+	coreStrategy := newAccessTokenStrategy(c, fc)
+	ctx.FositeStrategy = coreStrategy
+
+	// Database plugins might not implement the storage required for refresh token rotation, in which case refresh
+	// tokens are still rotated but their reuse is not detected.
+	refreshTokenGrantFactory := oauth2.NewRefreshTokenGrantFactory(c.GetLogger())
+	if _, ok := store.(oauth2.RefreshTokenRotationStorage); !ok {
+		c.GetLogger().Warnln("The database plugin does not support refresh token rotation, reuse of refresh tokens will not be detected.")
+		refreshTokenGrantFactory = compose.OAuth2RefreshTokenGrantFactory
+	}
+
+	factories := []compose.Factory{
 		compose.OAuth2AuthorizeExplicitFactory,
 		compose.OAuth2AuthorizeImplicitFactory,
 		compose.OAuth2ClientCredentialsGrantFactory,
-		compose.OAuth2RefreshTokenGrantFactory,
+		refreshTokenGrantFactory,
 		compose.OAuth2PKCEFactory,
 		compose.OpenIDConnectExplicitFactory,
 		compose.OpenIDConnectHybridFactory,
@@ -110,7 +143,88 @@ func newOAuth2Provider(c *config.Config) (fosite.OAuth2Provider, string) {
 		compose.OpenIDConnectRefreshFactory,
 		compose.OAuth2TokenRevocationFactory,
 		compose.OAuth2TokenIntrospectionFactory,
-	), publicKey.KeyID
+		oauth2.TokenExchangeGrantFactory,
+	}
+
+	// Database plugins might not implement the storage of the device authorization grant.
+	if _, ok := store.(oauth2.DeviceCodeStorage); ok {
+		factories = append(factories, oauth2.DeviceCodeGrantFactory)
+	}
+
+	jwtStrategy := &oauth2.OpenIDConnectStrategy{Keys: signingKeys, PublishedKeys: publishedKeys, Expiry: c.GetIDTokenLifespan()}
+	return compose.Compose(
+		fc,
+		store,
+		&compose.CommonStrategy{
+			CoreStrategy:               coreStrategy,
+			OpenIDConnectTokenStrategy: jwtStrategy,
+			JWTStrategy:                jwtStrategy,
+		},
+		hasher,
+		factories...,
+	), jwtStrategy
+}
+
+func newAccessTokenStrategy(c *config.Config, fc *compose.Config) foauth2.CoreStrategy {
+	hmacStrategy := compose.NewOAuth2HMACStrategy(fc, c.GetSystemSecret())
+	var rotatedStrategies []*foauth2.HMACSHAStrategy
+	for _, secret := range c.GetRotatedSystemSecrets() {
+		rotatedStrategies = append(rotatedStrategies, compose.NewOAuth2HMACStrategy(fc, secret))
+	}
+
+	if c.GetAccessTokenStrategy() != oauth2.AccessTokenStrategyJWT {
+		return withRotatedSecrets(hmacStrategy, rotatedStrategies, func(s *foauth2.HMACSHAStrategy) foauth2.CoreStrategy {
+			return s
+		})
+	}
+
+	subjectIdentifierAlgorithms := newSubjectIdentifierAlgorithms(c)
+	keys, publishedKeys, err := createOrGetSigningKeys(c, oauth2.AccessTokenKeyName, []string{c.GetAccessTokenJWTAlgorithm()})
+	if err != nil {
+		c.GetLogger().WithError(err).Fatalf(`Could not fetch signing key for JWT access tokens - did you forget to run "hydra migrate sql" or forget to set the SYSTEM_SECRET?`)
+	}
+
+	return withRotatedSecrets(hmacStrategy, rotatedStrategies, func(s *foauth2.HMACSHAStrategy) foauth2.CoreStrategy {
+		return &oauth2.JWTStrategy{
+			HMACSHAStrategy:            s,
+			Key:                        keys[0],
+			PublishedKeys:              publishedKeys,
+			Issuer:                     c.Issuer,
+			SubjectIdentifierAlgorithm: subjectIdentifierAlgorithms,
+		}
+	})
+}
+
+// withRotatedSecrets builds the strategy of the current system secret, which also validates tokens issued using the
+// rotated system secrets if there are any.
+func withRotatedSecrets(current *foauth2.HMACSHAStrategy, rotated []*foauth2.HMACSHAStrategy, build func(*foauth2.HMACSHAStrategy) foauth2.CoreStrategy) foauth2.CoreStrategy {
+	if len(rotated) == 0 {
+		return build(current)
+	}
+
+	strategy := &oauth2.SecretRotationStrategy{CoreStrategy: build(current)}
+	for _, s := range rotated {
+		strategy.Rotated = append(strategy.Rotated, build(s))
+	}
+	return strategy
+}
+
+func newSubjectIdentifierAlgorithms(c *config.Config) map[string]consent.SubjectIdentifierAlgorithm {
+	algorithms := map[string]consent.SubjectIdentifierAlgorithm{}
+	for _, t := range c.GetSubjectTypesSupported() {
+		switch t {
+		case consent.SubjectTypePublic:
+			algorithms[t] = consent.NewSubjectIdentifierAlgorithmPublic()
+		case consent.SubjectTypePairwise:
+			if len(c.PairwiseSubjectIdentifierSalt) < 8 {
+				c.GetLogger().Fatalf(`Subject type "pairwise" is enabled but OIDC_SUBJECT_TYPE_PAIRWISE_SALT is shorter than 8 characters`)
+			}
+			algorithms[t] = consent.NewSubjectIdentifierAlgorithmPairwise([]byte(c.PairwiseSubjectIdentifierSalt))
+		default:
+			c.GetLogger().Fatalf(`Subject type "%s" set in OIDC_SUBJECT_TYPES_SUPPORTED is not supported, use "public" or "pairwise"`, t)
+		}
+	}
+	return algorithms
 }
 
 func setDefaultConsentURL(s string, c *config.Config, path string) string {
@@ -129,47 +243,69 @@ func setDefaultConsentURL(s string, c *config.Config, path string) string {
 }
 
 //func newOAuth2Handler(c *config.Config, router *httprouter.Router, cm oauth2.ConsentRequestManager, o fosite.OAuth2Provider, idTokenKeyID string) *oauth2.Handler {
-func newOAuth2Handler(c *config.Config, router *httprouter.Router, cm consent.Manager, o fosite.OAuth2Provider, idTokenKeyID string) *oauth2.Handler {
+func newOAuth2Handler(c *config.Config, public, admin *httprouter.Router, cm consent.Manager, o fosite.OAuth2Provider, jwtStrategy *oauth2.OpenIDConnectStrategy, ca *oauth2.ClientAssertionAuthenticator) *oauth2.Handler {
 	c.ConsentURL = setDefaultConsentURL(c.ConsentURL, c, "oauth2/fallbacks/consent")
 	c.LoginURL = setDefaultConsentURL(c.LoginURL, c, "oauth2/fallbacks/consent")
 	c.ErrorURL = setDefaultConsentURL(c.ErrorURL, c, "oauth2/fallbacks/error")
+	c.LogoutRedirectURL = setDefaultConsentURL(c.LogoutRedirectURL, c, "oauth2/fallbacks/logout")
 
 	errorURL, err := url.Parse(c.ErrorURL)
 	pkg.Must(err, "Could not parse error url %s.", errorURL)
 
-	privateKey, err := createOrGetJWK(c, oauth2.OpenIDConnectKeyName, "private")
-	if err != nil {
-		c.GetLogger().WithError(err).Fatalf(`Could not fetch private signing key for OpenID Connect - did you forget to run "hydra migrate sql" or forget to set the SYSTEM_SECRET?`)
-	}
+	logoutRedirectURL, err := url.Parse(c.LogoutRedirectURL)
+	pkg.Must(err, "Could not parse logout redirect url %s.", logoutRedirectURL)
 
-	jwtStrategy := compose.NewOpenIDConnectStrategy(jwk.MustRSAPrivate(privateKey))
+	subjectIdentifierAlgorithms := newSubjectIdentifierAlgorithms(c)
+
+	consentStrategy := consent.NewStrategy(
+		c.LoginURL, c.ConsentURL, c.Issuer,
+		"/oauth2/auth", cm,
+		sessions.NewCookieStore(c.GetCookieSecret()), c.GetScopeStrategy(),
+		!c.ForceHTTP, consentRequestMaxAge,
+		jwtStrategy,
+		openid.NewOpenIDConnectRequestValidator(nil, jwtStrategy),
+		subjectIdentifierAlgorithms,
+		c.Context().FositeStore,
+		consent.NewBackChannelLogoutNotifier(jwtStrategy, "", c.Issuer, c.GetLogger()),
+	)
+	consentStrategy.IDTokenHintMaxAge = c.GetIDTokenHintMaxAge()
 
 	handler := &oauth2.Handler{
-		ScopesSupported:  c.OpenIDDiscoveryScopesSupported,
-		UserinfoEndpoint: c.OpenIDDiscoveryUserinfoEndpoint,
-		ClaimsSupported:  c.OpenIDDiscoveryClaimsSupported,
-		ForcedHTTP:       c.ForceHTTP,
-		OAuth2:           o,
-		ScopeStrategy:    c.GetScopeStrategy(),
-		Consent: consent.NewStrategy(
-			c.LoginURL, c.ConsentURL, c.Issuer,
-			"/oauth2/auth", cm,
-			sessions.NewCookieStore(c.GetCookieSecret()), c.GetScopeStrategy(),
-			!c.ForceHTTP, time.Minute*15,
-			jwtStrategy,
-			openid.NewOpenIDConnectRequestValidator(nil, jwtStrategy),
-		),
-		Storage:             c.Context().FositeStore,
-		ErrorURL:            *errorURL,
-		H:                   herodot.NewJSONWriter(c.GetLogger()),
-		AccessTokenLifespan: c.GetAccessTokenLifespan(),
-		CookieStore:         sessions.NewCookieStore(c.GetCookieSecret()),
-		IssuerURL:           c.Issuer,
-		L:                   c.GetLogger(),
-		IDTokenPublicKeyID:  idTokenKeyID,
-		IDTokenLifespan:     c.GetIDTokenLifespan(),
-	}
-
-	handler.SetRoutes(router)
+		ScopesSupported:              c.OpenIDDiscoveryScopesSupported,
+		UserinfoEndpoint:             c.OpenIDDiscoveryUserinfoEndpoint,
+		ClaimsSupported:              c.OpenIDDiscoveryClaimsSupported,
+		ForcedHTTP:                   c.ForceHTTP,
+		OAuth2:                       o,
+		ScopeStrategy:                c.GetScopeStrategy(),
+		Consent:                      consentStrategy,
+		Storage:                      c.Context().FositeStore,
+		ErrorURL:                     *errorURL,
+		LogoutRedirectURL:            *logoutRedirectURL,
+		H:                            herodot.NewJSONWriter(c.GetLogger()),
+		AccessTokenLifespan:          c.GetAccessTokenLifespan(),
+		CookieStore:                  sessions.NewCookieStore(c.GetCookieSecret()),
+		IssuerURL:                    c.Issuer,
+		L:                            c.GetLogger(),
+		IDTokenSigningAlgorithms:     jwtStrategy.Algorithms(),
+		JWTStrategy:                  jwtStrategy,
+		HTTPClient:                   &http.Client{Timeout: time.Second * 10},
+		IDTokenLifespan:              c.GetIDTokenLifespan(),
+		Metrics:                      c.GetPrometheusMetrics(),
+		SubjectTypes:                 c.GetSubjectTypesSupported(),
+		SubjectIdentifierAlgorithm:   subjectIdentifierAlgorithms,
+		ClientRegistrationEnabled:    c.ClientRegistrationEnabled,
+		ClientAssertionAuthenticator: ca,
+		Hasher:                       ca,
+		DeviceCodeLifespan:           c.GetDeviceCodeLifespan(),
+		ConsentManager:               cm,
+	}
+
+	if store, ok := c.Context().FositeStore.(oauth2.DeviceCodeStorage); ok {
+		handler.DeviceStorage = store
+	} else {
+		c.GetLogger().Warnln("The database plugin does not support the OAuth 2.0 Device Authorization Grant, the device endpoints are disabled.")
+	}
+
+	handler.SetRoutes(public, admin)
 	return handler
 }
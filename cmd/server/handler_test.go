@@ -28,11 +28,12 @@ import (
 )
 
 func TestStart(t *testing.T) {
-	router := httprouter.New()
+	frontend := httprouter.New()
+	backend := httprouter.New()
 	h := &Handler{
 		Config: &config.Config{
 			DatabaseURL: "memory",
 		},
 	}
-	h.registerRoutes(router)
+	h.registerRoutes(frontend, backend)
 }
@@ -26,6 +26,7 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"io/ioutil"
 	"math/big"
 	"strings"
 	"time"
@@ -42,12 +43,16 @@ const (
 	tlsKeyName = "hydra.https-tls"
 )
 
-func loadCertificateFromFile(cmd *cobra.Command, c *config.Config) *tls.Certificate {
-	keyPath := viper.GetString("HTTPS_TLS_KEY_PATH")
-	certPath := viper.GetString("HTTPS_TLS_CERT_PATH")
-	if kp, _ := cmd.Flags().GetString("https-tls-key-path"); kp != "" {
+// loadCertificateFromFile loads the certificate configured by the <prefix>HTTPS_TLS_KEY_PATH and
+// <prefix>HTTPS_TLS_CERT_PATH environment variables or the respective flags. The prefix is either empty for the
+// public listener or ADMIN_ for the administrative listener.
+func loadCertificateFromFile(cmd *cobra.Command, c *config.Config, prefix string) *tls.Certificate {
+	flagPrefix := strings.ToLower(strings.Replace(prefix, "_", "-", -1))
+	keyPath := viper.GetString(prefix + "HTTPS_TLS_KEY_PATH")
+	certPath := viper.GetString(prefix + "HTTPS_TLS_CERT_PATH")
+	if kp, _ := cmd.Flags().GetString(flagPrefix + "https-tls-key-path"); kp != "" {
 		keyPath = kp
-	} else if cp, _ := cmd.Flags().GetString("https-tls-cert-path"); cp != "" {
+	} else if cp, _ := cmd.Flags().GetString(flagPrefix + "https-tls-cert-path"); cp != "" {
 		certPath = cp
 	} else if keyPath == "" || certPath == "" {
 		return nil
@@ -61,9 +66,9 @@ func loadCertificateFromFile(cmd *cobra.Command, c *config.Config) *tls.Certific
 	return &cert
 }
 
-func loadCertificateFromEnv(c *config.Config) *tls.Certificate {
-	keyString := viper.GetString("HTTPS_TLS_KEY")
-	certString := viper.GetString("HTTPS_TLS_CERT")
+func loadCertificateFromEnv(c *config.Config, prefix string) *tls.Certificate {
+	keyString := viper.GetString(prefix + "HTTPS_TLS_KEY")
+	certString := viper.GetString(prefix + "HTTPS_TLS_CERT")
 	if keyString == "" || certString == "" {
 		return nil
 	}
@@ -81,11 +86,40 @@ func loadCertificateFromEnv(c *config.Config) *tls.Certificate {
 	return &cert
 }
 
+// getOrCreateAdminTLSCertificate returns the certificate of the administrative listener, which is configured using the
+// ADMIN_HTTPS_TLS_* environment variables. If none is configured, the certificate of the public listener is used.
+func getOrCreateAdminTLSCertificate(cmd *cobra.Command, c *config.Config) tls.Certificate {
+	if cert := loadCertificateFromFile(cmd, c, "ADMIN_"); cert != nil {
+		c.GetLogger().Info("Loaded admin tls certificate from file")
+		return *cert
+	} else if cert := loadCertificateFromEnv(c, "ADMIN_"); cert != nil {
+		c.GetLogger().Info("Loaded admin certificate from environment variable")
+		return *cert
+	}
+
+	return getOrCreateTLSCertificate(cmd, c)
+}
+
+// loadClientCertificateAuthorities loads the pem encoded certificate authorities which are used to verify TLS client
+// certificates.
+func loadClientCertificateAuthorities(c *config.Config, path string) *x509.CertPool {
+	pemCerts, err := ioutil.ReadFile(path)
+	if err != nil {
+		c.GetLogger().WithError(err).Fatalf("Could not read the TLS client certificate authorities from %s", path)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemCerts) {
+		c.GetLogger().Fatalf("Could not parse any TLS client certificate authority from %s", path)
+	}
+	return pool
+}
+
 func getOrCreateTLSCertificate(cmd *cobra.Command, c *config.Config) tls.Certificate {
-	if cert := loadCertificateFromFile(cmd, c); cert != nil {
+	if cert := loadCertificateFromFile(cmd, c, ""); cert != nil {
 		c.GetLogger().Info("Loaded tls certificate from file")
 		return *cert
-	} else if cert := loadCertificateFromEnv(c); cert != nil {
+	} else if cert := loadCertificateFromEnv(c, ""); cert != nil {
 		c.GetLogger().Info("Loaded certificate from environment variable")
 		return *cert
 	}
@@ -0,0 +1,58 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package server
+
+import (
+	"strings"
+
+	"github.com/rs/cors"
+	"github.com/spf13/viper"
+)
+
+// parseAdminCORSOptions returns the CORS options of the administrative listener, which are configured using the
+// ADMIN_CORS_* environment variables. CORS is disabled on the administrative listener unless
+// ADMIN_CORS_ALLOWED_ORIGINS is set, in which case the second return value is true.
+func parseAdminCORSOptions() (cors.Options, bool) {
+	origins := splitEnv("ADMIN_CORS_ALLOWED_ORIGINS")
+	if len(origins) == 0 {
+		return cors.Options{}, false
+	}
+
+	return cors.Options{
+		AllowedOrigins:   origins,
+		AllowedMethods:   splitEnv("ADMIN_CORS_ALLOWED_METHODS"),
+		AllowedHeaders:   splitEnv("ADMIN_CORS_ALLOWED_HEADERS"),
+		ExposedHeaders:   splitEnv("ADMIN_CORS_EXPOSED_HEADERS"),
+		AllowCredentials: viper.GetBool("ADMIN_CORS_ALLOWED_CREDENTIALS"),
+		MaxAge:           viper.GetInt("ADMIN_CORS_MAX_AGE"),
+		Debug:            viper.GetBool("ADMIN_CORS_DEBUG"),
+	}, true
+}
+
+func splitEnv(key string) []string {
+	var values []string
+	for _, v := range strings.Split(viper.GetString(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
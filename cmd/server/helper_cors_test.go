@@ -0,0 +1,46 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package server
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAdminCORSOptions(t *testing.T) {
+	_, enabled := parseAdminCORSOptions()
+	assert.False(t, enabled)
+
+	viper.Set("ADMIN_CORS_ALLOWED_ORIGINS", "https://admin.example.com, https://*.example.org")
+	viper.Set("ADMIN_CORS_ALLOWED_METHODS", "GET,POST")
+	viper.Set("ADMIN_CORS_MAX_AGE", "10")
+	defer viper.Reset()
+
+	options, enabled := parseAdminCORSOptions()
+	assert.True(t, enabled)
+	assert.Equal(t, []string{"https://admin.example.com", "https://*.example.org"}, options.AllowedOrigins)
+	assert.Equal(t, []string{"GET", "POST"}, options.AllowedMethods)
+	assert.Empty(t, options.AllowedHeaders)
+	assert.Equal(t, 10, options.MaxAge)
+	assert.False(t, options.AllowCredentials)
+}
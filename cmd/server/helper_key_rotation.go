@@ -0,0 +1,157 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package server
+
+import (
+	"time"
+
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/hydra/config"
+	"github.com/ory/hydra/jwk"
+	"github.com/ory/hydra/oauth2"
+)
+
+// signingKeySetter is implemented by the strategies which sign tokens with the keys of a JSON Web Key Set.
+type signingKeySetter interface {
+	SetKeys(keys, published []oauth2.SigningKey)
+}
+
+// signingKeyRotation keeps the keys of the strategies signing with a JSON Web Key Set up to date. It rotates the keys
+// once they are older than the rotation interval, removes retired keys and loads the keys which became active or
+// retired. If the key manager is shared by several nodes, only the node holding the rotation lock of the set rotates
+// and removes keys.
+type signingKeyRotation struct {
+	c          *config.Config
+	set        string
+	algorithms []string
+	rotator    *jwk.Rotator
+	strategies []signingKeySetter
+	interval   time.Duration
+
+	// node identifies this node as owner of the rotation lock.
+	node string
+}
+
+func newKeyRotator(c *config.Config) *jwk.Rotator {
+	if _, ok := c.Context().KeyManager.(jwk.MetadataManager); !ok {
+		c.GetLogger().Warnln("The JSON Web Key manager does not support key rotation, rotating keys is disabled.")
+		return nil
+	}
+
+	return &jwk.Rotator{
+		Manager:         c.Context().KeyManager,
+		Generators:      (&jwk.Handler{}).GetGenerators(),
+		ActivationDelay: c.GetKeyRotationActivationDelay(),
+		RetirementDelay: c.GetKeyRotationRetirementDelay(),
+	}
+}
+
+// jwtAccessTokenStrategies returns the JWT access token strategies of s, there is one for every system secret.
+func jwtAccessTokenStrategies(s foauth2.CoreStrategy) (strategies []signingKeySetter) {
+	switch s := s.(type) {
+	case *oauth2.JWTStrategy:
+		strategies = append(strategies, s)
+	case *oauth2.SecretRotationStrategy:
+		strategies = append(strategies, jwtAccessTokenStrategies(s.CoreStrategy)...)
+		for _, rotated := range s.Rotated {
+			strategies = append(strategies, jwtAccessTokenStrategies(rotated)...)
+		}
+	}
+	return strategies
+}
+
+// run refreshes the signing keys every tick, it never returns.
+func (r *signingKeyRotation) run(tick time.Duration) {
+	for range time.Tick(tick) {
+		r.refresh(tick * 3)
+	}
+}
+
+// lead returns true if this node may rotate the set and remove its retired keys. If the key manager can be shared by
+// several nodes, this requires holding the rotation lock of the set, which is held for lockFor and renewed by every
+// refresh.
+func (r *signingKeyRotation) lead(lockFor time.Duration) bool {
+	locker, ok := r.rotator.Manager.(jwk.Locker)
+	if !ok {
+		return true
+	}
+
+	ok, err := locker.TryLock("rotation:"+r.set, r.node, time.Now().UTC().Add(lockFor))
+	if err != nil {
+		r.c.GetLogger().WithError(err).Errorf("Could not acquire the rotation lock of JSON Web Key Set %s", r.set)
+		return false
+	}
+	return ok
+}
+
+func (r *signingKeyRotation) refresh(lockFor time.Duration) {
+	logger := r.c.GetLogger()
+
+	if r.lead(lockFor) {
+		r.rotate()
+	}
+
+	keys, published, err := createOrGetSigningKeys(r.c, r.set, r.algorithms)
+	if err != nil {
+		logger.WithError(err).Errorf("Could not reload the keys of JSON Web Key Set %s", r.set)
+		return
+	}
+	for _, strategy := range r.strategies {
+		strategy.SetKeys(keys, published)
+	}
+}
+
+// rotate rotates the set if the rotation interval has passed and removes retired keys.
+func (r *signingKeyRotation) rotate() {
+	logger := r.c.GetLogger()
+
+	if r.interval > 0 {
+		if due, err := r.rotationDue(); err != nil {
+			logger.WithError(err).Errorf("Could not check whether JSON Web Key Set %s has to be rotated", r.set)
+		} else if due {
+			logger.Infof("Rotating JSON Web Key Set %s...", r.set)
+			if _, err := r.rotator.Rotate(r.set); err != nil {
+				logger.WithError(err).Errorf("Could not rotate JSON Web Key Set %s", r.set)
+			}
+		}
+	}
+
+	if err := r.rotator.RemoveRetiredKeys(r.set); err != nil {
+		logger.WithError(err).Errorf("Could not remove retired keys from JSON Web Key Set %s", r.set)
+	}
+}
+
+// rotationDue returns true if the newest key of the set is older than the rotation interval.
+func (r *signingKeyRotation) rotationDue() (bool, error) {
+	metadata, err := r.rotator.Manager.(jwk.MetadataManager).GetKeySetMetadata(r.set)
+	if err != nil {
+		return false, err
+	}
+
+	var newest time.Time
+	for _, md := range metadata {
+		if md.CreatedAt.After(newest) {
+			newest = md.CreatedAt
+		}
+	}
+
+	return !newest.IsZero() && time.Now().UTC().After(newest.Add(r.interval)), nil
+}
@@ -21,23 +21,32 @@
 package server
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/rsa"
+	"time"
 
 	"github.com/ory/hydra/config"
 	"github.com/ory/hydra/jwk"
+	"github.com/ory/hydra/oauth2"
 	"github.com/ory/hydra/pkg"
 	"github.com/pkg/errors"
 	"github.com/square/go-jose"
+	"golang.org/x/crypto/ed25519"
 )
 
 func createOrGetJWK(c *config.Config, set string, prefix string) (key *jose.JSONWebKey, err error) {
+	return createOrGetJWKWithGenerator(c, set, prefix, "RS256", &jwk.RS256Generator{})
+}
+
+func createOrGetJWKWithGenerator(c *config.Config, set string, prefix string, alg string, generator jwk.KeyGenerator) (key *jose.JSONWebKey, err error) {
 	ctx := c.Context()
 
 	keys, err := ctx.KeyManager.GetKeySet(set)
 	if errors.Cause(err) == pkg.ErrNotFound || keys != nil && len(keys.Keys) == 0 {
 		c.GetLogger().Infof("JSON Web Key Set %s does not exist yet, generating new key pair...", set)
-		keys, err = createJWKS(ctx, set)
+		keys, err = createJWKS(ctx, set, alg, generator)
 		if err != nil {
 			return nil, err
 		}
@@ -49,7 +58,7 @@ func createOrGetJWK(c *config.Config, set string, prefix string) (key *jose.JSON
 	if err != nil {
 		c.GetLogger().Infof("JSON Web Key with prefix %s not found in JSON Web Key Set %s, generating new key pair...", prefix, set)
 
-		keys, err = createJWKS(ctx, set)
+		keys, err = createJWKS(ctx, set, alg, generator)
 		if err != nil {
 			return nil, err
 		}
@@ -63,8 +72,17 @@ func createOrGetJWK(c *config.Config, set string, prefix string) (key *jose.JSON
 	return key, nil
 }
 
-func createJWKS(ctx *config.Context, set string) (*jose.JSONWebKeySet, error) {
-	generator := jwk.RS256Generator{}
+// createJWKS generates a key pair of the JWS algorithm using the generator and adds it to the set. If the private keys
+// of the set are kept in an external key service, the key pair is generated by the key service instead.
+func createJWKS(ctx *config.Context, set string, alg string, generator jwk.KeyGenerator) (*jose.JSONWebKeySet, error) {
+	if m, ok := ctx.KeyManager.(jwk.ExternalManager); ok && m.IsExternalKeySet(set) {
+		keys, err := m.GenerateKeySet(set, "", alg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not generate %s key", set)
+		}
+		return keys, nil
+	}
+
 	keys, err := generator.Generate("")
 	if err != nil {
 		return nil, errors.Wrapf(err, "Could not generate %s key", set)
@@ -83,12 +101,163 @@ func createJWKS(ctx *config.Context, set string) (*jose.JSONWebKeySet, error) {
 	return keys, nil
 }
 
+// signingKeyGenerators are the generators of the keys for the JWS algorithms OpenID Connect can sign tokens with.
+var signingKeyGenerators = map[string]jwk.KeyGenerator{
+	"RS256": &jwk.RS256Generator{},
+	"PS256": &jwk.PS256Generator{},
+	"ES256": &jwk.ECDSA256Generator{},
+	"EdDSA": &jwk.EdDSAGenerator{},
+}
+
+// createOrGetSigningKeys returns the active signing key of each of the algorithms, in the same order, and the other
+// keys of the JSON Web Key Set which have not retired yet, which tokens are still verified with. If a set holds more
+// than one active key of an algorithm, the one activated last is used. Algorithms which do not have an active key yet
+// are given a new key which is active right away.
+func createOrGetSigningKeys(c *config.Config, set string, algorithms []string) ([]oauth2.SigningKey, []oauth2.SigningKey, error) {
+	ctx := c.Context()
+
+	active, published, err := getSigningKeys(ctx.KeyManager, set)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signingKeys := make([]oauth2.SigningKey, len(algorithms))
+	for i, alg := range algorithms {
+		if key, ok := active[alg]; ok {
+			signingKeys[i] = key
+			continue
+		}
+
+		generator, ok := signingKeyGenerators[alg]
+		if !ok {
+			return nil, nil, errors.Errorf("JWS algorithm %s is not supported", alg)
+		}
+
+		c.GetLogger().Infof("JSON Web Key Set %s does not contain an active %s signing key yet, generating new key pair...", set, alg)
+		generated, err := createJWKS(ctx, set, alg, generator)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		keys, _ := findSigningKeys(generated, nil, time.Now().UTC())
+		key, ok := keys[alg]
+		if !ok {
+			return nil, nil, errors.Errorf("Generated JSON Web Key Set %s does not contain a %s signing key", set, alg)
+		}
+		signingKeys[i] = key
+	}
+
+	var verificationKeys []oauth2.SigningKey
+	for _, key := range published {
+		if !hasSigningKey(signingKeys, key.PublicKeyID) {
+			verificationKeys = append(verificationKeys, key)
+		}
+	}
+
+	return signingKeys, verificationKeys, nil
+}
+
+// getSigningKeys returns the active signing keys of the set by algorithm and all keys of the set which have not retired
+// yet. Keys of managers which do not keep a rotation schedule are always active.
+func getSigningKeys(m jwk.Manager, set string) (map[string]oauth2.SigningKey, []oauth2.SigningKey, error) {
+	keys, err := m.GetKeySet(set)
+	if errors.Cause(err) == pkg.ErrNotFound {
+		return map[string]oauth2.SigningKey{}, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	var metadata map[string]jwk.KeyMetadata
+	if mm, ok := m.(jwk.MetadataManager); ok {
+		if metadata, err = mm.GetKeySetMetadata(set); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	active, published := findSigningKeys(keys, metadata, time.Now().UTC())
+	return active, published, nil
+}
+
+// findSigningKeys pairs the private keys of the set with their public keys. It returns the active keys by algorithm,
+// the one activated last if there are several, and all keys which have not retired at now.
+func findSigningKeys(set *jose.JSONWebKeySet, metadata map[string]jwk.KeyMetadata, now time.Time) (map[string]oauth2.SigningKey, []oauth2.SigningKey) {
+	active := map[string]oauth2.SigningKey{}
+	activatedAt := map[string]time.Time{}
+	var published []oauth2.SigningKey
+
+	private, err := jwk.FindKeysByPrefix(set, "private")
+	if err != nil {
+		return active, nil
+	}
+
+	public, err := jwk.FindKeysByPrefix(set, "public")
+	if err != nil {
+		return active, nil
+	}
+
+	for i, key := range private.Keys {
+		md, scheduled := metadata[key.KeyID]
+		if scheduled && md.IsRetired(now) {
+			continue
+		}
+
+		alg := jwk.KeyAlgorithm(&key)
+		if _, ok := signingKeyGenerators[alg]; !ok {
+			continue
+		}
+
+		// Private and public keys do not necessarily share the same key ID, so the public key is looked up by its
+		// thumbprint.
+		thumbprint, err := (&jose.JSONWebKey{Key: signingPublicKey(key.Key)}).Thumbprint(crypto.SHA256)
+		if err != nil {
+			continue
+		}
+
+		for _, p := range public.Keys {
+			if t, err := p.Thumbprint(crypto.SHA256); err != nil || !bytes.Equal(t, thumbprint) {
+				continue
+			}
+
+			signingKey := oauth2.SigningKey{Algorithm: alg, PrivateKey: &private.Keys[i], PublicKeyID: p.KeyID}
+			published = append(published, signingKey)
+
+			if !scheduled || md.IsActive(now) {
+				if previous, ok := activatedAt[alg]; !ok || md.ActivatesAt.After(previous) {
+					active[alg] = signingKey
+					activatedAt[alg] = md.ActivatesAt
+				}
+			}
+			break
+		}
+	}
+
+	return active, published
+}
+
+func hasSigningKey(keys []oauth2.SigningKey, kid string) bool {
+	for _, k := range keys {
+		if k.PublicKeyID == kid {
+			return true
+		}
+	}
+	return false
+}
+
+func signingPublicKey(key interface{}) interface{} {
+	if k, ok := key.(ed25519.PrivateKey); ok {
+		return k.Public()
+	}
+	return publicKey(key)
+}
+
 func publicKey(key interface{}) interface{} {
 	switch k := key.(type) {
 	case *rsa.PrivateKey:
 		return &k.PublicKey
 	case *ecdsa.PrivateKey:
 		return &k.PublicKey
+	case crypto.Signer:
+		return k.Public()
 	default:
 		return nil
 	}
@@ -0,0 +1,76 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ory/hydra/jwk"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindSigningKeys(t *testing.T) {
+	previous, err := (&jwk.ECDSA256Generator{}).Generate("previous")
+	require.NoError(t, err)
+	current, err := (&jwk.ECDSA256Generator{}).Generate("current")
+	require.NoError(t, err)
+	next, err := (&jwk.ECDSA256Generator{}).Generate("next")
+	require.NoError(t, err)
+	retired, err := (&jwk.ECDSA256Generator{}).Generate("retired")
+	require.NoError(t, err)
+
+	set := &jose.JSONWebKeySet{}
+	for _, keys := range []*jose.JSONWebKeySet{retired, previous, current, next} {
+		set.Keys = append(set.Keys, keys.Keys...)
+	}
+
+	now := time.Now().UTC()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	metadata := map[string]jwk.KeyMetadata{
+		"private:retired":  {KeyID: "private:retired", ActivatesAt: now.Add(-time.Hour * 3), RetiresAt: &past},
+		"private:previous": {KeyID: "private:previous", ActivatesAt: now.Add(-time.Hour * 2), RetiresAt: &future},
+		"private:current":  {KeyID: "private:current", ActivatesAt: past},
+		"private:next":     {KeyID: "private:next", ActivatesAt: future},
+	}
+
+	t.Run("case=without metadata", func(t *testing.T) {
+		active, published := findSigningKeys(set, nil, now)
+		require.Contains(t, active, "ES256")
+		assert.Equal(t, "public:retired", active["ES256"].PublicKeyID)
+		assert.Len(t, published, 4)
+	})
+
+	t.Run("case=with metadata", func(t *testing.T) {
+		active, published := findSigningKeys(set, metadata, now)
+		require.Contains(t, active, "ES256")
+		assert.Equal(t, "public:current", active["ES256"].PublicKeyID)
+		assert.Equal(t, "ES256", active["ES256"].Algorithm)
+
+		var ids []string
+		for _, key := range published {
+			ids = append(ids, key.PublicKeyID)
+		}
+		assert.Equal(t, []string{"public:previous", "public:current", "public:next"}, ids)
+	})
+}
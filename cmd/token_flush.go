@@ -39,5 +39,8 @@ func init() {
 
 	tokenFlushCmd.Flags().Duration("min-age", time.Duration(0), "Skip removing tokens which do not satisfy the minimum age (1s, 1m, 1h, 1d)")
 	tokenFlushCmd.Flags().String("access-token", os.Getenv("OAUTH2_ACCESS_TOKEN"), "Set an access token to be used in the Authorization header, defaults to environment variable ACCESS_TOKEN")
+	tokenFlushCmd.Flags().String("client-id", os.Getenv("OAUTH2_CLIENT_ID"), "Use the provided OAuth 2.0 Client ID to request an access token if no access token is set, defaults to environment variable OAUTH2_CLIENT_ID")
+	tokenFlushCmd.Flags().String("client-secret", os.Getenv("OAUTH2_CLIENT_SECRET"), "Use the provided OAuth 2.0 Client Secret to request an access token if no access token is set, defaults to environment variable OAUTH2_CLIENT_SECRET")
+	tokenFlushCmd.Flags().String("token-url", os.Getenv("OAUTH2_TOKEN_URL"), "Set the URL of the OAuth 2.0 Token Endpoint on the public port, which is used with --client-id and --client-secret, defaults to environment variable OAUTH2_TOKEN_URL")
 	tokenFlushCmd.Flags().String("endpoint", os.Getenv("HYDRA_URL"), "Set the URL where ORY Hydra is hosted, defaults to environment variable HYDRA_URL")
 }
@@ -23,7 +23,9 @@ package cmd
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
@@ -35,6 +37,7 @@ import (
 	"github.com/ory/go-convenience/urlx"
 	"github.com/ory/hydra/pkg"
 	"github.com/ory/hydra/rand/sequence"
+	hydra "github.com/ory/hydra/sdk/go/hydra/swagger"
 	"github.com/spf13/cobra"
 	"github.com/toqueteos/webbrowser"
 	"golang.org/x/oauth2"
@@ -97,6 +100,13 @@ var tokenUserCmd = &cobra.Command{
 			Scopes:      scopes,
 		}
 
+		if ok, _ := cmd.Flags().GetBool("device"); ok {
+			du, err := url.Parse(c.GetClusterURLWithoutTailingSlash(cmd))
+			pkg.Must(err, `Unable to parse cluster url ("%s"): %s`, c.GetClusterURLWithoutTailingSlash(cmd), err)
+			runDeviceFlow(ctx, conf, urlx.AppendPaths(du, "/oauth2/device/auth").String())
+			return
+		}
+
 		state, err := sequence.RuneSequence(24, sequence.AlphaLower)
 		pkg.Must(err, "Could not generate random state: %s", err)
 
@@ -187,9 +197,86 @@ var tokenUserCmd = &cobra.Command{
 	},
 }
 
+// runDeviceFlow performs the OAuth 2.0 Device Authorization Grant and polls the token endpoint until the end user has
+// approved or denied the request.
+func runDeviceFlow(ctx context.Context, conf oauth2.Config, deviceURL string) {
+	client := http.DefaultClient
+	if hc, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok {
+		client = hc
+	}
+
+	var auth hydra.DeviceAuthorizationResponse
+	if status, body := postDeviceForm(client, deviceURL, conf, url.Values{"scope": {strings.Join(conf.Scopes, " ")}}); status != http.StatusOK {
+		fmt.Printf("Device authorization request failed with status code %d: %s\n", status, body)
+		os.Exit(1)
+	} else {
+		err := json.Unmarshal(body, &auth)
+		pkg.Must(err, "Could not decode device authorization response: %s", err)
+	}
+
+	fmt.Printf("To authorize this device, navigate to:\n\n\t%s\n\nand enter the code:\n\n\t%s\n\n", auth.VerificationUri, auth.UserCode)
+	fmt.Println("Waiting for the authorization to complete...")
+
+	interval := time.Duration(auth.Interval) * time.Second
+	for {
+		time.Sleep(interval)
+
+		var token struct {
+			AccessToken      string `json:"access_token"`
+			RefreshToken     string `json:"refresh_token"`
+			IDToken          string `json:"id_token"`
+			ExpiresIn        int64  `json:"expires_in"`
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+
+		status, body := postDeviceForm(client, conf.Endpoint.TokenURL, conf, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {auth.DeviceCode},
+		})
+		err := json.Unmarshal(body, &token)
+		pkg.Must(err, "Could not decode token response: %s", err)
+
+		if status == http.StatusOK {
+			fmt.Printf("Access Token:\n\t%s\n", token.AccessToken)
+			fmt.Printf("Refresh Token:\n\t%s\n\n", token.RefreshToken)
+			fmt.Printf("Expires in:\n\t%s\n\n", time.Now().Add(time.Duration(token.ExpiresIn)*time.Second))
+			if token.IDToken != "" {
+				fmt.Printf("ID Token:\n\t%s\n\n", token.IDToken)
+			}
+			return
+		}
+
+		switch token.Error {
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			fmt.Printf("Unable to obtain a token: %s: %s\n", token.Error, token.ErrorDescription)
+			os.Exit(1)
+		}
+	}
+}
+
+func postDeviceForm(client *http.Client, endpoint string, conf oauth2.Config, form url.Values) (int, []byte) {
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	pkg.Must(err, "Could not create request: %s", err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(conf.ClientID), url.QueryEscape(conf.ClientSecret))
+
+	res, err := client.Do(req)
+	pkg.Must(err, "Could not perform request: %s", err)
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	pkg.Must(err, "Could not read response: %s", err)
+	return res.StatusCode, body
+}
+
 func init() {
 	tokenCmd.AddCommand(tokenUserCmd)
 	tokenUserCmd.Flags().Bool("no-open", false, "Do not open the browser window automatically")
+	tokenUserCmd.Flags().Bool("device", false, "Use the OAuth 2.0 Device Authorization Grant instead of starting a web server")
 	tokenUserCmd.Flags().IntP("port", "p", 4445, "The port on which the server should run")
 	tokenUserCmd.Flags().StringSlice("scope", []string{"offline", "openid"}, "Request OAuth2 scope")
 	tokenUserCmd.Flags().StringSlice("prompt", []string{}, "Set the OpenID Connect prompt parameter")
@@ -0,0 +1,66 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package config
+
+import (
+	"net/url"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/ory/hydra/pkg"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// BoltConnection is an embedded BoltDB database stored in a single file, for example "bolt:///var/lib/hydra/hydra.db".
+type BoltConnection struct {
+	db     *bolt.DB
+	Logger logrus.FieldLogger
+}
+
+func NewBoltConnection(dsn string, l logrus.FieldLogger) (*BoltConnection, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	path := u.Host + u.Path
+	if path == "" {
+		return nil, errors.Errorf(`DATABASE_URL "%s" does not contain the path of the database file`, dsn)
+	}
+
+	db, err := pkg.OpenBolt(path)
+	if err != nil {
+		return nil, err
+	}
+
+	l.Infof("Opened BoltDB database %s", path)
+	return &BoltConnection{db: db, Logger: l}, nil
+}
+
+func (c *BoltConnection) GetDatabase() *bolt.DB {
+	return c.db
+}
+
+func (c *BoltConnection) Ping() error {
+	return c.db.View(func(*bolt.Tx) error {
+		return nil
+	})
+}
@@ -115,7 +115,8 @@ func (c *PluginConnection) NewJWKManager() (jwk.Manager, error) {
 		return nil, errors.New("Unable to type assert `NewJWKManager`")
 	} else {
 		return m(c.db, &jwk.AEAD{
-			Key: c.Config.GetSystemSecret(),
+			Key:         c.Config.GetSystemSecret(),
+			RotatedKeys: c.Config.GetRotatedSystemSecrets(),
 		}), nil
 	}
 }
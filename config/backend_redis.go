@@ -0,0 +1,51 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package config
+
+import (
+	"github.com/go-redis/redis"
+	"github.com/ory/hydra/pkg"
+	"github.com/sirupsen/logrus"
+)
+
+// RedisConnection is a Redis server storing short-lived data, for example "redis://:password@localhost:6379/0".
+type RedisConnection struct {
+	r      *redis.Client
+	Logger logrus.FieldLogger
+}
+
+func NewRedisConnection(dsn string, l logrus.FieldLogger) (*RedisConnection, error) {
+	r, err := pkg.OpenRedis(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	l.Info("Connected to Redis server")
+	return &RedisConnection{r: r, Logger: l}, nil
+}
+
+func (c *RedisConnection) GetClient() *redis.Client {
+	return c.r
+}
+
+func (c *RedisConnection) Ping() error {
+	return c.r.Ping().Err()
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package config
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/ory/hydra/pkg"
+	"github.com/sirupsen/logrus"
+)
+
+// SQLiteConnection is a SQLite database stored in a single file, for example "sqlite:///var/lib/hydra/hydra.sqlite".
+type SQLiteConnection struct {
+	db     *sqlx.DB
+	Logger logrus.FieldLogger
+}
+
+func NewSQLiteConnection(dsn string, l logrus.FieldLogger) (*SQLiteConnection, error) {
+	db, err := pkg.OpenSQLite(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	l.Info("Opened SQLite database")
+	return &SQLiteConnection{db: db, Logger: l}, nil
+}
+
+func (c *SQLiteConnection) GetDatabase() *sqlx.DB {
+	return c.db
+}
+
+func (c *SQLiteConnection) Ping() error {
+	return c.db.Ping()
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/ory/fosite"
 	foauth2 "github.com/ory/fosite/handler/oauth2"
 	"github.com/ory/fosite/token/hmac"
+	"github.com/ory/go-convenience/stringslice"
 	"github.com/ory/go-convenience/urlx"
 	"github.com/ory/hydra/health"
 	"github.com/ory/hydra/metrics/prometheus"
@@ -55,19 +56,34 @@ type Config struct {
 	// These are used by the host command
 	BindPort                         int    `mapstructure:"PORT" yaml:"-"`
 	BindHost                         string `mapstructure:"HOST" yaml:"-"`
+	AdminBindPort                    int    `mapstructure:"ADMIN_PORT" yaml:"-"`
+	AdminBindHost                    string `mapstructure:"ADMIN_HOST" yaml:"-"`
 	Issuer                           string `mapstructure:"OAUTH2_ISSUER_URL" yaml:"-"`
 	SystemSecret                     string `mapstructure:"SYSTEM_SECRET" yaml:"-"`
+	RotatedSystemSecrets             string `mapstructure:"ROTATED_SYSTEM_SECRETS" yaml:"-"`
 	DatabaseURL                      string `mapstructure:"DATABASE_URL" yaml:"-"`
 	DatabasePlugin                   string `mapstructure:"DATABASE_PLUGIN" yaml:"-"`
+	EphemeralDatabaseURL             string `mapstructure:"EPHEMERAL_DATABASE_URL" yaml:"-"`
 	ConsentURL                       string `mapstructure:"OAUTH2_CONSENT_URL" yaml:"-"`
 	LoginURL                         string `mapstructure:"OAUTH2_LOGIN_URL" yaml:"-"`
 	ErrorURL                         string `mapstructure:"OAUTH2_ERROR_URL" yaml:"-"`
+	LogoutRedirectURL                string `mapstructure:"OAUTH2_LOGOUT_REDIRECT_URL" yaml:"-"`
 	AllowTLSTermination              string `mapstructure:"HTTPS_ALLOW_TERMINATION_FROM" yaml:"-"`
+	AdminAllowTLSTermination         string `mapstructure:"ADMIN_HTTPS_ALLOW_TERMINATION_FROM" yaml:"-"`
+	AdminClientCAPath                string `mapstructure:"ADMIN_HTTPS_CLIENT_CA_PATH" yaml:"-"`
+	AdminClientSubjects              string `mapstructure:"ADMIN_HTTPS_CLIENT_SUBJECTS" yaml:"-"`
+	AdminAuthorization               string `mapstructure:"ADMIN_AUTHORIZATION" yaml:"-"`
+	AdminAPIKeys                     string `mapstructure:"ADMIN_API_KEYS" yaml:"-"`
+	AdminClients                     string `mapstructure:"ADMIN_CLIENTS" yaml:"-"`
 	BCryptWorkFactor                 int    `mapstructure:"BCRYPT_COST" yaml:"-"`
 	AccessTokenLifespan              string `mapstructure:"ACCESS_TOKEN_LIFESPAN" yaml:"-"`
+	AccessTokenStrategy              string `mapstructure:"OAUTH2_ACCESS_TOKEN_STRATEGY" yaml:"-"`
+	AccessTokenJWTAlgorithm          string `mapstructure:"OAUTH2_ACCESS_TOKEN_JWT_ALGORITHM" yaml:"-"`
 	ScopeStrategy                    string `mapstructure:"SCOPE_STRATEGY" yaml:"-"`
 	AuthCodeLifespan                 string `mapstructure:"AUTH_CODE_LIFESPAN" yaml:"-"`
+	DeviceCodeLifespan               string `mapstructure:"DEVICE_CODE_LIFESPAN" yaml:"-"`
 	IDTokenLifespan                  string `mapstructure:"ID_TOKEN_LIFESPAN" yaml:"-"`
+	IDTokenHintMaxAge                string `mapstructure:"OIDC_ID_TOKEN_HINT_MAX_AGE" yaml:"-"`
 	ChallengeTokenLifespan           string `mapstructure:"CHALLENGE_TOKEN_LIFESPAN" yaml:"-"`
 	CookieSecret                     string `mapstructure:"COOKIE_SECRET" yaml:"-"`
 	LogLevel                         string `mapstructure:"LOG_LEVEL" yaml:"-"`
@@ -76,7 +92,19 @@ type Config struct {
 	OpenIDDiscoveryClaimsSupported   string `mapstructure:"OIDC_DISCOVERY_CLAIMS_SUPPORTED" yaml:"-"`
 	OpenIDDiscoveryScopesSupported   string `mapstructure:"OIDC_DISCOVERY_SCOPES_SUPPORTED" yaml:"-"`
 	OpenIDDiscoveryUserinfoEndpoint  string `mapstructure:"OIDC_DISCOVERY_USERINFO_ENDPOINT" yaml:"-"`
+	SubjectTypesSupported            string `mapstructure:"OIDC_SUBJECT_TYPES_SUPPORTED" yaml:"-"`
+	PairwiseSubjectIdentifierSalt    string `mapstructure:"OIDC_SUBJECT_TYPE_PAIRWISE_SALT" yaml:"-"`
+	IDTokenSigningAlgorithms         string `mapstructure:"OIDC_ID_TOKEN_SIGNING_ALGORITHMS" yaml:"-"`
+	KeyRotationInterval              string `mapstructure:"JWK_ROTATION_INTERVAL" yaml:"-"`
+	KeyRotationActivationDelay       string `mapstructure:"JWK_ROTATION_ACTIVATION_DELAY" yaml:"-"`
+	KeyRotationRetirementDelay       string `mapstructure:"JWK_ROTATION_RETIREMENT_DELAY" yaml:"-"`
+	HSMLibrary                       string `mapstructure:"HSM_LIBRARY" yaml:"-"`
+	HSMTokenLabel                    string `mapstructure:"HSM_TOKEN_LABEL" yaml:"-"`
+	HSMPin                           string `mapstructure:"HSM_PIN" yaml:"-"`
+	HSMKeySets                       string `mapstructure:"HSM_KEY_SETS" yaml:"-"`
 	SendOAuth2DebugMessagesToClients bool   `mapstructure:"OAUTH2_SHARE_ERROR_DEBUG" yaml:"-"`
+	ClientRegistrationEnabled        bool   `mapstructure:"OAUTH2_CLIENT_REGISTRATION_ENABLED" yaml:"-"`
+	ClientRegistrationInitialToken   string `mapstructure:"OAUTH2_CLIENT_REGISTRATION_INITIAL_ACCESS_TOKEN" yaml:"-"`
 	ForceHTTP                        bool   `yaml:"-"`
 
 	BuildVersion string                     `yaml:"-"`
@@ -107,6 +135,85 @@ func (c *Config) GetScopeStrategy() fosite.ScopeStrategy {
 	return fosite.WildcardScopeStrategy
 }
 
+// GetSubjectTypesSupported returns the OpenID Connect subject types enabled on this server. Defaults to `public`.
+func (c *Config) GetSubjectTypesSupported() []string {
+	var types []string
+	for _, t := range strings.Split(c.SubjectTypesSupported, ",") {
+		if t = strings.TrimSpace(t); t != "" && !stringslice.Has(types, t) {
+			types = append(types, t)
+		}
+	}
+
+	if len(types) == 0 {
+		return []string{"public"}
+	}
+	return types
+}
+
+// GetIDTokenSigningAlgorithms returns the algorithms OpenID Connect ID Tokens can be signed with, one of `RS256`,
+// `PS256`, `ES256` or `EdDSA` each. The first algorithm is used unless a client requests another one. Defaults to
+// `RS256`.
+func (c *Config) GetIDTokenSigningAlgorithms() []string {
+	var algorithms []string
+	for _, a := range strings.Split(c.IDTokenSigningAlgorithms, ",") {
+		a = strings.TrimSpace(a)
+		if strings.EqualFold(a, "EdDSA") {
+			a = "EdDSA"
+		} else {
+			a = strings.ToUpper(a)
+		}
+
+		switch a {
+		case "":
+			continue
+		case "RS256", "PS256", "ES256", "EdDSA":
+			if !stringslice.Has(algorithms, a) {
+				algorithms = append(algorithms, a)
+			}
+		default:
+			c.GetLogger().Fatalf(`Algorithm "%s" set in OIDC_ID_TOKEN_SIGNING_ALGORITHMS is not supported, use "RS256", "PS256", "ES256" or "EdDSA"`, a)
+		}
+	}
+
+	if len(algorithms) == 0 {
+		return []string{"RS256"}
+	}
+	return algorithms
+}
+
+// GetAdminAuthorization returns the authorizers which protect the administrative APIs, for example `introspection`,
+// `api_key` or `mtls`. If empty, the administrative APIs are not protected.
+func (c *Config) GetAdminAuthorization() []string {
+	return splitList(c.AdminAuthorization)
+}
+
+// GetAdminAPIKeys returns the API keys accepted by the `api_key` authorizer.
+func (c *Config) GetAdminAPIKeys() []string {
+	return splitList(c.AdminAPIKeys)
+}
+
+// GetAdminClients returns the IDs of the OAuth 2.0 Clients whose access tokens are accepted by the `introspection`
+// authorizer.
+func (c *Config) GetAdminClients() []string {
+	return splitList(c.AdminClients)
+}
+
+// GetAdminClientSubjects returns the common names of TLS client certificates accepted by the `mtls` authorizer. If
+// empty, all certificates issued by the client certificate authorities are accepted.
+func (c *Config) GetAdminClientSubjects() []string {
+	return splitList(c.AdminClientSubjects)
+}
+
+func splitList(list string) []string {
+	var values []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 func matchesRange(r *http.Request, ranges []string) error {
 	ip, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
@@ -170,8 +277,27 @@ func (c *Config) GetPrometheusMetrics() *prometheus.MetricsManager {
 	return c.prometheus
 }
 
+// GetAdminAllowTLSTermination returns the CIDR ranges which may terminate TLS in front of the administrative
+// listener. Defaults to HTTPS_ALLOW_TERMINATION_FROM.
+func (c *Config) GetAdminAllowTLSTermination() string {
+	if c.AdminAllowTLSTermination != "" {
+		return c.AdminAllowTLSTermination
+	}
+	return c.AllowTLSTermination
+}
+
 func (c *Config) DoesRequestSatisfyTermination(r *http.Request) error {
-	if c.AllowTLSTermination == "" {
+	return doesRequestSatisfyTermination(r, c.AllowTLSTermination)
+}
+
+// DoesAdminRequestSatisfyTermination works like DoesRequestSatisfyTermination but for requests served by the
+// administrative listener.
+func (c *Config) DoesAdminRequestSatisfyTermination(r *http.Request) error {
+	return doesRequestSatisfyTermination(r, c.GetAdminAllowTLSTermination())
+}
+
+func doesRequestSatisfyTermination(r *http.Request, allowTLSTermination string) error {
+	if allowTLSTermination == "" {
 		return errors.New("TLS termination is not enabled")
 	}
 
@@ -179,7 +305,7 @@ func (c *Config) DoesRequestSatisfyTermination(r *http.Request) error {
 		return nil
 	}
 
-	ranges := strings.Split(c.AllowTLSTermination, ",")
+	ranges := strings.Split(allowTLSTermination, ",")
 	if err := matchesRange(r, ranges); err != nil {
 		return err
 	}
@@ -194,6 +320,33 @@ func (c *Config) DoesRequestSatisfyTermination(r *http.Request) error {
 	return nil
 }
 
+// GetAccessTokenStrategy returns the format of issued access tokens, either `opaque` or `jwt`. Defaults to `opaque`.
+func (c *Config) GetAccessTokenStrategy() string {
+	switch s := strings.ToLower(strings.TrimSpace(c.AccessTokenStrategy)); s {
+	case "":
+		return "opaque"
+	case "opaque", "jwt":
+		return s
+	default:
+		c.GetLogger().Fatalf(`Access token strategy "%s" set in OAUTH2_ACCESS_TOKEN_STRATEGY is not supported, use "opaque" or "jwt"`, c.AccessTokenStrategy)
+		return ""
+	}
+}
+
+// GetAccessTokenJWTAlgorithm returns the algorithm used to sign JWT access tokens, either `RS256` or `ES256`.
+// Defaults to `RS256`.
+func (c *Config) GetAccessTokenJWTAlgorithm() string {
+	switch a := strings.ToUpper(strings.TrimSpace(c.AccessTokenJWTAlgorithm)); a {
+	case "":
+		return "RS256"
+	case "RS256", "ES256":
+		return a
+	default:
+		c.GetLogger().Fatalf(`Algorithm "%s" set in OAUTH2_ACCESS_TOKEN_JWT_ALGORITHM is not supported, use "RS256" or "ES256"`, c.AccessTokenJWTAlgorithm)
+		return ""
+	}
+}
+
 func (c *Config) GetChallengeTokenLifespan() time.Duration {
 	d, err := time.ParseDuration(c.ChallengeTokenLifespan)
 	if err != nil {
@@ -221,6 +374,15 @@ func (c *Config) GetAuthCodeLifespan() time.Duration {
 	return d
 }
 
+func (c *Config) GetDeviceCodeLifespan() time.Duration {
+	d, err := time.ParseDuration(c.DeviceCodeLifespan)
+	if err != nil {
+		c.GetLogger().Warnf("Could not parse device code lifespan value (%s). Defaulting to 10m", c.DeviceCodeLifespan)
+		return time.Minute * 10
+	}
+	return d
+}
+
 func (c *Config) GetIDTokenLifespan() time.Duration {
 	d, err := time.ParseDuration(c.IDTokenLifespan)
 	if err != nil {
@@ -230,6 +392,70 @@ func (c *Config) GetIDTokenLifespan() time.Duration {
 	return d
 }
 
+// GetIDTokenHintMaxAge returns how long after their expiry ID Tokens are accepted as id_token_hint of logout requests.
+func (c *Config) GetIDTokenHintMaxAge() time.Duration {
+	d, err := time.ParseDuration(c.IDTokenHintMaxAge)
+	if err != nil {
+		c.GetLogger().Warnf("Could not parse id token hint max age value (%s). Defaulting to 24h", c.IDTokenHintMaxAge)
+		return time.Hour * 24
+	}
+	return d
+}
+
+// GetHSMKeySets returns the JSON Web Key Sets whose private keys are kept in the PKCS#11 token. Defaults to the sets
+// of the OpenID Connect and JWT access token signing keys.
+func (c *Config) GetHSMKeySets() []string {
+	sets := splitList(c.HSMKeySets)
+	if len(sets) == 0 {
+		return []string{"hydra.openid.id-token", "hydra.jwt.access-token"}
+	}
+	return sets
+}
+
+// GetKeyRotationInterval returns how often the OpenID Connect signing keys are rotated, zero disables automatic
+// rotation.
+func (c *Config) GetKeyRotationInterval() time.Duration {
+	if c.KeyRotationInterval == "" {
+		return 0
+	}
+
+	d, err := time.ParseDuration(c.KeyRotationInterval)
+	if err != nil {
+		c.GetLogger().Warnf("Could not parse key rotation interval value (%s). Disabling automatic key rotation", c.KeyRotationInterval)
+		return 0
+	}
+	return d
+}
+
+// GetKeyRotationActivationDelay returns how long rotated keys are published before they are used for signing.
+func (c *Config) GetKeyRotationActivationDelay() time.Duration {
+	d, err := time.ParseDuration(c.KeyRotationActivationDelay)
+	if err != nil {
+		c.GetLogger().Warnf("Could not parse key rotation activation delay value (%s). Defaulting to 1h", c.KeyRotationActivationDelay)
+		return time.Hour
+	}
+	return d
+}
+
+// GetKeyRotationRetirementDelay returns how long the previous keys are published after the rotated keys became active.
+// It defaults to the longer of the access token lifespan and the ID Token lifespan plus the time expired ID Tokens are
+// accepted as id_token_hint, so that all tokens signed with the previous keys can be verified as long as they are
+// accepted.
+func (c *Config) GetKeyRotationRetirementDelay() time.Duration {
+	if c.KeyRotationRetirementDelay != "" {
+		d, err := time.ParseDuration(c.KeyRotationRetirementDelay)
+		if err == nil {
+			return d
+		}
+		c.GetLogger().Warnf("Could not parse key rotation retirement delay value (%s). Defaulting to the token lifespans", c.KeyRotationRetirementDelay)
+	}
+
+	if id, access := c.GetIDTokenLifespan()+c.GetIDTokenHintMaxAge(), c.GetAccessTokenLifespan(); id > access {
+		return id
+	}
+	return c.GetAccessTokenLifespan()
+}
+
 func (c *Config) Context() *Context {
 	if c.context != nil {
 		return c.context
@@ -260,13 +486,51 @@ func (c *Config) Context() *Context {
 				c.GetLogger().WithError(err).Fatalf(`Unable to initialize SQL connection`)
 			}
 			break
+		case "sqlite":
+			connection, err = NewSQLiteConnection(c.DatabaseURL, c.GetLogger())
+			if err != nil {
+				c.GetLogger().WithError(err).Fatalf(`Unable to open SQLite database`)
+			}
+			break
+		case "bolt":
+			connection, err = NewBoltConnection(c.DatabaseURL, c.GetLogger())
+			if err != nil {
+				c.GetLogger().WithError(err).Fatalf(`Unable to open BoltDB database`)
+			}
+			break
 		default:
 			c.GetLogger().Fatalf(`Unknown DSN "%s" in DATABASE_URL: %s`, u.Scheme, c.DatabaseURL)
 		}
 	}
 
+	var ephemeral *RedisConnection
+	if c.EphemeralDatabaseURL != "" {
+		if c.DatabasePlugin != "" {
+			c.GetLogger().Fatalf("EPHEMERAL_DATABASE_URL can not be used together with DATABASE_PLUGIN")
+		}
+
+		u, err := url.Parse(c.EphemeralDatabaseURL)
+		if err != nil {
+			c.GetLogger().Fatalf("Could not parse EPHEMERAL_DATABASE_URL: %s", err)
+		}
+
+		switch u.Scheme {
+		case "redis":
+			fallthrough
+		case "rediss":
+			ephemeral, err = NewRedisConnection(c.EphemeralDatabaseURL, c.GetLogger())
+			if err != nil {
+				c.GetLogger().WithError(err).Fatalf(`Unable to connect to Redis server`)
+			}
+			break
+		default:
+			c.GetLogger().Fatalf(`Unknown DSN "%s" in EPHEMERAL_DATABASE_URL: %s`, u.Scheme, c.EphemeralDatabaseURL)
+		}
+	}
+
 	c.context = &Context{
-		Connection: connection,
+		Connection:          connection,
+		EphemeralConnection: ephemeral,
 		Hasher: &fosite.BCrypt{
 			WorkFactor: c.BCryptWorkFactor,
 		},
@@ -329,10 +593,33 @@ func (c *Config) GetSystemSecret() []byte {
 	return secret
 }
 
+// GetRotatedSystemSecrets returns the previous system secrets, which are only used to decrypt data and validate tokens
+// which were encrypted or signed before SYSTEM_SECRET was rotated.
+func (c *Config) GetRotatedSystemSecrets() [][]byte {
+	var secrets [][]byte
+	for _, secret := range strings.Split(c.RotatedSystemSecrets, ",") {
+		secret = strings.TrimSpace(secret)
+		if secret == "" {
+			continue
+		} else if len(secret) < 16 {
+			c.GetLogger().Fatalf("Expected rotated system secrets to be at least %d characters long, got %d characters.", 16, len(secret))
+		}
+
+		hash := sha256.Sum256([]byte(secret))
+		secrets = append(secrets, hash[:])
+	}
+	return secrets
+}
+
 func (c *Config) GetAddress() string {
 	return fmt.Sprintf("%s:%d", c.BindHost, c.BindPort)
 }
 
+// GetAdminAddress returns the address the administrative APIs are served on.
+func (c *Config) GetAdminAddress() string {
+	return fmt.Sprintf("%s:%d", c.AdminBindHost, c.AdminBindPort)
+}
+
 func (c *Config) Persist() error {
 	out, err := yaml.Marshal(c)
 	if err != nil {
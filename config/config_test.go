@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig(t *testing.T) {
@@ -71,6 +72,60 @@ func TestDoesRequestSatisfyTermination(t *testing.T) {
 	assert.NoError(t, c.DoesRequestSatisfyTermination(r))
 }
 
+func TestDoesAdminRequestSatisfyTermination(t *testing.T) {
+	r := &http.Request{
+		RemoteAddr: "127.0.0.1:123",
+		Header:     http.Header{"X-Forwarded-Proto": []string{"https"}},
+		URL:        new(url.URL),
+	}
+
+	c := &Config{AllowTLSTermination: "127.0.0.1/24"}
+	assert.NoError(t, c.DoesAdminRequestSatisfyTermination(r))
+
+	c = &Config{AllowTLSTermination: "127.0.0.1/24", AdminAllowTLSTermination: "10.0.0.1/24"}
+	assert.Error(t, c.DoesAdminRequestSatisfyTermination(r))
+	assert.NoError(t, c.DoesRequestSatisfyTermination(r))
+
+	c = &Config{AdminAllowTLSTermination: "127.0.0.1/24"}
+	assert.NoError(t, c.DoesAdminRequestSatisfyTermination(r))
+	assert.Error(t, c.DoesRequestSatisfyTermination(r))
+}
+
+func TestGetAdminAuthorization(t *testing.T) {
+	c := &Config{}
+	assert.Empty(t, c.GetAdminAuthorization())
+	assert.Empty(t, c.GetAdminAPIKeys())
+	assert.Empty(t, c.GetAdminClients())
+
+	c = &Config{AdminAuthorization: "introspection, api_key,", AdminAPIKeys: "foo,bar", AdminClients: "admin-client", AdminClientSubjects: "admin"}
+	assert.Equal(t, []string{"introspection", "api_key"}, c.GetAdminAuthorization())
+	assert.Equal(t, []string{"foo", "bar"}, c.GetAdminAPIKeys())
+	assert.Equal(t, []string{"admin-client"}, c.GetAdminClients())
+	assert.Equal(t, []string{"admin"}, c.GetAdminClientSubjects())
+}
+
+func TestGetIDTokenSigningAlgorithms(t *testing.T) {
+	c := &Config{}
+	assert.Equal(t, []string{"RS256"}, c.GetIDTokenSigningAlgorithms())
+
+	c = &Config{IDTokenSigningAlgorithms: "es256, eddsa,RS256,ES256"}
+	assert.Equal(t, []string{"ES256", "EdDSA", "RS256"}, c.GetIDTokenSigningAlgorithms())
+}
+
+func TestGetHSMKeySets(t *testing.T) {
+	c := &Config{}
+	assert.Equal(t, []string{"hydra.openid.id-token", "hydra.jwt.access-token"}, c.GetHSMKeySets())
+
+	c = &Config{HSMKeySets: "hydra.openid.id-token, "}
+	assert.Equal(t, []string{"hydra.openid.id-token"}, c.GetHSMKeySets())
+}
+
+func TestGetAdminAddress(t *testing.T) {
+	c := &Config{BindHost: "localhost", BindPort: 4444, AdminBindPort: 4445}
+	assert.Equal(t, "localhost:4444", c.GetAddress())
+	assert.Equal(t, ":4445", c.GetAdminAddress())
+}
+
 func TestSystemSecret(t *testing.T) {
 	c3 := &Config{}
 	assert.EqualValues(t, c3.GetSystemSecret(), c3.GetSystemSecret())
@@ -80,6 +135,17 @@ func TestSystemSecret(t *testing.T) {
 	assert.EqualValues(t, c.GetSystemSecret(), c2.GetSystemSecret())
 }
 
+func TestRotatedSystemSecrets(t *testing.T) {
+	assert.Empty(t, (&Config{}).GetRotatedSystemSecrets())
+
+	c := &Config{SystemSecret: "foobarbazbarasdfasdffoobarbazbarasdfasdf"}
+	r := &Config{RotatedSystemSecrets: "barfoobazbarasdfasdf, foobarbazbarasdfasdffoobarbazbarasdfasdf"}
+	secrets := r.GetRotatedSystemSecrets()
+	require.Len(t, secrets, 2)
+	assert.EqualValues(t, c.GetSystemSecret(), secrets[1])
+	assert.NotEqual(t, secrets[0], secrets[1])
+}
+
 func TestResolve(t *testing.T) {
 	c := &Config{EndpointURL: "https://localhost:1234"}
 	assert.Equal(t, c.Resolve("foo", "bar").String(), "https://localhost:1234/foo/bar")
@@ -99,6 +165,23 @@ func TestLifespan(t *testing.T) {
 	assert.Equal(t, (&Config{}).GetAuthCodeLifespan(), time.Minute*10)
 	assert.Equal(t, (&Config{AuthCodeLifespan: "15m"}).GetAuthCodeLifespan(), time.Minute*15)
 
+	assert.Equal(t, (&Config{}).GetDeviceCodeLifespan(), time.Minute*10)
+	assert.Equal(t, (&Config{DeviceCodeLifespan: "5m"}).GetDeviceCodeLifespan(), time.Minute*5)
+
 	assert.Equal(t, (&Config{}).GetIDTokenLifespan(), time.Hour)
 	assert.Equal(t, (&Config{IDTokenLifespan: "10s"}).GetIDTokenLifespan(), time.Second*10)
+
+	assert.Equal(t, (&Config{}).GetKeyRotationInterval(), time.Duration(0))
+	assert.Equal(t, (&Config{KeyRotationInterval: "720h"}).GetKeyRotationInterval(), time.Hour*720)
+
+	assert.Equal(t, (&Config{}).GetKeyRotationActivationDelay(), time.Hour)
+	assert.Equal(t, (&Config{KeyRotationActivationDelay: "24h"}).GetKeyRotationActivationDelay(), time.Hour*24)
+
+	assert.Equal(t, (&Config{}).GetIDTokenHintMaxAge(), time.Hour*24)
+	assert.Equal(t, (&Config{IDTokenHintMaxAge: "1h"}).GetIDTokenHintMaxAge(), time.Hour)
+
+	assert.Equal(t, (&Config{}).GetKeyRotationRetirementDelay(), time.Hour*25)
+	assert.Equal(t, (&Config{IDTokenLifespan: "2h", IDTokenHintMaxAge: "1h", AccessTokenLifespan: "6h"}).GetKeyRotationRetirementDelay(), time.Hour*6)
+	assert.Equal(t, (&Config{IDTokenLifespan: "2h", IDTokenHintMaxAge: "48h", AccessTokenLifespan: "6h"}).GetKeyRotationRetirementDelay(), time.Hour*50)
+	assert.Equal(t, (&Config{KeyRotationRetirementDelay: "48h"}).GetKeyRotationRetirementDelay(), time.Hour*48)
 }
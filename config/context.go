@@ -31,6 +31,9 @@ import (
 type Context struct {
 	Connection interface{}
 
+	// EphemeralConnection stores short-lived data instead of Connection if EPHEMERAL_DATABASE_URL is set.
+	EphemeralConnection *RedisConnection
+
 	Hasher         fosite.Hasher
 	FositeStrategy oauth2.CoreStrategy
 	FositeStore    pkg.FositeStorer
@@ -0,0 +1,143 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @Copyright 	2017-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package consent
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/hydra/client"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// BackChannelLogoutEvent is the event member of OpenID Connect Back-Channel Logout Tokens.
+const BackChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// BackChannelLogoutNotifier delivers OpenID Connect Back-Channel Logout Tokens to the backchannel_logout_uri of
+// relying parties. Deliveries run in the background and are retried with an exponential backoff. Every attempt is
+// logged.
+type BackChannelLogoutNotifier struct {
+	// JWTStrategy signs the logout tokens, it should use the OpenID Connect ID Token key.
+	JWTStrategy jwt.JWTStrategy
+
+	// KeyID is set as the kid header of logout tokens.
+	KeyID string
+
+	IssuerURL string
+
+	HTTPClient *http.Client
+
+	// Retries is the number of times a failed delivery is retried.
+	Retries int
+
+	// RetryWait is the time to wait before the first retry, it doubles with every further retry.
+	RetryWait time.Duration
+
+	L logrus.FieldLogger
+}
+
+func NewBackChannelLogoutNotifier(jwtStrategy jwt.JWTStrategy, keyID string, issuerURL string, l logrus.FieldLogger) *BackChannelLogoutNotifier {
+	return &BackChannelLogoutNotifier{
+		JWTStrategy: jwtStrategy,
+		KeyID:       keyID,
+		IssuerURL:   issuerURL,
+		HTTPClient:  &http.Client{Timeout: time.Second * 10},
+		Retries:     3,
+		RetryWait:   time.Second,
+		L:           l,
+	}
+}
+
+// Notify sends a logout token for the given subject and session to the client in the background. Clients without a
+// backchannel_logout_uri are ignored.
+func (n *BackChannelLogoutNotifier) Notify(c *client.Client, subject string, sid string) {
+	if c.BackChannelLogoutURI == "" {
+		return
+	}
+
+	token, err := n.newLogoutToken(c, subject, sid)
+	if err != nil {
+		n.L.WithError(err).WithField("client_id", c.GetID()).Error("Unable to generate OpenID Connect Back-Channel Logout Token")
+		return
+	}
+
+	go n.deliver(c, sid, token)
+}
+
+func (n *BackChannelLogoutNotifier) newLogoutToken(c *client.Client, subject string, sid string) (string, error) {
+	now := time.Now().UTC()
+	token, _, err := n.JWTStrategy.Generate(jwtgo.MapClaims{
+		"iss":    strings.TrimRight(n.IssuerURL, "/") + "/",
+		"aud":    []string{c.GetID()},
+		"iat":    now.Unix(),
+		"jti":    uuid.New(),
+		"sub":    subject,
+		"sid":    sid,
+		"events": map[string]interface{}{BackChannelLogoutEvent: map[string]interface{}{}},
+	}, &jwt.Headers{Extra: map[string]interface{}{"kid": n.KeyID, "typ": "logout+jwt"}})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return token, nil
+}
+
+func (n *BackChannelLogoutNotifier) deliver(c *client.Client, sid string, token string) error {
+	wait := n.RetryWait
+	var err error
+	for attempt := 1; attempt <= n.Retries+1; attempt++ {
+		l := n.L.WithField("client_id", c.GetID()).WithField("sid", sid).WithField("attempt", attempt)
+
+		if err = n.post(c.BackChannelLogoutURI, token); err == nil {
+			l.Info("Delivered OpenID Connect Back-Channel Logout Token")
+			return nil
+		}
+
+		l.WithError(err).Warn("Unable to deliver OpenID Connect Back-Channel Logout Token")
+		if attempt <= n.Retries {
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+
+	n.L.WithError(err).WithField("client_id", c.GetID()).WithField("sid", sid).Error("Giving up delivering OpenID Connect Back-Channel Logout Token")
+	return err
+}
+
+func (n *BackChannelLogoutNotifier) post(location string, token string) error {
+	res, err := n.HTTPClient.PostForm(location, url.Values{"logout_token": {token}})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return errors.Errorf("Expected 2xx status code but got %d", res.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @Copyright 	2017-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package consent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/sessions"
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/pkg"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackChannelLogoutNotifier(t *testing.T) {
+	key := pkg.MustINSECURELOWENTROPYRSAKEYFORTEST()
+	jwts := &jwt.RS256JWTStrategy{PrivateKey: key}
+
+	var calls int32
+	tokens := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		tokens <- r.PostFormValue("logout_token")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	n := NewBackChannelLogoutNotifier(jwts, "public:key", "https://hydra.localhost", logrus.New())
+	n.RetryWait = time.Millisecond
+
+	c := &client.Client{ID: "client-id", BackChannelLogoutURI: ts.URL}
+	token, err := n.newLogoutToken(c, "foouser", "some-sid")
+	require.NoError(t, err)
+	require.NoError(t, n.deliver(c, "some-sid", token))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+
+	parsed, err := jwtgo.Parse(<-tokens, func(*jwtgo.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "public:key", parsed.Header["kid"])
+
+	claims := parsed.Claims.(jwtgo.MapClaims)
+	assert.Equal(t, "https://hydra.localhost/", claims["iss"])
+	assert.Equal(t, []interface{}{"client-id"}, claims["aud"])
+	assert.Equal(t, "foouser", claims["sub"])
+	assert.Equal(t, "some-sid", claims["sid"])
+	assert.NotEmpty(t, claims["jti"])
+	assert.Contains(t, claims["events"], BackChannelLogoutEvent)
+	assert.Nil(t, claims["nonce"])
+
+	n.Retries = 0
+	atomic.StoreInt32(&calls, 0)
+	assert.Error(t, n.deliver(c, "some-sid", token))
+}
+
+func TestStrategyBackChannelLogout(t *testing.T) {
+	jwts := &jwt.RS256JWTStrategy{
+		PrivateKey: pkg.MustINSECURELOWENTROPYRSAKEYFORTEST(),
+	}
+
+	tokens := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens <- r.PostFormValue("logout_token")
+	}))
+	defer ts.Close()
+
+	clients := client.NewMemoryManager(&fosite.BCrypt{WorkFactor: 4})
+	require.NoError(t, clients.CreateClient(&client.Client{ID: "backchannel-client", BackChannelLogoutURI: ts.URL}))
+	require.NoError(t, clients.CreateClient(&client.Client{ID: "other-client"}))
+
+	manager := NewMemoryManager()
+	require.NoError(t, manager.CreateAuthenticationSession(&AuthenticationSession{ID: "some-sid", Subject: "foouser", AuthenticatedAt: time.Now()}))
+	require.NoError(t, manager.AddAuthenticationSessionClient("some-sid", "backchannel-client"))
+	require.NoError(t, manager.AddAuthenticationSessionClient("some-sid", "other-client"))
+
+	cookieStore := sessions.NewCookieStore([]byte("dummy-secret-yay"))
+	strategy := NewStrategy(
+		"", "", "", "/oauth2/auth",
+		manager,
+		cookieStore,
+		fosite.ExactScopeStrategy,
+		false,
+		time.Hour,
+		jwts,
+		openid.NewOpenIDConnectRequestValidator(nil, jwts),
+		map[string]SubjectIdentifierAlgorithm{SubjectTypePublic: NewSubjectIdentifierAlgorithmPublic()},
+		clients,
+		NewBackChannelLogoutNotifier(jwts, "public:key", "https://hydra.localhost", logrus.New()),
+	)
+
+	hint, _, err := jwts.Generate(jwtgo.MapClaims{
+		"sub": "foouser",
+		"aud": "other-client",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}, jwt.NewHeaders())
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/oauth2/sessions/logout?id_token_hint="+hint, nil)
+	w := httptest.NewRecorder()
+	cookie, _ := cookieStore.Get(httptest.NewRequest("GET", "/", nil), cookieAuthenticationName)
+	cookie.Values[cookieAuthenticationSIDName] = "some-sid"
+	require.NoError(t, cookie.Save(r, w))
+	for _, c := range w.Result().Cookies() {
+		r.AddCookie(c)
+	}
+
+	result, err := strategy.HandleOpenIDConnectLogout(httptest.NewRecorder(), r)
+	require.NoError(t, err)
+	assert.Empty(t, result.RedirectTo)
+	assert.Empty(t, result.FrontChannelLogoutURLs)
+
+	select {
+	case token := <-tokens:
+		parsed, err := jwtgo.Parse(token, func(*jwtgo.Token) (interface{}, error) {
+			return &jwts.PrivateKey.PublicKey, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "some-sid", parsed.Claims.(jwtgo.MapClaims)["sid"])
+	case <-time.After(time.Second * 5):
+		t.Fatal("Back-channel logout token was not delivered")
+	}
+
+	_, err = manager.GetAuthenticationSession("some-sid")
+	assert.Error(t, err)
+}
@@ -56,3 +56,45 @@ type swaggerRejectRequest struct {
 	// in: body
 	Body RequestDeniedError
 }
+
+// swagger:parameters listUserConsentSessions
+type swaggerListUserConsentSessionsPayload struct {
+	// in: path
+	// required: true
+	User string `json:"user"`
+
+	// The maximum amount of consent sessions returned.
+	// in: query
+	Limit int `json:"limit"`
+
+	// The offset from where to start looking.
+	// in: query
+	Offset int `json:"offset"`
+}
+
+// A list of consent sessions.
+// swagger:response previousConsentSessionList
+type swaggerListUserConsentSessionsResult struct {
+	// in: body
+	// type: array
+	Body []PreviousConsentSession
+}
+
+// swagger:parameters revokeUserConsentSessions
+type swaggerRevokeUserConsentSessionsPayload struct {
+	// in: path
+	// required: true
+	User string `json:"user"`
+
+	// If set, only the consent and tokens of this OAuth 2.0 Client are revoked.
+	//
+	// in: query
+	Client string `json:"client"`
+}
+
+// swagger:parameters revokeUserLoginSessions
+type swaggerRevokeUserLoginSessionsPayload struct {
+	// in: path
+	// required: true
+	User string `json:"user"`
+}
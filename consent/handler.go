@@ -29,13 +29,18 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory/go-convenience/urlx"
 	"github.com/ory/herodot"
+	"github.com/ory/hydra/metrics/prometheus"
+	"github.com/ory/hydra/pkg"
+	"github.com/ory/pagination"
 	"github.com/pkg/errors"
 )
 
 type Handler struct {
 	H             herodot.Writer
 	M             Manager
+	Storage       pkg.FositeStorer
 	RequestMaxAge time.Duration
+	Metrics       *prometheus.MetricsManager
 }
 
 func NewHandler(
@@ -56,6 +61,10 @@ func (h *Handler) SetRoutes(r *httprouter.Router) {
 	r.GET("/oauth2/auth/requests/consent/:challenge", h.GetConsentRequest)
 	r.PUT("/oauth2/auth/requests/consent/:challenge/accept", h.AcceptConsentRequest)
 	r.PUT("/oauth2/auth/requests/consent/:challenge/reject", h.RejectConsentRequest)
+
+	r.GET("/oauth2/auth/sessions/consent/:user", h.ListUserConsentSessions)
+	r.DELETE("/oauth2/auth/sessions/consent/:user", h.RevokeUserConsentSessions)
+	r.DELETE("/oauth2/auth/sessions/login/:user", h.RevokeUserLoginSessions)
 }
 
 // swagger:route GET /oauth2/auth/requests/login/{challenge} oAuth2 getLoginRequest
@@ -160,6 +169,8 @@ func (h *Handler) AcceptLoginRequest(w http.ResponseWriter, r *http.Request, ps
 		return
 	}
 
+	h.Metrics.ObserveLoginRequest(prometheus.ResultAccepted)
+
 	ru, err := url.Parse(request.RequestURL)
 	if err != nil {
 		h.H.WriteError(w, r, err)
@@ -225,6 +236,8 @@ func (h *Handler) RejectLoginRequest(w http.ResponseWriter, r *http.Request, ps
 		return
 	}
 
+	h.Metrics.ObserveLoginRequest(prometheus.ResultRejected)
+
 	ru, err := url.Parse(request.RequestURL)
 	if err != nil {
 		h.H.WriteError(w, r, err)
@@ -325,6 +338,7 @@ func (h *Handler) AcceptConsentRequest(w http.ResponseWriter, r *http.Request, p
 
 	p.Challenge = ps.ByName("challenge")
 	p.RequestedAt = cr.RequestedAt
+	p.HandledAt = time.Now().UTC()
 
 	hr, err := h.M.HandleConsentRequest(ps.ByName("challenge"), &p)
 	if err != nil {
@@ -335,6 +349,8 @@ func (h *Handler) AcceptConsentRequest(w http.ResponseWriter, r *http.Request, p
 		return
 	}
 
+	h.Metrics.ObserveConsentRequest(prometheus.ResultAccepted)
+
 	ru, err := url.Parse(hr.RequestURL)
 	if err != nil {
 		h.H.WriteError(w, r, err)
@@ -397,12 +413,15 @@ func (h *Handler) RejectConsentRequest(w http.ResponseWriter, r *http.Request, p
 		Error:       &p,
 		Challenge:   ps.ByName("challenge"),
 		RequestedAt: hr.RequestedAt,
+		HandledAt:   time.Now().UTC(),
 	})
 	if err != nil {
 		h.H.WriteError(w, r, errors.WithStack(err))
 		return
 	}
 
+	h.Metrics.ObserveConsentRequest(prometheus.ResultRejected)
+
 	ru, err := url.Parse(request.RequestURL)
 	if err != nil {
 		h.H.WriteError(w, r, err)
@@ -413,3 +432,109 @@ func (h *Handler) RejectConsentRequest(w http.ResponseWriter, r *http.Request, p
 		RedirectTo: urlx.SetQuery(ru, url.Values{"consent_verifier": {request.Verifier}}).String(),
 	})
 }
+
+// swagger:route GET /oauth2/auth/sessions/consent/{user} oAuth2 listUserConsentSessions
+//
+// Lists all consent sessions of a user
+//
+// This endpoint lists all consent a user has granted to OAuth 2.0 Clients and which ORY Hydra remembers, most recent
+// first. Consent which has expired, or which was not remembered, is not listed. Client secrets are never returned.
+//
+// Use this endpoint to show users which applications they are connected to. Make sure that this endpoint is well
+// protected and only callable by first-party components.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: previousConsentSessionList
+//       401: genericError
+//       500: genericError
+func (h *Handler) ListUserConsentSessions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	limit, offset := pagination.Parse(r, 100, 0, 500)
+	cs, err := h.M.FindPreviouslyGrantedConsentRequestsBySubject(ps.ByName("user"), limit, offset)
+	if err != nil {
+		h.H.WriteError(w, r, err)
+		return
+	}
+
+	sessions := make([]PreviousConsentSession, len(cs))
+	for k, c := range cs {
+		sessions[k] = toPreviousConsentSession(c)
+	}
+
+	h.H.Write(w, r, sessions)
+}
+
+// swagger:route DELETE /oauth2/auth/sessions/consent/{user} oAuth2 revokeUserConsentSessions
+//
+// Revokes all consent sessions of a user
+//
+// This endpoint revokes the consent a user has granted and deletes all OAuth 2.0 Access Tokens, Refresh Tokens,
+// Authorize Codes, and OpenID Connect sessions issued to the user. If the client query parameter is set, only the
+// consent and tokens of that OAuth 2.0 Client are revoked.
+//
+// Use this endpoint when a user account has been compromised or removed. Make sure that this endpoint is well
+// protected and only callable by first-party components. If revoking the tokens fails, the consent may already have
+// been revoked. The request can be retried safely.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       401: genericError
+//       500: genericError
+func (h *Handler) RevokeUserConsentSessions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	user := ps.ByName("user")
+	client := r.URL.Query().Get("client")
+
+	revoker, ok := h.Storage.(pkg.SubjectTokenRevoker)
+	if !ok {
+		h.H.WriteErrorCode(w, r, http.StatusNotImplemented, errors.New("Revoking the tokens of a user is not supported by the OAuth 2.0 storage"))
+		return
+	}
+
+	// Consent and tokens are kept in different stores and can not be revoked in one transaction. The consent is
+	// revoked first so that no new tokens are issued without asking the user, both revocations are idempotent.
+	if err := h.M.RevokeUserConsentSessions(user, client); err != nil {
+		h.H.WriteError(w, r, err)
+		return
+	}
+
+	if err := revoker.RevokeSubjectTokens(r.Context(), user, client); err != nil {
+		h.H.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:route DELETE /oauth2/auth/sessions/login/{user} oAuth2 revokeUserLoginSessions
+//
+// Revokes all login sessions of a user
+//
+// This endpoint removes all authentication sessions of a user, which forces the user to authenticate again
+// on the next OAuth 2.0 or OpenID Connect flow. Tokens which have already been issued are not revoked.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       204: emptyResponse
+//       401: genericError
+//       500: genericError
+func (h *Handler) RevokeUserLoginSessions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.M.RevokeUserAuthenticationSessions(ps.ByName("user")); err != nil {
+		h.H.WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
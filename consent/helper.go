@@ -42,6 +42,20 @@ func sanitizeClient(c *client.Client) *client.Client {
 	return cc
 }
 
+func toPreviousConsentSession(c HandledConsentRequest) PreviousConsentSession {
+	s := PreviousConsentSession{
+		Client:       sanitizeClient(c.ConsentRequest.Client),
+		GrantedScope: c.GrantedScope,
+		GrantedAt:    c.HandledAt,
+	}
+
+	if expiresAt := c.rememberedUntil(); !expiresAt.IsZero() {
+		s.ExpiresAt = &expiresAt
+	}
+
+	return s
+}
+
 func matchScopes(scopeStrategy fosite.ScopeStrategy, previousConsent []HandledConsentRequest, requestedScope []string) *HandledConsentRequest {
 	for _, cs := range previousConsent {
 		var found = true
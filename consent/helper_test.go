@@ -23,6 +23,7 @@ package consent
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/client"
@@ -43,6 +44,29 @@ func TestSanitizeClient(t *testing.T) {
 	assert.NotEmpty(t, c.Secret)
 }
 
+func TestToPreviousConsentSession(t *testing.T) {
+	handledAt := time.Now().UTC()
+	c := HandledConsentRequest{
+		ConsentRequest: &ConsentRequest{Client: &client.Client{ID: "foo", Secret: "some-secret"}},
+		GrantedScope:   []string{"foo", "bar"},
+		RequestedAt:    handledAt.Add(-time.Minute),
+		HandledAt:      handledAt,
+	}
+
+	got := toPreviousConsentSession(c)
+	assert.Equal(t, "foo", got.Client.ID)
+	assert.Empty(t, got.Client.Secret)
+	assert.Equal(t, []string{"foo", "bar"}, got.GrantedScope)
+	assert.Equal(t, handledAt, got.GrantedAt)
+	assert.Nil(t, got.ExpiresAt)
+
+	c.RememberFor = 60
+	got = toPreviousConsentSession(c)
+	if assert.NotNil(t, got.ExpiresAt) {
+		assert.Equal(t, handledAt.Add(time.Minute), *got.ExpiresAt)
+	}
+}
+
 func TestMatchScopes(t *testing.T) {
 	for k, tc := range []struct {
 		granted         []HandledConsentRequest
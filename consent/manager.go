@@ -20,6 +20,10 @@
 
 package consent
 
+import (
+	"time"
+)
+
 type Manager interface {
 	CreateConsentRequest(*ConsentRequest) error
 	GetConsentRequest(challenge string) (*ConsentRequest, error)
@@ -28,10 +32,28 @@ type Manager interface {
 	VerifyAndInvalidateConsentRequest(verifier string) (*HandledConsentRequest, error)
 	FindPreviouslyGrantedConsentRequests(client string, user string) ([]HandledConsentRequest, error)
 
+	// FindPreviouslyGrantedConsentRequestsBySubject returns the consent a user has granted to any client and which is
+	// still remembered, most recent first.
+	FindPreviouslyGrantedConsentRequestsBySubject(user string, limit, offset int) ([]HandledConsentRequest, error)
+
+	// FlushExpiredConsentSessions removes remembered consent which has expired before notAfter, together with its
+	// consent request. Consent requests which have not been used yet are kept.
+	FlushExpiredConsentSessions(notAfter time.Time) error
+
+	// RevokeUserConsentSessions removes the consent a user has granted. If client is not empty, only the consent
+	// granted to that client is removed.
+	RevokeUserConsentSessions(user string, client string) error
+
 	// Cookie management
 	GetAuthenticationSession(id string) (*AuthenticationSession, error)
 	CreateAuthenticationSession(*AuthenticationSession) error
 	DeleteAuthenticationSession(id string) error
+	RevokeUserAuthenticationSessions(user string) error
+
+	// AddAuthenticationSessionClient remembers that the client received tokens during the authentication session,
+	// which is required for OpenID Connect Back-Channel Logout.
+	AddAuthenticationSessionClient(id string, client string) error
+	GetAuthenticationSessionClients(id string) ([]string, error)
 
 	CreateAuthenticationRequest(*AuthenticationRequest) error
 	GetAuthenticationRequest(challenge string) (*AuthenticationRequest, error)
@@ -0,0 +1,445 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package consent
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/ory/fosite"
+	"github.com/ory/go-convenience/stringslice"
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/pkg"
+	"github.com/ory/pagination"
+	"github.com/pkg/errors"
+)
+
+const (
+	boltBucketConsentRequests               = "hydra_oauth2_consent_request"
+	boltBucketConsentRequestsHandled        = "hydra_oauth2_consent_request_handled"
+	boltBucketAuthenticationRequests        = "hydra_oauth2_authentication_request"
+	boltBucketAuthenticationRequestsHandled = "hydra_oauth2_authentication_request_handled"
+	boltBucketAuthenticationSessions        = "hydra_oauth2_authentication_session"
+	boltBucketAuthenticationSessionClients  = "hydra_oauth2_authentication_session_client"
+
+	// Requests are looked up by their verifier using an index bucket which maps the verifier to the challenge.
+	boltBucketSuffixVerifier = "_verifier"
+)
+
+// BoltManager stores consent and authentication requests and sessions in an embedded BoltDB database. Values are
+// stored in the same format as the rows of the SQLManager.
+type BoltManager struct {
+	db *bolt.DB
+	c  client.Manager
+}
+
+func NewBoltManager(db *bolt.DB, c client.Manager) *BoltManager {
+	return &BoltManager{
+		db: db,
+		c:  c,
+	}
+}
+
+func (m *BoltManager) CreateConsentRequest(c *ConsentRequest) error {
+	d, err := newSQLConsentRequest(c)
+	if err != nil {
+		return err
+	}
+
+	return m.createRequest(boltBucketConsentRequests, d)
+}
+
+func (m *BoltManager) GetConsentRequest(challenge string) (*ConsentRequest, error) {
+	d, c, err := m.getRequest(boltBucketConsentRequests, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.toConsentRequest(c)
+}
+
+func (m *BoltManager) CreateAuthenticationRequest(c *AuthenticationRequest) error {
+	d, err := newSQLAuthenticationRequest(c)
+	if err != nil {
+		return err
+	}
+
+	return m.createRequest(boltBucketAuthenticationRequests, d)
+}
+
+func (m *BoltManager) GetAuthenticationRequest(challenge string) (*AuthenticationRequest, error) {
+	d, c, err := m.getRequest(boltBucketAuthenticationRequests, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.toAuthenticationRequest(c)
+}
+
+func (m *BoltManager) HandleConsentRequest(challenge string, r *HandledConsentRequest) (*ConsentRequest, error) {
+	d, err := newSQLHandledConsentRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.db.Update(func(tx *bolt.Tx) error {
+		return pkg.BoltPut(tx, boltBucketConsentRequestsHandled, d.Challenge, d)
+	}); err != nil {
+		return nil, err
+	}
+
+	return m.GetConsentRequest(challenge)
+}
+
+func (m *BoltManager) VerifyAndInvalidateConsentRequest(verifier string) (*HandledConsentRequest, error) {
+	var d sqlHandledConsentRequest
+	if err := m.db.Update(func(tx *bolt.Tx) error {
+		var challenge string
+		if err := pkg.BoltGet(tx, boltBucketConsentRequests+boltBucketSuffixVerifier, verifier, &challenge); err != nil {
+			return err
+		}
+
+		if err := pkg.BoltGet(tx, boltBucketConsentRequestsHandled, challenge, &d); err != nil {
+			return err
+		}
+
+		if d.WasUsed {
+			return errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Consent verifier has been used already"))
+		}
+
+		d.WasUsed = true
+		return pkg.BoltPut(tx, boltBucketConsentRequestsHandled, challenge, &d)
+	}); err != nil {
+		return nil, err
+	}
+
+	r, err := m.GetConsentRequest(d.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.toHandledConsentRequest(r)
+}
+
+func (m *BoltManager) HandleAuthenticationRequest(challenge string, r *HandledAuthenticationRequest) (*AuthenticationRequest, error) {
+	d, err := newSQLHandledAuthenticationRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.db.Update(func(tx *bolt.Tx) error {
+		return pkg.BoltPut(tx, boltBucketAuthenticationRequestsHandled, d.Challenge, d)
+	}); err != nil {
+		return nil, err
+	}
+
+	return m.GetAuthenticationRequest(challenge)
+}
+
+func (m *BoltManager) VerifyAndInvalidateAuthenticationRequest(verifier string) (*HandledAuthenticationRequest, error) {
+	var d sqlHandledAuthenticationRequest
+	if err := m.db.Update(func(tx *bolt.Tx) error {
+		var challenge string
+		if err := pkg.BoltGet(tx, boltBucketAuthenticationRequests+boltBucketSuffixVerifier, verifier, &challenge); err != nil {
+			return err
+		}
+
+		if err := pkg.BoltGet(tx, boltBucketAuthenticationRequestsHandled, challenge, &d); err != nil {
+			return err
+		}
+
+		if d.WasUsed {
+			return errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Authentication verifier has been used already"))
+		}
+
+		d.WasUsed = true
+		return pkg.BoltPut(tx, boltBucketAuthenticationRequestsHandled, challenge, &d)
+	}); err != nil {
+		return nil, err
+	}
+
+	r, err := m.GetAuthenticationRequest(d.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.toHandledAuthenticationRequest(r)
+}
+
+func (m *BoltManager) GetAuthenticationSession(id string) (*AuthenticationSession, error) {
+	var a AuthenticationSession
+	if err := m.db.View(func(tx *bolt.Tx) error {
+		return pkg.BoltGet(tx, boltBucketAuthenticationSessions, id, &a)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+func (m *BoltManager) CreateAuthenticationSession(a *AuthenticationSession) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		if err := pkg.BoltGet(tx, boltBucketAuthenticationSessions, a.ID, new(AuthenticationSession)); err == nil {
+			return errors.Errorf("Authentication session %s already exists", a.ID)
+		} else if errors.Cause(err) != pkg.ErrNotFound {
+			return err
+		}
+
+		return pkg.BoltPut(tx, boltBucketAuthenticationSessions, a.ID, a)
+	})
+}
+
+func (m *BoltManager) DeleteAuthenticationSession(id string) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		if err := pkg.BoltDelete(tx, boltBucketAuthenticationSessions, id); err != nil {
+			return err
+		}
+		return pkg.BoltDelete(tx, boltBucketAuthenticationSessionClients, id)
+	})
+}
+
+func (m *BoltManager) RevokeUserAuthenticationSessions(user string) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		var ids []string
+		if err := pkg.BoltForEach(tx, boltBucketAuthenticationSessions, func(id string, raw []byte) error {
+			var a AuthenticationSession
+			if err := json.Unmarshal(raw, &a); err != nil {
+				return errors.WithStack(err)
+			}
+			if a.Subject == user {
+				ids = append(ids, id)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if err := pkg.BoltDelete(tx, boltBucketAuthenticationSessions, ids...); err != nil {
+			return err
+		}
+		return pkg.BoltDelete(tx, boltBucketAuthenticationSessionClients, ids...)
+	})
+}
+
+func (m *BoltManager) AddAuthenticationSessionClient(id string, client string) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		var clients []string
+		if err := pkg.BoltGet(tx, boltBucketAuthenticationSessionClients, id, &clients); err != nil && errors.Cause(err) != pkg.ErrNotFound {
+			return err
+		}
+
+		if stringslice.Has(clients, client) {
+			return nil
+		}
+		return pkg.BoltPut(tx, boltBucketAuthenticationSessionClients, id, append(clients, client))
+	})
+}
+
+func (m *BoltManager) GetAuthenticationSessionClients(id string) ([]string, error) {
+	clients := []string{}
+	if err := m.db.View(func(tx *bolt.Tx) error {
+		return pkg.BoltGet(tx, boltBucketAuthenticationSessionClients, id, &clients)
+	}); err != nil && errors.Cause(err) != pkg.ErrNotFound {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+func (m *BoltManager) FindPreviouslyGrantedConsentRequests(client string, subject string) ([]HandledConsentRequest, error) {
+	return m.findGrantedConsentRequests(func(r *sqlRequest) bool {
+		return r.Subject == subject && r.Client == client
+	})
+}
+
+func (m *BoltManager) FindPreviouslyGrantedConsentRequestsBySubject(subject string, limit, offset int) ([]HandledConsentRequest, error) {
+	rs, err := m.findGrantedConsentRequests(func(r *sqlRequest) bool {
+		return r.Subject == subject
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := pagination.Index(limit, offset, len(rs))
+	return rs[start:end], nil
+}
+
+// findGrantedConsentRequests returns the remembered consent whose consent request matches the filter, most recent
+// first.
+func (m *BoltManager) findGrantedConsentRequests(filter func(r *sqlRequest) bool) ([]HandledConsentRequest, error) {
+	var hs []HandledConsentRequest
+	var rs []sqlRequest
+	if err := m.db.View(func(tx *bolt.Tx) error {
+		return pkg.BoltForEach(tx, boltBucketConsentRequestsHandled, func(challenge string, raw []byte) error {
+			var d sqlHandledConsentRequest
+			if err := json.Unmarshal(raw, &d); err != nil {
+				return errors.WithStack(err)
+			}
+
+			h, err := d.toHandledConsentRequest(nil)
+			if err != nil {
+				return err
+			}
+
+			if h.Error != nil || !h.Remember || h.hasExpired() {
+				return nil
+			}
+
+			var r sqlRequest
+			if err := pkg.BoltGet(tx, boltBucketConsentRequests, challenge, &r); errors.Cause(err) == pkg.ErrNotFound {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			if r.Skip || !filter(&r) {
+				return nil
+			}
+
+			hs = append(hs, *h)
+			rs = append(rs, r)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	for k := range hs {
+		c, err := m.c.GetConcreteClient(rs[k].Client)
+		if err != nil {
+			return nil, err
+		}
+
+		if hs[k].ConsentRequest, err = rs[k].toConsentRequest(c); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(hs, func(i, j int) bool {
+		if hs[i].RequestedAt.Equal(hs[j].RequestedAt) {
+			return hs[i].Challenge < hs[j].Challenge
+		}
+		return hs[i].RequestedAt.After(hs[j].RequestedAt)
+	})
+
+	return append([]HandledConsentRequest{}, hs...), nil
+}
+
+func (m *BoltManager) FlushExpiredConsentSessions(notAfter time.Time) error {
+	now := time.Now().UTC()
+	return m.db.Update(func(tx *bolt.Tx) error {
+		var challenges []string
+		if err := pkg.BoltForEach(tx, boltBucketConsentRequestsHandled, func(challenge string, raw []byte) error {
+			var d sqlHandledConsentRequest
+			if err := json.Unmarshal(raw, &d); err != nil {
+				return errors.WithStack(err)
+			}
+
+			if !d.WasUsed || d.RememberFor <= 0 {
+				return nil
+			}
+
+			if d.RememberUntil != nil && d.RememberUntil.Before(now) && d.RememberUntil.Before(notAfter) {
+				challenges = append(challenges, challenge)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, challenge := range challenges {
+			if err := m.deleteRequest(tx, boltBucketConsentRequests, challenge); err != nil {
+				return err
+			}
+		}
+		return pkg.BoltDelete(tx, boltBucketConsentRequestsHandled, challenges...)
+	})
+}
+
+func (m *BoltManager) RevokeUserConsentSessions(user string, client string) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		var challenges []string
+		if err := pkg.BoltForEach(tx, boltBucketConsentRequestsHandled, func(challenge string, _ []byte) error {
+			var r sqlRequest
+			if err := pkg.BoltGet(tx, boltBucketConsentRequests, challenge, &r); errors.Cause(err) == pkg.ErrNotFound {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			if r.Subject == user && (client == "" || r.Client == client) {
+				challenges = append(challenges, challenge)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		return pkg.BoltDelete(tx, boltBucketConsentRequestsHandled, challenges...)
+	})
+}
+
+func (m *BoltManager) createRequest(bucket string, d *sqlRequest) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		if err := pkg.BoltGet(tx, bucket, d.Challenge, new(sqlRequest)); err == nil {
+			return errors.Errorf("Request %s already exists", d.Challenge)
+		} else if errors.Cause(err) != pkg.ErrNotFound {
+			return err
+		}
+
+		if err := pkg.BoltPut(tx, bucket+boltBucketSuffixVerifier, d.Verifier, d.Challenge); err != nil {
+			return err
+		}
+		return pkg.BoltPut(tx, bucket, d.Challenge, d)
+	})
+}
+
+func (m *BoltManager) getRequest(bucket, challenge string) (*sqlRequest, *client.Client, error) {
+	var d sqlRequest
+	if err := m.db.View(func(tx *bolt.Tx) error {
+		return pkg.BoltGet(tx, bucket, challenge, &d)
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	c, err := m.c.GetConcreteClient(d.Client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &d, c, nil
+}
+
+func (m *BoltManager) deleteRequest(tx *bolt.Tx, bucket, challenge string) error {
+	var d sqlRequest
+	if err := pkg.BoltGet(tx, bucket, challenge, &d); errors.Cause(err) == pkg.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := pkg.BoltDelete(tx, bucket+boltBucketSuffixVerifier, d.Verifier); err != nil {
+		return err
+	}
+	return pkg.BoltDelete(tx, bucket, challenge)
+}
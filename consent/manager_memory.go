@@ -21,11 +21,14 @@
 package consent
 
 import (
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/ory/fosite"
+	"github.com/ory/go-convenience/stringslice"
 	"github.com/ory/hydra/pkg"
+	"github.com/ory/pagination"
 	"github.com/pkg/errors"
 )
 
@@ -35,6 +38,7 @@ type MemoryManager struct {
 	authRequests           map[string]AuthenticationRequest
 	handledAuthRequests    map[string]HandledAuthenticationRequest
 	authSessions           map[string]AuthenticationSession
+	authSessionClients     map[string][]string
 	m                      map[string]*sync.RWMutex
 }
 
@@ -45,6 +49,7 @@ func NewMemoryManager() *MemoryManager {
 		authRequests:           map[string]AuthenticationRequest{},
 		handledAuthRequests:    map[string]HandledAuthenticationRequest{},
 		authSessions:           map[string]AuthenticationSession{},
+		authSessionClients:     map[string][]string{},
 		m: map[string]*sync.RWMutex{
 			"consentRequests":        new(sync.RWMutex),
 			"handledConsentRequests": new(sync.RWMutex),
@@ -134,7 +139,7 @@ func (m *MemoryManager) FindPreviouslyGrantedConsentRequests(client string, subj
 			continue
 		}
 
-		if c.RememberFor > 0 && c.RequestedAt.Add(time.Duration(c.RememberFor)*time.Second).Before(time.Now().UTC()) {
+		if c.hasExpired() {
 			continue
 		}
 
@@ -148,6 +153,86 @@ func (m *MemoryManager) FindPreviouslyGrantedConsentRequests(client string, subj
 	return rs, nil
 }
 
+func (m *MemoryManager) FindPreviouslyGrantedConsentRequestsBySubject(subject string, limit, offset int) ([]HandledConsentRequest, error) {
+	m.m["handledConsentRequests"].RLock()
+	defer m.m["handledConsentRequests"].RUnlock()
+
+	var rs []HandledConsentRequest
+	for _, c := range m.handledConsentRequests {
+		cr, err := m.GetConsentRequest(c.Challenge)
+		if errors.Cause(err) == pkg.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if subject != cr.Subject || cr.Skip {
+			continue
+		}
+
+		if c.Error != nil || !c.Remember {
+			continue
+		}
+
+		if c.hasExpired() {
+			continue
+		}
+
+		c.ConsentRequest = cr
+		rs = append(rs, c)
+	}
+
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].RequestedAt.Equal(rs[j].RequestedAt) {
+			return rs[i].Challenge < rs[j].Challenge
+		}
+		return rs[i].RequestedAt.After(rs[j].RequestedAt)
+	})
+
+	start, end := pagination.Index(limit, offset, len(rs))
+	return append([]HandledConsentRequest{}, rs[start:end]...), nil
+}
+
+func (m *MemoryManager) FlushExpiredConsentSessions(notAfter time.Time) error {
+	m.m["consentRequests"].Lock()
+	defer m.m["consentRequests"].Unlock()
+	m.m["handledConsentRequests"].Lock()
+	defer m.m["handledConsentRequests"].Unlock()
+
+	for challenge, c := range m.handledConsentRequests {
+		if !c.WasUsed || !c.hasExpired() || !c.rememberedUntil().Before(notAfter) {
+			continue
+		}
+
+		delete(m.handledConsentRequests, challenge)
+		delete(m.consentRequests, challenge)
+	}
+
+	return nil
+}
+
+func (m *MemoryManager) RevokeUserConsentSessions(user string, client string) error {
+	m.m["consentRequests"].RLock()
+	defer m.m["consentRequests"].RUnlock()
+	m.m["handledConsentRequests"].Lock()
+	defer m.m["handledConsentRequests"].Unlock()
+
+	for challenge := range m.handledConsentRequests {
+		cr, ok := m.consentRequests[challenge]
+		if !ok || cr.Subject != user {
+			continue
+		}
+
+		if client != "" && cr.Client.GetID() != client {
+			continue
+		}
+
+		delete(m.handledConsentRequests, challenge)
+	}
+
+	return nil
+}
+
 func (m *MemoryManager) GetAuthenticationSession(id string) (*AuthenticationSession, error) {
 	m.m["authSessions"].RLock()
 	defer m.m["authSessions"].RUnlock()
@@ -171,9 +256,37 @@ func (m *MemoryManager) DeleteAuthenticationSession(id string) error {
 	m.m["authSessions"].Lock()
 	defer m.m["authSessions"].Unlock()
 	delete(m.authSessions, id)
+	delete(m.authSessionClients, id)
 	return nil
 }
 
+func (m *MemoryManager) RevokeUserAuthenticationSessions(user string) error {
+	m.m["authSessions"].Lock()
+	defer m.m["authSessions"].Unlock()
+	for id, s := range m.authSessions {
+		if s.Subject == user {
+			delete(m.authSessions, id)
+			delete(m.authSessionClients, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryManager) AddAuthenticationSessionClient(id string, client string) error {
+	m.m["authSessions"].Lock()
+	defer m.m["authSessions"].Unlock()
+	if !stringslice.Has(m.authSessionClients[id], client) {
+		m.authSessionClients[id] = append(m.authSessionClients[id], client)
+	}
+	return nil
+}
+
+func (m *MemoryManager) GetAuthenticationSessionClients(id string) ([]string, error) {
+	m.m["authSessions"].RLock()
+	defer m.m["authSessions"].RUnlock()
+	return append([]string{}, m.authSessionClients[id]...), nil
+}
+
 func (m *MemoryManager) CreateAuthenticationRequest(a *AuthenticationRequest) error {
 	m.m["authRequests"].Lock()
 	defer m.m["authRequests"].Unlock()
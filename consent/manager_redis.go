@@ -0,0 +1,320 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package consent
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/pkg"
+	"github.com/pkg/errors"
+)
+
+const (
+	redisConsentRequests               = "hydra_oauth2_consent_request"
+	redisConsentRequestsHandled        = "hydra_oauth2_consent_request_handled"
+	redisAuthenticationRequests        = "hydra_oauth2_authentication_request"
+	redisAuthenticationRequestsHandled = "hydra_oauth2_authentication_request_handled"
+
+	// Requests are looked up by their verifier using an index key which maps the verifier to the challenge.
+	redisSuffixVerifier = "_verifier"
+
+	// The keys of the requests of a subject are indexed in a set, so that they can be revoked.
+	redisSuffixSubject = "_subject"
+)
+
+func redisKey(name, key string) string {
+	return name + ":" + key
+}
+
+// RedisManager keeps login and consent requests, which are only needed for the duration of a flow, in Redis and lets
+// them expire after the maximum age of a request. Consent which the user asked to remember is moved to the wrapped
+// persistent manager when it is granted, which also stores the authentication sessions.
+type RedisManager struct {
+	Manager
+	r   *redis.Client
+	c   client.Manager
+	ttl time.Duration
+}
+
+func NewRedisManager(m Manager, r *redis.Client, c client.Manager, ttl time.Duration) *RedisManager {
+	return &RedisManager{
+		Manager: m,
+		r:       r,
+		c:       c,
+		ttl:     ttl,
+	}
+}
+
+func (m *RedisManager) CreateConsentRequest(c *ConsentRequest) error {
+	d, err := newSQLConsentRequest(c)
+	if err != nil {
+		return err
+	}
+
+	return m.createRequest(redisConsentRequests, d)
+}
+
+// GetConsentRequest falls back to the persistent manager for consent requests which have been remembered.
+func (m *RedisManager) GetConsentRequest(challenge string) (*ConsentRequest, error) {
+	d, c, err := m.getRequest(redisConsentRequests, challenge)
+	if errors.Cause(err) == pkg.ErrNotFound {
+		return m.Manager.GetConsentRequest(challenge)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return d.toConsentRequest(c)
+}
+
+// HandleConsentRequest moves the consent request to the persistent manager if the consent is granted and remembered.
+func (m *RedisManager) HandleConsentRequest(challenge string, r *HandledConsentRequest) (*ConsentRequest, error) {
+	var d sqlRequest
+	if err := pkg.RedisGet(m.r, redisKey(redisConsentRequests, challenge), &d); errors.Cause(err) == pkg.ErrNotFound {
+		return m.Manager.HandleConsentRequest(challenge, r)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !r.Remember || r.Error != nil {
+		h, err := newSQLHandledConsentRequest(r)
+		if err != nil {
+			return nil, err
+		}
+
+		ttl, err := m.remainingTTL(redisKey(redisConsentRequests, challenge))
+		if err != nil {
+			return nil, err
+		}
+
+		if err := pkg.RedisSet(m.r, redisKey(redisConsentRequestsHandled, challenge), h, ttl); err != nil {
+			return nil, err
+		}
+
+		return m.GetConsentRequest(challenge)
+	}
+
+	c, err := m.GetConsentRequest(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.Manager.CreateConsentRequest(c); err != nil {
+		return nil, err
+	}
+
+	if err := m.deleteRequest(redisConsentRequests, &d); err != nil {
+		return nil, err
+	}
+
+	return m.Manager.HandleConsentRequest(challenge, r)
+}
+
+// VerifyAndInvalidateConsentRequest falls back to the persistent manager for consent requests which have been
+// remembered.
+func (m *RedisManager) VerifyAndInvalidateConsentRequest(verifier string) (*HandledConsentRequest, error) {
+	var challenge string
+	if err := pkg.RedisGet(m.r, redisKey(redisConsentRequests+redisSuffixVerifier, verifier), &challenge); errors.Cause(err) == pkg.ErrNotFound {
+		return m.Manager.VerifyAndInvalidateConsentRequest(verifier)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var d sqlHandledConsentRequest
+	if err := pkg.RedisUpdate(m.r, redisKey(redisConsentRequestsHandled, challenge), &d, func() error {
+		if d.WasUsed {
+			return errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Consent verifier has been used already"))
+		}
+
+		d.WasUsed = true
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	r, err := m.GetConsentRequest(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.toHandledConsentRequest(r)
+}
+
+func (m *RedisManager) CreateAuthenticationRequest(c *AuthenticationRequest) error {
+	d, err := newSQLAuthenticationRequest(c)
+	if err != nil {
+		return err
+	}
+
+	return m.createRequest(redisAuthenticationRequests, d)
+}
+
+// GetAuthenticationRequest falls back to the persistent manager for authentication requests which were created before
+// Redis was used.
+func (m *RedisManager) GetAuthenticationRequest(challenge string) (*AuthenticationRequest, error) {
+	d, c, err := m.getRequest(redisAuthenticationRequests, challenge)
+	if errors.Cause(err) == pkg.ErrNotFound {
+		return m.Manager.GetAuthenticationRequest(challenge)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return d.toAuthenticationRequest(c)
+}
+
+// HandleAuthenticationRequest falls back to the persistent manager for authentication requests which were created
+// before Redis was used.
+func (m *RedisManager) HandleAuthenticationRequest(challenge string, r *HandledAuthenticationRequest) (*AuthenticationRequest, error) {
+	ttl, err := m.remainingTTL(redisKey(redisAuthenticationRequests, challenge))
+	if errors.Cause(err) == pkg.ErrNotFound {
+		return m.Manager.HandleAuthenticationRequest(challenge, r)
+	} else if err != nil {
+		return nil, err
+	}
+
+	d, err := newSQLHandledAuthenticationRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pkg.RedisSet(m.r, redisKey(redisAuthenticationRequestsHandled, challenge), d, ttl); err != nil {
+		return nil, err
+	}
+
+	return m.GetAuthenticationRequest(challenge)
+}
+
+// VerifyAndInvalidateAuthenticationRequest falls back to the persistent manager for authentication requests which were
+// created before Redis was used.
+func (m *RedisManager) VerifyAndInvalidateAuthenticationRequest(verifier string) (*HandledAuthenticationRequest, error) {
+	var challenge string
+	if err := pkg.RedisGet(m.r, redisKey(redisAuthenticationRequests+redisSuffixVerifier, verifier), &challenge); errors.Cause(err) == pkg.ErrNotFound {
+		return m.Manager.VerifyAndInvalidateAuthenticationRequest(verifier)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var d sqlHandledAuthenticationRequest
+	if err := pkg.RedisUpdate(m.r, redisKey(redisAuthenticationRequestsHandled, challenge), &d, func() error {
+		if d.WasUsed {
+			return errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Authentication verifier has been used already"))
+		}
+
+		d.WasUsed = true
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	r, err := m.GetAuthenticationRequest(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.toHandledAuthenticationRequest(r)
+}
+
+// RevokeUserConsentSessions deletes the consent requests of the user which are kept in Redis, so that consent which
+// has been handled but not remembered can not be redeemed anymore, in addition to revoking the remembered consent.
+func (m *RedisManager) RevokeUserConsentSessions(user string, client string) error {
+	if err := m.Manager.RevokeUserConsentSessions(user, client); err != nil {
+		return err
+	}
+
+	return m.deleteUserRequests(redisConsentRequests, redisConsentRequestsHandled, user, func(d *sqlRequest) bool {
+		return client == "" || d.Client == client
+	})
+}
+
+// RevokeUserAuthenticationSessions deletes the authentication requests of the user which are kept in Redis in addition
+// to revoking the authentication sessions.
+func (m *RedisManager) RevokeUserAuthenticationSessions(user string) error {
+	if err := m.Manager.RevokeUserAuthenticationSessions(user); err != nil {
+		return err
+	}
+
+	return m.deleteUserRequests(redisAuthenticationRequests, redisAuthenticationRequestsHandled, user, func(d *sqlRequest) bool {
+		return true
+	})
+}
+
+// deleteUserRequests deletes the requests of the user stored under name which match, their verifier index keys and
+// their handled requests stored under handled.
+func (m *RedisManager) deleteUserRequests(name, handled, user string, match func(d *sqlRequest) bool) error {
+	return pkg.RedisDeleteIndexed(m.r, redisKey(name+redisSuffixSubject, user), func(key string, raw []byte) ([]string, error) {
+		var d sqlRequest
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if !match(&d) {
+			return nil, nil
+		}
+		return []string{key, redisKey(name+redisSuffixVerifier, d.Verifier), redisKey(handled, d.Challenge)}, nil
+	})
+}
+
+// remainingTTL returns the time until the key expires. It returns pkg.ErrNotFound if the key does not exist or has
+// expired.
+func (m *RedisManager) remainingTTL(key string) (time.Duration, error) {
+	ttl, err := m.r.PTTL(key).Result()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	} else if ttl <= 0 {
+		return 0, errors.Wrap(pkg.ErrNotFound, "")
+	}
+	return ttl, nil
+}
+
+func (m *RedisManager) createRequest(name string, d *sqlRequest) error {
+	if err := pkg.RedisCreate(m.r, redisKey(name, d.Challenge), d, m.ttl); err != nil {
+		return err
+	}
+
+	if err := pkg.RedisSet(m.r, redisKey(name+redisSuffixVerifier, d.Verifier), d.Challenge, m.ttl); err != nil {
+		return err
+	} else if d.Subject == "" {
+		// Authentication requests only know the subject if the user is authenticated already.
+		return nil
+	}
+	return pkg.RedisIndex(m.r, redisKey(name+redisSuffixSubject, d.Subject), redisKey(name, d.Challenge), m.ttl)
+}
+
+func (m *RedisManager) getRequest(name, challenge string) (*sqlRequest, *client.Client, error) {
+	var d sqlRequest
+	if err := pkg.RedisGet(m.r, redisKey(name, challenge), &d); err != nil {
+		return nil, nil, err
+	}
+
+	c, err := m.c.GetConcreteClient(d.Client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &d, c, nil
+}
+
+func (m *RedisManager) deleteRequest(name string, d *sqlRequest) error {
+	return errors.WithStack(m.r.Del(redisKey(name, d.Challenge), redisKey(name+redisSuffixVerifier, d.Verifier)).Err())
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/pkg"
 	"github.com/ory/sqlcon"
 	"github.com/pkg/errors"
 	"github.com/rubenv/sql-migrate"
@@ -48,13 +49,45 @@ func NewSQLManager(db *sqlx.DB, c client.Manager) *SQLManager {
 
 func (m *SQLManager) CreateSchemas() (int, error) {
 	migrate.SetTable("hydra_oauth2_authentication_consent_migration")
-	n, err := migrate.Exec(m.db.DB, m.db.DriverName(), migrations, migrate.Up)
+	n, err := migrate.Exec(m.db.DB, m.db.DriverName(), pkg.SQLMigrations(migrations, m.db.DriverName(), nil), migrate.Up)
 	if err != nil {
 		return 0, errors.Wrapf(err, "Could not migrate sql schema, applied %d migrations", n)
 	}
+
+	if err := m.backfillRememberUntil(); err != nil {
+		return 0, err
+	}
 	return n, nil
 }
 
+// backfillRememberUntil sets remember_until of consent which was remembered for a limited time before the column was
+// added. Rows which already have remember_until set are left untouched, so it is safe to call this more than once.
+func (m *SQLManager) backfillRememberUntil() error {
+	var rows []struct {
+		Challenge   string     `db:"challenge"`
+		RememberFor int        `db:"remember_for"`
+		RequestedAt time.Time  `db:"requested_at"`
+		HandledAt   *time.Time `db:"handled_at"`
+	}
+	if err := m.db.Select(&rows, "SELECT challenge, remember_for, requested_at, handled_at FROM hydra_oauth2_consent_request_handled WHERE remember_for>0 AND remember_until IS NULL"); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	for _, row := range rows {
+		handledAt := row.RequestedAt
+		if row.HandledAt != nil {
+			handledAt = *row.HandledAt
+		}
+
+		rememberUntil := handledAt.Add(time.Duration(row.RememberFor) * time.Second)
+		if _, err := m.db.Exec(m.db.Rebind("UPDATE hydra_oauth2_consent_request_handled SET remember_until=? WHERE challenge=?"), rememberUntil, row.Challenge); err != nil {
+			return sqlcon.HandleError(err)
+		}
+	}
+
+	return nil
+}
+
 func (m *SQLManager) CreateConsentRequest(c *ConsentRequest) error {
 	d, err := newSQLConsentRequest(c)
 	if err != nil {
@@ -239,9 +272,59 @@ func (m *SQLManager) DeleteAuthenticationSession(id string) error {
 		return sqlcon.HandleError(err)
 	}
 
+	if _, err := m.db.Exec(m.db.Rebind("DELETE FROM hydra_oauth2_authentication_session_client WHERE session_id=?"), id); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	return nil
+}
+
+func (m *SQLManager) RevokeUserAuthenticationSessions(user string) error {
+	tx, err := m.db.Beginx()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	for _, query := range []string{
+		"DELETE FROM hydra_oauth2_authentication_session_client WHERE session_id IN (SELECT id FROM hydra_oauth2_authentication_session WHERE subject=?)",
+		"DELETE FROM hydra_oauth2_authentication_session WHERE subject=?",
+	} {
+		if _, err := tx.Exec(tx.Rebind(query), user); err != nil {
+			if re := tx.Rollback(); re != nil {
+				return errors.Wrap(err, re.Error())
+			}
+			return sqlcon.HandleError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		if re := tx.Rollback(); re != nil {
+			return errors.Wrap(err, re.Error())
+		}
+		return sqlcon.HandleError(err)
+	}
+	return nil
+}
+
+func (m *SQLManager) AddAuthenticationSessionClient(id string, client string) error {
+	if _, err := m.db.Exec(m.db.Rebind("INSERT INTO hydra_oauth2_authentication_session_client (session_id, client_id) VALUES (?, ?)"), id, client); err != nil {
+		if err := pkg.HandleSQLError(err); errors.Cause(err) != sqlcon.ErrUniqueViolation {
+			return err
+		}
+	}
+
 	return nil
 }
 
+func (m *SQLManager) GetAuthenticationSessionClients(id string) ([]string, error) {
+	var clients []string
+	if err := m.db.Select(&clients, m.db.Rebind("SELECT client_id FROM hydra_oauth2_authentication_session_client WHERE session_id=?"), id); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	return clients, nil
+}
+
 func (m *SQLManager) FindPreviouslyGrantedConsentRequests(client string, subject string) ([]HandledConsentRequest, error) {
 	var a []sqlHandledConsentRequest
 
@@ -253,7 +336,9 @@ WHERE
 		r.subject=? AND r.client_id=? AND r.skip=FALSE
 	AND
 		(h.error='{}' AND h.remember=TRUE)
-`), subject, client); err != nil {
+	AND
+		(h.remember_for=0 OR h.remember_until > ?)
+`), subject, client, time.Now().UTC()); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.WithStack(errNoPreviousConsentFound)
 		}
@@ -269,10 +354,6 @@ WHERE
 			return nil, errors.WithStack(errNoPreviousConsentFound)
 		}
 
-		if v.RememberFor > 0 && v.RequestedAt.Add(time.Duration(v.RememberFor)*time.Second).Before(time.Now().UTC()) {
-			continue
-		}
-
 		va, err := v.toHandledConsentRequest(r)
 		if err != nil {
 			return nil, err
@@ -287,3 +368,70 @@ WHERE
 
 	return aa, nil
 }
+
+func (m *SQLManager) FindPreviouslyGrantedConsentRequestsBySubject(subject string, limit, offset int) ([]HandledConsentRequest, error) {
+	var a []sqlHandledConsentRequest
+
+	if err := m.db.Select(&a, m.db.Rebind(`SELECT h.* FROM
+	hydra_oauth2_consent_request_handled as h
+JOIN
+	hydra_oauth2_consent_request as r ON (h.challenge = r.challenge)
+WHERE
+		r.subject=? AND r.skip=FALSE
+	AND
+		(h.error='{}' AND h.remember=TRUE)
+	AND
+		(h.remember_for=0 OR h.remember_until > ?)
+ORDER BY h.requested_at DESC, h.challenge
+LIMIT ? OFFSET ?
+`), subject, time.Now().UTC(), limit, offset); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	aa := []HandledConsentRequest{}
+	for _, v := range a {
+		r, err := m.GetConsentRequest(v.Challenge)
+		if err != nil {
+			return nil, err
+		}
+
+		va, err := v.toHandledConsentRequest(r)
+		if err != nil {
+			return nil, err
+		}
+
+		aa = append(aa, *va)
+	}
+
+	return aa, nil
+}
+
+func (m *SQLManager) FlushExpiredConsentSessions(notAfter time.Time) error {
+	args := []interface{}{time.Now().UTC(), notAfter}
+	for _, query := range []string{
+		// The consent request is deleted first because it is found through the handled consent request.
+		`DELETE FROM hydra_oauth2_consent_request WHERE challenge IN (SELECT challenge FROM hydra_oauth2_consent_request_handled WHERE was_used=TRUE AND remember_for>0 AND remember_until < ? AND remember_until < ?)`,
+		`DELETE FROM hydra_oauth2_consent_request_handled WHERE was_used=TRUE AND remember_for>0 AND remember_until < ? AND remember_until < ?`,
+	} {
+		if _, err := m.db.Exec(m.db.Rebind(query), args...); err != nil {
+			return sqlcon.HandleError(err)
+		}
+	}
+
+	return nil
+}
+
+func (m *SQLManager) RevokeUserConsentSessions(user string, client string) error {
+	query := "DELETE FROM hydra_oauth2_consent_request_handled WHERE challenge IN (SELECT challenge FROM hydra_oauth2_consent_request WHERE subject=?)"
+	args := []interface{}{user}
+	if client != "" {
+		query = "DELETE FROM hydra_oauth2_consent_request_handled WHERE challenge IN (SELECT challenge FROM hydra_oauth2_consent_request WHERE subject=? AND client_id=?)"
+		args = append(args, client)
+	}
+
+	if _, err := m.db.Exec(m.db.Rebind(query), args...); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	return nil
+}
@@ -30,7 +30,9 @@ import (
 	_ "github.com/lib/pq"
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/pkg"
 	"github.com/ory/sqlcon/dockertest"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -75,6 +77,7 @@ func mockConsentRequest(key string, remember bool, rememberFor int, hasError boo
 		Remember:        remember,
 		Challenge:       "challenge" + key,
 		RequestedAt:     time.Now().UTC().Add(-time.Minute),
+		HandledAt:       time.Now().UTC().Add(-time.Minute),
 		AuthenticatedAt: authenticatedAt,
 		Error:           err,
 	}
@@ -145,6 +148,16 @@ func connectToPostgres(managers map[string]Manager, c client.Manager) {
 	managers["postgres"] = s
 }
 
+func connectToSQLite(managers map[string]Manager, c client.Manager) {
+	s := NewSQLManager(pkg.SQLiteTestDatabase(), c)
+	if _, err := s.CreateSchemas(); err != nil {
+		log.Fatalf("Could not create sqlite schema: %v", err)
+		return
+	}
+
+	managers["sqlite"] = s
+}
+
 func connectToMySQL(managers map[string]Manager, c client.Manager) {
 	db, err := dockertest.ConnectToTestMySQL()
 	if err != nil {
@@ -164,12 +177,15 @@ func connectToMySQL(managers map[string]Manager, c client.Manager) {
 var clientManager = client.NewMemoryManager(&fosite.BCrypt{WorkFactor: 8})
 var managers = map[string]Manager{
 	"memory": NewMemoryManager(),
+	"bolt":   NewBoltManager(pkg.BoltTestDatabase(), clientManager),
+	"redis":  NewRedisManager(NewMemoryManager(), pkg.RedisTestDatabase(), clientManager, time.Hour),
 }
 
 func TestMain(m *testing.M) {
 	runner := dockertest.Register()
 
 	flag.Parse()
+	connectToSQLite(managers, clientManager)
 	if !testing.Short() {
 		dockertest.Parallel([]func(){
 			func() {
@@ -180,7 +196,9 @@ func TestMain(m *testing.M) {
 		})
 	}
 
-	runner.Exit(m.Run())
+	code := m.Run()
+	pkg.CleanupTestDatabases()
+	runner.Exit(code)
 }
 
 func TestManagers(t *testing.T) {
@@ -229,14 +247,52 @@ func TestManagers(t *testing.T) {
 						id: "session2",
 					},
 				} {
+					t.Run("case=add-get-clients-"+tc.id, func(t *testing.T) {
+						require.NoError(t, m.AddAuthenticationSessionClient(tc.id, "client-1"))
+						require.NoError(t, m.AddAuthenticationSessionClient(tc.id, "client-2"))
+						require.NoError(t, m.AddAuthenticationSessionClient(tc.id, "client-1"))
+
+						clients, err := m.GetAuthenticationSessionClients(tc.id)
+						require.NoError(t, err)
+						assert.Len(t, clients, 2)
+						assert.Contains(t, clients, "client-1")
+						assert.Contains(t, clients, "client-2")
+					})
+
 					t.Run("case=delete-get-"+tc.id, func(t *testing.T) {
 						err := m.DeleteAuthenticationSession(tc.id)
 						require.NoError(t, err)
 
 						_, err = m.GetAuthenticationSession(tc.id)
 						require.Error(t, err)
+
+						clients, err := m.GetAuthenticationSessionClients(tc.id)
+						require.NoError(t, err)
+						assert.Empty(t, clients)
 					})
 				}
+
+				t.Run("case=revoke-user-sessions", func(t *testing.T) {
+					for _, id := range []string{"session3", "session4"} {
+						require.NoError(t, m.CreateAuthenticationSession(&AuthenticationSession{
+							ID:              id,
+							AuthenticatedAt: time.Now().Round(time.Second).UTC(),
+							Subject:         "subject3",
+						}))
+						require.NoError(t, m.AddAuthenticationSessionClient(id, "client-1"))
+					}
+
+					require.NoError(t, m.RevokeUserAuthenticationSessions("subject3"))
+
+					for _, id := range []string{"session3", "session4"} {
+						_, err := m.GetAuthenticationSession(id)
+						require.Error(t, err)
+
+						clients, err := m.GetAuthenticationSessionClients(id)
+						require.NoError(t, err)
+						assert.Empty(t, clients)
+					}
+				})
 			})
 		}
 	})
@@ -307,6 +363,108 @@ func TestManagers(t *testing.T) {
 						assert.Len(t, rs, tc.expectedLength)
 					})
 				}
+
+				t.Run("case=find-by-subject", func(t *testing.T) {
+					c, h := mockConsentRequest("8", true, 0, false, false, true)
+					c.Subject = "subject1"
+					h.RequestedAt = time.Now().UTC()
+					h.HandledAt = time.Now().UTC()
+					clientManager.CreateClient(c.Client) // Ignore errors that are caused by duplication
+					require.NoError(t, m.CreateConsentRequest(c))
+					_, err := m.HandleConsentRequest("challenge8", h)
+					require.NoError(t, err)
+
+					rs, err := m.FindPreviouslyGrantedConsentRequestsBySubject("subject1", 100, 0)
+					require.NoError(t, err)
+					require.Len(t, rs, 2)
+					assert.Equal(t, "challenge8", rs[0].Challenge)
+					assert.Equal(t, "challenge1", rs[1].Challenge)
+					assert.Equal(t, "client1", rs[1].ConsentRequest.Client.GetID())
+
+					rs, err = m.FindPreviouslyGrantedConsentRequestsBySubject("subject1", 1, 1)
+					require.NoError(t, err)
+					require.Len(t, rs, 1)
+					assert.Equal(t, "challenge1", rs[0].Challenge)
+
+					rs, err = m.FindPreviouslyGrantedConsentRequestsBySubject("subject1", 100, 2)
+					require.NoError(t, err)
+					assert.Len(t, rs, 0)
+
+					for _, subject := range []string{"subject2", "subject3", "subject4", "subject6", "subject7"} {
+						rs, err = m.FindPreviouslyGrantedConsentRequestsBySubject(subject, 100, 0)
+						require.NoError(t, err)
+						assert.Len(t, rs, 0, "%s", subject)
+					}
+
+					rs, err = m.FindPreviouslyGrantedConsentRequestsBySubject("subject5", 100, 0)
+					require.NoError(t, err)
+					require.Len(t, rs, 1)
+					assert.Equal(t, 120, rs[0].RememberFor)
+				})
+
+				t.Run("case=flush-expired-consent-sessions", func(t *testing.T) {
+					for _, tc := range []struct {
+						key         string
+						rememberFor int
+						handledAt   time.Time
+						wasUsed     bool
+					}{
+						{"9", 60, time.Now().UTC().Add(-time.Hour), true},
+						{"10", 60, time.Now().UTC().Add(-time.Hour), false},
+						{"11", 7200, time.Now().UTC().Add(-time.Hour), true},
+						{"12", 0, time.Now().UTC().Add(-time.Hour), true},
+					} {
+						c, h := mockConsentRequest(tc.key, true, tc.rememberFor, false, false, true)
+						h.HandledAt = tc.handledAt
+						h.WasUsed = tc.wasUsed
+						clientManager.CreateClient(c.Client) // Ignore errors that are caused by duplication
+						require.NoError(t, m.CreateConsentRequest(c))
+						_, err := m.HandleConsentRequest("challenge"+tc.key, h)
+						require.NoError(t, err)
+					}
+
+					rs, err := m.FindPreviouslyGrantedConsentRequests("client9", "subject9")
+					require.NoError(t, err)
+					assert.Len(t, rs, 0)
+
+					require.NoError(t, m.FlushExpiredConsentSessions(time.Now().UTC().Add(-2*time.Hour)))
+					_, err = m.GetConsentRequest("challenge9")
+					require.NoError(t, err)
+
+					require.NoError(t, m.FlushExpiredConsentSessions(time.Now().UTC()))
+					_, err = m.GetConsentRequest("challenge9")
+					require.Error(t, err)
+
+					for _, key := range []string{"10", "11", "12"} {
+						_, err = m.GetConsentRequest("challenge" + key)
+						require.NoError(t, err, "%s", key)
+					}
+
+					rs, err = m.FindPreviouslyGrantedConsentRequests("client11", "subject11")
+					require.NoError(t, err)
+					assert.Len(t, rs, 1)
+				})
+
+				t.Run("case=revoke-user-consent-sessions", func(t *testing.T) {
+					require.NoError(t, m.RevokeUserConsentSessions("subject1", "client5"))
+					rs, err := m.FindPreviouslyGrantedConsentRequests("client1", "subject1")
+					require.NoError(t, err)
+					assert.Len(t, rs, 1)
+
+					require.NoError(t, m.RevokeUserConsentSessions("subject1", ""))
+					rs, err = m.FindPreviouslyGrantedConsentRequests("client1", "subject1")
+					require.NoError(t, err)
+					assert.Len(t, rs, 0)
+
+					rs, err = m.FindPreviouslyGrantedConsentRequests("client5", "subject5")
+					require.NoError(t, err)
+					assert.Len(t, rs, 1)
+
+					require.NoError(t, m.RevokeUserConsentSessions("subject5", "client5"))
+					rs, err = m.FindPreviouslyGrantedConsentRequests("client5", "subject5")
+					require.NoError(t, err)
+					assert.Len(t, rs, 0)
+				})
 			})
 		}
 	})
@@ -356,6 +514,94 @@ func TestManagers(t *testing.T) {
 	})
 }
 
+func TestSQLManagerBackfillRememberUntil(t *testing.T) {
+	for k, m := range managers {
+		s, ok := m.(*SQLManager)
+		if !ok {
+			continue
+		}
+
+		t.Run("manager="+k, func(t *testing.T) {
+			c, h := mockConsentRequest("backfill", true, 60, false, false, true)
+			h.HandledAt = time.Now().UTC().Add(-time.Hour)
+			h.WasUsed = true
+			clientManager.CreateClient(c.Client) // Ignore errors that are caused by duplication
+			require.NoError(t, s.CreateConsentRequest(c))
+			_, err := s.HandleConsentRequest("challengebackfill", h)
+			require.NoError(t, err)
+
+			// Simulate consent which was handled before remember_until was added.
+			_, err = s.db.Exec(s.db.Rebind("UPDATE hydra_oauth2_consent_request_handled SET remember_until=NULL WHERE challenge=?"), "challengebackfill")
+			require.NoError(t, err)
+
+			require.NoError(t, s.FlushExpiredConsentSessions(time.Now().UTC()))
+			_, err = s.GetConsentRequest("challengebackfill")
+			require.NoError(t, err)
+
+			_, err = s.CreateSchemas()
+			require.NoError(t, err)
+			_, err = s.CreateSchemas()
+			require.NoError(t, err)
+
+			require.NoError(t, s.FlushExpiredConsentSessions(time.Now().UTC()))
+			_, err = s.GetConsentRequest("challengebackfill")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRedisManagerExpiresRequests(t *testing.T) {
+	server, r := pkg.RedisTestServer()
+	m := NewRedisManager(NewMemoryManager(), r, clientManager, time.Minute)
+
+	c, h := mockConsentRequest("redisexpiry", false, 0, false, false, true)
+	clientManager.CreateClient(c.Client) // Ignore errors that are caused by duplication
+	require.NoError(t, m.CreateConsentRequest(c))
+
+	a, _ := mockAuthRequest("redisexpiry", true)
+	require.NoError(t, m.CreateAuthenticationRequest(a))
+
+	// The handled consent request must expire together with the consent request.
+	server.FastForward(time.Second * 30)
+	_, err := m.HandleConsentRequest("challengeredisexpiry", h)
+	require.NoError(t, err)
+
+	server.FastForward(time.Second * 31)
+
+	_, err = m.GetConsentRequest("challengeredisexpiry")
+	assert.Equal(t, pkg.ErrNotFound, errors.Cause(err))
+	_, err = m.VerifyAndInvalidateConsentRequest("verifierredisexpiry")
+	assert.Equal(t, pkg.ErrNotFound, errors.Cause(err))
+	_, err = m.GetAuthenticationRequest("challengeredisexpiry")
+	assert.Equal(t, pkg.ErrNotFound, errors.Cause(err))
+}
+
+func TestRedisManagerRevokeUserSessions(t *testing.T) {
+	m := NewRedisManager(NewMemoryManager(), pkg.RedisTestDatabase(), clientManager, time.Hour)
+
+	c, h := mockConsentRequest("redisrevoke", false, 0, false, false, true)
+	clientManager.CreateClient(c.Client) // Ignore errors that are caused by duplication
+	require.NoError(t, m.CreateConsentRequest(c))
+	_, err := m.HandleConsentRequest("challengeredisrevoke", h)
+	require.NoError(t, err)
+
+	a, _ := mockAuthRequest("redisrevoke", true)
+	require.NoError(t, m.CreateAuthenticationRequest(a))
+
+	require.NoError(t, m.RevokeUserConsentSessions("subjectredisrevoke", ""))
+	_, err = m.VerifyAndInvalidateConsentRequest("verifierredisrevoke")
+	assert.Equal(t, pkg.ErrNotFound, errors.Cause(err))
+	_, err = m.GetConsentRequest("challengeredisrevoke")
+	assert.Equal(t, pkg.ErrNotFound, errors.Cause(err))
+	assert.EqualValues(t, 0, m.r.Exists(redisKey(redisConsentRequests+redisSuffixSubject, "subjectredisrevoke")).Val())
+
+	_, err = m.GetAuthenticationRequest("challengeredisrevoke")
+	require.NoError(t, err)
+	require.NoError(t, m.RevokeUserAuthenticationSessions("subjectredisrevoke"))
+	_, err = m.GetAuthenticationRequest("challengeredisrevoke")
+	assert.Equal(t, pkg.ErrNotFound, errors.Cause(err))
+}
+
 func compareAuthenticationRequest(t *testing.T, a, b *AuthenticationRequest) {
 	assert.EqualValues(t, a.Client.ID, b.Client.ID)
 	assert.EqualValues(t, a.Challenge, b.Challenge)
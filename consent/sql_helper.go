@@ -105,6 +105,33 @@ var migrations = &migrate.MemoryMigrationSource{
 				"DROP TABLE hydra_oauth2_authentication_request_handled",
 			},
 		},
+		{
+			Id: "2",
+			Up: []string{
+				`CREATE TABLE hydra_oauth2_authentication_session_client (
+	session_id			varchar(40) NOT NULL,
+	client_id			varchar(255) NOT NULL,
+	PRIMARY KEY (session_id, client_id)
+)`,
+			},
+			Down: []string{
+				"DROP TABLE hydra_oauth2_authentication_session_client",
+			},
+		},
+		{
+			Id: "3",
+			Up: []string{
+				`ALTER TABLE hydra_oauth2_consent_request_handled ADD handled_at timestamp NULL`,
+				// remember_until is derived from handled_at and remember_for so that expired consent can be filtered
+				// without database specific date arithmetic. It is back-filled by SQLManager.CreateSchemas.
+				`ALTER TABLE hydra_oauth2_consent_request_handled ADD remember_until timestamp NULL`,
+				`UPDATE hydra_oauth2_consent_request_handled SET handled_at=requested_at`,
+			},
+			Down: []string{
+				`ALTER TABLE hydra_oauth2_consent_request_handled DROP COLUMN handled_at`,
+				`ALTER TABLE hydra_oauth2_consent_request_handled DROP COLUMN remember_until`,
+			},
+		},
 	},
 }
 
@@ -144,6 +171,8 @@ var sqlParamsConsentRequestHandled = []string{
 	"session_access_token",
 	"session_id_token",
 	"was_used",
+	"handled_at",
+	"remember_until",
 }
 var sqlParamsAuthSession = []string{
 	"id",
@@ -249,6 +278,8 @@ type sqlHandledConsentRequest struct {
 	RequestedAt        time.Time  `db:"requested_at"`
 	WasUsed            bool       `db:"was_used"`
 	AuthenticatedAt    *time.Time `db:"authenticated_at"`
+	HandledAt          *time.Time `db:"handled_at"`
+	RememberUntil      *time.Time `db:"remember_until"`
 }
 
 func newSQLHandledConsentRequest(c *HandledConsentRequest) (*sqlHandledConsentRequest, error) {
@@ -282,6 +313,11 @@ func newSQLHandledConsentRequest(c *HandledConsentRequest) (*sqlHandledConsentRe
 		}
 	}
 
+	var rememberUntil *time.Time
+	if !c.HandledAt.IsZero() {
+		rememberUntil = toMySQLDateHack(c.rememberedUntil())
+	}
+
 	return &sqlHandledConsentRequest{
 		GrantedScope:       strings.Join(c.GrantedScope, "|"),
 		SessionIDToken:     sidt,
@@ -293,6 +329,8 @@ func newSQLHandledConsentRequest(c *HandledConsentRequest) (*sqlHandledConsentRe
 		RequestedAt:        c.RequestedAt,
 		WasUsed:            c.WasUsed,
 		AuthenticatedAt:    toMySQLDateHack(c.AuthenticatedAt),
+		HandledAt:          toMySQLDateHack(c.HandledAt),
+		RememberUntil:      rememberUntil,
 	}, nil
 }
 
@@ -329,6 +367,7 @@ func (s *sqlHandledConsentRequest) toHandledConsentRequest(r *ConsentRequest) (*
 		Error:           e,
 		ConsentRequest:  r,
 		AuthenticatedAt: fromMySQLDateHack(s.AuthenticatedAt),
+		HandledAt:       fromMySQLDateHack(s.HandledAt),
 	}, nil
 }
 
@@ -124,6 +124,7 @@ func TestSQLConsentConverter(t *testing.T) {
 			IDToken:     map[string]interface{}{"foo": "fab"},
 		},
 		RequestedAt: time.Now().UTC().Add(-time.Minute),
+		HandledAt:   time.Now().UTC().Add(-time.Second),
 		Error: &RequestDeniedError{
 			Name:        "error_name",
 			Description: "error_description",
@@ -138,6 +139,7 @@ func TestSQLConsentConverter(t *testing.T) {
 
 	b1, err := newSQLHandledConsentRequest(b)
 	require.NoError(t, err)
+	assert.Equal(t, b.HandledAt.Add(10*time.Second), *b1.RememberUntil)
 
 	a2, err := a1.toConsentRequest(a.Client)
 	require.NoError(t, err)
@@ -28,4 +28,22 @@ import (
 
 type Strategy interface {
 	HandleOAuth2AuthorizationRequest(w http.ResponseWriter, r *http.Request, req fosite.AuthorizeRequester) (*HandledConsentRequest, error)
+	HandleOAuth2DeviceVerificationRequest(w http.ResponseWriter, r *http.Request, req fosite.AuthorizeRequester) (*HandledConsentRequest, error)
+	HandleOpenIDConnectLogout(w http.ResponseWriter, r *http.Request) (*LogoutResult, error)
+}
+
+// LogoutResult is the outcome of an OpenID Connect logout.
+type LogoutResult struct {
+	// RedirectTo is the post_logout_redirect_uri including the state, or empty if the relying party did not request
+	// one.
+	RedirectTo string
+
+	// FrontChannelLogoutURLs are the front-channel logout URIs, including the iss and sid query parameters, of all
+	// clients which participated in the ended authentication session.
+	FrontChannelLogoutURLs []string
+
+	// ConfirmationCSRF is set if the logout request has to be confirmed by the user, which is the case if it does not
+	// contain an id_token_hint. The confirmation is a POST request to the logout endpoint carrying this value in the
+	// csrf form field. The authentication session has not been revoked yet.
+	ConfirmationCSRF string
 }
@@ -36,6 +36,7 @@ import (
 	"github.com/ory/go-convenience/stringslice"
 	"github.com/ory/go-convenience/stringsx"
 	"github.com/ory/go-convenience/urlx"
+	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/pkg"
 	"github.com/ory/sqlcon"
 	"github.com/pborman/uuid"
@@ -48,6 +49,7 @@ const (
 
 	cookieAuthenticationCSRFName = "oauth2_authentication_csrf"
 	cookieConsentCSRFName        = "oauth2_consent_csrf"
+	cookieLogoutCSRFName         = "oauth2_logout_csrf"
 )
 
 type DefaultStrategy struct {
@@ -62,6 +64,12 @@ type DefaultStrategy struct {
 	RequestMaxAge                 time.Duration
 	JWTStrategy                   jwt.JWTStrategy
 	OpenIDConnectRequestValidator *openid.OpenIDConnectRequestValidator
+	SubjectIdentifierAlgorithm    map[string]SubjectIdentifierAlgorithm
+	Clients                       fosite.ClientManager
+	BackChannelLogout             *BackChannelLogoutNotifier
+
+	// IDTokenHintMaxAge is how long after their expiry ID Tokens are accepted as id_token_hint of logout requests.
+	IDTokenHintMaxAge time.Duration
 }
 
 func NewStrategy(
@@ -76,6 +84,9 @@ func NewStrategy(
 	requestMaxAge time.Duration,
 	jwtStrategy jwt.JWTStrategy,
 	openIDConnectRequestValidator *openid.OpenIDConnectRequestValidator,
+	subjectIdentifierAlgorithm map[string]SubjectIdentifierAlgorithm,
+	clients fosite.ClientManager,
+	backChannelLogout *BackChannelLogoutNotifier,
 ) *DefaultStrategy {
 	return &DefaultStrategy{
 		AuthenticationURL:             authenticationURL,
@@ -89,33 +100,36 @@ func NewStrategy(
 		RequestMaxAge:                 requestMaxAge,
 		JWTStrategy:                   jwtStrategy,
 		OpenIDConnectRequestValidator: openIDConnectRequestValidator,
+		SubjectIdentifierAlgorithm:    subjectIdentifierAlgorithm,
+		Clients:                       clients,
+		BackChannelLogout:             backChannelLogout,
 	}
 }
 
 var ErrAbortOAuth2Request = errors.New("The OAuth 2.0 Authorization request must be aborted")
 var errNoPreviousConsentFound = errors.New("No previous OAuth 2.0 Consent could be found for this access request")
 
-func (s *DefaultStrategy) requestAuthentication(w http.ResponseWriter, r *http.Request, ar fosite.AuthorizeRequester) error {
+func (s *DefaultStrategy) requestAuthentication(w http.ResponseWriter, r *http.Request, ar fosite.AuthorizeRequester, requestPath string) error {
 	prompt := stringsx.Splitx(ar.GetRequestForm().Get("prompt"), " ")
 	if stringslice.Has(prompt, "login") {
-		return s.forwardAuthenticationRequest(w, r, ar, "", time.Time{})
+		return s.forwardAuthenticationRequest(w, r, ar, requestPath, "", time.Time{})
 	}
 
 	// We try to open the session cookie. If it does not exist (indicated by the error), we must authenticate the user.
 	cookie, err := s.CookieStore.Get(r, cookieAuthenticationName)
 	if err != nil {
 		//id.L.WithError(err).Debug("No OAuth2 authentication session was found, performing consent authentication flow")
-		return s.forwardAuthenticationRequest(w, r, ar, "", time.Time{})
+		return s.forwardAuthenticationRequest(w, r, ar, requestPath, "", time.Time{})
 	}
 
 	sessionID := mapx.GetStringDefault(cookie.Values, cookieAuthenticationSIDName, "")
 	if sessionID == "" {
-		return s.forwardAuthenticationRequest(w, r, ar, "", time.Time{})
+		return s.forwardAuthenticationRequest(w, r, ar, requestPath, "", time.Time{})
 	}
 
 	session, err := s.M.GetAuthenticationSession(sessionID)
-	if errors.Cause(err) == sqlcon.ErrNoRows {
-		return s.forwardAuthenticationRequest(w, r, ar, "", time.Time{})
+	if errors.Cause(err) == sqlcon.ErrNoRows || errors.Cause(err) == pkg.ErrNotFound {
+		return s.forwardAuthenticationRequest(w, r, ar, requestPath, "", time.Time{})
 	} else if err != nil {
 		return err
 	}
@@ -133,12 +147,12 @@ func (s *DefaultStrategy) requestAuthentication(w http.ResponseWriter, r *http.R
 		if stringslice.Has(prompt, "none") {
 			return errors.WithStack(fosite.ErrLoginRequired.WithDebug("Request failed because prompt is set to \"none\" and authentication time reached max_age"))
 		}
-		return s.forwardAuthenticationRequest(w, r, ar, "", time.Time{})
+		return s.forwardAuthenticationRequest(w, r, ar, requestPath, "", time.Time{})
 	}
 
 	idTokenHint := ar.GetRequestForm().Get("id_token_hint")
 	if idTokenHint == "" {
-		return s.forwardAuthenticationRequest(w, r, ar, session.Subject, session.AuthenticatedAt)
+		return s.forwardAuthenticationRequest(w, r, ar, requestPath, session.Subject, session.AuthenticatedAt)
 	}
 
 	token, err := s.JWTStrategy.Decode(idTokenHint)
@@ -146,18 +160,31 @@ func (s *DefaultStrategy) requestAuthentication(w http.ResponseWriter, r *http.R
 		return err
 	}
 
+	// The id_token_hint contains the subject identifier the client has seen, which might be a pairwise identifier.
+	obfuscatedSubject, err := s.obfuscateSubjectIdentifier(ar.GetClient(), session.Subject)
+	if err != nil {
+		return err
+	}
+
 	if hintClaims, ok := token.Claims.(jwtgo.MapClaims); !ok {
 		return errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Failed to validate OpenID Connect request as decoding id token from id_token_hint to *jwt.StandardClaims failed"))
 	} else if hintSub, _ := hintClaims["sub"].(string); hintSub == "" {
 		return errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Failed to validate OpenID Connect request because provided id token from id_token_hint does not have a subject"))
-	} else if hintSub != session.Subject {
+	} else if hintSub != obfuscatedSubject {
 		return errors.WithStack(fosite.ErrLoginRequired.WithDebug("Request failed because subject claim from id_token_hint does not match subject from authentication session"))
 	} else {
-		return s.forwardAuthenticationRequest(w, r, ar, session.Subject, session.AuthenticatedAt)
+		return s.forwardAuthenticationRequest(w, r, ar, requestPath, session.Subject, session.AuthenticatedAt)
 	}
 }
 
-func (s *DefaultStrategy) forwardAuthenticationRequest(w http.ResponseWriter, r *http.Request, ar fosite.AuthorizeRequester, subject string, authenticatedAt time.Time) error {
+func (s *DefaultStrategy) obfuscateSubjectIdentifier(cl fosite.Client, subject string) (string, error) {
+	if c, ok := cl.(*client.Client); ok {
+		return ObfuscateSubjectIdentifier(s.SubjectIdentifierAlgorithm, c, subject)
+	}
+	return subject, nil
+}
+
+func (s *DefaultStrategy) forwardAuthenticationRequest(w http.ResponseWriter, r *http.Request, ar fosite.AuthorizeRequester, requestPath string, subject string, authenticatedAt time.Time) error {
 	if (subject != "" && authenticatedAt.IsZero()) || (subject == "" && !authenticatedAt.IsZero()) {
 		return errors.WithStack(fosite.ErrServerError.WithDebug("Consent strategy returned a non-empty subject with an empty auth date, or an empty subject with a non-empty auth date"))
 	}
@@ -183,7 +210,7 @@ func (s *DefaultStrategy) forwardAuthenticationRequest(w http.ResponseWriter, r
 	if err != nil {
 		return errors.WithStack(err)
 	}
-	iu = urlx.AppendPaths(iu, s.OAuth2AuthURL)
+	iu = urlx.AppendPaths(iu, requestPath)
 	iu.RawQuery = r.URL.RawQuery
 
 	var idTokenHintClaims jwtgo.MapClaims
@@ -254,9 +281,78 @@ func (s *DefaultStrategy) revokeAuthenticationSession(w http.ResponseWriter, r *
 		return nil
 	}
 
+	if err := s.notifyBackChannelLogout(r, sid); err != nil {
+		return err
+	}
+
 	return s.M.DeleteAuthenticationSession(sid)
 }
 
+// notifyBackChannelLogout sends logout tokens to all clients which received tokens during the authentication session.
+func (s *DefaultStrategy) notifyBackChannelLogout(r *http.Request, sid string) error {
+	if s.BackChannelLogout == nil {
+		return nil
+	}
+
+	session, err := s.M.GetAuthenticationSession(sid)
+	if errors.Cause(err) == pkg.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	clients, err := s.M.GetAuthenticationSessionClients(sid)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range clients {
+		c, err := s.Clients.GetClient(r.Context(), id)
+		if errors.Cause(err) == pkg.ErrNotFound || errors.Cause(err) == fosite.ErrNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		cl, ok := c.(*client.Client)
+		if !ok {
+			continue
+		}
+
+		// The logout token carries the subject identifier the client has seen, which might be a pairwise identifier.
+		subject, err := s.obfuscateSubjectIdentifier(cl, session.Subject)
+		if err != nil {
+			return err
+		}
+
+		s.BackChannelLogout.Notify(cl, subject, sid)
+	}
+
+	return nil
+}
+
+// authenticationSessionID returns the ID of the authentication session of the user agent, or an empty string if
+// there is none.
+func (s *DefaultStrategy) authenticationSessionID(r *http.Request) (string, error) {
+	cookie, err := s.CookieStore.Get(r, cookieAuthenticationName)
+	if err != nil {
+		return "", nil
+	}
+
+	sid := mapx.GetStringDefault(cookie.Values, cookieAuthenticationSIDName, "")
+	if sid == "" {
+		return "", nil
+	}
+
+	if _, err := s.M.GetAuthenticationSession(sid); errors.Cause(err) == pkg.ErrNotFound {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return sid, nil
+}
+
 func (s *DefaultStrategy) verifyAuthentication(w http.ResponseWriter, r *http.Request, req fosite.AuthorizeRequester, verifier string) (*HandledAuthenticationRequest, error) {
 	session, err := s.M.VerifyAndInvalidateAuthenticationRequest(verifier)
 	if errors.Cause(err) == pkg.ErrNotFound {
@@ -290,6 +386,11 @@ func (s *DefaultStrategy) verifyAuthentication(w http.ResponseWriter, r *http.Re
 		return nil, errors.WithStack(fosite.ErrServerError.WithDebug("The login request is marked as remember, but the subject from the login confirmation does not match the original subject from the cookie."))
 	}
 
+	obfuscatedSubject, err := s.obfuscateSubjectIdentifier(req.GetClient(), session.Subject)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := s.OpenIDConnectRequestValidator.ValidatePrompt(&fosite.AuthorizeRequest{
 		ResponseTypes: req.GetResponseTypes(),
 		RedirectURI:   req.GetRedirectURI(),
@@ -304,7 +405,7 @@ func (s *DefaultStrategy) verifyAuthentication(w http.ResponseWriter, r *http.Re
 			Form:          req.GetRequestForm(),
 			Session: &openid.DefaultSession{
 				Claims: &jwt.IDTokenClaims{
-					Subject:     session.Subject,
+					Subject:     obfuscatedSubject,
 					IssuedAt:    time.Now().UTC(),                // doesn't matter
 					ExpiresAt:   time.Now().Add(time.Hour).UTC(), // doesn't matter
 					AuthTime:    session.AuthenticatedAt,
@@ -390,7 +491,9 @@ func (s *DefaultStrategy) requestConsent(w http.ResponseWriter, r *http.Request,
 		//
 		// This is tracked as issue: https://github.com/ory/hydra/issues/866
 		// This is also tracked as upstream issue: https://github.com/openid-certification/oidctest/issues/97
-		if ar.GetRedirectURI().Scheme != "https" {
+		//
+		// Requests without a redirect URI, such as device authorization requests, can not prove the client's identity.
+		if ar.GetRedirectURI() == nil || ar.GetRedirectURI().Scheme != "https" {
 			return s.forwardConsentRequest(w, r, ar, authenticationSession, nil)
 		}
 	}
@@ -497,11 +600,22 @@ func (s *DefaultStrategy) verifyConsent(w http.ResponseWriter, r *http.Request,
 }
 
 func (s *DefaultStrategy) HandleOAuth2AuthorizationRequest(w http.ResponseWriter, r *http.Request, req fosite.AuthorizeRequester) (*HandledConsentRequest, error) {
+	return s.handleAuthorizationRequest(w, r, req, s.OAuth2AuthURL)
+}
+
+// HandleOAuth2DeviceVerificationRequest performs the login and consent flow for a device authorization request which
+// the user agent has been sent to verify. Once the flow has been completed, the user agent returns to the path of the
+// verification request instead of the OAuth 2.0 authorization endpoint.
+func (s *DefaultStrategy) HandleOAuth2DeviceVerificationRequest(w http.ResponseWriter, r *http.Request, req fosite.AuthorizeRequester) (*HandledConsentRequest, error) {
+	return s.handleAuthorizationRequest(w, r, req, r.URL.Path)
+}
+
+func (s *DefaultStrategy) handleAuthorizationRequest(w http.ResponseWriter, r *http.Request, req fosite.AuthorizeRequester, requestPath string) (*HandledConsentRequest, error) {
 	authenticationVerifier := strings.TrimSpace(req.GetRequestForm().Get("login_verifier"))
 	consentVerifier := strings.TrimSpace(req.GetRequestForm().Get("consent_verifier"))
 	if authenticationVerifier == "" && consentVerifier == "" {
 		// ok, we need to process this request and redirect to auth endpoint
-		return nil, s.requestAuthentication(w, r, req)
+		return nil, s.requestAuthentication(w, r, req, requestPath)
 	} else if authenticationVerifier != "" {
 		authSession, err := s.verifyAuthentication(w, r, req, authenticationVerifier)
 		if err != nil {
@@ -517,5 +631,221 @@ func (s *DefaultStrategy) HandleOAuth2AuthorizationRequest(w http.ResponseWriter
 		return nil, err
 	}
 
+	sid, err := s.authenticationSessionID(r)
+	if err != nil {
+		return nil, err
+	} else if sid != "" {
+		if err := s.M.AddAuthenticationSessionClient(sid, req.GetClient().GetID()); err != nil {
+			return nil, err
+		}
+		consentSession.AuthenticationSessionID = sid
+	}
+
 	return consentSession, nil
 }
+
+// HandleOpenIDConnectLogout ends the authentication session of the user agent as defined by OpenID Connect
+// RP-Initiated Logout. It returns the post_logout_redirect_uri, including the state, and the front-channel logout URIs
+// of all clients which participated in the session.
+func (s *DefaultStrategy) HandleOpenIDConnectLogout(w http.ResponseWriter, r *http.Request) (*LogoutResult, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug(err.Error()))
+	}
+
+	idTokenHint := r.Form.Get("id_token_hint")
+	redirectURI := r.Form.Get("post_logout_redirect_uri")
+	state := r.Form.Get("state")
+
+	if idTokenHint == "" {
+		if redirectURI != "" {
+			return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Parameter post_logout_redirect_uri can only be used together with id_token_hint"))
+		}
+
+		// Without an id_token_hint, any site could log the user out by embedding this endpoint, so the user has to
+		// confirm the logout first.
+		if r.Method != http.MethodPost {
+			return s.requestLogoutConfirmation(w, r)
+		}
+
+		csrf := r.PostForm.Get("csrf")
+		if csrf == "" {
+			return nil, errors.WithStack(fosite.ErrRequestForbidden.WithDebug("The logout confirmation does not contain a CSRF value"))
+		} else if err := validateCsrfSession(r, s.CookieStore, cookieLogoutCSRFName, csrf); err != nil {
+			return nil, err
+		}
+		return s.endAuthenticationSession(w, r, "")
+	}
+
+	hintClaims, err := s.decodeIDTokenHint(idTokenHint)
+	if err != nil {
+		return nil, err
+	}
+
+	var audience []string
+	switch aud := hintClaims["aud"].(type) {
+	case string:
+		audience = []string{aud}
+	case []interface{}:
+		for _, a := range aud {
+			if as, ok := a.(string); ok {
+				audience = append(audience, as)
+			}
+		}
+	}
+
+	var logoutClient fosite.Client
+	for _, id := range audience {
+		c, err := s.Clients.GetClient(r.Context(), id)
+		if errors.Cause(err) == pkg.ErrNotFound || errors.Cause(err) == fosite.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if cl, ok := c.(*client.Client); redirectURI == "" || ok && stringslice.Has(cl.PostLogoutRedirectURIs, redirectURI) {
+			logoutClient = c
+			break
+		}
+	}
+
+	if logoutClient == nil {
+		if redirectURI != "" {
+			return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Parameter post_logout_redirect_uri is not whitelisted for the client the id_token_hint was issued to"))
+		}
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug("The id_token_hint was not issued to a known client"))
+	}
+
+	if cookie, err := s.CookieStore.Get(r, cookieAuthenticationName); err == nil {
+		if sid := mapx.GetStringDefault(cookie.Values, cookieAuthenticationSIDName, ""); sid != "" {
+			session, err := s.M.GetAuthenticationSession(sid)
+			if err != nil && errors.Cause(err) != pkg.ErrNotFound {
+				return nil, err
+			} else if err == nil {
+				// The id_token_hint contains the subject identifier the client has seen, which might be a pairwise identifier.
+				obfuscatedSubject, err := s.obfuscateSubjectIdentifier(logoutClient, session.Subject)
+				if err != nil {
+					return nil, err
+				}
+
+				if hintSub, _ := hintClaims["sub"].(string); hintSub != obfuscatedSubject {
+					return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Subject claim from id_token_hint does not match subject from authentication session"))
+				}
+			}
+		}
+	}
+
+	if redirectURI == "" {
+		return s.endAuthenticationSession(w, r, "")
+	}
+
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug(err.Error()))
+	}
+
+	if state != "" {
+		query := u.Query()
+		query.Set("state", state)
+		u.RawQuery = query.Encode()
+	}
+
+	return s.endAuthenticationSession(w, r, u.String())
+}
+
+// decodeIDTokenHint verifies the signature of the id_token_hint of a logout request and returns its claims. ID Tokens
+// which expired less than IDTokenHintMaxAge ago are allowed as values of id_token_hint, but jwt.JWTStrategy
+// implementations do not return the token if its claims are invalid.
+func (s *DefaultStrategy) decodeIDTokenHint(idTokenHint string) (jwtgo.MapClaims, error) {
+	token, err := s.JWTStrategy.Decode(idTokenHint)
+	if ve, ok := errors.Cause(err).(*jwtgo.ValidationError); ok && ve.Errors == jwtgo.ValidationErrorExpired {
+		// jwt-go verifies the signature even if the claims are invalid and reports a mismatch by setting
+		// ValidationErrorSignatureInvalid, so the token is only expired and its claims can be parsed without
+		// validating them again.
+		token, _, err = new(jwtgo.Parser).ParseUnverified(idTokenHint, jwtgo.MapClaims{})
+		if err == nil && !token.Claims.(jwtgo.MapClaims).VerifyExpiresAt(time.Now().Add(-s.IDTokenHintMaxAge).Unix(), true) {
+			err = errors.New("Token expired more than the accepted maximum age ago")
+		}
+	}
+	if err != nil {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Unable to decode id token from id_token_hint: " + err.Error()))
+	}
+
+	claims, ok := token.Claims.(jwtgo.MapClaims)
+	if !ok {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Failed to decode claims of id token from id_token_hint"))
+	}
+	return claims, nil
+}
+
+// requestLogoutConfirmation asks the user to confirm a logout request without id_token_hint. There is nothing to
+// confirm if the user agent has no authentication session.
+func (s *DefaultStrategy) requestLogoutConfirmation(w http.ResponseWriter, r *http.Request) (*LogoutResult, error) {
+	if sid, err := s.authenticationSessionID(r); err != nil {
+		return nil, err
+	} else if sid == "" {
+		return s.endAuthenticationSession(w, r, "")
+	}
+
+	csrf := strings.Replace(uuid.New(), "-", "", -1)
+	if err := createCsrfSession(w, r, s.CookieStore, cookieLogoutCSRFName, csrf, s.RunsHTTPS); err != nil {
+		return nil, err
+	}
+
+	return &LogoutResult{ConfirmationCSRF: csrf}, nil
+}
+
+// endAuthenticationSession revokes the authentication session of the user agent after collecting the front-channel
+// logout URIs of all clients which participated in it.
+func (s *DefaultStrategy) endAuthenticationSession(w http.ResponseWriter, r *http.Request, redirectTo string) (*LogoutResult, error) {
+	result := &LogoutResult{RedirectTo: redirectTo}
+
+	sid, err := s.authenticationSessionID(r)
+	if err != nil {
+		return nil, err
+	} else if sid != "" {
+		if result.FrontChannelLogoutURLs, err = s.frontChannelLogoutURLs(r, sid); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.revokeAuthenticationSession(w, r); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s *DefaultStrategy) frontChannelLogoutURLs(r *http.Request, sid string) ([]string, error) {
+	clients, err := s.M.GetAuthenticationSessionClients(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	for _, id := range clients {
+		c, err := s.Clients.GetClient(r.Context(), id)
+		if errors.Cause(err) == pkg.ErrNotFound || errors.Cause(err) == fosite.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		cl, ok := c.(*client.Client)
+		if !ok || cl.FrontChannelLogoutURI == "" {
+			continue
+		}
+
+		u, err := url.Parse(cl.FrontChannelLogoutURI)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		query := u.Query()
+		query.Set("iss", strings.TrimRight(s.IssuerURL, "/")+"/")
+		query.Set("sid", sid)
+		u.RawQuery = query.Encode()
+		urls = append(urls, u.String())
+	}
+
+	return urls, nil
+}
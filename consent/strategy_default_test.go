@@ -29,9 +29,11 @@ import (
 	"net/http/cookiejar"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
+	jwtgo "github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/sessions"
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory/fosite"
@@ -112,6 +114,9 @@ func TestStrategy(t *testing.T) {
 		time.Hour,
 		jwts,
 		openid.NewOpenIDConnectRequestValidator(nil, jwts),
+		map[string]SubjectIdentifierAlgorithm{SubjectTypePublic: NewSubjectIdentifierAlgorithmPublic()},
+		client.NewMemoryManager(nil),
+		nil,
 	)
 	apiClient := swagger.NewOAuth2ApiWithBasePath(api.URL)
 
@@ -1052,3 +1057,193 @@ func TestStrategy(t *testing.T) {
 		})
 	}
 }
+
+func TestStrategyLogout(t *testing.T) {
+	jwts := &jwt.RS256JWTStrategy{
+		PrivateKey: pkg.MustINSECURELOWENTROPYRSAKEYFORTEST(),
+	}
+
+	idToken := func(subject string, expiresAt time.Time) string {
+		token, _, err := jwts.Generate(jwtgo.MapClaims{
+			"sub": subject,
+			"aud": "client-id",
+			"exp": expiresAt.Unix(),
+			"iat": time.Now().Unix(),
+		}, jwt.NewHeaders())
+		require.NoError(t, err)
+		return token
+	}
+
+	clients := client.NewMemoryManager(&fosite.BCrypt{WorkFactor: 4})
+	require.NoError(t, clients.CreateClient(&client.Client{
+		ID:                     "client-id",
+		PostLogoutRedirectURIs: []string{"https://client/logout"},
+	}))
+	require.NoError(t, clients.CreateClient(&client.Client{
+		ID:                    "frontchannel-client",
+		FrontChannelLogoutURI: "https://frontchannel-client/logout?foo=bar",
+	}))
+
+	manager := NewMemoryManager()
+	cookieStore := sessions.NewCookieStore([]byte("dummy-secret-yay"))
+	strategy := NewStrategy(
+		"", "", "https://hydra.localhost", "/oauth2/auth",
+		manager,
+		cookieStore,
+		fosite.ExactScopeStrategy,
+		false,
+		time.Hour,
+		jwts,
+		openid.NewOpenIDConnectRequestValidator(nil, jwts),
+		map[string]SubjectIdentifierAlgorithm{SubjectTypePublic: NewSubjectIdentifierAlgorithmPublic()},
+		clients,
+		nil,
+	)
+	strategy.IDTokenHintMaxAge = time.Hour * 24
+
+	newRequest := func(t *testing.T, query url.Values, sid string) *http.Request {
+		r := httptest.NewRequest("GET", "/oauth2/sessions/logout?"+query.Encode(), nil)
+		if sid == "" {
+			return r
+		}
+
+		require.NoError(t, manager.CreateAuthenticationSession(&AuthenticationSession{ID: sid, Subject: "foouser", AuthenticatedAt: time.Now()}))
+
+		w := httptest.NewRecorder()
+		cookie, _ := cookieStore.Get(httptest.NewRequest("GET", "/", nil), cookieAuthenticationName)
+		cookie.Values[cookieAuthenticationSIDName] = sid
+		require.NoError(t, cookie.Save(r, w))
+		for _, c := range w.Result().Cookies() {
+			r.AddCookie(c)
+		}
+		return r
+	}
+
+	// confirm returns the request confirming the logout request r, which the strategy responded to with w.
+	confirm := func(r *http.Request, w *httptest.ResponseRecorder, csrf string) *http.Request {
+		c := httptest.NewRequest("POST", "/oauth2/sessions/logout", strings.NewReader(url.Values{"csrf": {csrf}}.Encode()))
+		c.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for _, cookie := range append(r.Cookies(), w.Result().Cookies()...) {
+			c.AddCookie(cookie)
+		}
+		return c
+	}
+
+	for k, tc := range []struct {
+		d                  string
+		query              url.Values
+		sid                string
+		participants       []string
+		confirm            bool
+		csrf               string
+		expectErr          bool
+		expectConfirmation bool
+		expectURL          string
+		expectFrontChannel []string
+		expectAlive        bool
+	}{
+		{
+			d:                  "should ask for a confirmation without an id_token_hint",
+			sid:                "logout-1",
+			expectConfirmation: true,
+			expectAlive:        true,
+		},
+		{
+			d:       "should revoke the session without a redirect once confirmed",
+			sid:     "logout-8",
+			confirm: true,
+		},
+		{
+			d:           "should fail because the confirmation does not carry the CSRF value",
+			sid:         "logout-9",
+			confirm:     true,
+			csrf:        "forged-csrf",
+			expectErr:   true,
+			expectAlive: true,
+		},
+		{
+			d:           "should fail because post_logout_redirect_uri requires an id_token_hint",
+			sid:         "logout-2",
+			query:       url.Values{"post_logout_redirect_uri": {"https://client/logout"}},
+			expectErr:   true,
+			expectAlive: true,
+		},
+		{
+			d:           "should fail because post_logout_redirect_uri is not whitelisted",
+			sid:         "logout-3",
+			query:       url.Values{"id_token_hint": {idToken("foouser", time.Now().Add(time.Hour))}, "post_logout_redirect_uri": {"https://evil/logout"}},
+			expectErr:   true,
+			expectAlive: true,
+		},
+		{
+			d:           "should fail because the subject of the id_token_hint does not match the session",
+			sid:         "logout-4",
+			query:       url.Values{"id_token_hint": {idToken("baruser", time.Now().Add(time.Hour))}},
+			expectErr:   true,
+			expectAlive: true,
+		},
+		{
+			d:         "should redirect to the post_logout_redirect_uri including the state",
+			sid:       "logout-5",
+			query:     url.Values{"id_token_hint": {idToken("foouser", time.Now().Add(time.Hour))}, "post_logout_redirect_uri": {"https://client/logout"}, "state": {"some-state"}},
+			expectURL: "https://client/logout?state=some-state",
+		},
+		{
+			d:         "should accept an expired id_token_hint",
+			sid:       "logout-6",
+			query:     url.Values{"id_token_hint": {idToken("foouser", time.Now().Add(-time.Hour))}, "post_logout_redirect_uri": {"https://client/logout"}},
+			expectURL: "https://client/logout",
+		},
+		{
+			d:           "should fail because the id_token_hint expired longer ago than accepted",
+			sid:         "logout-10",
+			query:       url.Values{"id_token_hint": {idToken("foouser", time.Now().Add(-time.Hour*48))}, "post_logout_redirect_uri": {"https://client/logout"}},
+			expectErr:   true,
+			expectAlive: true,
+		},
+		{
+			d:                  "should return the front-channel logout URIs of participating clients",
+			sid:                "logout-7",
+			participants:       []string{"client-id", "frontchannel-client"},
+			confirm:            true,
+			expectFrontChannel: []string{"https://frontchannel-client/logout?foo=bar&iss=https%3A%2F%2Fhydra.localhost%2F&sid=logout-7"},
+		},
+	} {
+		t.Run(fmt.Sprintf("case=%d/description=%s", k, tc.d), func(t *testing.T) {
+			r := newRequest(t, tc.query, tc.sid)
+			for _, participant := range tc.participants {
+				require.NoError(t, manager.AddAuthenticationSessionClient(tc.sid, participant))
+			}
+
+			if tc.confirm {
+				w := httptest.NewRecorder()
+				result, err := strategy.HandleOpenIDConnectLogout(w, r)
+				require.NoError(t, err)
+				require.NotEmpty(t, result.ConfirmationCSRF)
+
+				csrf := result.ConfirmationCSRF
+				if tc.csrf != "" {
+					csrf = tc.csrf
+				}
+				r = confirm(r, w, csrf)
+			}
+
+			result, err := strategy.HandleOpenIDConnectLogout(httptest.NewRecorder(), r)
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectConfirmation, result.ConfirmationCSRF != "")
+				assert.Equal(t, tc.expectURL, result.RedirectTo)
+				assert.EqualValues(t, tc.expectFrontChannel, result.FrontChannelLogoutURLs)
+			}
+
+			_, err = manager.GetAuthenticationSession(tc.sid)
+			if tc.expectAlive {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, errors.Cause(err), pkg.ErrNotFound.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @Copyright 	2017-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package consent
+
+import (
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/client"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SubjectTypePublic is the subject type which exposes the same subject identifier to all clients.
+	SubjectTypePublic = "public"
+
+	// SubjectTypePairwise is the subject type which exposes a different subject identifier to each sector.
+	SubjectTypePairwise = "pairwise"
+)
+
+// SubjectIdentifierAlgorithm computes the subject identifier which is exposed to a client.
+type SubjectIdentifierAlgorithm interface {
+	// Obfuscate derives a subject identifier for the given client from the original subject.
+	Obfuscate(subject string, client *client.Client) (string, error)
+}
+
+// ObfuscateSubjectIdentifier returns the subject identifier for the subject type requested by the client. If the client
+// did not request a subject type, the public subject type is used.
+func ObfuscateSubjectIdentifier(algorithms map[string]SubjectIdentifierAlgorithm, c *client.Client, subject string) (string, error) {
+	subjectType := c.SubjectType
+	if subjectType == "" {
+		subjectType = SubjectTypePublic
+	}
+
+	algorithm, ok := algorithms[subjectType]
+	if !ok {
+		if subjectType == SubjectTypePublic {
+			return subject, nil
+		}
+		return "", errors.WithStack(fosite.ErrServerError.WithDebug("The subject type \"" + subjectType + "\" requested by the client is not supported by this server"))
+	}
+
+	return algorithm.Obfuscate(subject, c)
+}
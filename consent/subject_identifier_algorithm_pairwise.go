@@ -0,0 +1,81 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @Copyright 	2017-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package consent
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/client"
+	"github.com/pkg/errors"
+)
+
+// SubjectIdentifierAlgorithmPairwise computes pairwise pseudonymous subject identifiers as described in
+// OpenID Connect Core 1.0, section 8.1: sub = SHA-256 ( sector_identifier || local_account_id || salt ).
+type SubjectIdentifierAlgorithmPairwise struct {
+	Salt []byte
+}
+
+func NewSubjectIdentifierAlgorithmPairwise(salt []byte) *SubjectIdentifierAlgorithmPairwise {
+	return &SubjectIdentifierAlgorithmPairwise{Salt: salt}
+}
+
+func (g *SubjectIdentifierAlgorithmPairwise) Obfuscate(subject string, client *client.Client) (string, error) {
+	sector, err := SectorIdentifier(client)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(sector+subject+string(g.Salt)))), nil
+}
+
+// SectorIdentifier returns the host component of the client's sector_identifier_uri or, if it is not set, the host
+// component shared by all of the client's redirect URIs.
+func SectorIdentifier(c *client.Client) (string, error) {
+	if c.SectorIdentifierURI != "" {
+		u, err := url.Parse(c.SectorIdentifierURI)
+		if err != nil {
+			return "", errors.WithStack(fosite.ErrServerError.WithDebug("Unable to parse sector_identifier_uri: " + err.Error()))
+		}
+		return u.Host, nil
+	}
+
+	var host string
+	for _, r := range c.RedirectURIs {
+		u, err := url.Parse(r)
+		if err != nil {
+			return "", errors.WithStack(fosite.ErrServerError.WithDebug("Unable to parse redirect_uri: " + err.Error()))
+		}
+
+		if host != "" && host != u.Host {
+			return "", errors.WithStack(fosite.ErrServerError.WithDebug("The redirect URIs of a client with subject type pairwise must share the same host, or sector_identifier_uri must be set"))
+		}
+		host = u.Host
+	}
+
+	if host == "" {
+		return "", errors.WithStack(fosite.ErrServerError.WithDebug("Unable to determine the sector identifier because the client has neither a sector_identifier_uri nor redirect URIs"))
+	}
+
+	return host, nil
+}
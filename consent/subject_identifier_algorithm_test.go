@@ -0,0 +1,88 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @Copyright 	2017-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package consent
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ory/hydra/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubjectIdentifierAlgorithmPairwise(t *testing.T) {
+	algorithm := NewSubjectIdentifierAlgorithmPairwise([]byte("some-salt-which-is-long-enough"))
+
+	a, err := algorithm.Obfuscate("peter", &client.Client{RedirectURIs: []string{"https://foo.com/cb", "https://foo.com/other-cb"}})
+	require.NoError(t, err)
+	assert.NotEqual(t, "peter", a)
+
+	b, err := algorithm.Obfuscate("peter", &client.Client{SectorIdentifierURI: "https://foo.com/sector.json", RedirectURIs: []string{"https://bar.com/cb"}})
+	require.NoError(t, err)
+	assert.Equal(t, a, b, "clients of the same sector must receive the same identifier")
+
+	c, err := algorithm.Obfuscate("peter", &client.Client{RedirectURIs: []string{"https://bar.com/cb"}})
+	require.NoError(t, err)
+	assert.NotEqual(t, a, c, "clients of different sectors must receive different identifiers")
+
+	d, err := algorithm.Obfuscate("alice", &client.Client{RedirectURIs: []string{"https://foo.com/cb"}})
+	require.NoError(t, err)
+	assert.NotEqual(t, a, d)
+
+	_, err = algorithm.Obfuscate("peter", &client.Client{RedirectURIs: []string{"https://foo.com/cb", "https://bar.com/cb"}})
+	assert.Error(t, err)
+
+	_, err = algorithm.Obfuscate("peter", &client.Client{})
+	assert.Error(t, err)
+}
+
+func TestObfuscateSubjectIdentifier(t *testing.T) {
+	algorithms := map[string]SubjectIdentifierAlgorithm{
+		SubjectTypePublic:   NewSubjectIdentifierAlgorithmPublic(),
+		SubjectTypePairwise: NewSubjectIdentifierAlgorithmPairwise([]byte("some-salt-which-is-long-enough")),
+	}
+
+	for k, tc := range []struct {
+		algorithms map[string]SubjectIdentifierAlgorithm
+		client     *client.Client
+		public     bool
+		expectErr  bool
+	}{
+		{algorithms: algorithms, client: &client.Client{}, public: true},
+		{algorithms: algorithms, client: &client.Client{SubjectType: "public"}, public: true},
+		{algorithms: algorithms, client: &client.Client{SubjectType: "pairwise", RedirectURIs: []string{"https://foo.com/cb"}}},
+		{algorithms: algorithms, client: &client.Client{SubjectType: "unknown"}, expectErr: true},
+		{algorithms: map[string]SubjectIdentifierAlgorithm{}, client: &client.Client{}, public: true},
+		{algorithms: map[string]SubjectIdentifierAlgorithm{}, client: &client.Client{SubjectType: "pairwise", RedirectURIs: []string{"https://foo.com/cb"}}, expectErr: true},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			sub, err := ObfuscateSubjectIdentifier(tc.algorithms, tc.client, "peter")
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.public, sub == "peter")
+		})
+	}
+}
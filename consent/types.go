@@ -102,6 +102,45 @@ type HandledConsentRequest struct {
 	RequestedAt     time.Time           `json:"-"`
 	AuthenticatedAt time.Time           `json:"-"`
 	WasUsed         bool                `json:"-"`
+
+	// HandledAt is the time the consent request was accepted or rejected at. RememberFor is measured from it.
+	HandledAt time.Time `json:"-"`
+
+	// AuthenticationSessionID is the ID of the authentication session the consent was given in, if any. It is
+	// included as the sid claim in ID Tokens.
+	AuthenticationSessionID string `json:"-"`
+}
+
+// rememberedUntil returns the time the consent is remembered until, or the zero time if it is remembered
+// indefinitely.
+func (h *HandledConsentRequest) rememberedUntil() time.Time {
+	if h.RememberFor <= 0 {
+		return time.Time{}
+	}
+	return h.HandledAt.Add(time.Duration(h.RememberFor) * time.Second)
+}
+
+// hasExpired returns true if the consent is no longer remembered because RememberFor has passed.
+func (h *HandledConsentRequest) hasExpired() bool {
+	return h.RememberFor > 0 && h.rememberedUntil().Before(time.Now().UTC())
+}
+
+// Contains information on a consent the user has granted to an OAuth 2.0 Client and which is remembered.
+//
+// swagger:model previousConsentSession
+type PreviousConsentSession struct {
+	// Client is the OAuth 2.0 Client the consent was granted to.
+	Client *client.Client `json:"client"`
+
+	// GrantedScope contains the scopes the user granted to the client.
+	GrantedScope []string `json:"grant_scope"`
+
+	// GrantedAt is the time the consent was granted at.
+	GrantedAt time.Time `json:"granted_at"`
+
+	// ExpiresAt is the time the consent is remembered until. It is not set if the consent is remembered
+	// indefinitely.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 // The request payload used to accept a login request.
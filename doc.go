@@ -43,6 +43,10 @@
 //         tokenUrl: https://your-hydra-instance.com/oauth2/token
 //         flow: accessCode
 //         scopes:
+//           hydra.clients: "Manage OAuth 2.0 clients at the administrative APIs"
+//           hydra.consent: "Handle login and consent requests and manage login and consent sessions at the administrative APIs"
+//           hydra.flush: "Flush inactive access tokens at the administrative APIs"
+//           hydra.keys: "Manage JSON Web Keys at the administrative APIs"
 //           offline: "A scope required when requesting refresh tokens"
 //           openid: "Request an OpenID Connect ID Token"
 //     basic:
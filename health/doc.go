@@ -46,3 +46,15 @@ type swaggerVersion struct {
 //     Responses:
 //       200
 func prometheusDummy() {}
+
+// swagger:route GET /metrics metrics getMetrics
+//
+// Retrieve Prometheus metrics
+//
+// This endpoint returns metrics formatted for Prometheus. It includes HTTP request counters and latency histograms
+// labelled by route template and status code, as well as OAuth 2.0 specific metrics such as the number of issued
+// tokens per grant type, handled login and consent requests, and token introspection results.
+//
+//     Responses:
+//       200
+func metricsDummy() {}
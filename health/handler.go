@@ -35,6 +35,7 @@ const (
 	ReadyCheckPath        = "/health/ready"
 	VersionPath           = "/version"
 	MetricsPrometheusPath = "/metrics/prometheus"
+	MetricsPath           = "/metrics"
 )
 
 type ReadyChecker func() error
@@ -53,6 +54,7 @@ func (h *Handler) SetRoutes(r *httprouter.Router) {
 
 	// using r.Handler because promhttp.Handler() returns http.Handler
 	r.Handler("GET", MetricsPrometheusPath, promhttp.Handler())
+	r.Handler("GET", MetricsPath, promhttp.Handler())
 
 	// BC compatible health check
 	r.GET("/health/status", pkg.PermanentRedirect(AliveCheckPath))
@@ -26,12 +26,14 @@ import (
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/consent"
 	"github.com/ory/hydra/jwk"
 	"github.com/ory/hydra/oauth2"
+	"github.com/ory/hydra/pkg"
 	"github.com/ory/ladon"
 	lsql "github.com/ory/ladon/manager/sql"
 	"github.com/ory/sqlcon/dockertest"
@@ -40,47 +42,60 @@ import (
 )
 
 func TestSQLSchema(t *testing.T) {
-	if testing.Short() {
-		t.Skip("Skipping test in short mode.")
-		return
+	databases := map[string]*sqlx.DB{
+		"sqlite": pkg.SQLiteTestDatabase(),
 	}
 
-	var testGenerator = &jwk.RS256Generator{}
-	ks, _ := testGenerator.Generate("foo")
-	p1 := ks.Key("private:foo")
-	r := fosite.NewRequest()
-	r.ID = "foo"
+	if !testing.Short() {
+		db, err := dockertest.ConnectToTestPostgreSQL()
+		if err != nil {
+			log.Fatalf("Could not connect to database: %v", err)
+		}
+		databases["postgres"] = db
+	}
 
-	db, err := dockertest.ConnectToTestPostgreSQL()
-	if err != nil {
-		log.Fatalf("Could not connect to database: %v", err)
+	for name, db := range databases {
+		t.Run("database="+name, testSQLSchema(db))
 	}
+}
+
+func testSQLSchema(db *sqlx.DB) func(t *testing.T) {
+	return func(t *testing.T) {
+		var testGenerator = &jwk.RS256Generator{}
+		ks, _ := testGenerator.Generate("foo")
+		p1 := ks.Key("private:foo")
+		r := fosite.NewRequest()
+		r.ID = "foo"
 
-	cm := &client.SQLManager{DB: db, Hasher: &fosite.BCrypt{}}
-	jm := jwk.SQLManager{DB: db, Cipher: &jwk.AEAD{Key: []byte("11111111111111111111111111111111")}}
-	om := oauth2.FositeSQLStore{Manager: cm, DB: db, L: logrus.New()}
-	crm := consent.NewSQLManager(db, nil)
-	pm := lsql.NewSQLManager(db, nil)
+		cm := &client.SQLManager{DB: db, Hasher: &fosite.BCrypt{}}
+		jm := jwk.SQLManager{DB: db, Cipher: &jwk.AEAD{Key: []byte("11111111111111111111111111111111")}}
+		om := oauth2.FositeSQLStore{Manager: cm, DB: db, L: logrus.New()}
+		crm := consent.NewSQLManager(db, nil)
 
-	_, err = pm.CreateSchemas("", "hydra_policy_migration")
-	require.NoError(t, err)
-	_, err = cm.CreateSchemas()
-	require.NoError(t, err)
-	require.NoError(t, err)
-	_, err = jm.CreateSchemas()
-	require.NoError(t, err)
-	_, err = om.CreateSchemas()
-	require.NoError(t, err)
-	_, err = crm.CreateSchemas()
-	require.NoError(t, err)
+		// Ladon does not support SQLite.
+		if db.DriverName() != pkg.SQLiteDialect {
+			pm := lsql.NewSQLManager(db, nil)
+			_, err := pm.CreateSchemas("", "hydra_policy_migration")
+			require.NoError(t, err)
+			require.NoError(t, pm.Create(&ladon.DefaultPolicy{ID: "integration-test-foo", Resources: []string{"foo"}, Actions: []string{"bar"}, Subjects: []string{"baz"}, Effect: "allow"}))
+		}
 
-	require.NoError(t, jm.AddKey("integration-test-foo", jwk.First(p1)))
-	require.NoError(t, pm.Create(&ladon.DefaultPolicy{ID: "integration-test-foo", Resources: []string{"foo"}, Actions: []string{"bar"}, Subjects: []string{"baz"}, Effect: "allow"}))
-	require.NoError(t, cm.CreateClient(&client.Client{ID: "integration-test-foo"}))
-	require.NoError(t, crm.CreateAuthenticationSession(&consent.AuthenticationSession{
-		ID:              "foo",
-		AuthenticatedAt: time.Now(),
-		Subject:         "bar",
-	}))
-	require.NoError(t, om.CreateAccessTokenSession(nil, "asdfasdf", r))
+		_, err := cm.CreateSchemas()
+		require.NoError(t, err)
+		_, err = jm.CreateSchemas()
+		require.NoError(t, err)
+		_, err = om.CreateSchemas()
+		require.NoError(t, err)
+		_, err = crm.CreateSchemas()
+		require.NoError(t, err)
+
+		require.NoError(t, jm.AddKey("integration-test-foo", jwk.First(p1)))
+		require.NoError(t, cm.CreateClient(&client.Client{ID: "integration-test-foo"}))
+		require.NoError(t, crm.CreateAuthenticationSession(&consent.AuthenticationSession{
+			ID:              "foo",
+			AuthenticatedAt: time.Now(),
+			Subject:         "bar",
+		}))
+		require.NoError(t, om.CreateAccessTokenSession(nil, "asdfasdf", r))
+	}
 }
@@ -27,8 +27,11 @@ import (
 	"github.com/pkg/errors"
 )
 
+// AEAD encrypts data using AES-GCM. Data is always encrypted using Key, RotatedKeys are only used to decrypt data which
+// was encrypted before the key was rotated.
 type AEAD struct {
-	Key []byte
+	Key         []byte
+	RotatedKeys [][]byte
 }
 
 func (c *AEAD) Encrypt(plaintext []byte) (string, error) {
@@ -48,18 +51,36 @@ func (c *AEAD) Encrypt(plaintext []byte) (string, error) {
 }
 
 func (c *AEAD) Decrypt(ciphertext string) ([]byte, error) {
-	if len(c.Key) < 32 {
-		return []byte{}, errors.Errorf("Key must be longer 32 bytes, got %d bytes", len(c.Key))
+	raw, err := base64.URLEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return []byte{}, errors.WithStack(err)
+	}
+
+	plaintext, err := decrypt(c.Key, raw)
+	if err == nil {
+		return plaintext, nil
+	}
+
+	for _, rotated := range c.RotatedKeys {
+		if plaintext, rerr := decrypt(rotated, raw); rerr == nil {
+			return plaintext, nil
+		}
+	}
+
+	return []byte{}, err
+}
+
+func decrypt(k []byte, raw []byte) ([]byte, error) {
+	if len(k) < 32 {
+		return []byte{}, errors.Errorf("Key must be longer 32 bytes, got %d bytes", len(k))
 	}
 
 	var key [32]byte
-	copy(key[:], c.Key[:32])
+	copy(key[:], k[:32])
 
-	raw, err := base64.URLEncoding.DecodeString(ciphertext)
+	plaintext, err := cryptopasta.Decrypt(raw, &key)
 	if err != nil {
 		return []byte{}, errors.WithStack(err)
 	}
-
-	plaintext, err := cryptopasta.Decrypt(raw, &key)
 	return plaintext, nil
 }
@@ -58,3 +58,20 @@ func TestAEAD(t *testing.T) {
 		assert.Equal(t, plain, res)
 	}
 }
+
+func TestAEADWithRotatedKeys(t *testing.T) {
+	previous, err := randomBytes(32)
+	require.NoError(t, err)
+	current, err := randomBytes(32)
+	require.NoError(t, err)
+
+	ct, err := (&AEAD{Key: previous}).Encrypt([]byte("foo"))
+	require.NoError(t, err)
+
+	_, err = (&AEAD{Key: current}).Decrypt(ct)
+	require.Error(t, err)
+
+	res, err := (&AEAD{Key: current, RotatedKeys: [][]byte{previous}}).Decrypt(ct)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("foo"), res)
+}
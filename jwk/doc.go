@@ -78,7 +78,7 @@ type swaggerJwkCreateSet struct {
 	Body createRequest
 }
 
-// swagger:parameters getJsonWebKeySet deleteJsonWebKeySet
+// swagger:parameters getJsonWebKeySet deleteJsonWebKeySet rotateJsonWebKeySet
 type swaggerJwkSetQuery struct {
 	// The set
 	// in: path
@@ -86,6 +86,14 @@ type swaggerJwkSetQuery struct {
 	Set string `json:"set"`
 }
 
+// The rotation schedule of the keys of a JSON Web Key Set.
+// swagger:response jsonWebKeySetSchedule
+type swaggerJSONWebKeySetSchedule struct {
+	// in: body
+	// type: array
+	Body []KeyMetadata
+}
+
 // swagger:model jsonWebKeySet
 type swaggerJSONWebKeySet struct {
 	// The value of the "keys" parameter is an array of JWK values.  By
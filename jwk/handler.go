@@ -24,9 +24,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory/herodot"
+	"github.com/ory/hydra/pkg"
 	"github.com/pkg/errors"
 	"github.com/square/go-jose"
 )
@@ -41,13 +44,23 @@ type Handler struct {
 	Manager    Manager
 	Generators map[string]KeyGenerator
 	H          herodot.Writer
+
+	// WellKnownKeys are the names of additional JSON Web Key Sets whose public keys are published at
+	// /.well-known/jwks.json alongside the OpenID Connect ID Token key.
+	WellKnownKeys []string
+
+	// Rotator rotates key sets on request, key rotation is disabled if it is nil.
+	Rotator *Rotator
 }
 
 func (h *Handler) GetGenerators() map[string]KeyGenerator {
 	if h.Generators == nil || len(h.Generators) == 0 {
 		h.Generators = map[string]KeyGenerator{
 			"RS256": &RS256Generator{},
+			"PS256": &PS256Generator{},
+			"ES256": &ECDSA256Generator{},
 			"ES512": &ECDSA512Generator{},
+			"EdDSA": &EdDSAGenerator{},
 			"HS256": &HS256Generator{},
 			"HS512": &HS512Generator{},
 		}
@@ -55,12 +68,16 @@ func (h *Handler) GetGenerators() map[string]KeyGenerator {
 	return h.Generators
 }
 
-func (h *Handler) SetRoutes(r *httprouter.Router) {
-	r.GET(WellKnownKeysPath, h.WellKnown)
+// SetRoutes registers the public JSON Web Key Set on frontend and the key management endpoints on backend.
+func (h *Handler) SetRoutes(frontend, backend *httprouter.Router) {
+	frontend.GET(WellKnownKeysPath, h.WellKnown)
+
+	r := backend
 	r.GET(KeyHandlerPath+"/:set/:key", h.GetKey)
 	r.GET(KeyHandlerPath+"/:set", h.GetKeySet)
 
 	r.POST(KeyHandlerPath+"/:set", h.Create)
+	r.POST(KeyHandlerPath+"/:set/rotate", h.Rotate)
 
 	r.PUT(KeyHandlerPath+"/:set/:key", h.UpdateKey)
 	r.PUT(KeyHandlerPath+"/:set", h.UpdateKeySet)
@@ -71,7 +88,7 @@ func (h *Handler) SetRoutes(r *httprouter.Router) {
 
 // swagger:model jsonWebKeySetGeneratorRequest
 type createRequest struct {
-	// The algorithm to be used for creating the key. Supports "RS256", "ES512", "HS512", and "HS256"
+	// The algorithm to be used for creating the key. Supports "RS256", "PS256", "ES256", "ES512", "EdDSA", "HS512", and "HS256"
 	// required: true
 	// in: body
 	Algorithm string `json:"alg"`
@@ -90,7 +107,7 @@ type joseWebKeySetRequest struct {
 //
 // Get Well-Known JSON Web Keys
 //
-// Returns metadata for discovering important JSON Web Keys. Currently, this endpoint returns the public key for verifying OpenID Connect ID Tokens.
+// Returns metadata for discovering important JSON Web Keys. Currently, this endpoint returns the public keys for verifying OpenID Connect ID Tokens, one for each signing algorithm, and, if enabled, JWT access tokens.
 //
 // A JSON Web Key (JWK) is a JavaScript Object Notation (JSON) data structure that represents a cryptographic key. A JWK Set is a JSON data structure that represents a set of JWKs. A JSON Web Key is identified by its set and key id. ORY Hydra uses this functionality to store cryptographic keys used for TLS and JSON Web Tokens (such as OpenID Connect ID tokens), and allows storing user-defined keys as well.
 //
@@ -109,26 +126,73 @@ type joseWebKeySetRequest struct {
 //       403: genericError
 //       500: genericError
 func (h *Handler) WellKnown(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	keys, err := h.Manager.GetKeySet(IDTokenKeyName)
+	keys, err := h.publishedKeys(IDTokenKeyName)
 	if err != nil {
 		h.H.WriteError(w, r, err)
 		return
 	}
 
+	for _, set := range h.WellKnownKeys {
+		additional, err := h.publishedKeys(set)
+		if errors.Cause(err) == pkg.ErrNotFound {
+			continue
+		} else if err != nil {
+			h.H.WriteError(w, r, err)
+			return
+		}
+
+		keys.Keys = append(keys.Keys, additional.Keys...)
+	}
+
+	h.H.Write(w, r, keys)
+}
+
+// publishedKeys returns the public keys of the set which have not retired yet. Keys which are not active yet are
+// published so that relying parties know them before they are used.
+func (h *Handler) publishedKeys(set string) (*jose.JSONWebKeySet, error) {
+	keys, err := h.Manager.GetKeySet(set)
+	if err != nil {
+		return nil, err
+	}
+
 	keys, err = FindKeysByPrefix(keys, "public")
 	if err != nil {
-		h.H.WriteError(w, r, err)
-		return
+		return nil, err
 	}
 
-	h.H.Write(w, r, keys)
+	m, ok := h.Manager.(MetadataManager)
+	if !ok {
+		return keys, nil
+	}
+
+	metadata, err := m.GetKeySetMetadata(set)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	published := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	for _, key := range keys.Keys {
+		if md, ok := metadata[key.KeyID]; ok && md.IsRetired(now) {
+			continue
+		}
+		published.Keys = append(published.Keys, key)
+	}
+
+	return published, nil
+}
+
+// isExternalKeySet returns true if the private keys of the set are kept in an external key service.
+func (h *Handler) isExternalKeySet(set string) bool {
+	m, ok := h.Manager.(ExternalManager)
+	return ok && m.IsExternalKeySet(set)
 }
 
 // swagger:route GET /keys/{set}/{kid} jsonWebKey getJsonWebKey
 //
 // Retrieve a JSON Web Key
 //
-// This endpoint can be used to retrieve JWKs stored in ORY Hydra.
+// This endpoint can be used to retrieve JWKs stored in ORY Hydra. Private keys kept in a hardware security module can not be retrieved.
 //
 // A JSON Web Key (JWK) is a JavaScript Object Notation (JSON) data structure that represents a cryptographic key. A JWK Set is a JSON data structure that represents a set of JWKs. A JSON Web Key is identified by its set and key id. ORY Hydra uses this functionality to store cryptographic keys used for TLS and JSON Web Tokens (such as OpenID Connect ID tokens), and allows storing user-defined keys as well.
 //
@@ -155,14 +219,20 @@ func (h *Handler) GetKey(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		return
 	}
 
-	h.H.Write(w, r, keys)
+	exportable := WithoutExternalKeys(keys)
+	if len(exportable.Keys) == 0 {
+		h.H.WriteErrorCode(w, r, http.StatusForbidden, errors.Errorf("JSON Web Key %s is kept in an external key service and can not be exported", keyName))
+		return
+	}
+
+	h.H.Write(w, r, exportable)
 }
 
 // swagger:route GET /keys/{set} jsonWebKey getJsonWebKeySet
 //
 // Retrieve a JSON Web Key Set
 //
-// This endpoint can be used to retrieve JWK Sets stored in ORY Hydra.
+// This endpoint can be used to retrieve JWK Sets stored in ORY Hydra. Private keys kept in a hardware security module are left out.
 //
 // A JSON Web Key (JWK) is a JavaScript Object Notation (JSON) data structure that represents a cryptographic key. A JWK Set is a JSON data structure that represents a set of JWKs. A JSON Web Key is identified by its set and key id. ORY Hydra uses this functionality to store cryptographic keys used for TLS and JSON Web Tokens (such as OpenID Connect ID tokens), and allows storing user-defined keys as well.
 //
@@ -188,14 +258,14 @@ func (h *Handler) GetKeySet(w http.ResponseWriter, r *http.Request, ps httproute
 		return
 	}
 
-	h.H.Write(w, r, keys)
+	h.H.Write(w, r, WithoutExternalKeys(keys))
 }
 
 // swagger:route POST /keys/{set} jsonWebKey createJsonWebKeySet
 //
 // Generate a new JSON Web Key
 //
-// This endpoint is capable of generating JSON Web Key Sets for you. There a different strategies available, such as symmetric cryptographic keys (HS256, HS512) and asymetric cryptographic keys (RS256, ECDSA). If the specified JSON Web Key Set does not exist, it will be created.
+// This endpoint is capable of generating JSON Web Key Sets for you. There a different strategies available, such as symmetric cryptographic keys (HS256, HS512) and asymetric cryptographic keys (RS256, ECDSA). If the specified JSON Web Key Set does not exist, it will be created. If the set is kept in a hardware security module, the key pair is generated there and only its public key is returned.
 //
 // A JSON Web Key (JWK) is a JavaScript Object Notation (JSON) data structure that represents a cryptographic key. A JWK Set is a JSON data structure that represents a set of JWKs. A JSON Web Key is identified by its set and key id. ORY Hydra uses this functionality to store cryptographic keys used for TLS and JSON Web Tokens (such as OpenID Connect ID tokens), and allows storing user-defined keys as well.
 //
@@ -220,6 +290,17 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		h.H.WriteError(w, r, errors.WithStack(err))
 	}
 
+	if h.isExternalKeySet(set) {
+		keys, err := h.Manager.(ExternalManager).GenerateKeySet(set, keyRequest.KeyID, keyRequest.Algorithm)
+		if err != nil {
+			h.H.WriteError(w, r, err)
+			return
+		}
+
+		h.H.WriteCreated(w, r, fmt.Sprintf("%s://%s/keys/%s", r.URL.Scheme, r.URL.Host, set), WithoutExternalKeys(keys))
+		return
+	}
+
 	generator, found := h.GetGenerators()[keyRequest.Algorithm]
 	if !found {
 		h.H.WriteErrorCode(w, r, http.StatusBadRequest, errors.Errorf("Generator %s unknown", keyRequest.Algorithm))
@@ -240,6 +321,58 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request, ps httprouter.P
 	h.H.WriteCreated(w, r, fmt.Sprintf("%s://%s/keys/%s", r.URL.Scheme, r.URL.Host, set), keys)
 }
 
+// swagger:route POST /keys/{set}/rotate jsonWebKey rotateJsonWebKeySet
+//
+// Rotate a JSON Web Key Set
+//
+// This endpoint generates the next keys of a JSON Web Key Set, one key (pair) for every algorithm used in the set. The new keys are published right away but are only used for signing once their activation delay has passed. The previous keys retire, and are removed from the set, once the new keys have been active for longer than the lifespan of the tokens signed with them. The response contains the rotation schedule of all keys of the set.
+//
+// A JSON Web Key (JWK) is a JavaScript Object Notation (JSON) data structure that represents a cryptographic key. A JWK Set is a JSON data structure that represents a set of JWKs. A JSON Web Key is identified by its set and key id. ORY Hydra uses this functionality to store cryptographic keys used for TLS and JSON Web Tokens (such as OpenID Connect ID tokens), and allows storing user-defined keys as well.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: jsonWebKeySetSchedule
+//       401: genericError
+//       403: genericError
+//       404: genericError
+//       500: genericError
+func (h *Handler) Rotate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var set = ps.ByName("set")
+
+	if h.Rotator == nil {
+		h.H.WriteErrorCode(w, r, http.StatusNotImplemented, errors.New("Key rotation is not supported by the JSON Web Key manager"))
+		return
+	}
+
+	if _, err := h.Rotator.Rotate(set); err != nil {
+		h.H.WriteError(w, r, err)
+		return
+	}
+
+	metadata, err := h.Rotator.Manager.(MetadataManager).GetKeySetMetadata(set)
+	if err != nil {
+		h.H.WriteError(w, r, err)
+		return
+	}
+
+	schedule := []KeyMetadata{}
+	for _, md := range metadata {
+		schedule = append(schedule, md)
+	}
+	sort.Slice(schedule, func(i, j int) bool {
+		return schedule[i].CreatedAt.Before(schedule[j].CreatedAt) || (schedule[i].CreatedAt.Equal(schedule[j].CreatedAt) && schedule[i].KeyID < schedule[j].KeyID)
+	})
+
+	h.H.Write(w, r, schedule)
+}
+
 // swagger:route PUT /keys/{set} jsonWebKey updateJsonWebKeySet
 //
 // Update a JSON Web Key Set
@@ -279,6 +412,11 @@ func (h *Handler) UpdateKeySet(w http.ResponseWriter, r *http.Request, ps httpro
 		keySet.Keys = append(keySet.Keys, *key)
 	}
 
+	if h.isExternalKeySet(set) {
+		h.H.WriteErrorCode(w, r, http.StatusForbidden, errors.Errorf("The keys of JSON Web Key Set %s are kept in an external key service, keys can not be imported", set))
+		return
+	}
+
 	if err := h.Manager.AddKeySet(set, keySet); err != nil {
 		h.H.WriteError(w, r, err)
 		return
@@ -317,6 +455,11 @@ func (h *Handler) UpdateKey(w http.ResponseWriter, r *http.Request, ps httproute
 		return
 	}
 
+	if h.isExternalKeySet(set) {
+		h.H.WriteErrorCode(w, r, http.StatusForbidden, errors.Errorf("The keys of JSON Web Key Set %s are kept in an external key service, keys can not be imported", set))
+		return
+	}
+
 	if err := h.Manager.AddKey(set, &key); err != nil {
 		h.H.WriteError(w, r, err)
 		return
@@ -21,6 +21,8 @@
 package jwk_test
 
 import (
+	"bytes"
+	"crypto"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -46,7 +48,7 @@ func init() {
 		H:       herodot.NewJSONWriter(nil),
 	}
 	h.Manager.AddKeySet(IDTokenKeyName, IDKS)
-	h.SetRoutes(router)
+	h.SetRoutes(router, router)
 	testServer = httptest.NewServer(router)
 }
 
@@ -65,3 +67,69 @@ func TestHandlerWellKnown(t *testing.T) {
 	require.NotNil(t, resp, "Could not find key public")
 	assert.Equal(t, resp, IDKS.Key("public:test-id"))
 }
+
+// externalManager keeps the private keys of the set "external" behind a crypto.Signer, like a hardware security module.
+type externalManager struct {
+	*MemoryManager
+}
+
+type externalSigner struct {
+	crypto.Signer
+}
+
+func (m *externalManager) IsExternalKeySet(set string) bool {
+	return set == "external"
+}
+
+func (m *externalManager) GenerateKeySet(set, kid, alg string) (*jose.JSONWebKeySet, error) {
+	keys, err := (&ECDSA256Generator{}).Generate(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys.Keys {
+		if k, ok := key.Key.(crypto.Signer); ok {
+			keys.Keys[i].Key = &externalSigner{Signer: k}
+		}
+	}
+	return keys, m.MemoryManager.AddKeySet(set, keys)
+}
+
+func TestHandlerExternalKeys(t *testing.T) {
+	router := httprouter.New()
+	h := Handler{Manager: &externalManager{MemoryManager: &MemoryManager{}}, H: herodot.NewJSONWriter(nil)}
+	h.SetRoutes(router, router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL+"/keys/external", "application/json", bytes.NewBufferString(`{"alg":"ES256","kid":"foo"}`))
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	var created jose.JSONWebKeySet
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&created))
+	require.Len(t, created.Keys, 1)
+	assert.Equal(t, "public:foo", created.Keys[0].KeyID)
+
+	res, err = http.Get(ts.URL + "/keys/external")
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	var keys jose.JSONWebKeySet
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&keys))
+	require.Len(t, keys.Keys, 1)
+	assert.Equal(t, "public:foo", keys.Keys[0].KeyID)
+
+	res, err = http.Get(ts.URL + "/keys/external/private:foo")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	req, err := http.NewRequest("PUT", ts.URL+"/keys/external", bytes.NewBufferString(`{"keys":[]}`))
+	require.NoError(t, err)
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package jwk
+
+import (
+	"encoding/json"
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/ory/hydra/pkg"
+	"github.com/pkg/errors"
+	"github.com/square/go-jose"
+)
+
+// BoltManager stores JSON Web Keys in an embedded BoltDB database. Every set is a bucket, keys are encrypted using
+// Cipher and stored by key ID together with their rotation schedule.
+type BoltManager struct {
+	DB     *bolt.DB
+	Cipher *AEAD
+}
+
+func boltBucketKeySet(set string) string {
+	return "hydra_jwk:" + set
+}
+
+func (m *BoltManager) AddKey(set string, key *jose.JSONWebKey) error {
+	return m.AddKeySet(set, &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{*key}})
+}
+
+func (m *BoltManager) AddKeySet(set string, keys *jose.JSONWebKeySet) error {
+	return m.addKeySet(set, keys, nil)
+}
+
+func (m *BoltManager) AddKeySetWithActivation(set string, keys *jose.JSONWebKeySet, activatesAt time.Time) error {
+	return m.addKeySet(set, keys, &activatesAt)
+}
+
+func (m *BoltManager) addKeySet(set string, keys *jose.JSONWebKeySet, activatesAt *time.Time) error {
+	return m.DB.Update(func(tx *bolt.Tx) error {
+		for _, key := range keys.Keys {
+			out, err := json.Marshal(key)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			encrypted, err := m.Cipher.Encrypt(out)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			if err := pkg.BoltPut(tx, boltBucketKeySet(set), key.KeyID, &sqlData{
+				Set:         set,
+				KID:         key.KeyID,
+				Key:         encrypted,
+				CreatedAt:   time.Now().UTC(),
+				ActivatesAt: activatesAt,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *BoltManager) GetKey(set, kid string) (*jose.JSONWebKeySet, error) {
+	var d sqlData
+	if err := m.DB.View(func(tx *bolt.Tx) error {
+		return pkg.BoltGet(tx, boltBucketKeySet(set), kid, &d)
+	}); err != nil {
+		return nil, err
+	}
+
+	key, err := m.decrypt(&d)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{*key}}, nil
+}
+
+func (m *BoltManager) GetKeySet(set string) (*jose.JSONWebKeySet, error) {
+	ds, err := m.getKeySet(set)
+	if err != nil {
+		return nil, err
+	} else if len(ds) == 0 {
+		return nil, errors.Wrap(pkg.ErrNotFound, "")
+	}
+
+	keys := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	for _, d := range ds {
+		key, err := m.decrypt(&d)
+		if err != nil {
+			return nil, err
+		}
+		keys.Keys = append(keys.Keys, *key)
+	}
+
+	return keys, nil
+}
+
+func (m *BoltManager) DeleteKey(set, kid string) error {
+	return m.DB.Update(func(tx *bolt.Tx) error {
+		return pkg.BoltDelete(tx, boltBucketKeySet(set), kid)
+	})
+}
+
+func (m *BoltManager) DeleteKeySet(set string) error {
+	return m.DB.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(boltBucketKeySet(set))); err != nil && err != bolt.ErrBucketNotFound {
+			return errors.WithStack(err)
+		}
+		return nil
+	})
+}
+
+func (m *BoltManager) GetKeySetMetadata(set string) (map[string]KeyMetadata, error) {
+	ds, err := m.getKeySet(set)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]KeyMetadata{}
+	for _, d := range ds {
+		md := KeyMetadata{
+			KeyID:       d.KID,
+			CreatedAt:   d.CreatedAt.UTC(),
+			ActivatesAt: d.CreatedAt.UTC(),
+		}
+		if d.ActivatesAt != nil {
+			md.ActivatesAt = d.ActivatesAt.UTC()
+		}
+		if d.RetiresAt != nil {
+			retiresAt := d.RetiresAt.UTC()
+			md.RetiresAt = &retiresAt
+		}
+		metadata[d.KID] = md
+	}
+
+	return metadata, nil
+}
+
+func (m *BoltManager) RetireKey(set, kid string, retiresAt time.Time) error {
+	return m.DB.Update(func(tx *bolt.Tx) error {
+		var d sqlData
+		if err := pkg.BoltGet(tx, boltBucketKeySet(set), kid, &d); err != nil {
+			return err
+		}
+
+		retiresAt = retiresAt.UTC()
+		d.RetiresAt = &retiresAt
+		return pkg.BoltPut(tx, boltBucketKeySet(set), kid, &d)
+	})
+}
+
+func (m *BoltManager) getKeySet(set string) ([]sqlData, error) {
+	var ds []sqlData
+	if err := m.DB.View(func(tx *bolt.Tx) error {
+		return pkg.BoltForEach(tx, boltBucketKeySet(set), func(_ string, raw []byte) error {
+			var d sqlData
+			if err := json.Unmarshal(raw, &d); err != nil {
+				return errors.WithStack(err)
+			}
+			ds = append(ds, d)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+func (m *BoltManager) decrypt(d *sqlData) (*jose.JSONWebKey, error) {
+	key, err := m.Cipher.Decrypt(d.Key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var c jose.JSONWebKey
+	if err := json.Unmarshal(key, &c); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &c, nil
+}
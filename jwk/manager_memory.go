@@ -22,6 +22,7 @@ package jwk
 
 import (
 	"sync"
+	"time"
 
 	"github.com/ory/hydra/pkg"
 	"github.com/pkg/errors"
@@ -29,11 +30,32 @@ import (
 )
 
 type MemoryManager struct {
-	Keys map[string]*jose.JSONWebKeySet
+	Keys     map[string]*jose.JSONWebKeySet
+	Metadata map[string]map[string]KeyMetadata
 	sync.RWMutex
 }
 
 func (m *MemoryManager) AddKey(set string, key *jose.JSONWebKey) error {
+	m.addKey(set, key, time.Now().UTC())
+	return nil
+}
+
+func (m *MemoryManager) AddKeySet(set string, keys *jose.JSONWebKeySet) error {
+	now := time.Now().UTC()
+	for _, key := range keys.Keys {
+		m.addKey(set, &key, now)
+	}
+	return nil
+}
+
+func (m *MemoryManager) AddKeySetWithActivation(set string, keys *jose.JSONWebKeySet, activatesAt time.Time) error {
+	for _, key := range keys.Keys {
+		m.addKey(set, &key, activatesAt)
+	}
+	return nil
+}
+
+func (m *MemoryManager) addKey(set string, key *jose.JSONWebKey, activatesAt time.Time) {
 	m.Lock()
 	defer m.Unlock()
 
@@ -41,14 +63,39 @@ func (m *MemoryManager) AddKey(set string, key *jose.JSONWebKey) error {
 	if m.Keys[set] == nil {
 		m.Keys[set] = &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
 	}
+	if m.Metadata[set] == nil {
+		m.Metadata[set] = map[string]KeyMetadata{}
+	}
 	m.Keys[set].Keys = append(m.Keys[set].Keys, *key)
-	return nil
+	m.Metadata[set][key.KeyID] = KeyMetadata{
+		KeyID:       key.KeyID,
+		CreatedAt:   time.Now().UTC(),
+		ActivatesAt: activatesAt,
+	}
 }
 
-func (m *MemoryManager) AddKeySet(set string, keys *jose.JSONWebKeySet) error {
-	for _, key := range keys.Keys {
-		m.AddKey(set, &key)
+func (m *MemoryManager) GetKeySetMetadata(set string) (map[string]KeyMetadata, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	metadata := map[string]KeyMetadata{}
+	for kid, md := range m.Metadata[set] {
+		metadata[kid] = md
 	}
+	return metadata, nil
+}
+
+func (m *MemoryManager) RetireKey(set, kid string, retiresAt time.Time) error {
+	m.Lock()
+	defer m.Unlock()
+
+	md, ok := m.Metadata[set][kid]
+	if !ok {
+		return errors.Wrap(pkg.ErrNotFound, "")
+	}
+
+	md.RetiresAt = &retiresAt
+	m.Metadata[set][kid] = md
 	return nil
 }
 
@@ -95,10 +142,11 @@ func (m *MemoryManager) DeleteKey(set, kid string) error {
 	var results []jose.JSONWebKey
 	for _, key := range keys.Keys {
 		if key.KeyID != kid {
-			results = append(results)
+			results = append(results, key)
 		}
 	}
 	m.Keys[set].Keys = results
+	delete(m.Metadata[set], kid)
 	defer m.Unlock()
 
 	return nil
@@ -109,6 +157,7 @@ func (m *MemoryManager) DeleteKeySet(set string) error {
 	defer m.Unlock()
 
 	delete(m.Keys, set)
+	delete(m.Metadata, set)
 	return nil
 }
 
@@ -116,4 +165,7 @@ func (m *MemoryManager) alloc() {
 	if m.Keys == nil {
 		m.Keys = make(map[string]*jose.JSONWebKeySet)
 	}
+	if m.Metadata == nil {
+		m.Metadata = make(map[string]map[string]KeyMetadata)
+	}
 }
@@ -0,0 +1,455 @@
+// +build hsm
+
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+	"github.com/ory/go-convenience/stringslice"
+	"github.com/ory/hydra/pkg"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"github.com/square/go-jose"
+)
+
+var (
+	// oidNamedCurveP256 identifies the P-256 curve in CKA_EC_PARAMS.
+	oidNamedCurveP256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}
+
+	// sha256DigestInfoPrefix is the DER encoded DigestInfo prefix of SHA-256 digests, CKM_RSA_PKCS expects it in
+	// front of the digest.
+	sha256DigestInfoPrefix = []byte{0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20}
+)
+
+// PKCS11Manager keeps the private keys of the configured JSON Web Key Sets in a PKCS#11 token, such as a hardware
+// security module, and stores all other sets using Fallback. Key pairs are generated inside the token and their private
+// keys never leave it, they are returned as crypto.Signer values which sign using the token.
+//
+// A key pair is stored as a private and a public key object whose CKA_LABEL is the name of the set and whose CKA_ID is
+// the key ID without the "private:" and "public:" prefixes. RSA keys sign using RS256, P-256 keys using ES256.
+type PKCS11Manager struct {
+	Fallback Manager
+
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	sets    []string
+
+	sync.Mutex
+}
+
+// NewPKCS11Manager loads the PKCS#11 module, opens a session to the token with the label and logs in using the pin.
+// The private keys of the sets are kept in the token, other sets are stored using fallback.
+func NewPKCS11Manager(module, tokenLabel, pin string, sets []string, fallback Manager) (ExternalManager, error) {
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		return nil, errors.Errorf("Could not load PKCS#11 module %s", module)
+	}
+
+	if err := ctx.Initialize(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for _, slot := range slots {
+		info, err := ctx.GetTokenInfo(slot)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		} else if strings.TrimSpace(info.Label) != tokenLabel {
+			continue
+		}
+
+		session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if err := ctx.Login(session, pkcs11.CKU_USER, pin); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return &PKCS11Manager{Fallback: fallback, ctx: ctx, session: session, sets: sets}, nil
+	}
+
+	return nil, errors.Errorf("Could not find PKCS#11 token %s", tokenLabel)
+}
+
+func (m *PKCS11Manager) IsExternalKeySet(set string) bool {
+	return stringslice.Has(m.sets, set)
+}
+
+func (m *PKCS11Manager) GenerateKeySet(set, kid, alg string) (*jose.JSONWebKeySet, error) {
+	if !m.IsExternalKeySet(set) {
+		return nil, errors.Errorf("JSON Web Key Set %s is not kept in the PKCS#11 token", set)
+	}
+
+	if kid == "" {
+		kid = uuid.New()
+	}
+
+	public := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, set),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, kid),
+	}
+	private := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, set),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, kid),
+	}
+
+	var mechanism *pkcs11.Mechanism
+	switch alg {
+	case "RS256":
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_KEY_PAIR_GEN, nil)
+		public = append(public,
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_RSA),
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS_BITS, 4096),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, []byte{1, 0, 1}),
+		)
+		private = append(private, pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_RSA))
+	case "ES256":
+		params, err := asn1.Marshal(oidNamedCurveP256)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		mechanism = pkcs11.NewMechanism(pkcs11.CKM_EC_KEY_PAIR_GEN, nil)
+		public = append(public,
+			pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC),
+			pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, params),
+		)
+		private = append(private, pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC))
+	default:
+		return nil, errors.Errorf("JWS algorithm %s is not supported by the PKCS#11 token, use RS256 or ES256", alg)
+	}
+
+	m.Lock()
+	_, _, err := m.ctx.GenerateKeyPair(m.session, []*pkcs11.Mechanism{mechanism}, public, private)
+	m.Unlock()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return m.GetKey(set, kid)
+}
+
+func (m *PKCS11Manager) AddKey(set string, key *jose.JSONWebKey) error {
+	if m.IsExternalKeySet(set) {
+		return errors.Errorf("Keys can not be added to JSON Web Key Set %s because it is kept in the PKCS#11 token", set)
+	}
+	return m.Fallback.AddKey(set, key)
+}
+
+func (m *PKCS11Manager) AddKeySet(set string, keys *jose.JSONWebKeySet) error {
+	if m.IsExternalKeySet(set) {
+		return errors.Errorf("Keys can not be added to JSON Web Key Set %s because it is kept in the PKCS#11 token", set)
+	}
+	return m.Fallback.AddKeySet(set, keys)
+}
+
+func (m *PKCS11Manager) GetKey(set, kid string) (*jose.JSONWebKeySet, error) {
+	if !m.IsExternalKeySet(set) {
+		return m.Fallback.GetKey(set, kid)
+	}
+
+	keys, err := m.getKeys(set, keyPairID(kid))
+	if err != nil {
+		return nil, err
+	}
+
+	found := &jose.JSONWebKeySet{}
+	for _, key := range keys.Keys {
+		if key.KeyID == kid || !strings.Contains(kid, ":") {
+			found.Keys = append(found.Keys, key)
+		}
+	}
+
+	if len(found.Keys) == 0 {
+		return nil, errors.Wrap(pkg.ErrNotFound, "")
+	}
+	return found, nil
+}
+
+func (m *PKCS11Manager) GetKeySet(set string) (*jose.JSONWebKeySet, error) {
+	if !m.IsExternalKeySet(set) {
+		return m.Fallback.GetKeySet(set)
+	}
+
+	keys, err := m.getKeys(set, "")
+	if err != nil {
+		return nil, err
+	} else if len(keys.Keys) == 0 {
+		return nil, errors.Wrap(pkg.ErrNotFound, "")
+	}
+	return keys, nil
+}
+
+// DeleteKey deletes the key pair of the key from the token, deleting either key of a pair deletes both.
+func (m *PKCS11Manager) DeleteKey(set, kid string) error {
+	if !m.IsExternalKeySet(set) {
+		return m.Fallback.DeleteKey(set, kid)
+	}
+	return m.destroyObjects(set, keyPairID(kid))
+}
+
+func (m *PKCS11Manager) DeleteKeySet(set string) error {
+	if !m.IsExternalKeySet(set) {
+		return m.Fallback.DeleteKeySet(set)
+	}
+	return m.destroyObjects(set, "")
+}
+
+// getKeys returns the key pairs of the set, or only the key pair with the id if it is set.
+func (m *PKCS11Manager) getKeys(set, id string) (*jose.JSONWebKeySet, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	privateKeys, err := m.findObjects(pkcs11.CKO_PRIVATE_KEY, set, id)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	for _, privateKey := range privateKeys {
+		attributes, err := m.ctx.GetAttributeValue(m.session, privateKey, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_ID, nil)})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		keyID := string(attributes[0].Value)
+
+		publicKey, alg, err := m.publicKey(set, keyID)
+		if err != nil {
+			return nil, err
+		}
+
+		keys.Keys = append(keys.Keys, jose.JSONWebKey{
+			Algorithm:    alg,
+			Use:          "sig",
+			Key:          &pkcs11Signer{manager: m, handle: privateKey, public: publicKey},
+			KeyID:        ider("private", keyID),
+			Certificates: []*x509.Certificate{},
+		}, jose.JSONWebKey{
+			Algorithm:    alg,
+			Use:          "sig",
+			Key:          publicKey,
+			KeyID:        ider("public", keyID),
+			Certificates: []*x509.Certificate{},
+		})
+	}
+
+	return keys, nil
+}
+
+func (m *PKCS11Manager) destroyObjects(set, id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, class := range []uint{pkcs11.CKO_PRIVATE_KEY, pkcs11.CKO_PUBLIC_KEY} {
+		objects, err := m.findObjects(class, set, id)
+		if err != nil {
+			return err
+		}
+
+		for _, object := range objects {
+			if err := m.ctx.DestroyObject(m.session, object); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+	}
+	return nil
+}
+
+// findObjects returns the key objects of the class and set which have the attributes, or only those with the id if it
+// is set. The caller must hold the lock.
+func (m *PKCS11Manager) findObjects(class uint, set, id string, attributes ...*pkcs11.Attribute) ([]pkcs11.ObjectHandle, error) {
+	template := append([]*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, set),
+	}, attributes...)
+	if id != "" {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_ID, id))
+	}
+
+	if err := m.ctx.FindObjectsInit(m.session, template); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var objects []pkcs11.ObjectHandle
+	for {
+		found, _, err := m.ctx.FindObjects(m.session, 100)
+		if err != nil {
+			m.ctx.FindObjectsFinal(m.session)
+			return nil, errors.WithStack(err)
+		} else if len(found) == 0 {
+			break
+		}
+		objects = append(objects, found...)
+	}
+
+	if err := m.ctx.FindObjectsFinal(m.session); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return objects, nil
+}
+
+// publicKey reads the public key of the key pair and returns it together with the JWS algorithm it verifies. The
+// caller must hold the lock.
+func (m *PKCS11Manager) publicKey(set, id string) (crypto.PublicKey, string, error) {
+	objects, err := m.findObjects(pkcs11.CKO_PUBLIC_KEY, set, id, pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_RSA))
+	if err != nil {
+		return nil, "", err
+	} else if len(objects) > 0 {
+		attributes, err := m.ctx.GetAttributeValue(m.session, objects[0], []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+			pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+		})
+		if err != nil {
+			return nil, "", errors.WithStack(err)
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(attributes[0].Value),
+			E: int(new(big.Int).SetBytes(attributes[1].Value).Int64()),
+		}, "RS256", nil
+	}
+
+	objects, err = m.findObjects(pkcs11.CKO_PUBLIC_KEY, set, id, pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_EC))
+	if err != nil {
+		return nil, "", err
+	} else if len(objects) == 0 {
+		return nil, "", errors.Errorf("PKCS#11 token does not contain an RSA or elliptic curve public key for key %s of set %s", id, set)
+	}
+
+	attributes, err := m.ctx.GetAttributeValue(m.session, objects[0], []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	var curve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(attributes[0].Value, &curve); err != nil {
+		return nil, "", errors.WithStack(err)
+	} else if !curve.Equal(oidNamedCurveP256) {
+		return nil, "", errors.Errorf("Elliptic curve %s is not supported, use P-256", curve)
+	}
+
+	// CKA_EC_POINT is the DER encoding of an OCTET STRING containing the uncompressed point.
+	var point []byte
+	if _, err := asn1.Unmarshal(attributes[1].Value, &point); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+
+	x, y := elliptic.Unmarshal(elliptic.P256(), point)
+	if x == nil {
+		return nil, "", errors.New("Could not decode elliptic curve point")
+	}
+
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, "ES256", nil
+}
+
+// sign signs the digest using the private key object and the mechanism.
+func (m *PKCS11Manager) sign(object pkcs11.ObjectHandle, mechanism uint, digest []byte) ([]byte, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if err := m.ctx.SignInit(m.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(mechanism, nil)}, object); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	signature, err := m.ctx.Sign(m.session, digest)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return signature, nil
+}
+
+// pkcs11Signer is a private key kept in a PKCS#11 token. It signs SHA-256 digests using PKCS #1 v1.5 for RSA keys and
+// ECDSA for elliptic curve keys.
+type pkcs11Signer struct {
+	manager *PKCS11Manager
+	handle  pkcs11.ObjectHandle
+	public  crypto.PublicKey
+}
+
+func (s *pkcs11Signer) Public() crypto.PublicKey {
+	return s.public
+}
+
+func (s *pkcs11Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts.HashFunc() != crypto.SHA256 {
+		return nil, errors.New("Keys kept in the PKCS#11 token only sign SHA-256 digests")
+	} else if _, ok := opts.(*rsa.PSSOptions); ok {
+		return nil, errors.New("Keys kept in the PKCS#11 token do not support RSA-PSS")
+	}
+
+	switch s.public.(type) {
+	case *rsa.PublicKey:
+		return s.manager.sign(s.handle, pkcs11.CKM_RSA_PKCS, append(append([]byte{}, sha256DigestInfoPrefix...), digest...))
+	case *ecdsa.PublicKey:
+		raw, err := s.manager.sign(s.handle, pkcs11.CKM_ECDSA, digest)
+		if err != nil {
+			return nil, err
+		} else if len(raw)%2 != 0 {
+			return nil, errors.New("PKCS#11 token returned a malformed ECDSA signature")
+		}
+
+		// PKCS#11 returns r and s concatenated while crypto.Signer implementations return them ASN.1 encoded.
+		return asn1.Marshal(struct{ R, S *big.Int }{
+			R: new(big.Int).SetBytes(raw[:len(raw)/2]),
+			S: new(big.Int).SetBytes(raw[len(raw)/2:]),
+		})
+	default:
+		return nil, errors.New("Only RSA and elliptic curve keys are supported")
+	}
+}
+
+// keyPairID removes the "private:" or "public:" prefix from the key ID.
+func keyPairID(kid string) string {
+	if i := strings.Index(kid, ":"); i >= 0 {
+		return kid[i+1:]
+	}
+	return kid
+}
@@ -0,0 +1,31 @@
+// +build !hsm
+
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package jwk
+
+import "github.com/pkg/errors"
+
+// NewPKCS11Manager returns an error because this build does not support PKCS#11 tokens, build ORY Hydra with the hsm
+// tag to enable them.
+func NewPKCS11Manager(module, tokenLabel, pin string, sets []string, fallback Manager) (ExternalManager, error) {
+	return nil, errors.New(`This build of ORY Hydra does not support PKCS#11 tokens, rebuild it using "go build -tags hsm"`)
+}
@@ -0,0 +1,125 @@
+// +build hsm
+
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package jwk_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"math/big"
+	"os"
+	"testing"
+
+	. "github.com/ory/hydra/jwk"
+	"github.com/ory/hydra/pkg"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPKCS11Manager runs against a PKCS#11 token, for example one of SoftHSM:
+//
+//	softhsm2-util --init-token --free --label hydra --pin 1234 --so-pin 1234
+//	HSM_LIBRARY=/usr/lib/softhsm/libsofthsm2.so HSM_TOKEN_LABEL=hydra HSM_PIN=1234 go test -tags hsm ./jwk/...
+func TestPKCS11Manager(t *testing.T) {
+	if os.Getenv("HSM_LIBRARY") == "" {
+		t.Skip("HSM_LIBRARY is not set")
+	}
+
+	set := "hsm-" + uuid.New()
+	fallback := new(MemoryManager)
+	m, err := NewPKCS11Manager(os.Getenv("HSM_LIBRARY"), os.Getenv("HSM_TOKEN_LABEL"), os.Getenv("HSM_PIN"), []string{set}, fallback)
+	require.NoError(t, err)
+	defer m.DeleteKeySet(set)
+
+	assert.True(t, m.IsExternalKeySet(set))
+	assert.False(t, m.IsExternalKeySet("other"))
+
+	_, err = m.GetKeySet(set)
+	assert.Equal(t, pkg.ErrNotFound, errors.Cause(err))
+
+	for _, alg := range []string{"RS256", "ES256"} {
+		t.Run("alg="+alg, func(t *testing.T) {
+			generated, err := m.GenerateKeySet(set, "", alg)
+			require.NoError(t, err)
+			require.Len(t, generated.Keys, 2)
+
+			privateKey, err := FindKeyByPrefix(generated, "private")
+			require.NoError(t, err)
+			publicKey, err := FindKeyByPrefix(generated, "public")
+			require.NoError(t, err)
+			assert.True(t, IsExternalKey(privateKey))
+			assert.Equal(t, alg, privateKey.Algorithm)
+
+			digest := sha256.Sum256([]byte("hello world"))
+			signature, err := privateKey.Key.(crypto.Signer).Sign(rand.Reader, digest[:], crypto.SHA256)
+			require.NoError(t, err)
+
+			switch k := publicKey.Key.(type) {
+			case *rsa.PublicKey:
+				assert.NoError(t, rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature))
+			case *ecdsa.PublicKey:
+				var sig struct{ R, S *big.Int }
+				_, err := asn1.Unmarshal(signature, &sig)
+				require.NoError(t, err)
+				assert.True(t, ecdsa.Verify(k, digest[:], sig.R, sig.S))
+			default:
+				t.Fatalf("Unexpected public key type %T", k)
+			}
+
+			keys, err := m.GetKey(set, publicKey.KeyID)
+			require.NoError(t, err)
+			require.Len(t, keys.Keys, 1)
+			assert.Equal(t, publicKey.KeyID, keys.Keys[0].KeyID)
+		})
+	}
+
+	keys, err := m.GetKeySet(set)
+	require.NoError(t, err)
+	assert.Len(t, keys.Keys, 4)
+	assert.Len(t, WithoutExternalKeys(keys).Keys, 2)
+
+	assert.Error(t, m.AddKey(set, &keys.Keys[1]))
+	assert.Error(t, m.AddKeySet(set, keys))
+	_, err = m.GenerateKeySet(set, "", "EdDSA")
+	assert.Error(t, err)
+
+	require.NoError(t, m.DeleteKey(set, keys.Keys[0].KeyID))
+	keys, err = m.GetKeySet(set)
+	require.NoError(t, err)
+	assert.Len(t, keys.Keys, 2)
+
+	software, err := (&ECDSA256Generator{}).Generate("")
+	require.NoError(t, err)
+	require.NoError(t, m.AddKeySet("other", software))
+	_, err = fallback.GetKeySet("other")
+	assert.NoError(t, err)
+
+	require.NoError(t, m.DeleteKeySet(set))
+	_, err = m.GetKeySet(set)
+	assert.Equal(t, pkg.ErrNotFound, errors.Cause(err))
+}
@@ -23,9 +23,11 @@ package jwk
 import (
 	"database/sql"
 	"encoding/json"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/ory/hydra/pkg"
+	"github.com/ory/sqlcon"
 	"github.com/pkg/errors"
 	"github.com/rubenv/sql-migrate"
 	"github.com/square/go-jose"
@@ -53,19 +55,60 @@ var migrations = &migrate.MemoryMigrationSource{
 				"DROP TABLE hydra_jwk",
 			},
 		},
+		{
+			Id: "2",
+			Up: []string{
+				`ALTER TABLE hydra_jwk ADD created_at timestamp NOT NULL DEFAULT now()`,
+				// activates_at is NULL for keys which are active from the moment they are added
+				`ALTER TABLE hydra_jwk ADD activates_at timestamp NULL`,
+				`ALTER TABLE hydra_jwk ADD retires_at timestamp NULL`,
+			},
+			Down: []string{
+				`ALTER TABLE hydra_jwk DROP COLUMN created_at`,
+				`ALTER TABLE hydra_jwk DROP COLUMN activates_at`,
+				`ALTER TABLE hydra_jwk DROP COLUMN retires_at`,
+			},
+		},
+		{
+			Id: "3",
+			Up: []string{
+				`CREATE TABLE hydra_jwk_lock (
+	name       varchar(255) NOT NULL,
+	owner      varchar(255) NOT NULL,
+	expires_at timestamp NULL,
+	PRIMARY KEY (name)
+)`,
+			},
+			Down: []string{
+				"DROP TABLE hydra_jwk_lock",
+			},
+		},
+	},
+}
+
+// sqliteMigrations replaces statements which SQLite can not run. SQLite can not add a column using a non-constant
+// default, all inserts set created_at so the default is never used.
+var sqliteMigrations = map[string][]string{
+	"2": {
+		`ALTER TABLE hydra_jwk ADD created_at timestamp NOT NULL DEFAULT '1970-01-01 00:00:00'`,
+		`ALTER TABLE hydra_jwk ADD activates_at timestamp NULL`,
+		`ALTER TABLE hydra_jwk ADD retires_at timestamp NULL`,
 	},
 }
 
 type sqlData struct {
-	Set     string `db:"sid"`
-	KID     string `db:"kid"`
-	Version int    `db:"version"`
-	Key     string `db:"keydata"`
+	Set         string     `db:"sid"`
+	KID         string     `db:"kid"`
+	Version     int        `db:"version"`
+	Key         string     `db:"keydata"`
+	CreatedAt   time.Time  `db:"created_at"`
+	ActivatesAt *time.Time `db:"activates_at"`
+	RetiresAt   *time.Time `db:"retires_at"`
 }
 
 func (s *SQLManager) CreateSchemas() (int, error) {
 	migrate.SetTable("hydra_jwk_migration")
-	n, err := migrate.Exec(s.DB.DB, s.DB.DriverName(), migrations, migrate.Up)
+	n, err := migrate.Exec(s.DB.DB, s.DB.DriverName(), pkg.SQLMigrations(migrations, s.DB.DriverName(), sqliteMigrations), migrate.Up)
 	if err != nil {
 		return 0, errors.Wrapf(err, "Could not migrate sql schema, applied %d migrations", n)
 	}
@@ -83,11 +126,12 @@ func (m *SQLManager) AddKey(set string, key *jose.JSONWebKey) error {
 		return errors.WithStack(err)
 	}
 
-	if _, err = m.DB.NamedExec(`INSERT INTO hydra_jwk (sid, kid, version, keydata) VALUES (:sid, :kid, :version, :keydata)`, &sqlData{
-		Set:     set,
-		KID:     key.KeyID,
-		Version: 0,
-		Key:     encrypted,
+	if _, err = m.DB.NamedExec(`INSERT INTO hydra_jwk (sid, kid, version, keydata, created_at) VALUES (:sid, :kid, :version, :keydata, :created_at)`, &sqlData{
+		Set:       set,
+		KID:       key.KeyID,
+		Version:   0,
+		Key:       encrypted,
+		CreatedAt: time.Now().UTC(),
 	}); err != nil {
 		return errors.WithStack(err)
 	}
@@ -95,6 +139,14 @@ func (m *SQLManager) AddKey(set string, key *jose.JSONWebKey) error {
 }
 
 func (m *SQLManager) AddKeySet(set string, keys *jose.JSONWebKeySet) error {
+	return m.addKeySet(set, keys, nil)
+}
+
+func (m *SQLManager) AddKeySetWithActivation(set string, keys *jose.JSONWebKeySet, activatesAt time.Time) error {
+	return m.addKeySet(set, keys, &activatesAt)
+}
+
+func (m *SQLManager) addKeySet(set string, keys *jose.JSONWebKeySet, activatesAt *time.Time) error {
 	tx, err := m.DB.Beginx()
 	if err != nil {
 		return errors.WithStack(err)
@@ -117,11 +169,13 @@ func (m *SQLManager) AddKeySet(set string, keys *jose.JSONWebKeySet) error {
 			return errors.WithStack(err)
 		}
 
-		if _, err = tx.NamedExec(`INSERT INTO hydra_jwk (sid, kid, version, keydata) VALUES (:sid, :kid, :version, :keydata)`, &sqlData{
-			Set:     set,
-			KID:     key.KeyID,
-			Version: 0,
-			Key:     encrypted,
+		if _, err = tx.NamedExec(`INSERT INTO hydra_jwk (sid, kid, version, keydata, created_at, activates_at) VALUES (:sid, :kid, :version, :keydata, :created_at, :activates_at)`, &sqlData{
+			Set:         set,
+			KID:         key.KeyID,
+			Version:     0,
+			Key:         encrypted,
+			CreatedAt:   time.Now().UTC(),
+			ActivatesAt: activatesAt,
 		}); err != nil {
 			if re := tx.Rollback(); re != nil {
 				return errors.Wrap(err, re.Error())
@@ -204,3 +258,101 @@ func (m *SQLManager) DeleteKeySet(set string) error {
 	}
 	return nil
 }
+
+func (m *SQLManager) GetKeySetMetadata(set string) (map[string]KeyMetadata, error) {
+	var ds []sqlData
+	if err := m.DB.Select(&ds, m.DB.Rebind("SELECT kid, created_at, activates_at, retires_at FROM hydra_jwk WHERE sid=?"), set); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	metadata := map[string]KeyMetadata{}
+	for _, d := range ds {
+		md := KeyMetadata{
+			KeyID:       d.KID,
+			CreatedAt:   d.CreatedAt.UTC(),
+			ActivatesAt: d.CreatedAt.UTC(),
+		}
+		if d.ActivatesAt != nil {
+			md.ActivatesAt = d.ActivatesAt.UTC()
+		}
+		if d.RetiresAt != nil {
+			retiresAt := d.RetiresAt.UTC()
+			md.RetiresAt = &retiresAt
+		}
+		metadata[d.KID] = md
+	}
+
+	return metadata, nil
+}
+
+func (m *SQLManager) RetireKey(set, kid string, retiresAt time.Time) error {
+	if _, err := m.DB.Exec(m.DB.Rebind(`UPDATE hydra_jwk SET retires_at=? WHERE sid=? AND kid=?`), retiresAt.UTC(), set, kid); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+func (m *SQLManager) TryLock(name, owner string, expiresAt time.Time) (bool, error) {
+	if _, err := m.DB.Exec(m.DB.Rebind(`DELETE FROM hydra_jwk_lock WHERE name=? AND expires_at<?`), name, time.Now().UTC()); err != nil {
+		return false, sqlcon.HandleError(err)
+	}
+
+	if _, err := m.DB.Exec(m.DB.Rebind(`UPDATE hydra_jwk_lock SET expires_at=? WHERE name=? AND owner=?`), expiresAt.UTC(), name, owner); err != nil {
+		return false, sqlcon.HandleError(err)
+	}
+
+	if _, err := m.DB.Exec(m.DB.Rebind(`INSERT INTO hydra_jwk_lock (name, owner, expires_at) VALUES (?, ?, ?)`), name, owner, expiresAt.UTC()); err != nil {
+		if err := pkg.HandleSQLError(err); errors.Cause(err) != sqlcon.ErrUniqueViolation {
+			return false, err
+		}
+	}
+
+	// The lock exists now, it is ours if the insert succeeded or if we held it already.
+	var holder string
+	if err := m.DB.Get(&holder, m.DB.Rebind(`SELECT owner FROM hydra_jwk_lock WHERE name=?`), name); err != nil {
+		return false, sqlcon.HandleError(err)
+	}
+	return holder == owner, nil
+}
+
+// ReEncrypt encrypts all keys again using the current key of the cipher, which allows removing the rotated keys from
+// the cipher afterwards. It returns the number of keys which have been encrypted again.
+func (m *SQLManager) ReEncrypt() (int, error) {
+	var ds []sqlData
+	if err := m.DB.Select(&ds, "SELECT sid, kid, keydata FROM hydra_jwk"); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	rollback := func(err error) (int, error) {
+		if re := tx.Rollback(); re != nil {
+			return 0, errors.Wrap(err, re.Error())
+		}
+		return 0, err
+	}
+
+	for _, d := range ds {
+		key, err := m.Cipher.Decrypt(d.Key)
+		if err != nil {
+			return rollback(errors.Wrapf(err, "Could not decrypt JSON Web Key %s of set %s", d.KID, d.Set))
+		}
+
+		encrypted, err := m.Cipher.Encrypt(key)
+		if err != nil {
+			return rollback(err)
+		}
+
+		if _, err := tx.Exec(tx.Rebind(`UPDATE hydra_jwk SET keydata=? WHERE sid=? AND kid=?`), encrypted, d.Set, d.KID); err != nil {
+			return rollback(errors.WithStack(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return rollback(errors.WithStack(err))
+	}
+	return len(ds), nil
+}
@@ -25,15 +25,20 @@ import (
 	"fmt"
 	"log"
 	"testing"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
 	. "github.com/ory/hydra/jwk"
+	"github.com/ory/hydra/pkg"
 	"github.com/ory/sqlcon/dockertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var managers = map[string]Manager{
 	"memory": new(MemoryManager),
+	"bolt":   &BoltManager{DB: pkg.BoltTestDatabase(), Cipher: &AEAD{Key: encryptionKey}},
 }
 
 var testGenerator = &RS256Generator{}
@@ -44,6 +49,7 @@ func TestMain(m *testing.M) {
 	runner := dockertest.Register()
 
 	flag.Parse()
+	connectToSQLite()
 	if !testing.Short() {
 		dockertest.Parallel([]func(){
 			connectToPG,
@@ -51,7 +57,18 @@ func TestMain(m *testing.M) {
 		})
 	}
 
-	runner.Exit(m.Run())
+	code := m.Run()
+	pkg.CleanupTestDatabases()
+	runner.Exit(code)
+}
+
+func connectToSQLite() {
+	s := &SQLManager{DB: pkg.SQLiteTestDatabase(), Cipher: &AEAD{Key: encryptionKey}}
+	if _, err := s.CreateSchemas(); err != nil {
+		log.Fatalf("Could not create schema: %v", err)
+	}
+
+	managers["sqlite"] = s
 }
 
 func connectToPG() {
@@ -99,3 +116,69 @@ func TestManagerKeySet(t *testing.T) {
 		t.Run(fmt.Sprintf("case=%s", name), TestHelperManagerKeySet(m, ks, "TestManagerKeySet"))
 	}
 }
+
+func TestManagerKeyRotation(t *testing.T) {
+	for name, m := range managers {
+		t.Run(fmt.Sprintf("case=%s", name), TestHelperManagerKeyRotation(m))
+	}
+}
+
+func TestSQLManagerTryLock(t *testing.T) {
+	for name, m := range managers {
+		s, ok := m.(*SQLManager)
+		if !ok {
+			continue
+		}
+
+		t.Run(fmt.Sprintf("case=%s", name), func(t *testing.T) {
+			lock := "TestSQLManagerTryLock"
+
+			ok, err := s.TryLock(lock, "node-1", time.Now().UTC().Add(time.Hour))
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = s.TryLock(lock, "node-2", time.Now().UTC().Add(time.Hour))
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			ok, err = s.TryLock(lock, "node-1", time.Now().UTC().Add(-time.Hour))
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			// The lock of node-1 expired
+			ok, err = s.TryLock(lock, "node-2", time.Now().UTC().Add(time.Hour))
+			require.NoError(t, err)
+			assert.True(t, ok)
+		})
+	}
+}
+
+func TestSQLManagerReEncrypt(t *testing.T) {
+	ks, _ := testGenerator.Generate("TestSQLManagerReEncrypt")
+	rotatedKey, _ := RandomBytes(32)
+
+	for name, m := range managers {
+		s, ok := m.(*SQLManager)
+		if !ok {
+			continue
+		}
+
+		t.Run(fmt.Sprintf("case=%s", name), func(t *testing.T) {
+			require.NoError(t, s.AddKeySet("TestSQLManagerReEncrypt", ks))
+
+			rotated := &SQLManager{DB: s.DB, Cipher: &AEAD{Key: rotatedKey, RotatedKeys: [][]byte{encryptionKey}}}
+			_, err := rotated.ReEncrypt()
+			require.NoError(t, err)
+
+			_, err = s.GetKeySet("TestSQLManagerReEncrypt")
+			require.Error(t, err)
+			_, err = (&SQLManager{DB: s.DB, Cipher: &AEAD{Key: rotatedKey}}).GetKeySet("TestSQLManagerReEncrypt")
+			require.NoError(t, err)
+
+			// Restore the encryption key the other tests use
+			_, err = (&SQLManager{DB: s.DB, Cipher: &AEAD{Key: encryptionKey, RotatedKeys: [][]byte{rotatedKey}}}).ReEncrypt()
+			require.NoError(t, err)
+			require.NoError(t, s.DeleteKeySet("TestSQLManagerReEncrypt"))
+		})
+	}
+}
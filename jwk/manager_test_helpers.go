@@ -24,6 +24,7 @@ import (
 	"crypto/rand"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/square/go-jose"
@@ -95,3 +96,53 @@ func TestHelperManagerKeySet(m Manager, keys *jose.JSONWebKeySet, suffix string)
 		assert.NotNil(t, err)
 	}
 }
+
+func TestHelperManagerKeyRotation(m Manager) func(t *testing.T) {
+	return func(t *testing.T) {
+		t.Parallel()
+		keys, err := new(ECDSA256Generator).Generate("")
+		require.NoError(t, err)
+		require.NoError(t, m.AddKeySet("rotation", keys))
+
+		r := &Rotator{
+			Manager:         m,
+			Generators:      map[string]KeyGenerator{"ES256": new(ECDSA256Generator)},
+			ActivationDelay: time.Hour,
+			RetirementDelay: time.Hour,
+		}
+		next, err := r.Rotate("rotation")
+		require.NoError(t, err)
+		require.Len(t, next.Keys, 2)
+
+		metadata, err := m.(MetadataManager).GetKeySetMetadata("rotation")
+		require.NoError(t, err)
+		require.Len(t, metadata, 4)
+
+		// Timestamps might be rounded by the database.
+		now := time.Now().UTC().Add(time.Second)
+		for _, key := range keys.Keys {
+			md := metadata[key.KeyID]
+			assert.True(t, md.IsActive(now), "%s", key.KeyID)
+			require.NotNil(t, md.RetiresAt, "%s", key.KeyID)
+			assert.True(t, md.RetiresAt.After(now.Add(time.Hour*2-time.Minute)), "%s", key.KeyID)
+		}
+
+		for _, key := range next.Keys {
+			md := metadata[key.KeyID]
+			assert.Equal(t, "ES256", key.Algorithm)
+			assert.False(t, md.IsActive(now), "%s", key.KeyID)
+			assert.True(t, md.IsActive(now.Add(time.Hour+time.Minute)), "%s", key.KeyID)
+			assert.Nil(t, md.RetiresAt, "%s", key.KeyID)
+		}
+
+		require.NoError(t, m.(MetadataManager).RetireKey("rotation", keys.Keys[0].KeyID, now.Add(-time.Minute)))
+		require.NoError(t, r.RemoveRetiredKeys("rotation"))
+
+		got, err := m.GetKeySet("rotation")
+		require.NoError(t, err)
+		assert.Len(t, got.Keys, 3)
+		assert.Empty(t, got.Key(keys.Keys[0].KeyID))
+
+		require.NoError(t, m.DeleteKeySet("rotation"))
+	}
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"strings"
+	"time"
+
+	"github.com/ory/go-convenience/stringslice"
+	"github.com/pkg/errors"
+	"github.com/square/go-jose"
+	"golang.org/x/crypto/ed25519"
+)
+
+// KeyMetadata is the rotation schedule of a JSON Web Key.
+//
+// swagger:model jsonWebKeyMetadata
+type KeyMetadata struct {
+	// KeyID is the kid of the key.
+	KeyID string `json:"kid"`
+
+	// CreatedAt is the time the key was added to its set.
+	CreatedAt time.Time `json:"created_at"`
+
+	// ActivatesAt is the time the key starts being used for signing. Rotated keys are published before they become
+	// active so that relying parties can pick them up before the first token signed with them is issued.
+	ActivatesAt time.Time `json:"activates_at"`
+
+	// RetiresAt is the time the key is removed from its set. Keys without it never retire.
+	RetiresAt *time.Time `json:"retires_at,omitempty"`
+}
+
+// IsActive returns true if the key may be used for signing at t.
+func (m *KeyMetadata) IsActive(t time.Time) bool {
+	return !t.Before(m.ActivatesAt) && !m.IsRetired(t)
+}
+
+// IsRetired returns true if the key must no longer be used or published at t.
+func (m *KeyMetadata) IsRetired(t time.Time) bool {
+	return m.RetiresAt != nil && !t.Before(*m.RetiresAt)
+}
+
+// MetadataManager is implemented by managers which persist the rotation schedule of keys, which is required for key
+// rotation. Keys of managers which do not implement it are active as soon as they are added and never retire.
+type MetadataManager interface {
+	// AddKeySetWithActivation adds the keys to the set, they become active at activatesAt.
+	AddKeySetWithActivation(set string, keys *jose.JSONWebKeySet, activatesAt time.Time) error
+
+	// GetKeySetMetadata returns the rotation schedule of the keys of the set by key ID.
+	GetKeySetMetadata(set string) (map[string]KeyMetadata, error)
+
+	// RetireKey schedules the removal of the key from the set.
+	RetireKey(set, kid string, retiresAt time.Time) error
+}
+
+// Locker is implemented by managers which can be shared by several nodes of a cluster. It elects the node which
+// performs work that must only be done once, such as rotating a key set.
+type Locker interface {
+	// TryLock acquires the lock name for owner until expiresAt, or extends it if owner holds it already. It returns
+	// false if another owner holds the lock.
+	TryLock(name, owner string, expiresAt time.Time) (bool, error)
+}
+
+// Rotator rotates the keys of JSON Web Key Sets. Rotating a set generates a new key, or key pair, for every algorithm
+// used in the set. The new keys are published right away but only become active after ActivationDelay. The previous
+// keys retire RetirementDelay after the new keys became active, which should exceed the lifespan of the tokens signed
+// with them.
+type Rotator struct {
+	Manager    Manager
+	Generators map[string]KeyGenerator
+
+	ActivationDelay time.Duration
+	RetirementDelay time.Duration
+}
+
+// Rotate generates the next keys of the set and schedules the retirement of the current ones.
+func (r *Rotator) Rotate(set string) (*jose.JSONWebKeySet, error) {
+	m, ok := r.Manager.(MetadataManager)
+	if !ok {
+		return nil, errors.New("The JSON Web Key manager does not support key rotation")
+	}
+
+	keys, err := r.Manager.GetKeySet(set)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := m.GetKeySetMetadata(set)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var algorithms []string
+	uses := map[string]string{}
+	for _, key := range keys.Keys {
+		if md, ok := metadata[key.KeyID]; ok && md.IsRetired(now) {
+			continue
+		} else if strings.HasPrefix(key.KeyID, "public:") {
+			continue
+		}
+
+		if alg := KeyAlgorithm(&key); alg != "" && !stringslice.Has(algorithms, alg) {
+			algorithms = append(algorithms, alg)
+			uses[alg] = key.Use
+		}
+	}
+
+	if len(algorithms) == 0 {
+		return nil, errors.Errorf("JSON Web Key Set %s does not contain any keys which can be rotated", set)
+	}
+
+	generated := &jose.JSONWebKeySet{}
+	for _, alg := range algorithms {
+		generator, ok := r.Generators[alg]
+		if !ok {
+			return nil, errors.Errorf("Keys using algorithm %s can not be rotated", alg)
+		}
+
+		next, err := generator.Generate("")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, key := range next.Keys {
+			key.Use = uses[alg]
+			generated.Keys = append(generated.Keys, key)
+		}
+	}
+
+	activatesAt := now.Add(r.ActivationDelay)
+	if err := m.AddKeySetWithActivation(set, generated, activatesAt); err != nil {
+		return nil, err
+	}
+
+	retiresAt := activatesAt.Add(r.RetirementDelay)
+	for _, key := range keys.Keys {
+		if md, ok := metadata[key.KeyID]; ok && md.RetiresAt != nil {
+			continue
+		}
+
+		if err := m.RetireKey(set, key.KeyID, retiresAt); err != nil {
+			return nil, err
+		}
+	}
+
+	return generated, nil
+}
+
+// RemoveRetiredKeys deletes the keys of the set which are past their retirement.
+func (r *Rotator) RemoveRetiredKeys(set string) error {
+	m, ok := r.Manager.(MetadataManager)
+	if !ok {
+		return nil
+	}
+
+	metadata, err := m.GetKeySetMetadata(set)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for kid, md := range metadata {
+		if !md.IsRetired(now) {
+			continue
+		}
+
+		if err := r.Manager.DeleteKey(set, kid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// KeyAlgorithm returns the JWS algorithm of the key. Keys without an alg parameter are assumed to use RS256, ES256,
+// ES512 or EdDSA depending on their type.
+func KeyAlgorithm(key *jose.JSONWebKey) string {
+	if key.Algorithm != "" {
+		return key.Algorithm
+	}
+
+	switch k := key.Key.(type) {
+	case *rsa.PrivateKey, *rsa.PublicKey:
+		return "RS256"
+	case *ecdsa.PrivateKey:
+		return ecdsaAlgorithm(k.Curve.Params().BitSize)
+	case *ecdsa.PublicKey:
+		return ecdsaAlgorithm(k.Curve.Params().BitSize)
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		return "EdDSA"
+	}
+	return ""
+}
+
+func ecdsaAlgorithm(bitSize int) string {
+	switch bitSize {
+	case 256:
+		return "ES256"
+	case 521:
+		return "ES512"
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+
+	"github.com/square/go-jose"
+	"golang.org/x/crypto/ed25519"
+)
+
+// ExternalManager is implemented by managers which keep the private keys of some JSON Web Key Sets in an external key
+// service, such as a hardware security module, instead of storing them. The private keys of these sets are returned as
+// crypto.Signer values which sign without exporting the key material, so they are never returned by the API.
+type ExternalManager interface {
+	Manager
+
+	// IsExternalKeySet returns true if the private keys of the set are kept in the external key service.
+	IsExternalKeySet(set string) bool
+
+	// GenerateKeySet generates a key pair using the JWS algorithm inside the external key service and adds it to the
+	// set. The private key of the returned set is a crypto.Signer.
+	GenerateKeySet(set, kid, alg string) (*jose.JSONWebKeySet, error)
+}
+
+// IsExternalKey returns true if the key is a private key whose key material is kept in an external key service.
+func IsExternalKey(key *jose.JSONWebKey) bool {
+	switch key.Key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return false
+	case crypto.Signer:
+		return true
+	default:
+		return false
+	}
+}
+
+// WithoutExternalKeys returns the keys of the set except the private keys kept in an external key service.
+func WithoutExternalKeys(set *jose.JSONWebKeySet) *jose.JSONWebKeySet {
+	keys := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	for _, key := range set.Keys {
+		if !IsExternalKey(&key) {
+			keys.Keys = append(keys.Keys, key)
+		}
+	}
+	return keys
+}
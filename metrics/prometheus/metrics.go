@@ -1,42 +1,98 @@
 package prometheus
 
-// Metrics prototypes
-// Example:
-// 	Counter      *prometheus.CounterVec
-// 	ResponseTime *prometheus.HistogramVec
-type Metrics struct{}
-
-// Method for creation new custom Prometheus  metrics
-// Example:
-// 	pm := &Metrics{
-//		Counter: prometheus.NewCounterVec(
-//			prometheus.CounterOpts{
-//				Name:        "servicename_requests_total",
-//				Help:        "Description",
-//				ConstLabels: map[string]string{
-//					"version":   version,
-//					"hash":      hash,
-//					"buildTime": buildTime,
-//				},
-//			},
-//			[]string{"endpoint"},
-//		),
-//		ResponseTime: prometheus.NewHistogramVec(
-//			prometheus.HistogramOpts{
-//				Name:        "servicename_response_time_seconds",
-//				Help:        "Description",
-//				ConstLabels: map[string]string{
-//					"version":   version,
-//					"hash":      hash,
-//					"buildTime": buildTime,
-//				},
-//			},
-//			[]string{"endpoint"},
-//		),
-//	}
-//	prometheus.Register(pm.Counter)
-//  prometheus.Register(pm.ResponseTime)
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics holds the collectors exposed by ORY Hydra.
+type Metrics struct {
+	// RequestsTotal counts HTTP requests by method, route template and status code.
+	RequestsTotal *prometheus.CounterVec
+
+	// ResponseTime observes HTTP request latencies by method and route template.
+	ResponseTime *prometheus.HistogramVec
+
+	// TokensIssued counts tokens issued by the token and authorize endpoints, labelled by grant type.
+	TokensIssued *prometheus.CounterVec
+
+	// LoginRequests counts login requests handled by the login provider, labelled by result.
+	LoginRequests *prometheus.CounterVec
+
+	// ConsentRequests counts consent requests handled by the consent provider, labelled by result.
+	ConsentRequests *prometheus.CounterVec
+
+	// Introspections counts token introspection results, labelled by token type and whether the token was active.
+	Introspections *prometheus.CounterVec
+}
+
+// NewMetrics creates the collectors and registers them with the default Prometheus registry. If a collector
+// with the same description has already been registered, the existing collector is reused.
 func NewMetrics(version, hash, buildTime string) *Metrics {
-	pm := &Metrics{}
-	return pm
+	labels := map[string]string{
+		"version":   version,
+		"hash":      hash,
+		"buildTime": buildTime,
+	}
+
+	return &Metrics{
+		RequestsTotal: register(prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "hydra_http_requests_total",
+				Help:        "Total number of HTTP requests, labelled by method, route template and status code.",
+				ConstLabels: labels,
+			},
+			[]string{"method", "route", "code"},
+		)).(*prometheus.CounterVec),
+		ResponseTime: register(prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "hydra_http_request_duration_seconds",
+				Help:        "HTTP request latencies in seconds, labelled by method and route template.",
+				ConstLabels: labels,
+				Buckets:     prometheus.DefBuckets,
+			},
+			[]string{"method", "route"},
+		)).(*prometheus.HistogramVec),
+		TokensIssued: register(prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "hydra_oauth2_tokens_issued_total",
+				Help:        "Total number of OAuth 2.0 tokens issued, labelled by grant type.",
+				ConstLabels: labels,
+			},
+			[]string{"grant_type"},
+		)).(*prometheus.CounterVec),
+		LoginRequests: register(prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "hydra_login_requests_total",
+				Help:        "Total number of handled login requests, labelled by result (accepted or rejected).",
+				ConstLabels: labels,
+			},
+			[]string{"result"},
+		)).(*prometheus.CounterVec),
+		ConsentRequests: register(prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "hydra_consent_requests_total",
+				Help:        "Total number of handled consent requests, labelled by result (accepted or rejected).",
+				ConstLabels: labels,
+			},
+			[]string{"result"},
+		)).(*prometheus.CounterVec),
+		Introspections: register(prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "hydra_oauth2_introspections_total",
+				Help:        "Total number of token introspections, labelled by token type and whether the token was active.",
+				ConstLabels: labels,
+			},
+			[]string{"token_type", "active"},
+		)).(*prometheus.CounterVec),
+	}
+}
+
+func register(c prometheus.Collector) prometheus.Collector {
+	if err := prometheus.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+		panic(err)
+	}
+	return c
 }
@@ -2,10 +2,30 @@ package prometheus
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/urfave/negroni"
+)
+
+const (
+	// ResultAccepted labels a login or consent request which was accepted.
+	ResultAccepted = "accepted"
+
+	// ResultRejected labels a login or consent request which was rejected.
+	ResultRejected = "rejected"
+
+	unmatchedRoute = "unmatched"
+
+	// routeProbe replaces path segments to find out which of them are route parameters.
+	routeProbe = "\x00"
 )
 
 type MetricsManager struct {
 	prometheusMetrics *Metrics
+	router            *httprouter.Router
 }
 
 func NewMetricsManager(version, hash, buildTime string) *MetricsManager {
@@ -14,14 +34,97 @@ func NewMetricsManager(version, hash, buildTime string) *MetricsManager {
 	}
 }
 
+// WithRouter returns a copy of the metrics manager which uses router to resolve the route template of a request. The
+// copy shares its metrics with pmm, which allows to instrument several listeners with different routers. Without a
+// router, all requests are labelled as unmatched in order to keep the cardinality of the route label bounded.
+func (pmm *MetricsManager) WithRouter(router *httprouter.Router) *MetricsManager {
+	return &MetricsManager{
+		prometheusMetrics: pmm.prometheusMetrics,
+		router:            router,
+	}
+}
+
 // Main middleware method to collect metrics for Prometheus.
-// Example:
-//	start := time.Now()
-//	next(rw, r)
-//	Request counter metric
-//	pmm.prometheusMetrics.Counter.WithLabelValues(r.URL.Path).Inc()
-//	Response time metric
-//	pmm.prometheusMetrics.ResponseTime.WithLabelValues(r.URL.Path).Observe(time.Since(start).Seconds())
 func (pmm *MetricsManager) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	start := time.Now()
 	next(rw, r)
+
+	status := http.StatusOK
+	if res, ok := rw.(negroni.ResponseWriter); ok && res.Status() != 0 {
+		status = res.Status()
+	}
+
+	route := pmm.routeTemplate(r)
+	pmm.prometheusMetrics.RequestsTotal.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+	pmm.prometheusMetrics.ResponseTime.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+}
+
+// ObserveTokenIssued increments the issued tokens counter for the given grant type.
+func (pmm *MetricsManager) ObserveTokenIssued(grantType string) {
+	if pmm == nil {
+		return
+	}
+	pmm.prometheusMetrics.TokensIssued.WithLabelValues(grantType).Inc()
+}
+
+// ObserveLoginRequest increments the login requests counter for the given result.
+func (pmm *MetricsManager) ObserveLoginRequest(result string) {
+	if pmm == nil {
+		return
+	}
+	pmm.prometheusMetrics.LoginRequests.WithLabelValues(result).Inc()
+}
+
+// ObserveConsentRequest increments the consent requests counter for the given result.
+func (pmm *MetricsManager) ObserveConsentRequest(result string) {
+	if pmm == nil {
+		return
+	}
+	pmm.prometheusMetrics.ConsentRequests.WithLabelValues(result).Inc()
+}
+
+// ObserveIntrospection increments the introspection counter for the given token type and activity state.
+func (pmm *MetricsManager) ObserveIntrospection(tokenType string, active bool) {
+	if pmm == nil {
+		return
+	}
+	pmm.prometheusMetrics.Introspections.WithLabelValues(tokenType, strconv.FormatBool(active)).Inc()
+}
+
+// routeTemplate returns the route the request was matched against, for example `/clients/:id`, instead of the raw
+// request path. This prevents identifiers such as client IDs or challenges from ending up in the label values.
+func (pmm *MetricsManager) routeTemplate(r *http.Request) string {
+	if pmm.router == nil {
+		return unmatchedRoute
+	}
+
+	handle, params, _ := pmm.router.Lookup(r.Method, r.URL.Path)
+	if handle == nil {
+		return unmatchedRoute
+	}
+
+	// httprouter does not expose the pattern of the matched route. A segment belongs to a parameter of the route if
+	// the path still matches the same parameters when the segment is replaced, which is never the case for static
+	// segments, even if a parameter value equals one of them.
+	segments := strings.Split(r.URL.Path, "/")
+	for i := 0; i < len(segments) && len(params) > 0; i++ {
+		probe := append([]string{}, segments...)
+		probe[i] = routeProbe
+
+		_, ps, _ := pmm.router.Lookup(r.Method, strings.Join(probe, "/"))
+		if len(ps) != len(params) {
+			continue
+		}
+
+		for k, p := range ps {
+			if p.Key != params[k].Key {
+				break
+			} else if p.Value == routeProbe {
+				segments[i] = ":" + p.Key
+				break
+			}
+		}
+	}
+
+	return strings.Join(segments, "/")
 }
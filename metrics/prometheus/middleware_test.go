@@ -0,0 +1,76 @@
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/negroni"
+)
+
+func TestRouteTemplate(t *testing.T) {
+	router := httprouter.New()
+	router.GET("/clients/:id", func(http.ResponseWriter, *http.Request, httprouter.Params) {})
+	router.GET("/keys/:set/:key", func(http.ResponseWriter, *http.Request, httprouter.Params) {})
+	router.GET("/oauth2/auth", func(http.ResponseWriter, *http.Request, httprouter.Params) {})
+
+	pmm := NewMetricsManager("test", "test", "test").WithRouter(router)
+	for k, tc := range []struct {
+		path     string
+		expected string
+	}{
+		{path: "/clients/foo", expected: "/clients/:id"},
+		{path: "/keys/foo/foo", expected: "/keys/:set/:key"},
+		{path: "/keys/hydra.openid.id-token/public:1234", expected: "/keys/:set/:key"},
+		{path: "/clients/clients", expected: "/clients/:id"},
+		{path: "/keys/keys/keys", expected: "/keys/:set/:key"},
+		{path: "/keys/foo/foo/", expected: unmatchedRoute},
+		{path: "/oauth2/auth", expected: "/oauth2/auth"},
+		{path: "/does-not-exist/1234", expected: unmatchedRoute},
+	} {
+		r := httptest.NewRequest("GET", tc.path, nil)
+		assert.Equal(t, tc.expected, pmm.routeTemplate(r), "case %d", k)
+	}
+
+	assert.Equal(t, unmatchedRoute, NewMetricsManager("test", "test", "test").routeTemplate(httptest.NewRequest("GET", "/clients/foo", nil)))
+
+	admin := httprouter.New()
+	admin.GET("/oauth2/auth/requests/login/:challenge", func(http.ResponseWriter, *http.Request, httprouter.Params) {})
+	apmm := pmm.WithRouter(admin)
+	assert.True(t, apmm.prometheusMetrics == pmm.prometheusMetrics)
+	assert.Equal(t, "/oauth2/auth/requests/login/:challenge", apmm.routeTemplate(httptest.NewRequest("GET", "/oauth2/auth/requests/login/foo", nil)))
+	assert.Equal(t, unmatchedRoute, apmm.routeTemplate(httptest.NewRequest("GET", "/clients/foo", nil)))
+	assert.Equal(t, "/clients/:id", pmm.routeTemplate(httptest.NewRequest("GET", "/clients/foo", nil)))
+}
+
+func TestMiddleware(t *testing.T) {
+	router := httprouter.New()
+	router.GET("/clients/:id", func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	pmm := NewMetricsManager("middleware", "test", "test").WithRouter(router)
+	n := negroni.New()
+	n.Use(pmm)
+	n.UseHandler(router)
+
+	for _, id := range []string{"foo", "bar"} {
+		n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/clients/"+id, nil))
+	}
+
+	var m dto.Metric
+	require.NoError(t, pmm.prometheusMetrics.RequestsTotal.WithLabelValues("GET", "/clients/:id", "404").Write(&m))
+	assert.EqualValues(t, 2, m.GetCounter().GetValue())
+
+	pmm.ObserveTokenIssued("client_credentials")
+	require.NoError(t, pmm.prometheusMetrics.TokensIssued.WithLabelValues("client_credentials").Write(&m))
+	assert.EqualValues(t, 1, m.GetCounter().GetValue())
+
+	var nilManager *MetricsManager
+	nilManager.ObserveTokenIssued("client_credentials")
+	nilManager.ObserveIntrospection("access_token", true)
+}
@@ -0,0 +1,358 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @Copyright 	2017-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/jwk"
+	"github.com/ory/hydra/pkg"
+	"github.com/pkg/errors"
+	"github.com/square/go-jose"
+)
+
+// ClientAssertionTypeJWTBearer is the client assertion type of JWT client authentication as defined in
+// https://tools.ietf.org/html/rfc7523#section-2.2 .
+const ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// ErrJTIKnown is returned by SetClientAssertionJWT if the JWT ID has already been used.
+var ErrJTIKnown = errors.New("The JWT ID has been used before")
+
+// ClientAssertionStorage keeps track of the JWT IDs of client assertions in order to prevent replay attacks.
+type ClientAssertionStorage interface {
+	SetClientAssertionJWT(ctx context.Context, jti string, exp time.Time) error
+}
+
+// ClientAssertionAuthenticator implements client authentication using private_key_jwt and client_secret_jwt as
+// defined in http://openid.net/specs/openid-connect-core-1_0.html#ClientAuthentication .
+//
+// The assertion is verified before the request is passed on to fosite. Once verified, the assertion is replaced with a
+// one-time credential which is sent using HTTP basic authorization. The ClientAssertionAuthenticator must therefore
+// be used as the fosite.Hasher of the OAuth2 provider, so the one-time credential is accepted in place of the client
+// secret.
+type ClientAssertionAuthenticator struct {
+	Hasher  fosite.Hasher
+	Manager client.Manager
+	Storage ClientAssertionStorage
+
+	// Cipher decrypts the secrets of clients using client_secret_jwt.
+	Cipher *jwk.AEAD
+
+	// Audience contains the values accepted in the aud claim of an assertion, usually the issuer and the URLs of the
+	// token, introspection and revocation endpoints.
+	Audience []string
+
+	HTTPClient *http.Client
+
+	credentials map[string][]byte
+	sync.Mutex
+}
+
+func NewClientAssertionAuthenticator(
+	hasher fosite.Hasher,
+	manager client.Manager,
+	storage ClientAssertionStorage,
+	cipher *jwk.AEAD,
+	audience []string,
+) *ClientAssertionAuthenticator {
+	return &ClientAssertionAuthenticator{
+		Hasher:      hasher,
+		Manager:     manager,
+		Storage:     storage,
+		Cipher:      cipher,
+		Audience:    audience,
+		HTTPClient:  &http.Client{Timeout: time.Second * 10},
+		credentials: map[string][]byte{},
+	}
+}
+
+// SigningAlgorithms returns the JWS algorithms accepted for client assertions, the asymmetric ones for
+// private_key_jwt and the HMAC ones for client_secret_jwt.
+func (a *ClientAssertionAuthenticator) SigningAlgorithms() []string {
+	var algorithms []string
+	for _, m := range []jwtgo.SigningMethod{
+		jwtgo.SigningMethodRS256, jwtgo.SigningMethodRS384, jwtgo.SigningMethodRS512,
+		jwtgo.SigningMethodES256, jwtgo.SigningMethodES384, jwtgo.SigningMethodES512,
+		jwtgo.SigningMethodPS256, jwtgo.SigningMethodPS384, jwtgo.SigningMethodPS512,
+		jwtgo.SigningMethodHS256, jwtgo.SigningMethodHS384, jwtgo.SigningMethodHS512,
+	} {
+		algorithms = append(algorithms, m.Alg())
+	}
+	return algorithms
+}
+
+// Hash delegates to the underlying hasher.
+func (a *ClientAssertionAuthenticator) Hash(data []byte) ([]byte, error) {
+	return a.Hasher.Hash(data)
+}
+
+// Compare accepts one-time credentials issued by AuthenticateRequest and otherwise delegates to the underlying hasher.
+func (a *ClientAssertionAuthenticator) Compare(hash, data []byte) error {
+	a.Lock()
+	expected, ok := a.credentials[string(data)]
+	if ok && bytes.Equal(expected, hash) {
+		delete(a.credentials, string(data))
+		a.Unlock()
+		return nil
+	}
+	a.Unlock()
+
+	return a.Hasher.Compare(hash, data)
+}
+
+// AuthenticateRequest verifies the client assertion of the request, if one is present, and replaces it with a
+// one-time credential. The returned function releases the credential and must be called once the request has been
+// handled. Clients which are registered with a JWT based authentication method are not allowed to authenticate
+// using their client secret.
+func (a *ClientAssertionAuthenticator) AuthenticateRequest(r *http.Request) (func(), error) {
+	release := func() {}
+	if err := r.ParseForm(); err != nil {
+		return release, errors.WithStack(fosite.ErrInvalidRequest.WithDebug(err.Error()))
+	}
+
+	if r.PostForm.Get("client_assertion_type") == "" && r.PostForm.Get("client_assertion") == "" {
+		id, _, ok := r.BasicAuth()
+		if !ok {
+			id = r.PostForm.Get("client_id")
+		} else if unescaped, err := url.QueryUnescape(id); err == nil {
+			id = unescaped
+		}
+
+		if id == "" {
+			return release, nil
+		}
+
+		if c, err := a.Manager.GetConcreteClient(id); err == nil && isJWTAuthMethod(c.TokenEndpointAuthMethod) {
+			return release, errors.WithStack(fosite.ErrInvalidClient.WithDebug(fmt.Sprintf("The client must authenticate using token endpoint authentication method %s", c.TokenEndpointAuthMethod)))
+		}
+		return release, nil
+	}
+
+	if r.PostForm.Get("client_assertion_type") != ClientAssertionTypeJWTBearer {
+		return release, errors.WithStack(fosite.ErrInvalidRequest.WithDebug(fmt.Sprintf("Parameter client_assertion_type must be set to %s", ClientAssertionTypeJWTBearer)))
+	} else if _, _, ok := r.BasicAuth(); ok {
+		return release, errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Client assertions can not be combined with HTTP basic authorization"))
+	}
+
+	c, err := a.verify(r.Context(), r.PostForm.Get("client_assertion"), r.PostForm.Get("client_id"))
+	if err != nil {
+		return release, err
+	}
+
+	credential, err := pkg.GenerateSecret(32)
+	if err != nil {
+		return release, errors.WithStack(err)
+	}
+
+	a.Lock()
+	a.credentials[string(credential)] = c.GetHashedSecret()
+	a.Unlock()
+
+	for _, form := range []url.Values{r.Form, r.PostForm} {
+		form.Del("client_assertion")
+		form.Del("client_assertion_type")
+		form.Del("client_id")
+	}
+	r.SetBasicAuth(url.QueryEscape(c.ID), string(credential))
+
+	return func() {
+		a.Lock()
+		delete(a.credentials, string(credential))
+		a.Unlock()
+	}, nil
+}
+
+func (a *ClientAssertionAuthenticator) verify(ctx context.Context, assertion, clientID string) (*client.Client, error) {
+	var c *client.Client
+	claims := jwtgo.MapClaims{}
+	token, err := jwtgo.ParseWithClaims(assertion, claims, func(t *jwtgo.Token) (interface{}, error) {
+		sub, ok := claims["sub"].(string)
+		if !ok || sub == "" {
+			return nil, errors.New("Claim sub must be set")
+		} else if clientID != "" && clientID != sub {
+			return nil, errors.New("Claim sub does not match parameter client_id")
+		}
+
+		var err error
+		if c, err = a.Manager.GetConcreteClient(sub); err != nil {
+			return nil, errors.New("The client does not exist")
+		}
+
+		switch c.TokenEndpointAuthMethod {
+		case client.TokenEndpointAuthMethodPrivateKeyJWT:
+			switch t.Method.(type) {
+			case *jwtgo.SigningMethodRSA, *jwtgo.SigningMethodECDSA, *jwtgo.SigningMethodRSAPSS:
+			default:
+				return nil, errors.Errorf("Signing algorithm %s is not allowed for token endpoint authentication method private_key_jwt", t.Header["alg"])
+			}
+			kid, _ := t.Header["kid"].(string)
+			return a.findPublicKey(ctx, c, kid)
+		case client.TokenEndpointAuthMethodClientSecretJWT:
+			if _, ok := t.Method.(*jwtgo.SigningMethodHMAC); !ok {
+				return nil, errors.Errorf("Signing algorithm %s is not allowed for token endpoint authentication method client_secret_jwt", t.Header["alg"])
+			} else if c.EncryptedSecret == "" {
+				return nil, errors.New("The client secret is not available for token endpoint authentication method client_secret_jwt")
+			}
+			return a.Cipher.Decrypt(c.EncryptedSecret)
+		default:
+			return nil, errors.Errorf("The client is not allowed to authenticate using token endpoint authentication method %s", c.GetTokenEndpointAuthMethod())
+		}
+	})
+	if err != nil {
+		return nil, errors.WithStack(fosite.ErrInvalidClient.WithDebug(fmt.Sprintf("Unable to verify the client assertion: %s", err)))
+	} else if !token.Valid {
+		return nil, errors.WithStack(fosite.ErrInvalidClient.WithDebug("The client assertion is not valid"))
+	}
+
+	if iss, _ := claims["iss"].(string); iss != c.ID {
+		return nil, errors.WithStack(fosite.ErrInvalidClient.WithDebug("Claim iss must be equal to the client id"))
+	}
+
+	if !a.isValidAudience(claims["aud"]) {
+		return nil, errors.WithStack(fosite.ErrInvalidClient.WithDebug("Claim aud does not contain the issuer or the URL of the requested endpoint"))
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, errors.WithStack(fosite.ErrInvalidClient.WithDebug("Claim exp must be set"))
+	}
+
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return nil, errors.WithStack(fosite.ErrInvalidClient.WithDebug("Claim jti must be set"))
+	}
+
+	if err := a.Storage.SetClientAssertionJWT(ctx, c.ID+":"+jti, time.Unix(int64(exp), 0)); errors.Cause(err) == ErrJTIKnown {
+		return nil, errors.WithStack(fosite.ErrInvalidClient.WithDebug("The client assertion has been used before"))
+	} else if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (a *ClientAssertionAuthenticator) isValidAudience(aud interface{}) bool {
+	var audience []string
+	switch v := aud.(type) {
+	case string:
+		audience = []string{v}
+	case []interface{}:
+		for _, value := range v {
+			if s, ok := value.(string); ok {
+				audience = append(audience, s)
+			}
+		}
+	}
+
+	for _, actual := range audience {
+		for _, expected := range a.Audience {
+			if actual == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (a *ClientAssertionAuthenticator) findPublicKey(ctx context.Context, c *client.Client, kid string) (interface{}, error) {
+	set, err := clientJSONWebKeys(ctx, a.HTTPClient, c)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := set.Keys
+	if kid != "" {
+		keys = set.Key(kid)
+	}
+
+	if len(keys) != 1 {
+		return nil, errors.Errorf("Unable to find a unique public key for key id \"%s\"", kid)
+	}
+
+	switch k := keys[0].Key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return k, nil
+	default:
+		return nil, errors.Errorf("Key \"%s\" is not a RSA or ECDSA public key", kid)
+	}
+}
+
+// clientJSONWebKeys returns the JSON Web Key Set a client registered by value or by reference.
+func clientJSONWebKeys(ctx context.Context, hc *http.Client, c *client.Client) (*jose.JSONWebKeySet, error) {
+	set := c.JSONWebKeys
+	if c.JSONWebKeysURI != "" {
+		var err error
+		if set, err = fetchJSONWebKeys(ctx, hc, c.JSONWebKeysURI); err != nil {
+			return nil, err
+		}
+	}
+
+	if set == nil {
+		return nil, errors.New("The client has no JSON Web Keys registered")
+	}
+
+	return set, nil
+}
+
+func fetchJSONWebKeys(ctx context.Context, hc *http.Client, location string) (*jose.JSONWebKeySet, error) {
+	req, err := http.NewRequest("GET", location, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res, err := hc.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to fetch JSON Web Keys from %s", location)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Expected status code 200 when fetching JSON Web Keys from %s but got %d", location, res.StatusCode)
+	}
+
+	var set jose.JSONWebKeySet
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, errors.Wrapf(err, "Unable to decode JSON Web Keys from %s", location)
+	}
+
+	return &set, nil
+}
+
+func isJWTAuthMethod(method string) bool {
+	return method == client.TokenEndpointAuthMethodPrivateKeyJWT || method == client.TokenEndpointAuthMethodClientSecretJWT
+}
+
+func jtiSignature(jti string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(jti)))
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @Copyright 	2017-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	"github.com/ory/herodot"
+	hc "github.com/ory/hydra/client"
+	"github.com/ory/hydra/jwk"
+	. "github.com/ory/hydra/oauth2"
+	"github.com/pborman/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientAssertion(t *testing.T) {
+	router := httprouter.New()
+	l := logrus.New()
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	cipher := &jwk.AEAD{Key: []byte("some-secret-thats-random-and-long")}
+	manager := hc.NewMemoryManager(hasher)
+	store := NewFositeMemoryStore(manager, time.Second)
+	authenticator := NewClientAssertionAuthenticator(hasher, manager, store, cipher, []string{ts.URL + TokenPath})
+
+	handler := &Handler{
+		OAuth2: compose.Compose(
+			fc,
+			store,
+			oauth2Strategy,
+			authenticator,
+			compose.OAuth2ClientCredentialsGrantFactory,
+		),
+		ScopeStrategy:                fosite.HierarchicScopeStrategy,
+		H:                            herodot.NewJSONWriter(l),
+		L:                            l,
+		IssuerURL:                    ts.URL,
+		ClientAssertionAuthenticator: authenticator,
+	}
+	handler.SetRoutes(router, router)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.CreateClient(&hc.Client{
+		ID:                      "private-key-jwt-client",
+		Secret:                  "secret",
+		GrantTypes:              []string{"client_credentials"},
+		TokenEndpointAuthMethod: hc.TokenEndpointAuthMethodPrivateKeyJWT,
+		JSONWebKeys: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "key-1", Algorithm: "RS256", Use: "sig"},
+		}},
+	}))
+
+	encrypted, err := cipher.Encrypt([]byte("client-secret-jwt-secret"))
+	require.NoError(t, err)
+	require.NoError(t, manager.CreateClient(&hc.Client{
+		ID:                      "client-secret-jwt-client",
+		Secret:                  "client-secret-jwt-secret",
+		EncryptedSecret:         encrypted,
+		GrantTypes:              []string{"client_credentials"},
+		TokenEndpointAuthMethod: hc.TokenEndpointAuthMethodClientSecretJWT,
+	}))
+
+	assertion := func(method jwtgo.SigningMethod, signingKey interface{}, clientID, jti string, aud string) string {
+		token := jwtgo.NewWithClaims(method, jwtgo.MapClaims{
+			"iss": clientID,
+			"sub": clientID,
+			"aud": aud,
+			"jti": jti,
+			"exp": time.Now().Add(time.Minute).Unix(),
+		})
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(signingKey)
+		require.NoError(t, err)
+		return signed
+	}
+
+	request := func(form url.Values, basicID, basicSecret string) int {
+		form.Set("grant_type", "client_credentials")
+		req, err := http.NewRequest("POST", ts.URL+TokenPath, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if basicID != "" {
+			req.SetBasicAuth(basicID, basicSecret)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	privateKeyJTI := uuid.New()
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	for k, tc := range []struct {
+		d           string
+		form        url.Values
+		basicID     string
+		basicSecret string
+		expected    int
+	}{
+		{
+			d: "private_key_jwt assertion is accepted",
+			form: url.Values{
+				"client_assertion_type": {ClientAssertionTypeJWTBearer},
+				"client_assertion":      {assertion(jwtgo.SigningMethodRS256, key, "private-key-jwt-client", privateKeyJTI, ts.URL+TokenPath)},
+			},
+			expected: http.StatusOK,
+		},
+		{
+			d: "replayed assertion is rejected",
+			form: url.Values{
+				"client_assertion_type": {ClientAssertionTypeJWTBearer},
+				"client_assertion":      {assertion(jwtgo.SigningMethodRS256, key, "private-key-jwt-client", privateKeyJTI, ts.URL+TokenPath)},
+			},
+			expected: http.StatusUnauthorized,
+		},
+		{
+			d: "assertion signed with an unknown key is rejected",
+			form: url.Values{
+				"client_assertion_type": {ClientAssertionTypeJWTBearer},
+				"client_assertion":      {assertion(jwtgo.SigningMethodRS256, otherKey, "private-key-jwt-client", uuid.New(), ts.URL+TokenPath)},
+			},
+			expected: http.StatusUnauthorized,
+		},
+		{
+			d: "assertion with a wrong audience is rejected",
+			form: url.Values{
+				"client_assertion_type": {ClientAssertionTypeJWTBearer},
+				"client_assertion":      {assertion(jwtgo.SigningMethodRS256, key, "private-key-jwt-client", uuid.New(), "https://not-hydra/")},
+			},
+			expected: http.StatusUnauthorized,
+		},
+		{
+			d: "assertion with a mismatching client_id is rejected",
+			form: url.Values{
+				"client_id":             {"client-secret-jwt-client"},
+				"client_assertion_type": {ClientAssertionTypeJWTBearer},
+				"client_assertion":      {assertion(jwtgo.SigningMethodRS256, key, "private-key-jwt-client", uuid.New(), ts.URL+TokenPath)},
+			},
+			expected: http.StatusUnauthorized,
+		},
+		{
+			d:           "client secret is rejected for private_key_jwt clients",
+			form:        url.Values{},
+			basicID:     "private-key-jwt-client",
+			basicSecret: "secret",
+			expected:    http.StatusUnauthorized,
+		},
+		{
+			d: "client_secret_jwt assertion is accepted",
+			form: url.Values{
+				"client_assertion_type": {ClientAssertionTypeJWTBearer},
+				"client_assertion":      {assertion(jwtgo.SigningMethodHS256, []byte("client-secret-jwt-secret"), "client-secret-jwt-client", uuid.New(), ts.URL+TokenPath)},
+			},
+			expected: http.StatusOK,
+		},
+		{
+			d: "client_secret_jwt assertion signed with a wrong secret is rejected",
+			form: url.Values{
+				"client_assertion_type": {ClientAssertionTypeJWTBearer},
+				"client_assertion":      {assertion(jwtgo.SigningMethodHS256, []byte("wrong-secret"), "client-secret-jwt-client", uuid.New(), ts.URL+TokenPath)},
+			},
+			expected: http.StatusUnauthorized,
+		},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			assert.Equal(t, tc.expected, request(tc.form, tc.basicID, tc.basicSecret), "case %d", k)
+		})
+	}
+}
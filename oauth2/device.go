@@ -0,0 +1,275 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @Copyright 	2017-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/hydra/rand/sequence"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DeviceCodeGrantType is the grant type of the OAuth 2.0 Device Authorization Grant as defined in
+	// https://tools.ietf.org/html/rfc8628#section-3.4 .
+	DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// DefaultDeviceCodePollInterval is the minimum amount of time a client must wait between polling requests to the
+	// token endpoint.
+	DefaultDeviceCodePollInterval = time.Second * 5
+
+	DeviceStatusPending  = "pending"
+	DeviceStatusApproved = "approved"
+	DeviceStatusDenied   = "denied"
+)
+
+// userCodeCharSet contains only consonants to avoid spelling words and characters which are easily confused, as
+// recommended by https://tools.ietf.org/html/rfc8628#section-6.1 .
+var userCodeCharSet = []rune("BCDFGHJKLMNPQRSTVWXZ")
+
+var (
+	ErrAuthorizationPending = &fosite.RFC6749Error{
+		Name:        "authorization_pending",
+		Description: "The authorization request is still pending as the end user hasn't yet completed the user interaction steps",
+		Code:        http.StatusBadRequest,
+	}
+	ErrSlowDown = &fosite.RFC6749Error{
+		Name:        "slow_down",
+		Description: "The authorization request is still pending and polling should continue, but the interval must be increased",
+		Code:        http.StatusBadRequest,
+	}
+	ErrExpiredToken = &fosite.RFC6749Error{
+		Name:        "expired_token",
+		Description: "The device_code has expired and the device authorization session has concluded",
+		Code:        http.StatusBadRequest,
+	}
+)
+
+// ErrUserCodeKnown is returned by CreateDeviceCodeSession if the user code is already in use.
+var ErrUserCodeKnown = errors.New("The user code is already in use")
+
+// ErrDeviceStatusChanged is returned by UpdateDeviceCodeSession if the status of the stored device authorization
+// request is not the expected one, because it was approved, denied or redeemed concurrently.
+var ErrDeviceStatusChanged = errors.New("The status of the device authorization request has changed")
+
+// DeviceRequest is a device authorization request as defined in https://tools.ietf.org/html/rfc8628#section-3.1 .
+type DeviceRequest struct {
+	fosite.Requester
+
+	// Signature is the signature of the device code.
+	Signature string
+
+	// UserCode is the normalized user code the end user enters at the verification URI.
+	UserCode string
+
+	// Status is one of pending, approved, or denied.
+	Status string
+
+	ExpiresAt    time.Time
+	LastPolledAt time.Time
+}
+
+// DeviceCodeStorage stores device authorization requests.
+type DeviceCodeStorage interface {
+	CreateDeviceCodeSession(ctx context.Context, req *DeviceRequest) error
+	GetDeviceCodeSession(ctx context.Context, signature string, session fosite.Session) (*DeviceRequest, error)
+	GetDeviceCodeSessionByUserCode(ctx context.Context, userCode string, session fosite.Session) (*DeviceRequest, error)
+
+	// UpdateDeviceCodeSession stores req only if the stored request still has the given status, and returns
+	// ErrDeviceStatusChanged otherwise.
+	UpdateDeviceCodeSession(ctx context.Context, req *DeviceRequest, status string) error
+
+	// DeleteDeviceCodeSession returns fosite.ErrNotFound if the request does not exist, so that only one of several
+	// concurrent token requests can redeem a device code.
+	DeleteDeviceCodeSession(ctx context.Context, signature string) error
+}
+
+// DeviceCodeSignature returns the signature under which a device code is stored.
+func DeviceCodeSignature(code string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(code)))
+}
+
+// GenerateUserCode returns a random user code formatted as XXXX-XXXX.
+func GenerateUserCode() (string, error) {
+	code, err := sequence.RuneSequence(8, userCodeCharSet)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(code[:4]) + "-" + string(code[4:]), nil
+}
+
+// NormalizeUserCode removes formatting characters from a user code entered by the end user.
+func NormalizeUserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+}
+
+// DeviceCodeGrantHandler implements the token endpoint part of the OAuth 2.0 Device Authorization Grant.
+type DeviceCodeGrantHandler struct {
+	*foauth2.HandleHelper
+
+	RefreshTokenStrategy foauth2.RefreshTokenStrategy
+	RefreshTokenStorage  foauth2.RefreshTokenStorage
+	IDTokenHandleHelper  *openid.IDTokenHandleHelper
+
+	Storage      DeviceCodeStorage
+	PollInterval time.Duration
+}
+
+// DeviceCodeGrantFactory creates a DeviceCodeGrantHandler and is meant to be used with compose.Compose.
+func DeviceCodeGrantFactory(config *compose.Config, storage interface{}, strategy interface{}) interface{} {
+	return &DeviceCodeGrantHandler{
+		HandleHelper: &foauth2.HandleHelper{
+			AccessTokenStrategy: strategy.(foauth2.AccessTokenStrategy),
+			AccessTokenStorage:  storage.(foauth2.AccessTokenStorage),
+			AccessTokenLifespan: config.GetAccessTokenLifespan(),
+		},
+		RefreshTokenStrategy: strategy.(foauth2.RefreshTokenStrategy),
+		RefreshTokenStorage:  storage.(foauth2.RefreshTokenStorage),
+		IDTokenHandleHelper: &openid.IDTokenHandleHelper{
+			IDTokenStrategy: strategy.(openid.OpenIDConnectTokenStrategy),
+		},
+		Storage:      storage.(DeviceCodeStorage),
+		PollInterval: DefaultDeviceCodePollInterval,
+	}
+}
+
+// HandleTokenEndpointRequest verifies the device code and returns authorization_pending or slow_down as long as the
+// end user has not completed the verification.
+func (h *DeviceCodeGrantHandler) HandleTokenEndpointRequest(ctx context.Context, request fosite.AccessRequester) error {
+	if !request.GetGrantTypes().Exact(DeviceCodeGrantType) {
+		return errors.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	if !request.GetClient().GetGrantTypes().Has(DeviceCodeGrantType) {
+		return errors.WithStack(fosite.ErrInvalidGrant.WithDebug(fmt.Sprintf("The client is not allowed to use grant type %s", DeviceCodeGrantType)))
+	}
+
+	code := request.GetRequestForm().Get("device_code")
+	if code == "" {
+		return errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Parameter device_code is missing"))
+	}
+
+	dr, err := h.Storage.GetDeviceCodeSession(ctx, DeviceCodeSignature(code), request.GetSession())
+	if errors.Cause(err) == fosite.ErrNotFound {
+		return errors.WithStack(fosite.ErrInvalidGrant.WithDebug("The device code is unknown or has already been used"))
+	} else if err != nil {
+		return errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+	}
+
+	if dr.GetClient().GetID() != request.GetClient().GetID() {
+		return errors.WithStack(fosite.ErrInvalidGrant.WithDebug("The device code was issued to a different client"))
+	}
+
+	now := time.Now().UTC()
+	if dr.ExpiresAt.Before(now) {
+		return errors.WithStack(ErrExpiredToken)
+	}
+
+	switch dr.Status {
+	case DeviceStatusApproved:
+	case DeviceStatusDenied:
+		return errors.WithStack(fosite.ErrAccessDenied.WithDebug("The end user denied the device authorization request"))
+	default:
+		tooFast := dr.LastPolledAt.Add(h.PollInterval).After(now)
+		dr.LastPolledAt = now
+		if err := h.Storage.UpdateDeviceCodeSession(ctx, dr, DeviceStatusPending); errors.Cause(err) == ErrDeviceStatusChanged {
+			// The end user completed the verification in the meantime, which the next poll picks up.
+			return errors.WithStack(ErrAuthorizationPending)
+		} else if err != nil {
+			return errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+		}
+
+		if tooFast {
+			return errors.WithStack(ErrSlowDown)
+		}
+		return errors.WithStack(ErrAuthorizationPending)
+	}
+
+	request.SetID(dr.GetID())
+	request.SetSession(dr.GetSession())
+	for _, scope := range dr.GetGrantedScopes() {
+		request.GrantScope(scope)
+	}
+
+	request.GetSession().SetExpiresAt(fosite.AccessToken, now.Add(h.AccessTokenLifespan))
+	return nil
+}
+
+// PopulateTokenEndpointResponse invalidates the device code and issues the access, refresh and ID tokens.
+func (h *DeviceCodeGrantHandler) PopulateTokenEndpointResponse(ctx context.Context, request fosite.AccessRequester, responder fosite.AccessResponder) error {
+	if !request.GetGrantTypes().Exact(DeviceCodeGrantType) {
+		return errors.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	signature := DeviceCodeSignature(request.GetRequestForm().Get("device_code"))
+	dr, err := h.Storage.GetDeviceCodeSession(ctx, signature, request.GetSession())
+	if errors.Cause(err) == fosite.ErrNotFound {
+		return errors.WithStack(fosite.ErrInvalidGrant.WithDebug("The device code is unknown or has already been used"))
+	} else if err != nil {
+		return errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+	}
+
+	if err := h.Storage.DeleteDeviceCodeSession(ctx, signature); errors.Cause(err) == fosite.ErrNotFound {
+		return errors.WithStack(fosite.ErrInvalidGrant.WithDebug("The device code has already been used"))
+	} else if err != nil {
+		return errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+	}
+
+	if err := h.IssueAccessToken(ctx, request, responder); err != nil {
+		return err
+	}
+
+	if request.GetGrantedScopes().Has("offline") {
+		refresh, refreshSignature, err := h.RefreshTokenStrategy.GenerateRefreshToken(ctx, request)
+		if err != nil {
+			return errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+		}
+
+		if err := h.RefreshTokenStorage.CreateRefreshTokenSession(ctx, refreshSignature, request.Sanitize([]string{})); err != nil {
+			return errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+		}
+		responder.SetExtra("refresh_token", refresh)
+	}
+
+	if request.GetGrantedScopes().Has("openid") {
+		// The ID Token is generated from the device authorization request, because it carries the original form values.
+		dr.SetSession(request.GetSession())
+		if err := h.IDTokenHandleHelper.IssueExplicitIDToken(ctx, dr, responder); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
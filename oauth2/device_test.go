@@ -0,0 +1,51 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateUserCode(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		code, err := GenerateUserCode()
+		require.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile("^[BCDFGHJKLMNPQRSTVWXZ]{4}-[BCDFGHJKLMNPQRSTVWXZ]{4}$"), code)
+	}
+}
+
+func TestNormalizeUserCode(t *testing.T) {
+	for k, tc := range []struct {
+		in  string
+		out string
+	}{
+		{in: "BCDF-GHJK", out: "BCDFGHJK"},
+		{in: "bcdf-ghjk", out: "BCDFGHJK"},
+		{in: " bcdf ghjk ", out: "BCDFGHJK"},
+		{in: "", out: ""},
+	} {
+		assert.Equal(t, tc.out, NormalizeUserCode(tc.in), "%d", k)
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2
+
+import (
+	"github.com/ory/fosite"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/handler/pkce"
+	"github.com/ory/go-convenience/stringslice"
+)
+
+// capabilities describes the features of the composed OAuth 2.0 provider which are advertised by the discovery
+// documents. They are derived from the handlers registered with fosite, so a grant or response type is only
+// advertised if the corresponding factory has been composed.
+type capabilities struct {
+	GrantTypes           []string
+	ResponseTypes        []string
+	ResponseModes        []string
+	CodeChallengeMethods []string
+	Revocation           bool
+	Introspection        bool
+}
+
+func (c *capabilities) addGrantTypes(values ...string) {
+	c.GrantTypes = appendUnique(c.GrantTypes, values...)
+}
+
+func (c *capabilities) addResponseTypes(values ...string) {
+	c.ResponseTypes = appendUnique(c.ResponseTypes, values...)
+}
+
+func (c *capabilities) addResponseModes(values ...string) {
+	c.ResponseModes = appendUnique(c.ResponseModes, values...)
+}
+
+func (c *capabilities) addCodeChallengeMethods(values ...string) {
+	c.CodeChallengeMethods = appendUnique(c.CodeChallengeMethods, values...)
+}
+
+// capabilities inspects the handlers of the OAuth 2.0 provider. Providers which are not composed by fosite support
+// no grant or response types as far as discovery is concerned.
+func (h *Handler) capabilities() *capabilities {
+	c := new(capabilities)
+	f, ok := h.OAuth2.(*fosite.Fosite)
+	if !ok {
+		return c
+	}
+
+	for _, handler := range f.AuthorizeEndpointHandlers {
+		switch handler := handler.(type) {
+		case *foauth2.AuthorizeExplicitGrantHandler:
+			c.addResponseTypes("code")
+			c.addResponseModes("query")
+		case *foauth2.AuthorizeImplicitGrantTypeHandler:
+			c.addResponseTypes("token")
+			c.addResponseModes("fragment")
+		case *openid.OpenIDConnectImplicitHandler:
+			c.addResponseTypes("id_token", "id_token token")
+			c.addResponseModes("fragment")
+		case *openid.OpenIDConnectHybridHandler:
+			c.addResponseTypes("code id_token", "code token", "code id_token token")
+			c.addResponseModes("fragment")
+		case *pkce.Handler:
+			c.addCodeChallengeMethods("S256")
+			if handler.EnablePlainChallengeMethod {
+				c.addCodeChallengeMethods("plain")
+			}
+		}
+	}
+
+	for _, handler := range f.TokenEndpointHandlers {
+		switch handler.(type) {
+		case *foauth2.AuthorizeExplicitGrantHandler:
+			c.addGrantTypes("authorization_code")
+		case *foauth2.ClientCredentialsGrantHandler:
+			c.addGrantTypes("client_credentials")
+		case *foauth2.RefreshTokenGrantHandler, *RefreshTokenGrantHandler:
+			c.addGrantTypes("refresh_token")
+		case *foauth2.ResourceOwnerPasswordCredentialsGrantHandler:
+			c.addGrantTypes("password")
+		case *TokenExchangeGrantHandler:
+			c.addGrantTypes(TokenExchangeGrantType)
+		case *DeviceCodeGrantHandler:
+			c.addGrantTypes(DeviceCodeGrantType)
+		}
+	}
+
+	// The implicit grant has no token endpoint handler, it is implied by the response types.
+	for _, t := range c.ResponseTypes {
+		if t != "code" {
+			c.addGrantTypes("implicit")
+			break
+		}
+	}
+
+	c.Revocation = len(f.RevocationHandlers) > 0
+	c.Introspection = len(f.TokenIntrospectionHandlers) > 0
+	return c
+}
+
+func appendUnique(values []string, add ...string) []string {
+	for _, a := range add {
+		if !stringslice.Has(values, a) {
+			values = append(values, a)
+		}
+	}
+	return values
+}
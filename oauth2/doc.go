@@ -27,6 +27,18 @@ type swaggerRevokeOAuth2TokenParameters struct {
 	Token string `json:"token"`
 }
 
+// swagger:parameters logout
+type swaggerLogoutParameters struct {
+	// in: query
+	IDTokenHint string `json:"id_token_hint"`
+
+	// in: query
+	PostLogoutRedirectURI string `json:"post_logout_redirect_uri"`
+
+	// in: query
+	State string `json:"state"`
+}
+
 // swagger:parameters flushInactiveOAuth2Tokens
 type swaggerFlushInactiveAccessTokens struct {
 	// in: body
@@ -0,0 +1,453 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/pkg"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Device codes are looked up by their user code using an index bucket which maps the user code to the signature.
+const boltBucketDeviceUserCode = "hydra_oauth2_device_code_user_code"
+
+// FositeBoltStore stores OAuth 2.0 and OpenID Connect sessions in an embedded BoltDB database. Every kind of session
+// is a bucket named like the table of the FositeSQLStore and values are stored in the same format as its rows.
+type FositeBoltStore struct {
+	client.Manager
+	DB                  *bolt.DB
+	L                   logrus.FieldLogger
+	AccessTokenLifespan time.Duration
+}
+
+func NewFositeBoltStore(m client.Manager,
+	db *bolt.DB,
+	l logrus.FieldLogger,
+	accessTokenLifespan time.Duration,
+) *FositeBoltStore {
+	return &FositeBoltStore{
+		Manager:             m,
+		L:                   l,
+		DB:                  db,
+		AccessTokenLifespan: accessTokenLifespan,
+	}
+}
+
+// boltRefreshTokenData adds the time a refresh token has been rotated at to the stored session.
+type boltRefreshTokenData struct {
+	sqlData
+	RotatedAt *time.Time
+}
+
+func boltBucket(table string) string {
+	return "hydra_oauth2_" + table
+}
+
+func boltGet(tx *bolt.Tx, table, signature string, v interface{}) error {
+	if err := pkg.BoltGet(tx, boltBucket(table), signature, v); errors.Cause(err) == pkg.ErrNotFound {
+		return errors.Wrap(fosite.ErrNotFound, "")
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *FositeBoltStore) createSession(signature string, requester fosite.Requester, table string) error {
+	data, err := sqlSchemaFromRequest(signature, requester, s.L)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		return pkg.BoltPut(tx, boltBucket(table), signature, data)
+	})
+}
+
+func (s *FositeBoltStore) findSessionBySignature(signature string, session fosite.Session, table string) (fosite.Requester, error) {
+	var d sqlData
+	if err := s.DB.View(func(tx *bolt.Tx) error {
+		return boltGet(tx, table, signature, &d)
+	}); err != nil {
+		return nil, err
+	} else if !d.Active && table == sqlTableCode {
+		if r, err := d.toRequest(session, s.Manager, s.L); err != nil {
+			return nil, err
+		} else {
+			return r, errors.WithStack(fosite.ErrInvalidatedAuthorizeCode)
+		}
+	} else if !d.Active {
+		return nil, errors.WithStack(fosite.ErrInactiveToken)
+	}
+
+	return d.toRequest(session, s.Manager, s.L)
+}
+
+func (s *FositeBoltStore) deleteSession(signature string, table string) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		return pkg.BoltDelete(tx, boltBucket(table), signature)
+	})
+}
+
+func (s *FositeBoltStore) CreateOpenIDConnectSession(_ context.Context, signature string, requester fosite.Requester) error {
+	return s.createSession(signature, requester, sqlTableOpenID)
+}
+
+func (s *FositeBoltStore) GetOpenIDConnectSession(_ context.Context, signature string, requester fosite.Requester) (fosite.Requester, error) {
+	return s.findSessionBySignature(signature, requester.GetSession(), sqlTableOpenID)
+}
+
+func (s *FositeBoltStore) DeleteOpenIDConnectSession(_ context.Context, signature string) error {
+	return s.deleteSession(signature, sqlTableOpenID)
+}
+
+func (s *FositeBoltStore) CreateAuthorizeCodeSession(_ context.Context, signature string, requester fosite.Requester) error {
+	return s.createSession(signature, requester, sqlTableCode)
+}
+
+func (s *FositeBoltStore) GetAuthorizeCodeSession(_ context.Context, signature string, session fosite.Session) (fosite.Requester, error) {
+	return s.findSessionBySignature(signature, session, sqlTableCode)
+}
+
+func (s *FositeBoltStore) InvalidateAuthorizeCodeSession(ctx context.Context, signature string) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		var d sqlData
+		if err := boltGet(tx, sqlTableCode, signature, &d); errors.Cause(err) == fosite.ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		d.Active = false
+		return pkg.BoltPut(tx, boltBucket(sqlTableCode), signature, &d)
+	})
+}
+
+func (s *FositeBoltStore) CreateAccessTokenSession(_ context.Context, signature string, requester fosite.Requester) error {
+	return s.createSession(signature, requester, sqlTableAccess)
+}
+
+func (s *FositeBoltStore) GetAccessTokenSession(_ context.Context, signature string, session fosite.Session) (fosite.Requester, error) {
+	return s.findSessionBySignature(signature, session, sqlTableAccess)
+}
+
+func (s *FositeBoltStore) DeleteAccessTokenSession(_ context.Context, signature string) error {
+	return s.deleteSession(signature, sqlTableAccess)
+}
+
+func (s *FositeBoltStore) CreateRefreshTokenSession(_ context.Context, signature string, requester fosite.Requester) error {
+	return s.createSession(signature, requester, sqlTableRefresh)
+}
+
+func (s *FositeBoltStore) GetRefreshTokenSession(_ context.Context, signature string, session fosite.Session) (fosite.Requester, error) {
+	return s.findSessionBySignature(signature, session, sqlTableRefresh)
+}
+
+func (s *FositeBoltStore) DeleteRefreshTokenSession(_ context.Context, signature string) error {
+	return s.deleteSession(signature, sqlTableRefresh)
+}
+
+func (s *FositeBoltStore) RotateRefreshTokenSession(_ context.Context, signature string, rotatedAt time.Time) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		var d boltRefreshTokenData
+		if err := boltGet(tx, sqlTableRefresh, signature, &d); err != nil {
+			return err
+		} else if !d.Active {
+			return errors.WithStack(ErrRefreshTokenRotated)
+		}
+
+		rotatedAt = rotatedAt.UTC()
+		d.Active = false
+		d.RotatedAt = &rotatedAt
+		return pkg.BoltPut(tx, boltBucket(sqlTableRefresh), signature, &d)
+	})
+}
+
+func (s *FositeBoltStore) GetRotatedRefreshTokenSession(_ context.Context, signature string, session fosite.Session) (fosite.Requester, time.Time, error) {
+	var d boltRefreshTokenData
+	if err := s.DB.View(func(tx *bolt.Tx) error {
+		return boltGet(tx, sqlTableRefresh, signature, &d)
+	}); err != nil {
+		return nil, time.Time{}, err
+	} else if d.Active || d.RotatedAt == nil {
+		return nil, time.Time{}, errors.Wrap(fosite.ErrNotFound, "")
+	}
+
+	r, err := d.toRequest(session, s.Manager, s.L)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return r, *d.RotatedAt, nil
+}
+
+func (s *FositeBoltStore) CreatePKCERequestSession(_ context.Context, signature string, requester fosite.Requester) error {
+	return s.createSession(signature, requester, sqlTablePKCE)
+}
+
+func (s *FositeBoltStore) GetPKCERequestSession(_ context.Context, signature string, session fosite.Session) (fosite.Requester, error) {
+	return s.findSessionBySignature(signature, session, sqlTablePKCE)
+}
+
+func (s *FositeBoltStore) DeletePKCERequestSession(_ context.Context, signature string) error {
+	return s.deleteSession(signature, sqlTablePKCE)
+}
+
+func (s *FositeBoltStore) CreateImplicitAccessTokenSession(ctx context.Context, signature string, requester fosite.Requester) error {
+	return s.CreateAccessTokenSession(ctx, signature, requester)
+}
+
+func (s *FositeBoltStore) RevokeRefreshToken(ctx context.Context, id string) error {
+	return s.revokeSession(id, sqlTableRefresh)
+}
+
+func (s *FositeBoltStore) RevokeAccessToken(ctx context.Context, id string) error {
+	return s.revokeSession(id, sqlTableAccess)
+}
+
+func (s *FositeBoltStore) revokeSession(id string, table string) error {
+	return s.deleteSessions(table, func(d *sqlDeviceData) bool {
+		return d.Request == id
+	})
+}
+
+func (s *FositeBoltStore) RevokeSubjectTokens(ctx context.Context, subject string, client string) error {
+	for _, table := range []string{sqlTableAccess, sqlTableRefresh, sqlTableCode, sqlTableOpenID, sqlTablePKCE, sqlTableDevice} {
+		if err := s.deleteSessions(table, func(d *sqlDeviceData) bool {
+			return d.Subject == subject && (client == "" || d.Client == client)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FositeBoltStore) FlushInactiveAccessTokens(ctx context.Context, notAfter time.Time) error {
+	lifespan := time.Now().Add(-s.AccessTokenLifespan)
+	if err := s.deleteSessions(sqlTableAccess, func(d *sqlDeviceData) bool {
+		return d.RequestedAt.Before(lifespan) && d.RequestedAt.Before(notAfter)
+	}); err != nil {
+		return err
+	}
+
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		var rotated []string
+		if err := pkg.BoltForEach(tx, boltBucket(sqlTableRefresh), func(signature string, raw []byte) error {
+			var d boltRefreshTokenData
+			if err := json.Unmarshal(raw, &d); err != nil {
+				return errors.WithStack(err)
+			}
+
+			if d.RotatedAt != nil && d.RotatedAt.Before(lifespan) && d.RotatedAt.Before(notAfter) {
+				rotated = append(rotated, signature)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		return pkg.BoltDelete(tx, boltBucket(sqlTableRefresh), rotated...)
+	})
+}
+
+// deleteSessions removes all sessions of the table matching the filter. Sessions are decoded as device codes, which
+// is a superset of the other sessions, so that the user code index is cleaned up as well.
+func (s *FositeBoltStore) deleteSessions(table string, filter func(d *sqlDeviceData) bool) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		var signatures, userCodes []string
+		if err := pkg.BoltForEach(tx, boltBucket(table), func(signature string, raw []byte) error {
+			var d sqlDeviceData
+			if err := json.Unmarshal(raw, &d); err != nil {
+				return errors.WithStack(err)
+			}
+
+			if filter(&d) {
+				signatures = append(signatures, signature)
+				userCodes = append(userCodes, d.UserCode)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if table == sqlTableDevice {
+			if err := pkg.BoltDelete(tx, boltBucketDeviceUserCode, userCodes...); err != nil {
+				return err
+			}
+		}
+		return pkg.BoltDelete(tx, boltBucket(table), signatures...)
+	})
+}
+
+func (s *FositeBoltStore) SetClientAssertionJWT(ctx context.Context, jti string, exp time.Time) error {
+	now := time.Now().UTC()
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		var expired []string
+		if err := pkg.BoltForEach(tx, boltBucket(sqlTableJTI), func(signature string, raw []byte) error {
+			var expiresAt time.Time
+			if err := json.Unmarshal(raw, &expiresAt); err != nil {
+				return errors.WithStack(err)
+			}
+
+			if expiresAt.Before(now) {
+				expired = append(expired, signature)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if err := pkg.BoltDelete(tx, boltBucket(sqlTableJTI), expired...); err != nil {
+			return err
+		}
+
+		signature := jtiSignature(jti)
+		if err := boltGet(tx, sqlTableJTI, signature, new(time.Time)); err == nil {
+			return errors.WithStack(ErrJTIKnown)
+		} else if errors.Cause(err) != fosite.ErrNotFound {
+			return err
+		}
+
+		return pkg.BoltPut(tx, boltBucket(sqlTableJTI), signature, exp.UTC())
+	})
+}
+
+func (s *FositeBoltStore) CreateDeviceCodeSession(_ context.Context, req *DeviceRequest) error {
+	now := time.Now().UTC()
+	if err := s.deleteSessions(sqlTableDevice, func(d *sqlDeviceData) bool {
+		return d.ExpiresAt.Before(now)
+	}); err != nil {
+		return err
+	}
+
+	data, err := sqlSchemaFromRequest(req.Signature, req.Requester, s.L)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		var signature string
+		if err := pkg.BoltGet(tx, boltBucketDeviceUserCode, req.UserCode, &signature); err == nil {
+			return errors.WithStack(ErrUserCodeKnown)
+		} else if errors.Cause(err) != pkg.ErrNotFound {
+			return err
+		}
+
+		if err := pkg.BoltPut(tx, boltBucketDeviceUserCode, req.UserCode, req.Signature); err != nil {
+			return err
+		}
+
+		return pkg.BoltPut(tx, boltBucket(sqlTableDevice), req.Signature, &sqlDeviceData{
+			sqlData:      *data,
+			UserCode:     req.UserCode,
+			Status:       req.Status,
+			ExpiresAt:    req.ExpiresAt.UTC(),
+			LastPolledAt: req.LastPolledAt.UTC(),
+		})
+	})
+}
+
+func (s *FositeBoltStore) GetDeviceCodeSession(_ context.Context, signature string, session fosite.Session) (*DeviceRequest, error) {
+	var d sqlDeviceData
+	if err := s.DB.View(func(tx *bolt.Tx) error {
+		return boltGet(tx, sqlTableDevice, signature, &d)
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.toDeviceRequest(&d, session)
+}
+
+func (s *FositeBoltStore) GetDeviceCodeSessionByUserCode(_ context.Context, userCode string, session fosite.Session) (*DeviceRequest, error) {
+	var d sqlDeviceData
+	if err := s.DB.View(func(tx *bolt.Tx) error {
+		var signature string
+		if err := pkg.BoltGet(tx, boltBucketDeviceUserCode, userCode, &signature); errors.Cause(err) == pkg.ErrNotFound {
+			return errors.Wrap(fosite.ErrNotFound, "")
+		} else if err != nil {
+			return err
+		}
+
+		return boltGet(tx, sqlTableDevice, signature, &d)
+	}); err != nil {
+		return nil, err
+	}
+
+	return s.toDeviceRequest(&d, session)
+}
+
+func (s *FositeBoltStore) toDeviceRequest(d *sqlDeviceData, session fosite.Session) (*DeviceRequest, error) {
+	r, err := d.toRequest(session, s.Manager, s.L)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeviceRequest{
+		Requester:    r,
+		Signature:    d.Signature,
+		UserCode:     d.UserCode,
+		Status:       d.Status,
+		ExpiresAt:    d.ExpiresAt,
+		LastPolledAt: d.LastPolledAt,
+	}, nil
+}
+
+func (s *FositeBoltStore) UpdateDeviceCodeSession(_ context.Context, req *DeviceRequest, status string) error {
+	data, err := sqlSchemaFromRequest(req.Signature, req.Requester, s.L)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		var d sqlDeviceData
+		if err := boltGet(tx, sqlTableDevice, req.Signature, &d); err != nil {
+			return err
+		} else if d.Status != status {
+			return errors.WithStack(ErrDeviceStatusChanged)
+		}
+
+		d.GrantedScopes = data.GrantedScopes
+		d.Session = data.Session
+		d.Subject = data.Subject
+		d.Status = req.Status
+		d.LastPolledAt = req.LastPolledAt.UTC()
+		return pkg.BoltPut(tx, boltBucket(sqlTableDevice), req.Signature, &d)
+	})
+}
+
+func (s *FositeBoltStore) DeleteDeviceCodeSession(_ context.Context, signature string) error {
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		var d sqlDeviceData
+		if err := boltGet(tx, sqlTableDevice, signature, &d); err != nil {
+			return err
+		}
+
+		if err := pkg.BoltDelete(tx, boltBucketDeviceUserCode, d.UserCode); err != nil {
+			return err
+		}
+		return pkg.BoltDelete(tx, boltBucket(sqlTableDevice), signature)
+	})
+}
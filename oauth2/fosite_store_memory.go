@@ -32,25 +32,31 @@ import (
 
 func NewFositeMemoryStore(m client.Manager, ls time.Duration) *FositeMemoryStore {
 	return &FositeMemoryStore{
-		AuthorizeCodes:      make(map[string]authorizeCode),
-		IDSessions:          make(map[string]fosite.Requester),
-		AccessTokens:        make(map[string]fosite.Requester),
-		PKCES:               make(map[string]fosite.Requester),
-		RefreshTokens:       make(map[string]fosite.Requester),
-		AccessTokenLifespan: ls,
-		Manager:             m,
+		AuthorizeCodes:       make(map[string]authorizeCode),
+		IDSessions:           make(map[string]fosite.Requester),
+		AccessTokens:         make(map[string]fosite.Requester),
+		PKCES:                make(map[string]fosite.Requester),
+		RefreshTokens:        make(map[string]fosite.Requester),
+		RotatedRefreshTokens: make(map[string]time.Time),
+		BlacklistedJTIs:      make(map[string]time.Time),
+		DeviceCodes:          make(map[string]*DeviceRequest),
+		AccessTokenLifespan:  ls,
+		Manager:              m,
 	}
 }
 
 type FositeMemoryStore struct {
 	client.Manager
 
-	AuthorizeCodes      map[string]authorizeCode
-	IDSessions          map[string]fosite.Requester
-	AccessTokens        map[string]fosite.Requester
-	RefreshTokens       map[string]fosite.Requester
-	PKCES               map[string]fosite.Requester
-	AccessTokenLifespan time.Duration
+	AuthorizeCodes       map[string]authorizeCode
+	IDSessions           map[string]fosite.Requester
+	AccessTokens         map[string]fosite.Requester
+	RefreshTokens        map[string]fosite.Requester
+	RotatedRefreshTokens map[string]time.Time
+	PKCES                map[string]fosite.Requester
+	BlacklistedJTIs      map[string]time.Time
+	DeviceCodes          map[string]*DeviceRequest
+	AccessTokenLifespan  time.Duration
 
 	sync.RWMutex
 }
@@ -161,6 +167,8 @@ func (s *FositeMemoryStore) GetRefreshTokenSession(_ context.Context, signature
 	rel, ok := s.RefreshTokens[signature]
 	if !ok {
 		return nil, errors.Wrap(fosite.ErrNotFound, "")
+	} else if _, rotated := s.RotatedRefreshTokens[signature]; rotated {
+		return nil, errors.WithStack(fosite.ErrInactiveToken)
 	}
 	return rel, nil
 }
@@ -173,9 +181,39 @@ func (s *FositeMemoryStore) DeleteRefreshTokenSession(ctx context.Context, signa
 
 func (s *FositeMemoryStore) deleteRefreshTokenSession(_ context.Context, signature string) error {
 	delete(s.RefreshTokens, signature)
+	delete(s.RotatedRefreshTokens, signature)
 	return nil
 }
 
+func (s *FositeMemoryStore) RotateRefreshTokenSession(_ context.Context, signature string, rotatedAt time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.RefreshTokens[signature]; !ok {
+		return errors.Wrap(fosite.ErrNotFound, "")
+	} else if _, rotated := s.RotatedRefreshTokens[signature]; rotated {
+		return errors.WithStack(ErrRefreshTokenRotated)
+	}
+	s.RotatedRefreshTokens[signature] = rotatedAt.UTC()
+	return nil
+}
+
+func (s *FositeMemoryStore) GetRotatedRefreshTokenSession(_ context.Context, signature string, _ fosite.Session) (fosite.Requester, time.Time, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	rel, ok := s.RefreshTokens[signature]
+	if !ok {
+		return nil, time.Time{}, errors.Wrap(fosite.ErrNotFound, "")
+	}
+
+	rotatedAt, ok := s.RotatedRefreshTokens[signature]
+	if !ok {
+		return nil, time.Time{}, errors.Wrap(fosite.ErrNotFound, "")
+	}
+	return rel, rotatedAt, nil
+}
+
 func (s *FositeMemoryStore) CreateImplicitAccessTokenSession(ctx context.Context, code string, req fosite.Requester) error {
 	return s.CreateAccessTokenSession(ctx, code, req)
 }
@@ -216,6 +254,51 @@ func (s *FositeMemoryStore) RevokeAccessToken(ctx context.Context, id string) er
 	return nil
 }
 
+func (s *FositeMemoryStore) RevokeSubjectTokens(ctx context.Context, subject string, client string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	matches := func(r fosite.Requester) bool {
+		if r.GetSession() == nil || r.GetSession().GetSubject() != subject {
+			return false
+		}
+		return client == "" || r.GetClient().GetID() == client
+	}
+
+	for sig, r := range s.AuthorizeCodes {
+		if matches(r.Requester) {
+			delete(s.AuthorizeCodes, sig)
+		}
+	}
+	for sig, r := range s.IDSessions {
+		if matches(r) {
+			delete(s.IDSessions, sig)
+		}
+	}
+	for sig, r := range s.AccessTokens {
+		if matches(r) {
+			delete(s.AccessTokens, sig)
+		}
+	}
+	for sig, r := range s.RefreshTokens {
+		if matches(r) {
+			delete(s.RefreshTokens, sig)
+			delete(s.RotatedRefreshTokens, sig)
+		}
+	}
+	for sig, r := range s.PKCES {
+		if matches(r) {
+			delete(s.PKCES, sig)
+		}
+	}
+	for sig, r := range s.DeviceCodes {
+		if matches(r) {
+			delete(s.DeviceCodes, sig)
+		}
+	}
+	return nil
+}
+
 func (s *FositeMemoryStore) FlushInactiveAccessTokens(ctx context.Context, notAfter time.Time) error {
 	s.Lock()
 	defer s.Unlock()
@@ -233,6 +316,14 @@ func (s *FositeMemoryStore) FlushInactiveAccessTokens(ctx context.Context, notAf
 		}
 	}
 
+	for sig, rotatedAt := range s.RotatedRefreshTokens {
+		if rotatedAt.Add(s.AccessTokenLifespan).Before(now) && rotatedAt.Before(notAfter) {
+			if err := s.deleteRefreshTokenSession(ctx, sig); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -253,3 +344,99 @@ func (s *FositeMemoryStore) DeletePKCERequestSession(_ context.Context, code str
 	delete(s.PKCES, code)
 	return nil
 }
+
+func (s *FositeMemoryStore) SetClientAssertionJWT(_ context.Context, jti string, exp time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now().UTC()
+	for j, e := range s.BlacklistedJTIs {
+		if e.Before(now) {
+			delete(s.BlacklistedJTIs, j)
+		}
+	}
+
+	signature := jtiSignature(jti)
+	if _, ok := s.BlacklistedJTIs[signature]; ok {
+		return errors.WithStack(ErrJTIKnown)
+	}
+
+	s.BlacklistedJTIs[signature] = exp.UTC()
+	return nil
+}
+
+func (s *FositeMemoryStore) CreateDeviceCodeSession(_ context.Context, req *DeviceRequest) error {
+	s.Lock()
+	defer s.Unlock()
+
+	now := time.Now().UTC()
+	for signature, dr := range s.DeviceCodes {
+		if dr.ExpiresAt.Before(now) {
+			delete(s.DeviceCodes, signature)
+		} else if dr.UserCode == req.UserCode {
+			return errors.WithStack(ErrUserCodeKnown)
+		}
+	}
+
+	s.DeviceCodes[req.Signature] = copyDeviceRequest(req)
+	return nil
+}
+
+func (s *FositeMemoryStore) GetDeviceCodeSession(_ context.Context, signature string, _ fosite.Session) (*DeviceRequest, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	dr, ok := s.DeviceCodes[signature]
+	if !ok {
+		return nil, errors.Wrap(fosite.ErrNotFound, "")
+	}
+	return copyDeviceRequest(dr), nil
+}
+
+func (s *FositeMemoryStore) GetDeviceCodeSessionByUserCode(_ context.Context, userCode string, _ fosite.Session) (*DeviceRequest, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, dr := range s.DeviceCodes {
+		if dr.UserCode == userCode {
+			return copyDeviceRequest(dr), nil
+		}
+	}
+	return nil, errors.Wrap(fosite.ErrNotFound, "")
+}
+
+func (s *FositeMemoryStore) UpdateDeviceCodeSession(_ context.Context, req *DeviceRequest, status string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if dr, ok := s.DeviceCodes[req.Signature]; !ok {
+		return errors.Wrap(fosite.ErrNotFound, "")
+	} else if dr.Status != status {
+		return errors.WithStack(ErrDeviceStatusChanged)
+	}
+	s.DeviceCodes[req.Signature] = copyDeviceRequest(req)
+	return nil
+}
+
+func (s *FositeMemoryStore) DeleteDeviceCodeSession(_ context.Context, signature string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.DeviceCodes[signature]; !ok {
+		return errors.Wrap(fosite.ErrNotFound, "")
+	}
+	delete(s.DeviceCodes, signature)
+	return nil
+}
+
+// copyDeviceRequest copies a device authorization request so that callers can modify it without holding the lock of
+// the store.
+func copyDeviceRequest(dr *DeviceRequest) *DeviceRequest {
+	c := *dr
+	if r, ok := dr.Requester.(*fosite.Request); ok {
+		rc := *r
+		rc.GrantedScopes = append(fosite.Arguments{}, r.GrantedScopes...)
+		c.Requester = &rc
+	}
+	return &c
+}
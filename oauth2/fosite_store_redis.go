@@ -0,0 +1,209 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/pkg"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// FositeRedisStore keeps authorize codes and PKCE sessions, which are only needed until the authorize code has been
+// exchanged, in Redis and lets them expire after the lifespan of authorize codes. All other sessions are stored by the
+// wrapped persistent store. Values are stored in the same format as the rows of the FositeSQLStore, the keys of the
+// sessions of a subject are indexed in a set which expires with them.
+type FositeRedisStore struct {
+	pkg.FositeStorer
+	Clients               client.Manager
+	Redis                 *redis.Client
+	L                     logrus.FieldLogger
+	AuthorizeCodeLifespan time.Duration
+}
+
+func NewFositeRedisStore(s pkg.FositeStorer,
+	clients client.Manager,
+	r *redis.Client,
+	l logrus.FieldLogger,
+	authorizeCodeLifespan time.Duration,
+) *FositeRedisStore {
+	return &FositeRedisStore{
+		FositeStorer:          s,
+		Clients:               clients,
+		Redis:                 r,
+		L:                     l,
+		AuthorizeCodeLifespan: authorizeCodeLifespan,
+	}
+}
+
+// redisSubjectIndex is the table name of the sets indexing the sessions of a subject.
+const redisSubjectIndex = "subject"
+
+func redisKey(table, signature string) string {
+	return "hydra_oauth2_" + table + ":" + signature
+}
+
+func (s *FositeRedisStore) createSession(signature string, requester fosite.Requester, table string) error {
+	data, err := sqlSchemaFromRequest(signature, requester, s.L)
+	if err != nil {
+		return err
+	}
+
+	if err := pkg.RedisSet(s.Redis, redisKey(table, signature), data, s.AuthorizeCodeLifespan); err != nil {
+		return err
+	}
+	return pkg.RedisIndex(s.Redis, redisKey(redisSubjectIndex, data.Subject), redisKey(table, signature), s.AuthorizeCodeLifespan)
+}
+
+func (s *FositeRedisStore) findSessionBySignature(signature string, session fosite.Session, table string) (fosite.Requester, error) {
+	var d sqlData
+	if err := pkg.RedisGet(s.Redis, redisKey(table, signature), &d); errors.Cause(err) == pkg.ErrNotFound {
+		return nil, errors.Wrap(fosite.ErrNotFound, "")
+	} else if err != nil {
+		return nil, err
+	} else if !d.Active && table == sqlTableCode {
+		if r, err := d.toRequest(session, s.Clients, s.L); err != nil {
+			return nil, err
+		} else {
+			return r, errors.WithStack(fosite.ErrInvalidatedAuthorizeCode)
+		}
+	} else if !d.Active {
+		return nil, errors.WithStack(fosite.ErrInactiveToken)
+	}
+
+	return d.toRequest(session, s.Clients, s.L)
+}
+
+func (s *FositeRedisStore) deleteSession(signature string, table string) error {
+	return errors.WithStack(s.Redis.Del(redisKey(table, signature)).Err())
+}
+
+func (s *FositeRedisStore) CreateAuthorizeCodeSession(_ context.Context, signature string, requester fosite.Requester) error {
+	return s.createSession(signature, requester, sqlTableCode)
+}
+
+func (s *FositeRedisStore) GetAuthorizeCodeSession(_ context.Context, signature string, session fosite.Session) (fosite.Requester, error) {
+	return s.findSessionBySignature(signature, session, sqlTableCode)
+}
+
+// InvalidateAuthorizeCodeSession keeps the authorize code until it expires, so that its reuse is detected.
+func (s *FositeRedisStore) InvalidateAuthorizeCodeSession(_ context.Context, signature string) error {
+	var d sqlData
+	if err := pkg.RedisUpdate(s.Redis, redisKey(sqlTableCode, signature), &d, func() error {
+		d.Active = false
+		return nil
+	}); errors.Cause(err) != pkg.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+func (s *FositeRedisStore) CreatePKCERequestSession(_ context.Context, signature string, requester fosite.Requester) error {
+	return s.createSession(signature, requester, sqlTablePKCE)
+}
+
+func (s *FositeRedisStore) GetPKCERequestSession(_ context.Context, signature string, session fosite.Session) (fosite.Requester, error) {
+	return s.findSessionBySignature(signature, session, sqlTablePKCE)
+}
+
+func (s *FositeRedisStore) DeletePKCERequestSession(_ context.Context, signature string) error {
+	return s.deleteSession(signature, sqlTablePKCE)
+}
+
+// fositeRedisCapabilities is the store returned by FositeRedisStore.WithCapabilities.
+type fositeRedisCapabilities struct {
+	*FositeRedisStore
+	fositeRedisRotation
+	DeviceCodeStorage
+	fositeRedisRevoker
+}
+
+// WithCapabilities returns the store together with the refresh token rotation, device code and subject token
+// revocation storage of the persistent store. It fails if the persistent store does not implement
+// RefreshTokenRotationStorage, DeviceCodeStorage and pkg.SubjectTokenRevoker.
+func (s *FositeRedisStore) WithCapabilities() (pkg.FositeStorer, error) {
+	rs, ok := s.FositeStorer.(RefreshTokenRotationStorage)
+	if !ok {
+		return nil, errors.New("The persistent OAuth 2.0 storage does not support refresh token rotation")
+	}
+
+	ds, ok := s.FositeStorer.(DeviceCodeStorage)
+	if !ok {
+		return nil, errors.New("The persistent OAuth 2.0 storage does not support device codes")
+	}
+
+	sr, ok := s.FositeStorer.(pkg.SubjectTokenRevoker)
+	if !ok {
+		return nil, errors.New("The persistent OAuth 2.0 storage does not support revoking the tokens of a user")
+	}
+
+	return &fositeRedisCapabilities{
+		FositeRedisStore:    s,
+		fositeRedisRotation: fositeRedisRotation{storage: rs},
+		DeviceCodeStorage:   ds,
+		fositeRedisRevoker:  fositeRedisRevoker{store: s, revoker: sr},
+	}, nil
+}
+
+// fositeRedisRotation only exposes the methods of RefreshTokenRotationStorage which are not part of pkg.FositeStorer,
+// because embedding the whole interface would make the token revocation methods ambiguous.
+type fositeRedisRotation struct {
+	storage RefreshTokenRotationStorage
+}
+
+func (r fositeRedisRotation) RotateRefreshTokenSession(ctx context.Context, signature string, rotatedAt time.Time) error {
+	return r.storage.RotateRefreshTokenSession(ctx, signature, rotatedAt)
+}
+
+func (r fositeRedisRotation) GetRotatedRefreshTokenSession(ctx context.Context, signature string, session fosite.Session) (fosite.Requester, time.Time, error) {
+	return r.storage.GetRotatedRefreshTokenSession(ctx, signature, session)
+}
+
+type fositeRedisRevoker struct {
+	store   *FositeRedisStore
+	revoker pkg.SubjectTokenRevoker
+}
+
+// RevokeSubjectTokens revokes the tokens in the persistent store and deletes the authorize codes and PKCE sessions
+// of the subject from Redis.
+func (r fositeRedisRevoker) RevokeSubjectTokens(ctx context.Context, subject string, client string) error {
+	if err := r.revoker.RevokeSubjectTokens(ctx, subject, client); err != nil {
+		return err
+	}
+
+	return pkg.RedisDeleteIndexed(r.store.Redis, redisKey(redisSubjectIndex, subject), func(key string, raw []byte) ([]string, error) {
+		var d sqlData
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		if client != "" && d.Client != client {
+			return nil, nil
+		}
+		return []string{key}, nil
+	})
+}
@@ -32,6 +32,8 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/ory/fosite"
 	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/pkg"
+	"github.com/ory/sqlcon"
 	"github.com/pkg/errors"
 	"github.com/rubenv/sql-migrate"
 	"github.com/sirupsen/logrus"
@@ -82,6 +84,26 @@ func sqlSchemaUp(table string, id string) string {
 	subject 		varchar(255) NOT NULL
 )`,
 		"4": fmt.Sprintf("ALTER TABLE hydra_oauth2_%s ADD active BOOL NOT NULL DEFAULT TRUE", table),
+		"5": `CREATE TABLE IF NOT EXISTS hydra_oauth2_jti_blacklist (
+	signature      	varchar(64) NOT NULL PRIMARY KEY,
+	expires_at  	timestamp NOT NULL DEFAULT now()
+)`,
+		"6": `CREATE TABLE IF NOT EXISTS hydra_oauth2_device_code (
+	signature      	varchar(64) NOT NULL PRIMARY KEY,
+	request_id  	varchar(255) NOT NULL,
+	requested_at  	timestamp NOT NULL DEFAULT now(),
+	client_id  		text NOT NULL,
+	scope  			text NOT NULL,
+	granted_scope 	text NOT NULL,
+	form_data  		text NOT NULL,
+	session_data  	text NOT NULL,
+	subject 		varchar(255) NOT NULL,
+	user_code 		varchar(32) NOT NULL UNIQUE,
+	status 			varchar(32) NOT NULL,
+	expires_at  	timestamp NOT NULL DEFAULT now(),
+	last_polled_at 	timestamp NOT NULL DEFAULT now()
+)`,
+		"7": fmt.Sprintf("ALTER TABLE hydra_oauth2_%s ADD rotated_at timestamp NULL", table),
 	}
 
 	return schemas[id]
@@ -93,6 +115,9 @@ func sqlSchemaDown(table string, id string) string {
 		"2": fmt.Sprintf("ALTER TABLE hydra_oauth2_%s DROP COLUMN subject", table),
 		"3": "DROP TABLE hydra_oauth2_pkce",
 		"4": fmt.Sprintf("ALTER TABLE hydra_oauth2_%s DROP COLUMN active", table),
+		"5": "DROP TABLE hydra_oauth2_jti_blacklist",
+		"6": "DROP TABLE hydra_oauth2_device_code",
+		"7": fmt.Sprintf("ALTER TABLE hydra_oauth2_%s DROP COLUMN rotated_at", table),
 	}
 
 	return schemas[id]
@@ -104,6 +129,8 @@ const (
 	sqlTableRefresh = "refresh"
 	sqlTableCode    = "code"
 	sqlTablePKCE    = "pkce"
+	sqlTableJTI     = "jti_blacklist"
+	sqlTableDevice  = "device_code"
 )
 
 var migrations = &migrate.MemoryMigrationSource{
@@ -164,6 +191,33 @@ var migrations = &migrate.MemoryMigrationSource{
 				sqlSchemaDown(sqlTablePKCE, "4"),
 			},
 		},
+		{
+			Id: "5",
+			Up: []string{
+				sqlSchemaUp(sqlTableJTI, "5"),
+			},
+			Down: []string{
+				sqlSchemaDown(sqlTableJTI, "5"),
+			},
+		},
+		{
+			Id: "6",
+			Up: []string{
+				sqlSchemaUp(sqlTableDevice, "6"),
+			},
+			Down: []string{
+				sqlSchemaDown(sqlTableDevice, "6"),
+			},
+		},
+		{
+			Id: "7",
+			Up: []string{
+				sqlSchemaUp(sqlTableRefresh, "7"),
+			},
+			Down: []string{
+				sqlSchemaDown(sqlTableRefresh, "7"),
+			},
+		},
 	},
 }
 
@@ -193,6 +247,30 @@ type sqlData struct {
 	Session       []byte    `db:"session_data"`
 }
 
+var sqlDeviceParams = []string{
+	"signature",
+	"request_id",
+	"requested_at",
+	"client_id",
+	"scope",
+	"granted_scope",
+	"form_data",
+	"session_data",
+	"subject",
+	"user_code",
+	"status",
+	"expires_at",
+	"last_polled_at",
+}
+
+type sqlDeviceData struct {
+	sqlData
+	UserCode     string    `db:"user_code"`
+	Status       string    `db:"status"`
+	ExpiresAt    time.Time `db:"expires_at"`
+	LastPolledAt time.Time `db:"last_polled_at"`
+}
+
 func sqlSchemaFromRequest(signature string, r fosite.Requester, logger logrus.FieldLogger) (*sqlData, error) {
 	subject := ""
 	if r.GetSession() == nil {
@@ -272,7 +350,7 @@ func (s *FositeSQLStore) createSession(signature string, requester fosite.Reques
 
 func (s *FositeSQLStore) findSessionBySignature(signature string, session fosite.Session, table string) (fosite.Requester, error) {
 	var d sqlData
-	if err := s.DB.Get(&d, s.DB.Rebind(fmt.Sprintf("SELECT * FROM hydra_oauth2_%s WHERE signature=?", table)), signature); err == sql.ErrNoRows {
+	if err := s.DB.Get(&d, s.DB.Rebind(fmt.Sprintf("SELECT %s FROM hydra_oauth2_%s WHERE signature=?", strings.Join(sqlParams, ", "), table)), signature); err == sql.ErrNoRows {
 		return nil, errors.Wrap(fosite.ErrNotFound, "")
 	} else if err != nil {
 		return nil, errors.WithStack(err)
@@ -298,7 +376,7 @@ func (s *FositeSQLStore) deleteSession(signature string, table string) error {
 
 func (s *FositeSQLStore) CreateSchemas() (int, error) {
 	migrate.SetTable("hydra_oauth2_migration")
-	n, err := migrate.Exec(s.DB.DB, s.DB.DriverName(), migrations, migrate.Up)
+	n, err := migrate.Exec(s.DB.DB, s.DB.DriverName(), pkg.SQLMigrations(migrations, s.DB.DriverName(), nil), migrate.Up)
 	if err != nil {
 		return 0, errors.Wrapf(err, "Could not migrate sql schema, applied %d migrations", n)
 	}
@@ -360,6 +438,52 @@ func (s *FositeSQLStore) DeleteRefreshTokenSession(_ context.Context, signature
 	return s.deleteSession(signature, sqlTableRefresh)
 }
 
+func (s *FositeSQLStore) RotateRefreshTokenSession(_ context.Context, signature string, rotatedAt time.Time) error {
+	result, err := s.DB.Exec(s.DB.Rebind(fmt.Sprintf(
+		"UPDATE hydra_oauth2_%s SET active=false, rotated_at=? WHERE signature=? AND active=true",
+		sqlTableRefresh,
+	)), rotatedAt.UTC(), signature)
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	if count, err := result.RowsAffected(); err != nil {
+		return errors.WithStack(err)
+	} else if count > 0 {
+		return nil
+	}
+
+	var active bool
+	if err := s.DB.Get(&active, s.DB.Rebind(fmt.Sprintf("SELECT active FROM hydra_oauth2_%s WHERE signature=?", sqlTableRefresh)), signature); err == sql.ErrNoRows {
+		return errors.Wrap(fosite.ErrNotFound, "")
+	} else if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	return errors.WithStack(ErrRefreshTokenRotated)
+}
+
+func (s *FositeSQLStore) GetRotatedRefreshTokenSession(_ context.Context, signature string, session fosite.Session) (fosite.Requester, time.Time, error) {
+	var d struct {
+		sqlData
+		RotatedAt time.Time `db:"rotated_at"`
+	}
+	if err := s.DB.Get(&d, s.DB.Rebind(fmt.Sprintf(
+		"SELECT %s, rotated_at FROM hydra_oauth2_%s WHERE signature=? AND active=false AND rotated_at IS NOT NULL",
+		strings.Join(sqlParams, ", "),
+		sqlTableRefresh,
+	)), signature); err == sql.ErrNoRows {
+		return nil, time.Time{}, errors.Wrap(fosite.ErrNotFound, "")
+	} else if err != nil {
+		return nil, time.Time{}, errors.WithStack(err)
+	}
+
+	r, err := d.toRequest(session, s.Manager, s.L)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return r, d.RotatedAt, nil
+}
+
 func (s *FositeSQLStore) CreatePKCERequestSession(_ context.Context, signature string, requester fosite.Requester) error {
 	return s.createSession(signature, requester, sqlTablePKCE)
 }
@@ -393,12 +517,184 @@ func (s *FositeSQLStore) revokeSession(id string, table string) error {
 	return nil
 }
 
+func (s *FositeSQLStore) RevokeSubjectTokens(ctx context.Context, subject string, client string) error {
+	tx, err := s.DB.Beginx()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	for _, table := range []string{sqlTableAccess, sqlTableRefresh, sqlTableCode, sqlTableOpenID, sqlTablePKCE, sqlTableDevice} {
+		query := fmt.Sprintf("DELETE FROM hydra_oauth2_%s WHERE subject=?", table)
+		args := []interface{}{subject}
+		if client != "" {
+			query += " AND client_id=?"
+			args = append(args, client)
+		}
+
+		if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+			if re := tx.Rollback(); re != nil {
+				return errors.Wrap(err, re.Error())
+			}
+			return sqlcon.HandleError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		if re := tx.Rollback(); re != nil {
+			return errors.Wrap(err, re.Error())
+		}
+		return sqlcon.HandleError(err)
+	}
+	return nil
+}
+
 func (s *FositeSQLStore) FlushInactiveAccessTokens(ctx context.Context, notAfter time.Time) error {
-	if _, err := s.DB.Exec(s.DB.Rebind(fmt.Sprintf("DELETE FROM hydra_oauth2_%s WHERE requested_at < ? AND requested_at < ?", sqlTableAccess)), time.Now().Add(-s.AccessTokenLifespan), notAfter); err == sql.ErrNoRows {
+	if _, err := s.DB.Exec(s.DB.Rebind(fmt.Sprintf("DELETE FROM hydra_oauth2_%s WHERE requested_at < ? AND requested_at < ?", sqlTableAccess)), time.Now().Add(-s.AccessTokenLifespan).UTC(), notAfter.UTC()); err == sql.ErrNoRows {
 		return errors.Wrap(fosite.ErrNotFound, "")
 	} else if err != nil {
 		return errors.WithStack(err)
 	}
 
+	if _, err := s.DB.Exec(s.DB.Rebind(fmt.Sprintf("DELETE FROM hydra_oauth2_%s WHERE rotated_at IS NOT NULL AND rotated_at < ? AND rotated_at < ?", sqlTableRefresh)), time.Now().Add(-s.AccessTokenLifespan).UTC(), notAfter.UTC()); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	return nil
+}
+
+func (s *FositeSQLStore) SetClientAssertionJWT(ctx context.Context, jti string, exp time.Time) error {
+	if _, err := s.DB.Exec(s.DB.Rebind("DELETE FROM hydra_oauth2_jti_blacklist WHERE expires_at < ?"), time.Now().UTC()); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	if _, err := s.DB.Exec(s.DB.Rebind("INSERT INTO hydra_oauth2_jti_blacklist (signature, expires_at) VALUES (?, ?)"), jtiSignature(jti), exp.UTC()); err != nil {
+		if err := pkg.HandleSQLError(err); errors.Cause(err) == sqlcon.ErrUniqueViolation {
+			return errors.WithStack(ErrJTIKnown)
+		} else {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *FositeSQLStore) CreateDeviceCodeSession(_ context.Context, req *DeviceRequest) error {
+	if _, err := s.DB.Exec(s.DB.Rebind("DELETE FROM hydra_oauth2_device_code WHERE expires_at < ?"), time.Now().UTC()); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	data, err := sqlSchemaFromRequest(req.Signature, req.Requester, s.L)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO hydra_oauth2_device_code (%s) VALUES (%s)",
+		strings.Join(sqlDeviceParams, ", "),
+		":"+strings.Join(sqlDeviceParams, ", :"),
+	)
+	if _, err := s.DB.NamedExec(query, &sqlDeviceData{
+		sqlData:      *data,
+		UserCode:     req.UserCode,
+		Status:       req.Status,
+		ExpiresAt:    req.ExpiresAt.UTC(),
+		LastPolledAt: req.LastPolledAt.UTC(),
+	}); err != nil {
+		if err := pkg.HandleSQLError(err); errors.Cause(err) == sqlcon.ErrUniqueViolation {
+			return errors.WithStack(ErrUserCodeKnown)
+		} else {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FositeSQLStore) GetDeviceCodeSession(_ context.Context, signature string, session fosite.Session) (*DeviceRequest, error) {
+	return s.findDeviceCodeSession("signature", signature, session)
+}
+
+func (s *FositeSQLStore) GetDeviceCodeSessionByUserCode(_ context.Context, userCode string, session fosite.Session) (*DeviceRequest, error) {
+	return s.findDeviceCodeSession("user_code", userCode, session)
+}
+
+func (s *FositeSQLStore) findDeviceCodeSession(column, value string, session fosite.Session) (*DeviceRequest, error) {
+	var d sqlDeviceData
+	if err := s.DB.Get(&d, s.DB.Rebind(fmt.Sprintf("SELECT * FROM hydra_oauth2_device_code WHERE %s=?", column)), value); err == sql.ErrNoRows {
+		return nil, errors.Wrap(fosite.ErrNotFound, "")
+	} else if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	r, err := d.toRequest(session, s.Manager, s.L)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeviceRequest{
+		Requester:    r,
+		Signature:    d.Signature,
+		UserCode:     d.UserCode,
+		Status:       d.Status,
+		ExpiresAt:    d.ExpiresAt,
+		LastPolledAt: d.LastPolledAt,
+	}, nil
+}
+
+func (s *FositeSQLStore) UpdateDeviceCodeSession(_ context.Context, req *DeviceRequest, status string) error {
+	data, err := sqlSchemaFromRequest(req.Signature, req.Requester, s.L)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.DB.NamedExec(`UPDATE hydra_oauth2_device_code SET
+	granted_scope=:granted_scope,
+	session_data=:session_data,
+	subject=:subject,
+	status=:status,
+	last_polled_at=:last_polled_at
+WHERE signature=:signature AND status=:previous_status`, &struct {
+		sqlDeviceData
+		PreviousStatus string `db:"previous_status"`
+	}{
+		sqlDeviceData: sqlDeviceData{
+			sqlData:      *data,
+			Status:       req.Status,
+			LastPolledAt: req.LastPolledAt.UTC(),
+		},
+		PreviousStatus: status,
+	})
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	if count, err := result.RowsAffected(); err != nil {
+		return errors.WithStack(err)
+	} else if count > 0 {
+		return nil
+	}
+
+	// MySQL does not count rows whose values did not change, so the status is checked again.
+	var current string
+	if err := s.DB.Get(&current, s.DB.Rebind("SELECT status FROM hydra_oauth2_device_code WHERE signature=?"), req.Signature); err == sql.ErrNoRows {
+		return errors.Wrap(fosite.ErrNotFound, "")
+	} else if err != nil {
+		return sqlcon.HandleError(err)
+	} else if current != status {
+		return errors.WithStack(ErrDeviceStatusChanged)
+	}
+	return nil
+}
+
+func (s *FositeSQLStore) DeleteDeviceCodeSession(_ context.Context, signature string) error {
+	result, err := s.DB.Exec(s.DB.Rebind("DELETE FROM hydra_oauth2_device_code WHERE signature=?"), signature)
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	if count, err := result.RowsAffected(); err != nil {
+		return errors.WithStack(err)
+	} else if count == 0 {
+		return errors.Wrap(fosite.ErrNotFound, "")
+	}
 	return nil
 }
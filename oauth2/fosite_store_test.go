@@ -21,6 +21,7 @@
 package oauth2_test
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -35,24 +36,36 @@ import (
 	. "github.com/ory/hydra/oauth2"
 	"github.com/ory/hydra/pkg"
 	"github.com/ory/sqlcon/dockertest"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var fositeStores = map[string]pkg.FositeStorer{}
 var clientManager = &client.MemoryManager{
-	Clients: []client.Client{{ID: "foobar"}},
+	Clients: []client.Client{{ID: "foobar"}, {ID: "barfoo"}},
 	Hasher:  &fosite.BCrypt{},
 }
 var databases = make(map[string]*sqlx.DB)
+var redisStore = NewFositeRedisStore(NewFositeMemoryStore(clientManager, time.Hour), clientManager, pkg.RedisTestDatabase(), logrus.New(), time.Hour)
 
 func init() {
 	fositeStores["memory"] = NewFositeMemoryStore(nil, time.Hour)
+	fositeStores["bolt"] = NewFositeBoltStore(clientManager, pkg.BoltTestDatabase(), logrus.New(), time.Hour)
+
+	s, err := redisStore.WithCapabilities()
+	if err != nil {
+		panic(err)
+	}
+	fositeStores["redis"] = s
 }
 
 func TestMain(m *testing.M) {
 	runner := dockertest.Register()
 
 	flag.Parse()
+	connectToSQLite()
 	if !testing.Short() {
 		dockertest.Parallel([]func(){
 			connectToPG,
@@ -60,7 +73,20 @@ func TestMain(m *testing.M) {
 		})
 	}
 
-	runner.Exit(m.Run())
+	code := m.Run()
+	pkg.CleanupTestDatabases()
+	runner.Exit(code)
+}
+
+func connectToSQLite() {
+	db := pkg.SQLiteTestDatabase()
+	s := &FositeSQLStore{DB: db, Manager: clientManager, L: logrus.New(), AccessTokenLifespan: time.Hour}
+	if _, err := s.CreateSchemas(); err != nil {
+		log.Fatalf("Could not create sqlite schema: %v", err)
+	}
+
+	databases["sqlite"] = db
+	fositeStores["sqlite"] = s
 }
 
 func connectToPG() {
@@ -128,6 +154,20 @@ func TestRevokeRefreshToken(t *testing.T) {
 	}
 }
 
+func TestRotateRefreshToken(t *testing.T) {
+	t.Parallel()
+	for k, m := range fositeStores {
+		t.Run(fmt.Sprintf("case=%s", k), TestHelperRotateRefreshToken(m.(RefreshTokenRotationStorage)))
+	}
+}
+
+func TestRevokeSubjectTokens(t *testing.T) {
+	t.Parallel()
+	for k, m := range fositeStores {
+		t.Run(fmt.Sprintf("case=%s", k), TestHelperRevokeSubjectTokens(m.(pkg.SubjectTokenRevoker)))
+	}
+}
+
 func TestPKCEReuqest(t *testing.T) {
 	t.Parallel()
 	for k, m := range fositeStores {
@@ -141,3 +181,57 @@ func TestFlushAccessTokens(t *testing.T) {
 		t.Run(fmt.Sprintf("case=%s", k), TestHelperFlushTokens(m, time.Hour))
 	}
 }
+
+func TestSetClientAssertionJWT(t *testing.T) {
+	t.Parallel()
+	for k, m := range fositeStores {
+		t.Run(fmt.Sprintf("case=%s", k), TestHelperSetClientAssertionJWT(m))
+	}
+}
+
+func TestDeviceCodeSession(t *testing.T) {
+	t.Parallel()
+	for k, m := range fositeStores {
+		t.Run(fmt.Sprintf("case=%s", k), TestHelperDeviceCodeSession(m.(DeviceCodeStorage)))
+	}
+}
+
+func TestFositeRedisStoreExpiresAuthorizeCodes(t *testing.T) {
+	server, r := pkg.RedisTestServer()
+	m := NewFositeRedisStore(NewFositeMemoryStore(clientManager, time.Hour), clientManager, r, logrus.New(), time.Minute)
+
+	ctx := context.Background()
+	req := &fosite.Request{
+		ID:          "expiring-code",
+		RequestedAt: time.Now().UTC().Round(time.Second),
+		Client:      &client.Client{ID: "foobar"},
+		Session:     &fosite.DefaultSession{Subject: "peter"},
+	}
+	require.NoError(t, m.CreateAuthorizeCodeSession(ctx, "expiring-code", req))
+	require.NoError(t, m.CreatePKCERequestSession(ctx, "expiring-code", req))
+
+	_, err := m.GetAuthorizeCodeSession(ctx, "expiring-code", &fosite.DefaultSession{})
+	require.NoError(t, err)
+
+	server.FastForward(time.Minute + time.Second)
+
+	_, err = m.GetAuthorizeCodeSession(ctx, "expiring-code", &fosite.DefaultSession{})
+	assert.Equal(t, fosite.ErrNotFound, errors.Cause(err))
+	_, err = m.GetPKCERequestSession(ctx, "expiring-code", &fosite.DefaultSession{})
+	assert.Equal(t, fosite.ErrNotFound, errors.Cause(err))
+}
+
+func TestFositeRedisStoreCapabilities(t *testing.T) {
+	m, err := redisStore.WithCapabilities()
+	require.NoError(t, err)
+	_, ok := m.(RefreshTokenRotationStorage)
+	assert.True(t, ok)
+	_, ok = m.(DeviceCodeStorage)
+	assert.True(t, ok)
+	_, ok = m.(pkg.SubjectTokenRevoker)
+	assert.True(t, ok)
+
+	// Only the methods of pkg.FositeStorer are promoted from the embedded interface.
+	_, err = NewFositeRedisStore(struct{ pkg.FositeStorer }{NewFositeMemoryStore(clientManager, time.Hour)}, clientManager, redisStore.Redis, logrus.New(), time.Hour).WithCapabilities()
+	assert.Error(t, err)
+}
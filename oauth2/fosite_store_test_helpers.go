@@ -30,6 +30,7 @@ import (
 	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/pkg"
 	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -113,6 +114,104 @@ func TestHelperRevokeRefreshToken(m pkg.FositeStorer) func(t *testing.T) {
 	}
 
 }
+func TestHelperRotateRefreshToken(m RefreshTokenRotationStorage) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		id := uuid.New()
+		rotatedAt := time.Now().UTC().Round(time.Second)
+
+		err := m.RotateRefreshTokenSession(ctx, "3333", rotatedAt)
+		assert.EqualError(t, errors.Cause(err), fosite.ErrNotFound.Error())
+
+		err = m.CreateRefreshTokenSession(ctx, "3333", &fosite.Request{ID: id, Client: &client.Client{ID: "foobar"}, RequestedAt: time.Now().UTC().Round(time.Second), Session: &fosite.DefaultSession{}})
+		require.NoError(t, err)
+
+		err = m.CreateRefreshTokenSession(ctx, "3344", &fosite.Request{ID: id, Client: &client.Client{ID: "foobar"}, RequestedAt: time.Now().UTC().Round(time.Second), Session: &fosite.DefaultSession{}})
+		require.NoError(t, err)
+
+		_, _, err = m.GetRotatedRefreshTokenSession(ctx, "3333", &fosite.DefaultSession{})
+		assert.EqualError(t, errors.Cause(err), fosite.ErrNotFound.Error())
+
+		require.NoError(t, m.RotateRefreshTokenSession(ctx, "3333", rotatedAt))
+		assert.Equal(t, ErrRefreshTokenRotated, errors.Cause(m.RotateRefreshTokenSession(ctx, "3333", rotatedAt.Add(time.Second))))
+
+		_, err = m.GetRefreshTokenSession(ctx, "3333", &fosite.DefaultSession{})
+		assert.EqualError(t, errors.Cause(err), fosite.ErrInactiveToken.Error())
+
+		res, at, err := m.GetRotatedRefreshTokenSession(ctx, "3333", &fosite.DefaultSession{})
+		require.NoError(t, err)
+		assert.Equal(t, id, res.GetID())
+		assert.Equal(t, rotatedAt.Unix(), at.Unix())
+
+		_, err = m.GetRefreshTokenSession(ctx, "3344", &fosite.DefaultSession{})
+		require.NoError(t, err)
+
+		require.NoError(t, m.RevokeRefreshToken(ctx, id))
+
+		_, _, err = m.GetRotatedRefreshTokenSession(ctx, "3333", &fosite.DefaultSession{})
+		assert.EqualError(t, errors.Cause(err), fosite.ErrNotFound.Error())
+
+		_, err = m.GetRefreshTokenSession(ctx, "3344", &fosite.DefaultSession{})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestHelperRevokeSubjectTokens(m pkg.SubjectTokenRevoker) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		fs := m.(pkg.FositeStorer)
+		requestOf := func(clientID, subject string) *fosite.Request {
+			return &fosite.Request{ID: uuid.New(), Client: &client.Client{ID: clientID}, RequestedAt: time.Now().UTC().Round(time.Second), Session: &fosite.DefaultSession{Subject: subject}}
+		}
+		request := func(subject string) *fosite.Request {
+			return requestOf("foobar", subject)
+		}
+		oidc := &fosite.Request{Session: &fosite.DefaultSession{}}
+
+		require.NoError(t, fs.CreateAccessTokenSession(ctx, "5511", request("alice")))
+		require.NoError(t, fs.CreateRefreshTokenSession(ctx, "5512", request("alice")))
+		require.NoError(t, fs.CreateAuthorizeCodeSession(ctx, "5513", request("alice")))
+		require.NoError(t, fs.CreateOpenIDConnectSession(ctx, "5514", request("alice")))
+		require.NoError(t, fs.CreateAccessTokenSession(ctx, "5516", request("bob")))
+		require.NoError(t, fs.CreateAccessTokenSession(ctx, "5517", requestOf("barfoo", "alice")))
+		require.NoError(t, fs.CreateRefreshTokenSession(ctx, "5518", requestOf("barfoo", "alice")))
+
+		require.NoError(t, m.RevokeSubjectTokens(ctx, "alice", "some-other-client"))
+
+		_, err := fs.GetOpenIDConnectSession(ctx, "5514", oidc)
+		require.NoError(t, err)
+		_, err = fs.GetAccessTokenSession(ctx, "5511", &fosite.DefaultSession{})
+		require.NoError(t, err)
+
+		require.NoError(t, m.RevokeSubjectTokens(ctx, "alice", "foobar"))
+
+		_, err = fs.GetOpenIDConnectSession(ctx, "5514", oidc)
+		assert.NotNil(t, err)
+		_, err = fs.GetAccessTokenSession(ctx, "5511", &fosite.DefaultSession{})
+		assert.NotNil(t, err)
+		_, err = fs.GetAccessTokenSession(ctx, "5517", &fosite.DefaultSession{})
+		require.NoError(t, err)
+		_, err = fs.GetRefreshTokenSession(ctx, "5518", &fosite.DefaultSession{})
+		require.NoError(t, err)
+
+		require.NoError(t, fs.CreateAccessTokenSession(ctx, "5515", request("alice")))
+		require.NoError(t, m.RevokeSubjectTokens(ctx, "alice", ""))
+
+		_, err = fs.GetAccessTokenSession(ctx, "5515", &fosite.DefaultSession{})
+		assert.NotNil(t, err)
+		_, err = fs.GetRefreshTokenSession(ctx, "5512", &fosite.DefaultSession{})
+		assert.NotNil(t, err)
+		_, err = fs.GetAuthorizeCodeSession(ctx, "5513", &fosite.DefaultSession{})
+		assert.NotNil(t, err)
+		_, err = fs.GetAccessTokenSession(ctx, "5517", &fosite.DefaultSession{})
+		assert.NotNil(t, err)
+		_, err = fs.GetRefreshTokenSession(ctx, "5518", &fosite.DefaultSession{})
+		assert.NotNil(t, err)
+		_, err = fs.GetAccessTokenSession(ctx, "5516", &fosite.DefaultSession{})
+		require.NoError(t, err)
+	}
+}
+
 func TestHelperCreateGetDeleteAuthorizeCodes(m pkg.FositeStorer) func(t *testing.T) {
 	return func(t *testing.T) {
 		ctx := context.Background()
@@ -179,6 +278,87 @@ func TestHelperCreateGetDeletePKCERequestSession(m pkg.FositeStorer) func(t *tes
 	}
 }
 
+func TestHelperSetClientAssertionJWT(m pkg.FositeStorer) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		jti := uuid.New()
+
+		require.NoError(t, m.SetClientAssertionJWT(ctx, jti, time.Now().Add(time.Hour)))
+		err := m.SetClientAssertionJWT(ctx, jti, time.Now().Add(time.Hour))
+		require.Error(t, err)
+		assert.Equal(t, ErrJTIKnown, errors.Cause(err))
+
+		expired := uuid.New()
+		require.NoError(t, m.SetClientAssertionJWT(ctx, expired, time.Now().Add(-time.Minute)))
+		require.NoError(t, m.SetClientAssertionJWT(ctx, expired, time.Now().Add(time.Hour)))
+	}
+}
+
+func TestHelperDeviceCodeSession(m DeviceCodeStorage) func(t *testing.T) {
+	return func(t *testing.T) {
+		ctx := context.Background()
+		signature := DeviceCodeSignature(uuid.New())
+		userCode := NormalizeUserCode(uuid.New()[:8])
+
+		_, err := m.GetDeviceCodeSession(ctx, signature, &fosite.DefaultSession{})
+		assert.Equal(t, fosite.ErrNotFound, errors.Cause(err))
+
+		// The request is copied because the memory store keeps a reference to it which is modified below.
+		request := defaultRequest
+		req := &DeviceRequest{
+			Requester:    &request,
+			Signature:    signature,
+			UserCode:     userCode,
+			Status:       DeviceStatusPending,
+			ExpiresAt:    time.Now().UTC().Add(time.Hour).Round(time.Second),
+			LastPolledAt: time.Now().UTC().Round(time.Second),
+		}
+		require.NoError(t, m.CreateDeviceCodeSession(ctx, req))
+
+		err = m.CreateDeviceCodeSession(ctx, &DeviceRequest{
+			Requester: &request,
+			Signature: DeviceCodeSignature(uuid.New()),
+			UserCode:  userCode,
+			Status:    DeviceStatusPending,
+			ExpiresAt: time.Now().UTC().Add(time.Hour),
+		})
+		assert.Equal(t, ErrUserCodeKnown, errors.Cause(err))
+
+		res, err := m.GetDeviceCodeSession(ctx, signature, &fosite.DefaultSession{})
+		require.NoError(t, err)
+		AssertObjectKeysEqual(t, &defaultRequest, res.Requester, "Scopes", "GrantedScopes", "Form", "Session")
+		assert.Equal(t, userCode, res.UserCode)
+		assert.Equal(t, DeviceStatusPending, res.Status)
+		assert.Equal(t, req.ExpiresAt.Unix(), res.ExpiresAt.Unix())
+
+		res.Status = DeviceStatusApproved
+		res.LastPolledAt = time.Now().UTC().Add(time.Minute).Round(time.Second)
+		res.GrantScope("foo")
+		require.NoError(t, m.UpdateDeviceCodeSession(ctx, res, DeviceStatusPending))
+
+		res, err = m.GetDeviceCodeSessionByUserCode(ctx, userCode, &fosite.DefaultSession{})
+		require.NoError(t, err)
+		assert.Equal(t, signature, res.Signature)
+		assert.Equal(t, DeviceStatusApproved, res.Status)
+		assert.True(t, res.GetGrantedScopes().Has("foo"))
+
+		res.Status = DeviceStatusDenied
+		err = m.UpdateDeviceCodeSession(ctx, res, DeviceStatusPending)
+		assert.Equal(t, ErrDeviceStatusChanged, errors.Cause(err))
+
+		res, err = m.GetDeviceCodeSession(ctx, signature, &fosite.DefaultSession{})
+		require.NoError(t, err)
+		assert.Equal(t, DeviceStatusApproved, res.Status)
+
+		require.NoError(t, m.DeleteDeviceCodeSession(ctx, signature))
+		err = m.DeleteDeviceCodeSession(ctx, signature)
+		assert.Equal(t, fosite.ErrNotFound, errors.Cause(err))
+
+		_, err = m.GetDeviceCodeSessionByUserCode(ctx, userCode, &fosite.DefaultSession{})
+		assert.Equal(t, fosite.ErrNotFound, errors.Cause(err))
+	}
+}
+
 var lifespan = time.Hour
 var flushRequests = []*fosite.Request{
 	{
@@ -245,5 +425,27 @@ func TestHelperFlushTokens(m pkg.FositeStorer, lifespan time.Duration) func(t *t
 		require.Error(t, err)
 		_, err = m.GetAccessTokenSession(ctx, "flush-3", ds)
 		require.Error(t, err)
+
+		rs, ok := m.(RefreshTokenRotationStorage)
+		if !ok {
+			return
+		}
+
+		// Refresh tokens which have been rotated are flushed like access tokens, using the time of the rotation.
+		for _, r := range flushRequests {
+			require.NoError(t, m.CreateRefreshTokenSession(ctx, "flush-rotated-"+r.ID, r))
+			require.NoError(t, rs.RotateRefreshTokenSession(ctx, "flush-rotated-"+r.ID, r.RequestedAt))
+		}
+		require.NoError(t, m.CreateRefreshTokenSession(ctx, "flush-active", flushRequests[2]))
+
+		require.NoError(t, m.FlushInactiveAccessTokens(ctx, time.Now()))
+		_, _, err = rs.GetRotatedRefreshTokenSession(ctx, "flush-rotated-flush-1", ds)
+		require.NoError(t, err)
+		_, _, err = rs.GetRotatedRefreshTokenSession(ctx, "flush-rotated-flush-2", ds)
+		assert.Equal(t, fosite.ErrNotFound, errors.Cause(err))
+		_, _, err = rs.GetRotatedRefreshTokenSession(ctx, "flush-rotated-flush-3", ds)
+		assert.Equal(t, fosite.ErrNotFound, errors.Cause(err))
+		_, err = m.GetRefreshTokenSession(ctx, "flush-active", ds)
+		require.NoError(t, err)
 	}
 }
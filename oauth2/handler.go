@@ -23,6 +23,7 @@ package oauth2
 import (
 	"context"
 	"encoding/json"
+	"html/template"
 	"net/http"
 	"strings"
 	"time"
@@ -31,6 +32,7 @@ import (
 	"github.com/ory/fosite"
 	"github.com/ory/fosite/handler/openid"
 	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/consent"
 	"github.com/ory/hydra/pkg"
 	"github.com/pkg/errors"
@@ -41,6 +43,7 @@ const (
 
 	DefaultConsentPath = "/oauth2/fallbacks/consent"
 	DefaultErrorPath   = "/oauth2/fallbacks/error"
+	DefaultLogoutPath  = "/oauth2/fallbacks/logout"
 	TokenPath          = "/oauth2/token"
 	AuthPath           = "/oauth2/auth"
 
@@ -48,10 +51,21 @@ const (
 	WellKnownPath = "/.well-known/openid-configuration"
 	JWKPath       = "/.well-known/jwks.json"
 
+	// AuthorizationServerMetadataPath points to the OAuth 2.0 Authorization Server Metadata document.
+	AuthorizationServerMetadataPath = "/.well-known/oauth-authorization-server"
+
 	// IntrospectPath points to the OAuth2 introspection endpoint.
 	IntrospectPath = "/oauth2/introspect"
 	RevocationPath = "/oauth2/revoke"
 	FlushPath      = "/oauth2/flush"
+
+	// LogoutPath points to the OpenID Connect RP-initiated logout endpoint.
+	LogoutPath = "/oauth2/sessions/logout"
+
+	// DeviceAuthPath points to the OAuth 2.0 device authorization endpoint and DeviceVerificationPath to the page
+	// where the end user enters the user code.
+	DeviceAuthPath         = "/oauth2/device/auth"
+	DeviceVerificationPath = "/oauth2/device/verify"
 )
 
 // swagger:model wellKnown
@@ -116,6 +130,136 @@ type WellKnown struct {
 	//
 	// required: true
 	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+
+	// URL of the OP's Dynamic Client Registration Endpoint. Only set if dynamic client registration is enabled.
+	RegistrationEndpoint string `json:"registration_endpoint,omitempty"`
+
+	// URL at the OP to which an RP can perform a redirect to request that the End-User be logged out at the OP.
+	EndSessionEndpoint string `json:"end_session_endpoint"`
+
+	// Boolean value specifying whether the OP supports back-channel logout, with true indicating support.
+	BackChannelLogoutSupported bool `json:"backchannel_logout_supported"`
+
+	// Boolean value specifying whether the OP can pass a sid (session ID) Claim in the Logout Token to identify the RP
+	// session with the OP. If supported, the sid Claim is also included in ID Tokens issued by the OP.
+	BackChannelLogoutSessionSupported bool `json:"backchannel_logout_session_supported"`
+
+	// Boolean value specifying whether the OP supports HTTP-based logout, with true indicating support.
+	FrontChannelLogoutSupported bool `json:"frontchannel_logout_supported"`
+
+	// Boolean value specifying whether the OP can pass iss (issuer) and sid (session ID) query parameters to identify
+	// the RP session with the OP when the frontchannel_logout_uri is used.
+	FrontChannelLogoutSessionSupported bool `json:"frontchannel_logout_session_supported"`
+
+	// URL of the OP's OAuth 2.0 Device Authorization Endpoint. Only set if the device authorization grant is enabled.
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
+
+	// JSON array containing a list of the OAuth 2.0 Grant Type values that this OP supports.
+	GrantTypesSupported []string `json:"grant_types_supported"`
+
+	// JSON array containing a list of the OAuth 2.0 response_mode values that this OP supports.
+	ResponseModesSupported []string `json:"response_modes_supported"`
+
+	// JSON array containing a list of the PKCE code challenge methods supported by this OP.
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+
+	// JSON array containing a list of the JWS signing algorithms supported by the Token Endpoint for the signature
+	// on the JWT used to authenticate the Client at the Token Endpoint for the private_key_jwt and client_secret_jwt
+	// authentication methods.
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported,omitempty"`
+
+	// URL of the OP's OAuth 2.0 Token Revocation Endpoint. Only set if token revocation is enabled.
+	RevocationEndpoint string `json:"revocation_endpoint,omitempty"`
+
+	// URL of the OP's OAuth 2.0 Token Introspection Endpoint. Only set if token introspection is enabled.
+	IntrospectionEndpoint string `json:"introspection_endpoint,omitempty"`
+
+	// Boolean value specifying whether the OP supports use of the request parameter, with true indicating support.
+	RequestParameterSupported bool `json:"request_parameter_supported"`
+
+	// Boolean value specifying whether the OP supports use of the request_uri parameter, with true indicating support.
+	RequestURIParameterSupported bool `json:"request_uri_parameter_supported"`
+
+	// Boolean value specifying whether the OP supports use of the claims parameter, with true indicating support.
+	ClaimsParameterSupported bool `json:"claims_parameter_supported"`
+
+	// JSON array containing a list of the JWS signing algorithms (alg values) [JWA] supported by the UserInfo
+	// Endpoint to encode the Claims in a JWT.
+	UserinfoSigningAlgValuesSupported []string `json:"userinfo_signing_alg_values_supported"`
+
+	// JSON array containing a list of the JWE encryption algorithms (alg values) [JWA] supported by the UserInfo
+	// Endpoint to encode the Claims in a JWT.
+	UserinfoEncryptionAlgValuesSupported []string `json:"userinfo_encryption_alg_values_supported"`
+
+	// JSON array containing a list of the JWE encryption algorithms (enc values) [JWA] supported by the UserInfo
+	// Endpoint to encode the Claims in a JWT.
+	UserinfoEncryptionEncValuesSupported []string `json:"userinfo_encryption_enc_values_supported"`
+}
+
+// swagger:model authorizationServerMetadata
+type AuthorizationServerMetadata struct {
+	// The authorization server's issuer identifier, which is a URL that uses the https scheme and has no query or
+	// fragment components.
+	//
+	// required: true
+	Issuer string `json:"issuer"`
+
+	// URL of the authorization server's authorization endpoint.
+	//
+	// required: true
+	AuthURL string `json:"authorization_endpoint"`
+
+	// URL of the authorization server's token endpoint.
+	//
+	// required: true
+	TokenURL string `json:"token_endpoint"`
+
+	// URL of the authorization server's JWK Set document.
+	JWKsURI string `json:"jwks_uri"`
+
+	// URL of the authorization server's OAuth 2.0 Dynamic Client Registration endpoint. Only set if dynamic client
+	// registration is enabled.
+	RegistrationEndpoint string `json:"registration_endpoint,omitempty"`
+
+	// JSON array containing a list of the OAuth 2.0 scope values that this authorization server supports.
+	ScopesSupported []string `json:"scopes_supported"`
+
+	// JSON array containing a list of the OAuth 2.0 response_type values that this authorization server supports.
+	//
+	// required: true
+	ResponseTypesSupported []string `json:"response_types_supported"`
+
+	// JSON array containing a list of the OAuth 2.0 response_mode values that this authorization server supports.
+	ResponseModesSupported []string `json:"response_modes_supported"`
+
+	// JSON array containing a list of the OAuth 2.0 grant type values that this authorization server supports.
+	GrantTypesSupported []string `json:"grant_types_supported"`
+
+	// JSON array containing a list of client authentication methods supported by this token endpoint.
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+
+	// JSON array containing a list of the JWS signing algorithms supported by the token endpoint for the signature on
+	// the JWT used to authenticate the client at the token endpoint.
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported,omitempty"`
+
+	// URL of the authorization server's OAuth 2.0 revocation endpoint. Only set if token revocation is enabled.
+	RevocationEndpoint string `json:"revocation_endpoint,omitempty"`
+
+	// JSON array containing a list of client authentication methods supported by this revocation endpoint.
+	RevocationEndpointAuthMethodsSupported []string `json:"revocation_endpoint_auth_methods_supported,omitempty"`
+
+	// URL of the authorization server's OAuth 2.0 introspection endpoint. Only set if token introspection is enabled.
+	IntrospectionEndpoint string `json:"introspection_endpoint,omitempty"`
+
+	// JSON array containing a list of client authentication methods supported by this introspection endpoint.
+	IntrospectionEndpointAuthMethodsSupported []string `json:"introspection_endpoint_auth_methods_supported,omitempty"`
+
+	// JSON array containing a list of PKCE code challenge methods supported by this authorization server.
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+
+	// URL of the authorization server's OAuth 2.0 Device Authorization Endpoint. Only set if the device authorization
+	// grant is enabled.
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
 }
 
 // swagger:model flushInactiveOAuth2TokensRequest
@@ -125,18 +269,31 @@ type FlushInactiveOAuth2TokensRequest struct {
 	NotAfter time.Time `json:"notAfter"`
 }
 
-func (h *Handler) SetRoutes(r *httprouter.Router) {
+// SetRoutes registers the public OAuth 2.0 and OpenID Connect endpoints on frontend and the administrative endpoints on
+// backend.
+func (h *Handler) SetRoutes(frontend, backend *httprouter.Router) {
+	r := frontend
 	r.POST(TokenPath, h.TokenHandler)
 	r.GET(AuthPath, h.AuthHandler)
 	r.POST(AuthPath, h.AuthHandler)
 	r.GET(DefaultConsentPath, h.DefaultConsentHandler)
 	r.GET(DefaultErrorPath, h.DefaultErrorHandler)
+	r.GET(DefaultLogoutPath, h.DefaultLogoutHandler)
 	r.POST(IntrospectPath, h.IntrospectHandler)
 	r.POST(RevocationPath, h.RevocationHandler)
 	r.GET(WellKnownPath, h.WellKnownHandler)
+	r.GET(AuthorizationServerMetadataPath, h.AuthorizationServerMetadataHandler)
 	r.GET(UserinfoPath, h.UserinfoHandler)
 	r.POST(UserinfoPath, h.UserinfoHandler)
-	r.POST(FlushPath, h.FlushHandler)
+	r.GET(LogoutPath, h.LogoutHandler)
+	r.POST(LogoutPath, h.LogoutHandler)
+
+	if h.DeviceStorage != nil {
+		r.POST(DeviceAuthPath, h.DeviceAuthHandler)
+		r.GET(DeviceVerificationPath, h.DeviceVerificationHandler)
+	}
+
+	backend.POST(FlushPath, h.FlushHandler)
 }
 
 // swagger:route GET /.well-known/openid-configuration oAuth2 getWellKnown
@@ -167,26 +324,219 @@ func (h *Handler) WellKnownHandler(w http.ResponseWriter, r *http.Request, _ htt
 		claimsSupported = append(claimsSupported, strings.Split(h.ClaimsSupported, ",")...)
 	}
 
+	subjectTypes := h.SubjectTypes
+	if len(subjectTypes) == 0 {
+		subjectTypes = []string{consent.SubjectTypePublic}
+	}
+
+	m := h.authorizationServerMetadata()
+	h.H.Write(w, r, &WellKnown{
+		Issuer:                                     m.Issuer,
+		AuthURL:                                    m.AuthURL,
+		TokenURL:                                   m.TokenURL,
+		JWKsURI:                                    m.JWKsURI,
+		SubjectTypes:                               subjectTypes,
+		ResponseTypes:                              m.ResponseTypesSupported,
+		ClaimsSupported:                            claimsSupported,
+		ScopesSupported:                            m.ScopesSupported,
+		UserinfoEndpoint:                           userInfoEndpoint,
+		TokenEndpointAuthMethodsSupported:          m.TokenEndpointAuthMethodsSupported,
+		IDTokenSigningAlgValuesSupported:           h.IDTokenSigningAlgorithms,
+		RegistrationEndpoint:                       m.RegistrationEndpoint,
+		EndSessionEndpoint:                         strings.TrimRight(h.IssuerURL, "/") + LogoutPath,
+		BackChannelLogoutSupported:                 true,
+		BackChannelLogoutSessionSupported:          true,
+		FrontChannelLogoutSupported:                true,
+		FrontChannelLogoutSessionSupported:         true,
+		DeviceAuthorizationEndpoint:                m.DeviceAuthorizationEndpoint,
+		GrantTypesSupported:                        m.GrantTypesSupported,
+		ResponseModesSupported:                     m.ResponseModesSupported,
+		CodeChallengeMethodsSupported:              m.CodeChallengeMethodsSupported,
+		TokenEndpointAuthSigningAlgValuesSupported: m.TokenEndpointAuthSigningAlgValuesSupported,
+		RevocationEndpoint:                         m.RevocationEndpoint,
+		IntrospectionEndpoint:                      m.IntrospectionEndpoint,
+		RequestParameterSupported:                  false,
+		RequestURIParameterSupported:               false,
+		ClaimsParameterSupported:                   false,
+		UserinfoSigningAlgValuesSupported:          h.IDTokenSigningAlgorithms,
+		UserinfoEncryptionAlgValuesSupported:       client.UserinfoEncryptionAlgorithms,
+		UserinfoEncryptionEncValuesSupported:       client.UserinfoEncryptionEncodings,
+	})
+}
+
+// swagger:route GET /.well-known/oauth-authorization-server oAuth2 getAuthorizationServerMetadata
+//
+// OAuth 2.0 Authorization Server Metadata
+//
+// This endpoint returns the metadata of the authorization server as defined in
+// https://tools.ietf.org/html/rfc8414 . It describes the same endpoints and capabilities as the OpenID Connect
+// discovery document, which can be used by OAuth 2.0 clients that do not use OpenID Connect.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: authorizationServerMetadata
+//       401: genericError
+//       500: genericError
+func (h *Handler) AuthorizationServerMetadataHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.H.Write(w, r, h.authorizationServerMetadata())
+}
+
+// authorizationServerMetadata describes the endpoints and capabilities which are shared by the OpenID Connect
+// discovery document and the OAuth 2.0 Authorization Server Metadata.
+func (h *Handler) authorizationServerMetadata() *AuthorizationServerMetadata {
+	issuer := strings.TrimRight(h.IssuerURL, "/")
+	c := h.capabilities()
+
 	scopesSupported := []string{"offline", "openid"}
 	if h.ScopesSupported != "" {
 		scopesSupported = append(scopesSupported, strings.Split(h.ScopesSupported, ",")...)
 	}
 
-	h.H.Write(w, r, &WellKnown{
-		Issuer:                            strings.TrimRight(h.IssuerURL, "/") + "/",
-		AuthURL:                           strings.TrimRight(h.IssuerURL, "/") + AuthPath,
-		TokenURL:                          strings.TrimRight(h.IssuerURL, "/") + TokenPath,
-		JWKsURI:                           strings.TrimRight(h.IssuerURL, "/") + JWKPath,
-		SubjectTypes:                      []string{"pairwise", "public"},
-		ResponseTypes:                     []string{"code", "code id_token", "id_token", "token id_token", "token", "token id_token code"},
-		ClaimsSupported:                   claimsSupported,
+	var registrationEndpoint string
+	if h.ClientRegistrationEnabled {
+		registrationEndpoint = issuer + client.RegistrationPath
+	}
+
+	authMethods := []string{"client_secret_post", "client_secret_basic"}
+	var authSigningAlgorithms []string
+	if h.ClientAssertionAuthenticator != nil {
+		authMethods = append(authMethods, client.TokenEndpointAuthMethodPrivateKeyJWT, client.TokenEndpointAuthMethodClientSecretJWT)
+		authSigningAlgorithms = h.ClientAssertionAuthenticator.SigningAlgorithms()
+	}
+
+	var deviceAuthorizationEndpoint string
+	if h.DeviceStorage != nil {
+		deviceAuthorizationEndpoint = issuer + DeviceAuthPath
+	}
+
+	m := &AuthorizationServerMetadata{
+		Issuer:                            issuer + "/",
+		AuthURL:                           issuer + AuthPath,
+		TokenURL:                          issuer + TokenPath,
+		JWKsURI:                           issuer + JWKPath,
+		RegistrationEndpoint:              registrationEndpoint,
 		ScopesSupported:                   scopesSupported,
-		UserinfoEndpoint:                  userInfoEndpoint,
-		TokenEndpointAuthMethodsSupported: []string{"client_secret_post", "client_secret_basic"},
-		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
-	})
+		ResponseTypesSupported:            c.ResponseTypes,
+		ResponseModesSupported:            c.ResponseModes,
+		GrantTypesSupported:               c.GrantTypes,
+		TokenEndpointAuthMethodsSupported: authMethods,
+		TokenEndpointAuthSigningAlgValuesSupported: authSigningAlgorithms,
+		CodeChallengeMethodsSupported:              c.CodeChallengeMethods,
+		DeviceAuthorizationEndpoint:                deviceAuthorizationEndpoint,
+	}
+
+	if c.Revocation {
+		m.RevocationEndpoint = issuer + RevocationPath
+		m.RevocationEndpointAuthMethodsSupported = authMethods
+	}
+
+	if c.Introspection {
+		m.IntrospectionEndpoint = issuer + IntrospectPath
+		m.IntrospectionEndpointAuthMethodsSupported = authMethods
+	}
+
+	return m
+}
+
+// swagger:route GET /oauth2/sessions/logout oAuth2 logout
+//
+// OpenID Connect RP-Initiated Logout
+//
+// This endpoint initiates and completes user logout at ORY Hydra as defined by OpenID Connect RP-Initiated Logout. It
+// revokes the authentication session of the user agent and redirects it to the post_logout_redirect_uri, which must
+// be whitelisted in the post_logout_redirect_uris of the client the id_token_hint was issued to. If no
+// post_logout_redirect_uri is given, the user agent is redirected to the default logout page.
+//
+// If clients which participated in the authentication session registered a frontchannel_logout_uri, a page is rendered
+// instead which loads each of these URIs in an iframe before redirecting the user agent.
+//
+// Logout requests without an id_token_hint must be confirmed by the user: a page is rendered which asks the user to
+// submit a form, and the session is only revoked once the form is posted back to this endpoint.
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: emptyResponse
+//       302: emptyResponse
+func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	result, err := h.Consent.HandleOpenIDConnectLogout(w, r)
+	if err != nil {
+		pkg.LogError(err, h.L)
+		h.redirectToErrorURL(w, err)
+		return
+	}
+
+	if result.ConfirmationCSRF != "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := logoutConfirmationTemplate.Execute(w, struct {
+			Action string
+			CSRF   string
+		}{
+			Action: strings.TrimRight(h.IssuerURL, "/") + LogoutPath,
+			CSRF:   result.ConfirmationCSRF,
+		}); err != nil {
+			pkg.LogError(errors.WithStack(err), h.L)
+		}
+		return
+	}
+
+	redirectTo := result.RedirectTo
+	if redirectTo == "" {
+		redirectTo = h.LogoutRedirectURL.String()
+	}
+
+	if len(result.FrontChannelLogoutURLs) == 0 {
+		w.Header().Set("Location", redirectTo)
+		w.WriteHeader(http.StatusFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := frontChannelLogoutTemplate.Execute(w, struct {
+		RedirectTo string
+		URLs       []string
+	}{
+		RedirectTo: redirectTo,
+		URLs:       result.FrontChannelLogoutURLs,
+	}); err != nil {
+		pkg.LogError(errors.WithStack(err), h.L)
+	}
 }
 
+var frontChannelLogoutTemplate = template.Must(template.New("frontchannel_logout").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<title>Logging out</title>
+</head>
+<body>
+{{ range .URLs }}<iframe src="{{ . }}" style="display:none"></iframe>
+{{ end }}<noscript><p>You have been logged out. <a href="{{ .RedirectTo }}">Continue</a></p></noscript>
+<script>
+	window.onload = function () { window.location.replace({{ .RedirectTo }}); };
+</script>
+</body>
+</html>
+`))
+
+var logoutConfirmationTemplate = template.Must(template.New("logout_confirmation").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<title>Log out</title>
+</head>
+<body>
+<form method="post" action="{{ .Action }}">
+	<p>Do you want to log out?</p>
+	<input type="hidden" name="csrf" value="{{ .CSRF }}">
+	<button type="submit">Log out</button>
+</form>
+</body>
+</html>
+`))
+
 // swagger:route POST /userinfo oAuth2 userinfo
 //
 // OpenID Connect Userinfo
@@ -194,8 +544,12 @@ func (h *Handler) WellKnownHandler(w http.ResponseWriter, r *http.Request, _ htt
 // This endpoint returns the payload of the ID Token, including the idTokenExtra values, of the provided OAuth 2.0 access token.
 // The endpoint implements http://openid.net/specs/openid-connect-core-1_0.html#UserInfo .
 //
+// If the client registered userinfo_signed_response_alg or userinfo_encrypted_response_alg, the claims are returned
+// as a signed and/or encrypted JSON Web Token using the application/jwt content type instead.
+//
 //     Produces:
 //     - application/json
+//     - application/jwt
 //
 //     Schemes: http, https
 //
@@ -231,7 +585,21 @@ func (h *Handler) UserinfoHandler(w http.ResponseWriter, r *http.Request, _ http
 	delete(interim, "exp")
 	delete(interim, "jti")
 
-	h.H.Write(w, r, interim)
+	c, ok := ar.GetClient().(*client.Client)
+	if !ok || c.UserinfoSignedResponseAlg == "" && c.UserinfoEncryptedResponseAlg == "" {
+		h.H.Write(w, r, interim)
+		return
+	}
+
+	token, err := h.userinfoResponseJWT(r.Context(), c, interim)
+	if err != nil {
+		pkg.LogError(err, h.L)
+		h.H.WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/jwt")
+	w.Write([]byte(token))
 }
 
 // swagger:route POST /oauth2/revoke oAuth2 revokeOAuth2Token
@@ -258,7 +626,11 @@ func (h *Handler) UserinfoHandler(w http.ResponseWriter, r *http.Request, _ http
 func (h *Handler) RevocationHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	var ctx = fosite.NewContext()
 
-	err := h.OAuth2.NewRevocationRequest(ctx, r)
+	release, err := h.authenticateClientAssertion(r)
+	defer release()
+	if err == nil {
+		err = h.OAuth2.NewRevocationRequest(ctx, r)
+	}
 	if err != nil {
 		pkg.LogError(err, h.L)
 	}
@@ -293,6 +665,14 @@ func (h *Handler) RevocationHandler(w http.ResponseWriter, r *http.Request, _ ht
 func (h *Handler) IntrospectHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	var session = NewSession("")
 
+	release, err := h.authenticateClientAssertion(r)
+	defer release()
+	if err != nil {
+		pkg.LogError(err, h.L)
+		h.OAuth2.WriteIntrospectionError(w, err)
+		return
+	}
+
 	var ctx = fosite.NewContext()
 	resp, err := h.OAuth2.NewIntrospectionRequest(ctx, r, session)
 	if err != nil {
@@ -306,6 +686,19 @@ func (h *Handler) IntrospectHandler(w http.ResponseWriter, r *http.Request, _ ht
 		exp = resp.GetAccessRequester().GetRequestedAt().Add(h.AccessTokenLifespan)
 	}
 
+	// Resource servers of a client with the pairwise subject type must only learn the subject identifier the client
+	// has seen, not the one of the end-user.
+	subject := resp.GetAccessRequester().GetSession().GetSubject()
+	if c, ok := resp.GetAccessRequester().GetClient().(*client.Client); ok && subject != "" {
+		if subject, err = consent.ObfuscateSubjectIdentifier(h.SubjectIdentifierAlgorithm, c, subject); err != nil {
+			pkg.LogError(err, h.L)
+			h.OAuth2.WriteIntrospectionError(w, err)
+			return
+		}
+	}
+
+	h.Metrics.ObserveIntrospection(string(resp.GetTokenType()), resp.IsActive())
+
 	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
 	if err = json.NewEncoder(w).Encode(&Introspection{
 		Active:    resp.IsActive(),
@@ -313,10 +706,11 @@ func (h *Handler) IntrospectHandler(w http.ResponseWriter, r *http.Request, _ ht
 		Scope:     strings.Join(resp.GetAccessRequester().GetGrantedScopes(), " "),
 		ExpiresAt: exp.Unix(),
 		IssuedAt:  resp.GetAccessRequester().GetRequestedAt().Unix(),
-		Subject:   resp.GetAccessRequester().GetSession().GetSubject(),
+		Subject:   subject,
 		Username:  resp.GetAccessRequester().GetSession().GetUsername(),
 		Extra:     resp.GetAccessRequester().GetSession().(*Session).Extra,
 		Audience:  resp.GetAccessRequester().GetSession().(*Session).Audience,
+		Actor:     resp.GetAccessRequester().GetSession().(*Session).Actor,
 		Issuer:    strings.TrimRight(h.IssuerURL, "/") + "/",
 		TokenType: string(resp.GetTokenType()),
 	}); err != nil {
@@ -332,6 +726,11 @@ func (h *Handler) IntrospectHandler(w http.ResponseWriter, r *http.Request, _ ht
 // not be touched, in case you want to keep recent tokens for auditing. Refresh tokens can not be flushed as they are deleted
 // automatically when performing the refresh flow.
 //
+// Refresh tokens which have been rotated are kept to detect their reuse. They are flushed once they were rotated longer
+// than the access token lifespan ago, unless they were rotated after that time.
+//
+// Remembered consent which has expired is flushed as well, unless it expired after that time.
+//
 //     Consumes:
 //     - application/json
 //
@@ -357,6 +756,13 @@ func (h *Handler) FlushHandler(w http.ResponseWriter, r *http.Request, _ httprou
 		return
 	}
 
+	if h.ConsentManager != nil {
+		if err := h.ConsentManager.FlushExpiredConsentSessions(fr.NotAfter); err != nil {
+			h.H.WriteError(w, r, err)
+			return
+		}
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -389,6 +795,14 @@ func (h *Handler) TokenHandler(w http.ResponseWriter, r *http.Request, _ httprou
 	var session = NewSession("")
 	var ctx = fosite.NewContext()
 
+	release, err := h.authenticateClientAssertion(r)
+	defer release()
+	if err != nil {
+		pkg.LogError(err, h.L)
+		h.OAuth2.WriteAccessError(w, fosite.NewAccessRequest(session), err)
+		return
+	}
+
 	accessRequest, err := h.OAuth2.NewAccessRequest(ctx, r, session)
 	if err != nil {
 		pkg.LogError(err, h.L)
@@ -412,6 +826,7 @@ func (h *Handler) TokenHandler(w http.ResponseWriter, r *http.Request, _ httprou
 		return
 	}
 
+	h.Metrics.ObserveTokenIssued(strings.Join(accessRequest.GetGrantTypes(), " "))
 	h.OAuth2.WriteAccessResponse(w, accessRequest, accessResponse)
 }
 
@@ -457,51 +872,110 @@ func (h *Handler) AuthHandler(w http.ResponseWriter, r *http.Request, _ httprout
 		authorizeRequest.GrantScope(scope)
 	}
 
+	oauth2Session, err := h.newSession(session, authorizeRequest.GetClient())
+	if err != nil {
+		pkg.LogError(err, h.L)
+		h.writeAuthorizeError(w, authorizeRequest, err)
+		return
+	}
+
 	// done
-	response, err := h.OAuth2.NewAuthorizeResponse(ctx, authorizeRequest, &Session{
+	response, err := h.OAuth2.NewAuthorizeResponse(ctx, authorizeRequest, oauth2Session)
+	if err != nil {
+		pkg.LogError(err, h.L)
+		h.writeAuthorizeError(w, authorizeRequest, err)
+		return
+	}
+
+	if authorizeRequest.GetResponseTypes().Has("token") {
+		h.Metrics.ObserveTokenIssued("implicit")
+	}
+
+	h.OAuth2.WriteAuthorizeResponse(w, authorizeRequest, response)
+}
+
+// newSession creates the session of the tokens issued for a completed consent request.
+func (h *Handler) newSession(session *consent.HandledConsentRequest, cl fosite.Client) (*Session, error) {
+	// The ID Token and the userinfo response carry the subject identifier for the client's subject type, while the
+	// access token keeps the original subject so that introspection and the consent APIs keep working.
+	obfuscatedSubject := session.ConsentRequest.Subject
+	if c, ok := cl.(*client.Client); ok {
+		var err error
+		obfuscatedSubject, err = consent.ObfuscateSubjectIdentifier(h.SubjectIdentifierAlgorithm, c, session.ConsentRequest.Subject)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	idTokenExtra := map[string]interface{}{}
+	for k, v := range session.Session.IDToken {
+		idTokenExtra[k] = v
+	}
+
+	// The sid claim ties the ID Token to the authentication session, which is required for Back-Channel Logout.
+	if session.AuthenticationSessionID != "" {
+		idTokenExtra["sid"] = session.AuthenticationSessionID
+	}
+
+	return &Session{
 		DefaultSession: &openid.DefaultSession{
 			Claims: &jwt.IDTokenClaims{
 				// We do not need to pass the audience because it's included directly by ORY Fosite
 				//Audience:    []string{authorizeRequest.GetClient().GetID()},
-				Subject:     session.ConsentRequest.Subject,
+				Subject:     obfuscatedSubject,
 				Issuer:      strings.TrimRight(h.IssuerURL, "/") + "/",
 				IssuedAt:    time.Now().UTC(),
 				ExpiresAt:   time.Now().Add(h.IDTokenLifespan).UTC(),
 				AuthTime:    session.AuthenticatedAt,
 				RequestedAt: session.RequestedAt,
-				Extra:       session.Session.IDToken,
+				Extra:       idTokenExtra,
 			},
-			// required for lookup on jwk endpoint
-			Headers: &jwt.Headers{Extra: map[string]interface{}{"kid": h.IDTokenPublicKeyID}},
+			// the signing strategy sets the kid, which is required for lookup on jwk endpoint
+			Headers: &jwt.Headers{Extra: idTokenHeaders(cl)},
 			Subject: session.ConsentRequest.Subject,
 		},
 		Extra: session.Session.AccessToken,
 		// Here, we do not include the client because it's typically not the audience.
 		Audience: []string{},
-	})
-	if err != nil {
-		pkg.LogError(err, h.L)
-		h.writeAuthorizeError(w, authorizeRequest, err)
-		return
+	}, nil
+}
+
+// idTokenHeaders returns the headers of the ID Tokens of the client. The alg header selects the signing key of the
+// client's id_token_signed_response_alg, if set, which the signing strategy replaces with the actual algorithm.
+func idTokenHeaders(cl fosite.Client) map[string]interface{} {
+	if c, ok := cl.(*client.Client); ok && c.IDTokenSignedResponseAlg != "" {
+		return map[string]interface{}{"alg": c.IDTokenSignedResponseAlg}
 	}
+	return map[string]interface{}{}
+}
 
-	h.OAuth2.WriteAuthorizeResponse(w, authorizeRequest, response)
+// authenticateClientAssertion verifies the client assertion of the request, if any. The returned function must be
+// called once the request has been handled.
+func (h *Handler) authenticateClientAssertion(r *http.Request) (func(), error) {
+	if h.ClientAssertionAuthenticator == nil {
+		return func() {}, nil
+	}
+	return h.ClientAssertionAuthenticator.AuthenticateRequest(r)
 }
 
 func (h *Handler) writeAuthorizeError(w http.ResponseWriter, ar fosite.AuthorizeRequester, err error) {
 	if !ar.IsRedirectURIValid() {
-		var rfcerr = fosite.ErrorToRFC6749Error(err)
-
-		redirectURI := h.ErrorURL
-		query := redirectURI.Query()
-		query.Add("error", rfcerr.Name)
-		query.Add("error_description", rfcerr.Description)
-		redirectURI.RawQuery = query.Encode()
-
-		w.Header().Add("Location", redirectURI.String())
-		w.WriteHeader(http.StatusFound)
+		h.redirectToErrorURL(w, err)
 		return
 	}
 
 	h.OAuth2.WriteAuthorizeError(w, ar, err)
 }
+
+func (h *Handler) redirectToErrorURL(w http.ResponseWriter, err error) {
+	var rfcerr = fosite.ErrorToRFC6749Error(err)
+
+	redirectURI := h.ErrorURL
+	query := redirectURI.Query()
+	query.Add("error", rfcerr.Name)
+	query.Add("error_description", rfcerr.Description)
+	redirectURI.RawQuery = query.Encode()
+
+	w.Header().Add("Location", redirectURI.String())
+	w.WriteHeader(http.StatusFound)
+}
@@ -0,0 +1,370 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @Copyright 	2017-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/fosite"
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/consent"
+	"github.com/ory/hydra/pkg"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+// swagger:model deviceAuthorizationResponse
+type DeviceAuthorizationResponse struct {
+	// The device verification code.
+	DeviceCode string `json:"device_code"`
+
+	// The end-user verification code.
+	UserCode string `json:"user_code"`
+
+	// The end-user verification URI on the authorization server.
+	VerificationURI string `json:"verification_uri"`
+
+	// A verification URI that includes the user_code, designed for non-textual transmission.
+	VerificationURIComplete string `json:"verification_uri_complete"`
+
+	// The lifetime in seconds of the device_code and user_code.
+	ExpiresIn int64 `json:"expires_in"`
+
+	// The minimum amount of time in seconds that the client should wait between polling requests to the token
+	// endpoint.
+	Interval int64 `json:"interval"`
+}
+
+// swagger:route POST /oauth2/device/auth oAuth2 deviceAuthorization
+//
+// The OAuth 2.0 device authorization endpoint
+//
+// This endpoint implements the device authorization request of the OAuth 2.0 Device Authorization Grant
+// (https://tools.ietf.org/html/rfc8628). It issues a device code, which the client uses to poll the token endpoint
+// with grant type urn:ietf:params:oauth:grant-type:device_code, and a user code, which the end user enters at the
+// verification URI.
+//
+// This endpoint is only available if the device authorization grant is enabled.
+//
+//     Consumes:
+//     - application/x-www-form-urlencoded
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Security:
+//       basic:
+//
+//     Responses:
+//       200: deviceAuthorizationResponse
+//       400: genericError
+//       401: genericError
+//       500: genericError
+func (h *Handler) DeviceAuthHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var session = NewSession("")
+
+	release, err := h.authenticateClientAssertion(r)
+	defer release()
+	if err != nil {
+		pkg.LogError(err, h.L)
+		h.OAuth2.WriteAccessError(w, fosite.NewAccessRequest(session), err)
+		return
+	}
+
+	c, err := h.authenticateClient(r)
+	if err != nil {
+		pkg.LogError(err, h.L)
+		h.OAuth2.WriteAccessError(w, fosite.NewAccessRequest(session), err)
+		return
+	}
+
+	if !fosite.Arguments(c.GetGrantTypes()).Has(DeviceCodeGrantType) {
+		err := errors.WithStack(fosite.ErrUnauthorizedClient.WithDebug("The client is not allowed to use grant type " + DeviceCodeGrantType))
+		pkg.LogError(err, h.L)
+		h.OAuth2.WriteAccessError(w, fosite.NewAccessRequest(session), err)
+		return
+	}
+
+	scopes := fosite.Arguments(fosite.RemoveEmpty(strings.Split(r.PostForm.Get("scope"), " ")))
+	for _, scope := range scopes {
+		if !h.ScopeStrategy(c.GetScopes(), scope) {
+			err := errors.WithStack(fosite.ErrInvalidScope.WithDebug("The client is not allowed to request scope " + scope))
+			pkg.LogError(err, h.L)
+			h.OAuth2.WriteAccessError(w, fosite.NewAccessRequest(session), err)
+			return
+		}
+	}
+
+	deviceCode, err := pkg.GenerateSecret(32)
+	if err != nil {
+		pkg.LogError(errors.WithStack(err), h.L)
+		h.OAuth2.WriteAccessError(w, fosite.NewAccessRequest(session), errors.WithStack(fosite.ErrServerError.WithDebug(err.Error())))
+		return
+	}
+
+	now := time.Now().UTC()
+	dr := &DeviceRequest{
+		Requester: &fosite.Request{
+			ID:            uuid.New(),
+			RequestedAt:   now,
+			Client:        c,
+			Scopes:        scopes,
+			GrantedScopes: fosite.Arguments{},
+			Form:          url.Values{"client_id": {c.GetID()}, "scope": {r.PostForm.Get("scope")}},
+			Session:       session,
+		},
+		Signature: DeviceCodeSignature(string(deviceCode)),
+		Status:    DeviceStatusPending,
+		ExpiresAt: now.Add(h.DeviceCodeLifespan),
+		// The first poll of the token endpoint must not be answered with slow_down.
+		LastPolledAt: now.Add(-DefaultDeviceCodePollInterval),
+	}
+
+	// The user code is short, so it might collide with the user code of another pending request.
+	var userCode string
+	for i := 0; i < 3; i++ {
+		if userCode, err = GenerateUserCode(); err != nil {
+			break
+		}
+
+		dr.UserCode = NormalizeUserCode(userCode)
+		if err = h.DeviceStorage.CreateDeviceCodeSession(r.Context(), dr); errors.Cause(err) != ErrUserCodeKnown {
+			break
+		}
+	}
+	if err != nil {
+		pkg.LogError(err, h.L)
+		h.OAuth2.WriteAccessError(w, fosite.NewAccessRequest(session), errors.WithStack(fosite.ErrServerError.WithDebug(err.Error())))
+		return
+	}
+
+	verificationURI := strings.TrimRight(h.IssuerURL, "/") + DeviceVerificationPath
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	if err := json.NewEncoder(w).Encode(&DeviceAuthorizationResponse{
+		DeviceCode:              string(deviceCode),
+		UserCode:                userCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?" + url.Values{"user_code": {userCode}}.Encode(),
+		ExpiresIn:               int64(h.DeviceCodeLifespan / time.Second),
+		Interval:                int64(DefaultDeviceCodePollInterval / time.Second),
+	}); err != nil {
+		pkg.LogError(errors.WithStack(err), h.L)
+	}
+}
+
+// swagger:route GET /oauth2/device/verify oAuth2 deviceVerification
+//
+// The OAuth 2.0 device verification endpoint
+//
+// This endpoint is where the end user enters the user code displayed by the device. Once the user code is submitted,
+// the user agent is sent through the login and consent flow. When the flow has been completed, the device receives
+// its tokens the next time it polls the token endpoint.
+//
+// This endpoint is only available if the device authorization grant is enabled.
+//
+//     Schemes: http, https
+//
+//     Responses:
+//       200: emptyResponse
+//       302: emptyResponse
+func (h *Handler) DeviceVerificationHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	query := r.URL.Query()
+	userCode := NormalizeUserCode(query.Get("user_code"))
+	if userCode == "" {
+		h.writeDevicePage(w, deviceVerificationTemplate)
+		return
+	}
+
+	var session = NewSession("")
+	dr, err := h.DeviceStorage.GetDeviceCodeSessionByUserCode(r.Context(), userCode, session)
+	if errors.Cause(err) == fosite.ErrNotFound {
+		h.redirectToErrorURL(w, errors.WithStack(fosite.ErrInvalidRequest.WithDebug("The user code is unknown")))
+		return
+	} else if err != nil {
+		pkg.LogError(err, h.L)
+		h.redirectToErrorURL(w, err)
+		return
+	}
+
+	if dr.Status != DeviceStatusPending || dr.ExpiresAt.Before(time.Now().UTC()) {
+		h.redirectToErrorURL(w, errors.WithStack(fosite.ErrInvalidRequest.WithDebug("The user code has expired or has already been used")))
+		return
+	}
+
+	// Only the parameters of the login and consent flow are passed on, the device authorization request has no
+	// redirect URI which could be used to skip the consent screen.
+	ar := &fosite.AuthorizeRequest{
+		ResponseTypes: fosite.Arguments{},
+		Request: fosite.Request{
+			ID:            dr.GetID(),
+			RequestedAt:   dr.GetRequestedAt(),
+			Client:        dr.GetClient(),
+			Scopes:        dr.GetRequestedScopes(),
+			GrantedScopes: fosite.Arguments{},
+			Form: url.Values{
+				"user_code":        {query.Get("user_code")},
+				"login_verifier":   {query.Get("login_verifier")},
+				"consent_verifier": {query.Get("consent_verifier")},
+			},
+			Session: session,
+		},
+	}
+
+	consentSession, err := h.Consent.HandleOAuth2DeviceVerificationRequest(w, r, ar)
+	if errors.Cause(err) == consent.ErrAbortOAuth2Request {
+		// do nothing
+		return
+	} else if err != nil {
+		pkg.LogError(err, h.L)
+		if fosite.ErrorToRFC6749Error(err).Name == fosite.ErrAccessDenied.Name {
+			dr.Status = DeviceStatusDenied
+			if err := h.DeviceStorage.UpdateDeviceCodeSession(r.Context(), dr, DeviceStatusPending); err != nil {
+				pkg.LogError(err, h.L)
+			}
+		}
+		h.redirectToErrorURL(w, err)
+		return
+	}
+
+	for _, scope := range consentSession.GrantedScope {
+		dr.GrantScope(scope)
+	}
+
+	oauth2Session, err := h.newSession(consentSession, dr.GetClient())
+	if err != nil {
+		pkg.LogError(err, h.L)
+		h.redirectToErrorURL(w, err)
+		return
+	}
+
+	dr.SetSession(oauth2Session)
+	dr.Status = DeviceStatusApproved
+	if err := h.DeviceStorage.UpdateDeviceCodeSession(r.Context(), dr, DeviceStatusPending); errors.Cause(err) == ErrDeviceStatusChanged {
+		h.redirectToErrorURL(w, errors.WithStack(fosite.ErrInvalidRequest.WithDebug("The user code has expired or has already been used")))
+		return
+	} else if err != nil {
+		pkg.LogError(err, h.L)
+		h.redirectToErrorURL(w, err)
+		return
+	}
+
+	h.writeDevicePage(w, deviceApprovedTemplate)
+}
+
+// authenticateClient authenticates the client of a request using HTTP basic authorization or the client_id and
+// client_secret form parameters, whichever its token endpoint authentication method requires. Public clients only
+// need to send their client_id.
+func (h *Handler) authenticateClient(r *http.Request) (fosite.Client, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug(err.Error()))
+	}
+
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		var err error
+		if id, err = url.QueryUnescape(id); err != nil {
+			return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug("The client id in the HTTP authorization header could not be decoded"))
+		} else if secret, err = url.QueryUnescape(secret); err != nil {
+			return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug("The client secret in the HTTP authorization header could not be decoded"))
+		}
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	if id == "" {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Client credentials are missing"))
+	}
+
+	c, err := h.Storage.GetClient(r.Context(), id)
+	if err != nil {
+		return nil, errors.WithStack(fosite.ErrInvalidClient.WithDebug("The client does not exist"))
+	}
+
+	if c.IsPublic() {
+		return c, nil
+	}
+
+	if cl, isClient := c.(*client.Client); isClient {
+		switch method := cl.GetTokenEndpointAuthMethod(); method {
+		case client.TokenEndpointAuthMethodClientSecretPost:
+			if ok {
+				return nil, errors.WithStack(fosite.ErrInvalidClient.WithDebug("The client must authenticate using token endpoint authentication method " + method))
+			}
+		default:
+			// Assertions of clients using client_secret_jwt or private_key_jwt have been replaced with HTTP basic
+			// authorization by the ClientAssertionAuthenticator.
+			if !ok {
+				return nil, errors.WithStack(fosite.ErrInvalidClient.WithDebug("The client must authenticate using token endpoint authentication method " + method))
+			}
+		}
+	}
+
+	if err := h.Hasher.Compare(c.GetHashedSecret(), []byte(secret)); err != nil {
+		return nil, errors.WithStack(fosite.ErrInvalidClient.WithDebug("The client credentials are invalid"))
+	}
+
+	return c, nil
+}
+
+func (h *Handler) writeDevicePage(w http.ResponseWriter, t *template.Template) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := t.Execute(w, DeviceVerificationPath); err != nil {
+		pkg.LogError(errors.WithStack(err), h.L)
+	}
+}
+
+var deviceVerificationTemplate = template.Must(template.New("device_verification").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<title>Connect a device</title>
+</head>
+<body>
+<h1>Connect a device</h1>
+<form method="get" action="{{ . }}">
+	<p>Enter the code displayed on your device.</p>
+	<input type="text" name="user_code" autocomplete="off" autofocus>
+	<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+var deviceApprovedTemplate = template.Must(template.New("device_approved").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<title>Device connected</title>
+</head>
+<body>
+<h1>Device connected</h1>
+<p>You may now return to your device.</p>
+</body>
+</html>
+`))
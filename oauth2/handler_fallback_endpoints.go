@@ -74,3 +74,24 @@ func (h *Handler) DefaultErrorHandler(w http.ResponseWriter, r *http.Request, _
 </html>
 `, r.URL.Query().Get("error"), r.URL.Query().Get("error_description"))
 }
+
+func (h *Handler) DefaultLogoutHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	h.L.Warnln("It looks like no OAuth2 Logout Redirect URL was set.")
+
+	w.Write([]byte(`
+<html>
+<head>
+	<title>You have been logged out</title>
+</head>
+<body>
+<p>
+	You have been logged out successfully.
+</p>
+<p>
+	You are seeing this default page because the administrator has not set a dedicated logout redirect URL, which can
+	be set using the <code>OAUTH2_LOGOUT_REDIRECT_URL</code> environment variable.
+</p>
+</body>
+</html>
+`))
+}
@@ -38,7 +38,7 @@ func TestHandlerConsent(t *testing.T) {
 		ScopeStrategy: fosite.HierarchicScopeStrategy,
 	}
 	r := httprouter.New()
-	h.SetRoutes(r)
+	h.SetRoutes(r, r)
 	ts := httptest.NewServer(r)
 
 	res, err := http.Get(ts.URL + DefaultConsentPath)
@@ -50,3 +50,22 @@ func TestHandlerConsent(t *testing.T) {
 
 	assert.NotEmpty(t, body)
 }
+
+func TestHandlerLogout(t *testing.T) {
+	h := &Handler{
+		L:             logrus.New(),
+		ScopeStrategy: fosite.HierarchicScopeStrategy,
+	}
+	r := httprouter.New()
+	h.SetRoutes(r, r)
+	ts := httptest.NewServer(r)
+
+	res, err := http.Get(ts.URL + DefaultLogoutPath)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	assert.Nil(t, err)
+
+	assert.NotEmpty(t, body)
+}
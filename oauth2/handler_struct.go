@@ -21,13 +21,16 @@
 package oauth2
 
 import (
+	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/gorilla/sessions"
 	"github.com/ory/fosite"
+	"github.com/ory/fosite/token/jwt"
 	"github.com/ory/herodot"
 	"github.com/ory/hydra/consent"
+	"github.com/ory/hydra/metrics/prometheus"
 	"github.com/ory/hydra/pkg"
 	"github.com/sirupsen/logrus"
 )
@@ -42,19 +45,54 @@ type Handler struct {
 	ForcedHTTP bool
 	ErrorURL   url.URL
 
+	// LogoutRedirectURL is where the user agent is redirected to after logging out, unless the relying party
+	// requested a post_logout_redirect_uri.
+	LogoutRedirectURL url.URL
+
 	AccessTokenLifespan time.Duration
 	IDTokenLifespan     time.Duration
 	CookieStore         sessions.Store
 
-	IDTokenPublicKeyID string
+	// IDTokenSigningAlgorithms are the JWS algorithms ID tokens are signed with, as advertised by OpenID Connect
+	// discovery.
+	IDTokenSigningAlgorithms []string
+
+	// JWTStrategy signs userinfo responses for clients which registered userinfo_signed_response_alg, it should use
+	// the OpenID Connect ID Token keys.
+	JWTStrategy jwt.JWTStrategy
+
+	// HTTPClient fetches the JSON Web Key Sets of clients which registered userinfo_encrypted_response_alg.
+	HTTPClient *http.Client
 
 	L logrus.FieldLogger
 
+	Metrics *prometheus.MetricsManager
+
 	ScopeStrategy fosite.ScopeStrategy
 
 	IssuerURL string
 
+	SubjectTypes               []string
+	SubjectIdentifierAlgorithm map[string]consent.SubjectIdentifierAlgorithm
+
 	ClaimsSupported  string
 	ScopesSupported  string
 	UserinfoEndpoint string
+
+	ClientRegistrationEnabled bool
+
+	// ClientAssertionAuthenticator, if set, verifies client assertions sent to the token, introspection and
+	// revocation endpoints.
+	ClientAssertionAuthenticator *ClientAssertionAuthenticator
+
+	// Hasher compares client secrets at endpoints which are not handled by fosite, such as the device authorization
+	// endpoint.
+	Hasher fosite.Hasher
+
+	// DeviceStorage, if set, enables the device authorization and verification endpoints of the OAuth 2.0 Device
+	// Authorization Grant.
+	DeviceStorage      DeviceCodeStorage
+	DeviceCodeLifespan time.Duration
+	// ConsentManager, if set, is used to flush expired consent sessions together with inactive access tokens.
+	ConsentManager consent.Manager
 }
@@ -32,6 +32,7 @@ import (
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
 	"github.com/ory/herodot"
 	"github.com/ory/hydra/client"
 	"github.com/ory/hydra/oauth2"
@@ -85,7 +86,7 @@ func TestHandlerFlushHandler(t *testing.T) {
 	}
 
 	r := httprouter.New()
-	h.SetRoutes(r)
+	h.SetRoutes(r, r)
 	ts := httptest.NewServer(r)
 	c := hydra.NewOAuth2ApiWithBasePath(ts.URL)
 
@@ -126,11 +127,30 @@ func TestHandlerFlushHandler(t *testing.T) {
 	require.Error(t, err)
 }
 
+func newDiscoveryProvider(factories ...compose.Factory) fosite.OAuth2Provider {
+	store := oauth2.NewFositeMemoryStore(client.NewMemoryManager(hasher), time.Hour)
+	return compose.Compose(fc, store, oauth2Strategy, hasher, factories...)
+}
+
 func TestHandlerWellKnown(t *testing.T) {
 	h := &oauth2.Handler{
 		H:             herodot.NewJSONWriter(nil),
 		ScopeStrategy: fosite.HierarchicScopeStrategy,
 		IssuerURL:     "http://hydra.localhost",
+		SubjectTypes:  []string{"pairwise", "public"},
+		OAuth2: newDiscoveryProvider(
+			compose.OAuth2AuthorizeExplicitFactory,
+			compose.OAuth2AuthorizeImplicitFactory,
+			compose.OAuth2ClientCredentialsGrantFactory,
+			compose.OAuth2RefreshTokenGrantFactory,
+			compose.OAuth2PKCEFactory,
+			compose.OpenIDConnectExplicitFactory,
+			compose.OpenIDConnectHybridFactory,
+			compose.OpenIDConnectImplicitFactory,
+			compose.OAuth2TokenRevocationFactory,
+			compose.OAuth2TokenIntrospectionFactory,
+		),
+		IDTokenSigningAlgorithms: []string{"RS256"},
 	}
 
 	AuthPathT := "/oauth2/auth"
@@ -138,7 +158,7 @@ func TestHandlerWellKnown(t *testing.T) {
 	JWKPathT := "/.well-known/jwks.json"
 
 	r := httprouter.New()
-	h.SetRoutes(r)
+	h.SetRoutes(r, r)
 	ts := httptest.NewServer(r)
 
 	res, err := http.Get(ts.URL + "/.well-known/openid-configuration")
@@ -146,17 +166,30 @@ func TestHandlerWellKnown(t *testing.T) {
 	defer res.Body.Close()
 
 	trueConfig := oauth2.WellKnown{
-		Issuer:                            strings.TrimRight(h.IssuerURL, "/") + "/",
-		AuthURL:                           strings.TrimRight(h.IssuerURL, "/") + AuthPathT,
-		TokenURL:                          strings.TrimRight(h.IssuerURL, "/") + TokenPathT,
-		JWKsURI:                           strings.TrimRight(h.IssuerURL, "/") + JWKPathT,
-		SubjectTypes:                      []string{"pairwise", "public"},
-		ResponseTypes:                     []string{"code", "code id_token", "id_token", "token id_token", "token", "token id_token code"},
-		ClaimsSupported:                   []string{"sub"},
-		ScopesSupported:                   []string{"offline", "openid"},
-		UserinfoEndpoint:                  strings.TrimRight(h.IssuerURL, "/") + oauth2.UserinfoPath,
-		TokenEndpointAuthMethodsSupported: []string{"client_secret_post", "client_secret_basic"},
-		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		Issuer:                               strings.TrimRight(h.IssuerURL, "/") + "/",
+		AuthURL:                              strings.TrimRight(h.IssuerURL, "/") + AuthPathT,
+		TokenURL:                             strings.TrimRight(h.IssuerURL, "/") + TokenPathT,
+		JWKsURI:                              strings.TrimRight(h.IssuerURL, "/") + JWKPathT,
+		SubjectTypes:                         []string{"pairwise", "public"},
+		ResponseTypes:                        []string{"code", "token", "code id_token", "code token", "code id_token token", "id_token", "id_token token"},
+		ClaimsSupported:                      []string{"sub"},
+		ScopesSupported:                      []string{"offline", "openid"},
+		UserinfoEndpoint:                     strings.TrimRight(h.IssuerURL, "/") + oauth2.UserinfoPath,
+		TokenEndpointAuthMethodsSupported:    []string{"client_secret_post", "client_secret_basic"},
+		IDTokenSigningAlgValuesSupported:     []string{"RS256"},
+		EndSessionEndpoint:                   strings.TrimRight(h.IssuerURL, "/") + oauth2.LogoutPath,
+		BackChannelLogoutSupported:           true,
+		BackChannelLogoutSessionSupported:    true,
+		FrontChannelLogoutSupported:          true,
+		FrontChannelLogoutSessionSupported:   true,
+		GrantTypesSupported:                  []string{"authorization_code", "client_credentials", "refresh_token", "implicit"},
+		ResponseModesSupported:               []string{"query", "fragment"},
+		CodeChallengeMethodsSupported:        []string{"S256"},
+		RevocationEndpoint:                   strings.TrimRight(h.IssuerURL, "/") + oauth2.RevocationPath,
+		IntrospectionEndpoint:                strings.TrimRight(h.IssuerURL, "/") + oauth2.IntrospectPath,
+		UserinfoSigningAlgValuesSupported:    []string{"RS256"},
+		UserinfoEncryptionAlgValuesSupported: client.UserinfoEncryptionAlgorithms,
+		UserinfoEncryptionEncValuesSupported: client.UserinfoEncryptionEncodings,
 	}
 	var wellKnownResp oauth2.WellKnown
 	err = json.NewDecoder(res.Body).Decode(&wellKnownResp)
@@ -175,4 +208,83 @@ func TestHandlerWellKnown(t *testing.T) {
 	assert.EqualValues(t, wellKnownResp.ClaimsSupported, []string{"sub", "baz", "oof"})
 	assert.EqualValues(t, wellKnownResp.ScopesSupported, []string{"offline", "openid", "foo", "bar"})
 	assert.Equal(t, wellKnownResp.UserinfoEndpoint, "bar")
+
+	h.SubjectTypes = nil
+
+	res, err = http.Get(ts.URL + "/.well-known/openid-configuration")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&wellKnownResp))
+
+	assert.EqualValues(t, []string{"public"}, wellKnownResp.SubjectTypes)
+	assert.Empty(t, wellKnownResp.RegistrationEndpoint)
+
+	h.ClientRegistrationEnabled = true
+
+	res, err = http.Get(ts.URL + "/.well-known/openid-configuration")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&wellKnownResp))
+
+	assert.Equal(t, "http://hydra.localhost/oauth2/register", wellKnownResp.RegistrationEndpoint)
+}
+
+func TestHandlerAuthorizationServerMetadata(t *testing.T) {
+	h := &oauth2.Handler{
+		H:         herodot.NewJSONWriter(nil),
+		IssuerURL: "http://hydra.localhost/",
+		OAuth2: newDiscoveryProvider(
+			compose.OAuth2ClientCredentialsGrantFactory,
+			oauth2.TokenExchangeGrantFactory,
+		),
+	}
+
+	r := httprouter.New()
+	h.SetRoutes(r, r)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	get := func(t *testing.T) *oauth2.AuthorizationServerMetadata {
+		res, err := http.Get(ts.URL + "/.well-known/oauth-authorization-server")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var m oauth2.AuthorizationServerMetadata
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&m))
+		return &m
+	}
+
+	t.Run("case=only advertises composed handlers", func(t *testing.T) {
+		m := get(t)
+		assert.Equal(t, "http://hydra.localhost/", m.Issuer)
+		assert.Equal(t, "http://hydra.localhost/oauth2/token", m.TokenURL)
+		assert.EqualValues(t, []string{"client_credentials", oauth2.TokenExchangeGrantType}, m.GrantTypesSupported)
+		assert.Empty(t, m.ResponseTypesSupported)
+		assert.Empty(t, m.CodeChallengeMethodsSupported)
+		assert.Empty(t, m.RevocationEndpoint)
+		assert.Empty(t, m.IntrospectionEndpoint)
+		assert.Empty(t, m.TokenEndpointAuthSigningAlgValuesSupported)
+	})
+
+	t.Run("case=advertises revocation, introspection and client assertions", func(t *testing.T) {
+		h.OAuth2 = newDiscoveryProvider(
+			compose.OAuth2AuthorizeExplicitFactory,
+			compose.OAuth2PKCEFactory,
+			compose.OAuth2TokenRevocationFactory,
+			compose.OAuth2TokenIntrospectionFactory,
+		)
+		h.ClientAssertionAuthenticator = &oauth2.ClientAssertionAuthenticator{}
+
+		m := get(t)
+		assert.EqualValues(t, []string{"authorization_code"}, m.GrantTypesSupported)
+		assert.EqualValues(t, []string{"code"}, m.ResponseTypesSupported)
+		assert.EqualValues(t, []string{"S256"}, m.CodeChallengeMethodsSupported)
+		assert.Equal(t, "http://hydra.localhost/oauth2/revoke", m.RevocationEndpoint)
+		assert.Equal(t, "http://hydra.localhost/oauth2/introspect", m.IntrospectionEndpoint)
+		assert.Contains(t, m.TokenEndpointAuthMethodsSupported, "private_key_jwt")
+		assert.EqualValues(t, m.TokenEndpointAuthMethodsSupported, m.IntrospectionEndpointAuthMethodsSupported)
+		assert.Contains(t, m.TokenEndpointAuthSigningAlgValuesSupported, "ES256")
+		assert.Contains(t, m.TokenEndpointAuthSigningAlgValuesSupported, "HS256")
+	})
 }
@@ -76,4 +76,8 @@ type Introspection struct {
 
 	// Extra is arbitrary data set by the session.
 	Extra map[string]interface{} `json:"ext,omitempty"`
+
+	// Actor identifies the party acting on behalf of the subject, if the token was obtained through delegation
+	// using OAuth 2.0 Token Exchange.
+	Actor map[string]interface{} `json:"act,omitempty"`
 }
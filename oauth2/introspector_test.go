@@ -33,6 +33,8 @@ import (
 	"github.com/ory/fosite/compose"
 	"github.com/ory/fosite/storage"
 	"github.com/ory/herodot"
+	hc "github.com/ory/hydra/client"
+	"github.com/ory/hydra/consent"
 	"github.com/ory/hydra/oauth2"
 	"github.com/ory/hydra/pkg"
 	hydra "github.com/ory/hydra/sdk/go/hydra/swagger"
@@ -42,7 +44,7 @@ import (
 )
 
 func TestIntrospectorSDK(t *testing.T) {
-	tokens := pkg.Tokens(3)
+	tokens := pkg.Tokens(4)
 	memoryStore := storage.NewExampleStore()
 	memoryStore.Clients["my-client"].Scopes = []string{"fosite", "openid", "photos", "offline", "foo.*"}
 
@@ -65,8 +67,11 @@ func TestIntrospectorSDK(t *testing.T) {
 		),
 		H:         herodot.NewJSONWriter(l),
 		IssuerURL: "foobariss",
+		SubjectIdentifierAlgorithm: map[string]consent.SubjectIdentifierAlgorithm{
+			consent.SubjectTypePairwise: consent.NewSubjectIdentifierAlgorithmPairwise([]byte("76d5d2bf-747f-4592-9fbd-d2b895a54b3a")),
+		},
 	}
-	handler.SetRoutes(router)
+	handler.SetRoutes(router, router)
 	server := httptest.NewServer(router)
 
 	now := time.Now().UTC().Round(time.Minute)
@@ -74,6 +79,17 @@ func TestIntrospectorSDK(t *testing.T) {
 	createAccessTokenSession("siri", "my-client", tokens[1][0], now.Add(-time.Hour), memoryStore, fosite.Arguments{"core", "foo.*"})
 	createAccessTokenSession("my-client", "my-client", tokens[2][0], now.Add(time.Hour), memoryStore, fosite.Arguments{"hydra.introspect"})
 
+	pairwiseClient := &hc.Client{ID: "pairwise-client", SubjectType: consent.SubjectTypePairwise, RedirectURIs: []string{"https://pairwise.example.com/callback"}}
+	pairwiseSubject, err := consent.ObfuscateSubjectIdentifier(handler.SubjectIdentifierAlgorithm, pairwiseClient, "alice")
+	require.NoError(t, err)
+
+	ar := fosite.NewAccessRequest(oauth2.NewSession("alice"))
+	ar.GrantedScopes = fosite.Arguments{"core"}
+	ar.RequestedAt = now
+	ar.Client = pairwiseClient
+	ar.Session.SetExpiresAt(fosite.AccessToken, now.Add(time.Hour))
+	require.NoError(t, memoryStore.CreateAccessTokenSession(nil, tokens[3][0], ar))
+
 	t.Run("TestIntrospect", func(t *testing.T) {
 		for k, c := range []struct {
 			token          string
@@ -148,6 +164,16 @@ func TestIntrospectorSDK(t *testing.T) {
 					assert.Equal(t, map[string]interface{}{"foo": "bar"}, c.Ext)
 				},
 			},
+			{
+				description:    "should return the pairwise subject identifier of the client the token was issued to",
+				token:          tokens[3][1],
+				expectInactive: false,
+				assert: func(t *testing.T, c *hydra.OAuth2TokenIntrospection) {
+					assert.Equal(t, pairwiseSubject, c.Sub)
+					assert.NotEqual(t, "alice", c.Sub)
+					assert.Equal(t, "pairwise-client", c.ClientId)
+				},
+			},
 		} {
 			t.Run(fmt.Sprintf("case=%d/description=%s", k, c.description), func(t *testing.T) {
 				var client *hydra.OAuth2Api
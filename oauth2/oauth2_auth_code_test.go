@@ -119,6 +119,15 @@ func TestAuthCodeWithDefaultStrategy(t *testing.T) {
 			case "memory":
 				cm = consent.NewMemoryManager()
 				fs.(*FositeMemoryStore).Manager = hc.NewMemoryManager(hasher)
+			case "bolt":
+				cm = consent.NewBoltManager(fs.(*FositeBoltStore).DB, fs.(*FositeBoltStore).Manager)
+			case "redis":
+				rs := redisStore
+				rs.Clients = hc.NewMemoryManager(hasher)
+				rs.FositeStorer.(*FositeMemoryStore).Manager = rs.Clients
+				cm = consent.NewRedisManager(consent.NewMemoryManager(), rs.Redis, rs.Clients, time.Hour)
+			case "sqlite":
+				fallthrough
 			case "mysql":
 				fallthrough
 			case "postgres":
@@ -141,6 +150,9 @@ func TestAuthCodeWithDefaultStrategy(t *testing.T) {
 				cookieStore,
 				fosite.ExactScopeStrategy, false, time.Hour, jwts,
 				openid.NewOpenIDConnectRequestValidator(nil, jwts),
+				map[string]consent.SubjectIdentifierAlgorithm{consent.SubjectTypePublic: consent.NewSubjectIdentifierAlgorithmPublic()},
+				fs,
+				nil,
 			)
 
 			handler := &Handler{
@@ -162,7 +174,7 @@ func TestAuthCodeWithDefaultStrategy(t *testing.T) {
 				ScopeStrategy:   fosite.ExactScopeStrategy,
 				IDTokenLifespan: time.Minute, IssuerURL: ts.URL, ForcedHTTP: true, L: l,
 			}
-			handler.SetRoutes(router)
+			handler.SetRoutes(router, router)
 
 			apiHandler := consent.NewHandler(herodot.NewJSONWriter(l), cm)
 			apiRouter := httprouter.New()
@@ -181,7 +193,12 @@ func TestAuthCodeWithDefaultStrategy(t *testing.T) {
 				RedirectURL: client.RedirectURIs[0], Scopes: []string{"hydra", "offline", "openid"},
 			}
 
-			require.NoError(t, fs.(clientCreator).CreateClient(&client))
+			creator, ok := fs.(clientCreator)
+			if !ok {
+				// The Redis store does not embed the client manager.
+				creator = redisStore.Clients
+			}
+			require.NoError(t, creator.CreateClient(&client))
 			apiClient := swagger.NewOAuth2ApiWithBasePath(api.URL)
 
 			var callbackHandler *httprouter.Handle
@@ -620,7 +637,7 @@ func TestAuthCodeWithMockStrategy(t *testing.T) {
 		IDTokenLifespan: time.Minute,
 		IssuerURL:       ts.URL,
 	}
-	handler.SetRoutes(router)
+	handler.SetRoutes(router, router)
 
 	var callbackHandler *httprouter.Handle
 	router.GET("/callback", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
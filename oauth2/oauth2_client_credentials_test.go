@@ -65,7 +65,7 @@ func TestClientCredentials(t *testing.T) {
 		IssuerURL:       ts.URL,
 	}
 
-	handler.SetRoutes(router)
+	handler.SetRoutes(router, router)
 
 	require.NoError(t, store.CreateClient(&hc.Client{
 		ID:            "app-client",
@@ -0,0 +1,185 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	"github.com/ory/herodot"
+	hc "github.com/ory/hydra/client"
+	. "github.com/ory/hydra/oauth2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceAuthorizationGrant(t *testing.T) {
+	router := httprouter.New()
+	l := logrus.New()
+	l.Level = logrus.DebugLevel
+	store := NewFositeMemoryStore(hc.NewMemoryManager(hasher), time.Second)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	handler := &Handler{
+		OAuth2: compose.Compose(
+			fc,
+			store,
+			oauth2Strategy,
+			hasher,
+			DeviceCodeGrantFactory,
+		),
+		Consent:            &consentMock{},
+		Storage:            store,
+		CookieStore:        sessions.NewCookieStore([]byte("foo-secret")),
+		ForcedHTTP:         true,
+		ScopeStrategy:      fosite.HierarchicScopeStrategy,
+		IDTokenLifespan:    time.Minute,
+		H:                  herodot.NewJSONWriter(l),
+		L:                  l,
+		IssuerURL:          ts.URL,
+		Hasher:             hasher,
+		DeviceStorage:      store,
+		DeviceCodeLifespan: time.Minute,
+	}
+	handler.SetRoutes(router, router)
+
+	require.NoError(t, store.CreateClient(&hc.Client{
+		ID:         "device-client",
+		Secret:     "secret",
+		GrantTypes: []string{DeviceCodeGrantType, "refresh_token"},
+		Scope:      "offline openid hydra",
+	}))
+	require.NoError(t, store.CreateClient(&hc.Client{
+		ID:         "other-client",
+		Secret:     "secret",
+		GrantTypes: []string{"client_credentials"},
+		Scope:      "offline openid hydra",
+	}))
+
+	post := func(t *testing.T, path, id string, form url.Values) (int, map[string]interface{}) {
+		req, err := http.NewRequest("POST", ts.URL+path, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(id, "secret")
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		return res.StatusCode, body
+	}
+
+	t.Run("case=client without the device code grant type is rejected", func(t *testing.T) {
+		code, body := post(t, "/oauth2/device/auth", "other-client", url.Values{"scope": {"openid"}})
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "unauthorized_client", body["error"])
+	})
+
+	t.Run("case=clients must authenticate using their token endpoint authentication method", func(t *testing.T) {
+		for k, form := range []url.Values{
+			{"client_id": {"device-client"}},
+			{"client_id": {"device-client"}, "client_secret": {"secret"}},
+			{"client_id": {"device-client"}, "client_secret": {"not-the-secret"}},
+		} {
+			res, err := http.PostForm(ts.URL+"/oauth2/device/auth", form)
+			require.NoError(t, err, "%d", k)
+			res.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "%d", k)
+		}
+	})
+
+	t.Run("case=unknown scopes are rejected", func(t *testing.T) {
+		code, body := post(t, "/oauth2/device/auth", "device-client", url.Values{"scope": {"foo"}})
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "invalid_scope", body["error"])
+	})
+
+	t.Run("case=device flow completes", func(t *testing.T) {
+		code, body := post(t, "/oauth2/device/auth", "device-client", url.Values{"scope": {"offline openid hydra"}})
+		require.Equal(t, http.StatusOK, code, "%+v", body)
+
+		var auth DeviceAuthorizationResponse
+		out, _ := json.Marshal(body)
+		require.NoError(t, json.Unmarshal(out, &auth))
+		assert.NotEmpty(t, auth.DeviceCode)
+		assert.Len(t, auth.UserCode, 9)
+		assert.Equal(t, ts.URL+"/oauth2/device/verify", auth.VerificationURI)
+		assert.EqualValues(t, 60, auth.ExpiresIn)
+		assert.EqualValues(t, 5, auth.Interval)
+
+		poll := url.Values{"grant_type": {DeviceCodeGrantType}, "device_code": {auth.DeviceCode}}
+
+		code, body = post(t, "/oauth2/token", "device-client", poll)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "authorization_pending", body["error"])
+
+		code, body = post(t, "/oauth2/token", "device-client", poll)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "slow_down", body["error"])
+
+		code, body = post(t, "/oauth2/token", "other-client", poll)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "invalid_grant", body["error"])
+
+		res, err := http.Get(auth.VerificationURIComplete)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		page, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Contains(t, string(page), "Device connected")
+
+		code, body = post(t, "/oauth2/token", "device-client", poll)
+		require.Equal(t, http.StatusOK, code, "%+v", body)
+		assert.NotEmpty(t, body["access_token"])
+		assert.NotEmpty(t, body["refresh_token"])
+		assert.NotEmpty(t, body["id_token"])
+
+		code, body = post(t, "/oauth2/token", "device-client", poll)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "invalid_grant", body["error"])
+	})
+
+	t.Run("case=unknown user codes are redirected to the error url", func(t *testing.T) {
+		c := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		res, err := c.Get(ts.URL + "/oauth2/device/verify?user_code=BBBB-BBBB")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+	})
+}
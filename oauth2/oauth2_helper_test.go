@@ -65,3 +65,11 @@ func (c *consentMock) HandleOAuth2AuthorizationRequest(w http.ResponseWriter, r
 		RequestedAt: c.requestTime,
 	}, nil
 }
+
+func (c *consentMock) HandleOAuth2DeviceVerificationRequest(w http.ResponseWriter, r *http.Request, req fosite.AuthorizeRequester) (*consent.HandledConsentRequest, error) {
+	return c.HandleOAuth2AuthorizationRequest(w, r, req)
+}
+
+func (c *consentMock) HandleOpenIDConnectLogout(w http.ResponseWriter, r *http.Request) (*consent.LogoutResult, error) {
+	return &consent.LogoutResult{}, nil
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	"github.com/ory/herodot"
+	hc "github.com/ory/hydra/client"
+	. "github.com/ory/hydra/oauth2"
+	"github.com/pborman/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshTokenRotation(t *testing.T) {
+	router := httprouter.New()
+	l := logrus.New()
+	l.Level = logrus.DebugLevel
+	store := NewFositeMemoryStore(hc.NewMemoryManager(hasher), time.Hour)
+	config := &compose.Config{
+		AccessTokenLifespan:        time.Hour,
+		SendDebugMessagesToClients: true,
+	}
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	handler := &Handler{
+		OAuth2: compose.Compose(
+			config,
+			store,
+			oauth2Strategy,
+			hasher,
+			NewRefreshTokenGrantFactory(l),
+			compose.OAuth2TokenIntrospectionFactory,
+		),
+		CookieStore:     sessions.NewCookieStore([]byte("foo-secret")),
+		ForcedHTTP:      true,
+		ScopeStrategy:   fosite.HierarchicScopeStrategy,
+		IDTokenLifespan: time.Minute,
+		H:               herodot.NewJSONWriter(l),
+		L:               l,
+		IssuerURL:       ts.URL,
+	}
+	handler.SetRoutes(router, router)
+
+	require.NoError(t, store.CreateClient(&hc.Client{
+		ID:         "strict-client",
+		Secret:     "secret",
+		GrantTypes: []string{"refresh_token"},
+		Scope:      "offline",
+	}))
+	require.NoError(t, store.CreateClient(&hc.Client{
+		ID:                      "mobile-client",
+		Secret:                  "secret",
+		GrantTypes:              []string{"refresh_token"},
+		Scope:                   "offline",
+		RefreshTokenGracePeriod: 60,
+	}))
+
+	// authorize issues an access and a refresh token as if the client had just completed an authorization.
+	authorize := func(t *testing.T, id string) string {
+		ctx := context.Background()
+		c, err := store.GetClient(ctx, id)
+		require.NoError(t, err)
+
+		req := &fosite.Request{
+			ID:            uuid.New(),
+			RequestedAt:   time.Now().UTC(),
+			Client:        c,
+			Scopes:        fosite.Arguments{"offline"},
+			GrantedScopes: fosite.Arguments{"offline"},
+			Form:          url.Values{},
+			Session:       NewSession("foo"),
+		}
+
+		_, accessSignature, err := oauth2Strategy.GenerateAccessToken(ctx, req)
+		require.NoError(t, err)
+		require.NoError(t, store.CreateAccessTokenSession(ctx, accessSignature, req))
+
+		refresh, refreshSignature, err := oauth2Strategy.GenerateRefreshToken(ctx, req)
+		require.NoError(t, err)
+		require.NoError(t, store.CreateRefreshTokenSession(ctx, refreshSignature, req))
+		return refresh
+	}
+
+	post := func(t *testing.T, path, id string, form url.Values) (int, map[string]interface{}) {
+		req, err := http.NewRequest("POST", ts.URL+path, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(id, "secret")
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		return res.StatusCode, body
+	}
+
+	refresh := func(t *testing.T, id, token string) (int, map[string]interface{}) {
+		return post(t, "/oauth2/token", id, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {token}})
+	}
+
+	active := func(t *testing.T, id, token string) bool {
+		code, body := post(t, "/oauth2/introspect", id, url.Values{"token": {token}})
+		require.Equal(t, http.StatusOK, code, "%+v", body)
+		return body["active"] == true
+	}
+
+	t.Run("case=reuse of a rotated refresh token revokes the family", func(t *testing.T) {
+		original := authorize(t, "strict-client")
+
+		code, first := refresh(t, "strict-client", original)
+		require.Equal(t, http.StatusOK, code, "%+v", first)
+		require.NotEmpty(t, first["refresh_token"])
+		assert.NotEqual(t, original, first["refresh_token"])
+		assert.True(t, active(t, "strict-client", first["access_token"].(string)))
+
+		code, body := refresh(t, "strict-client", original)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "invalid_grant", body["error"])
+
+		assert.False(t, active(t, "strict-client", first["access_token"].(string)))
+		assert.False(t, active(t, "strict-client", first["refresh_token"].(string)))
+
+		code, body = refresh(t, "strict-client", first["refresh_token"].(string))
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "invalid_grant", body["error"])
+	})
+
+	t.Run("case=only one of several concurrent refreshes succeeds without a grace period", func(t *testing.T) {
+		original := authorize(t, "strict-client")
+
+		var wg sync.WaitGroup
+		codes := make([]int, 5)
+		for k := range codes {
+			wg.Add(1)
+			go func(k int) {
+				defer wg.Done()
+				codes[k], _ = refresh(t, "strict-client", original)
+			}(k)
+		}
+		wg.Wait()
+
+		var succeeded int
+		for _, code := range codes {
+			if code == http.StatusOK {
+				succeeded++
+			} else {
+				assert.Equal(t, http.StatusBadRequest, code)
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+	})
+
+	t.Run("case=refresh tokens issued to another client are rejected", func(t *testing.T) {
+		code, body := refresh(t, "mobile-client", authorize(t, "strict-client"))
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "invalid_request", body["error"])
+	})
+
+	t.Run("case=rotated refresh tokens are accepted within the grace period", func(t *testing.T) {
+		original := authorize(t, "mobile-client")
+
+		code, first := refresh(t, "mobile-client", original)
+		require.Equal(t, http.StatusOK, code, "%+v", first)
+
+		code, second := refresh(t, "mobile-client", original)
+		require.Equal(t, http.StatusOK, code, "%+v", second)
+		assert.NotEqual(t, first["refresh_token"], second["refresh_token"])
+
+		assert.True(t, active(t, "mobile-client", first["access_token"].(string)))
+		assert.True(t, active(t, "mobile-client", second["access_token"].(string)))
+
+		code, third := refresh(t, "mobile-client", first["refresh_token"].(string))
+		require.Equal(t, http.StatusOK, code, "%+v", third)
+	})
+}
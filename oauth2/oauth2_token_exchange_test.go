@@ -0,0 +1,245 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	"github.com/ory/herodot"
+	hc "github.com/ory/hydra/client"
+	. "github.com/ory/hydra/oauth2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+func TestTokenExchange(t *testing.T) {
+	router := httprouter.New()
+	l := logrus.New()
+	l.Level = logrus.DebugLevel
+	store := NewFositeMemoryStore(hc.NewMemoryManager(hasher), time.Hour)
+	config := &compose.Config{
+		AccessTokenLifespan:        time.Hour,
+		SendDebugMessagesToClients: true,
+		ScopeStrategy:              fosite.HierarchicScopeStrategy,
+	}
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	handler := &Handler{
+		OAuth2: compose.Compose(
+			config,
+			store,
+			oauth2Strategy,
+			hasher,
+			compose.OAuth2ClientCredentialsGrantFactory,
+			compose.OAuth2TokenIntrospectionFactory,
+			compose.OAuth2TokenRevocationFactory,
+			TokenExchangeGrantFactory,
+		),
+		CookieStore:     sessions.NewCookieStore([]byte("foo-secret")),
+		ForcedHTTP:      true,
+		ScopeStrategy:   fosite.HierarchicScopeStrategy,
+		IDTokenLifespan: time.Minute,
+		H:               herodot.NewJSONWriter(l),
+		L:               l,
+		IssuerURL:       ts.URL,
+	}
+	handler.SetRoutes(router, router)
+
+	require.NoError(t, store.CreateClient(&hc.Client{
+		ID:         "frontend",
+		Secret:     "secret",
+		GrantTypes: []string{"client_credentials"},
+		Scope:      "foo bar baz",
+	}))
+	require.NoError(t, store.CreateClient(&hc.Client{
+		ID:                     "gateway",
+		Secret:                 "secret",
+		GrantTypes:             []string{"client_credentials", TokenExchangeGrantType},
+		Scope:                  "gateway",
+		TokenExchangeAudiences: []string{"https://downstream.example.com"},
+		TokenExchangeScope:     "foo bar",
+	}))
+
+	require.NoError(t, store.CreateClient(&hc.Client{
+		ID:                     "public-gateway",
+		Public:                 true,
+		GrantTypes:             []string{TokenExchangeGrantType},
+		TokenExchangeAudiences: []string{"https://downstream.example.com"},
+		TokenExchangeScope:     "foo bar",
+	}))
+
+	token := func(t *testing.T, id string, scopes ...string) string {
+		tok, err := (&clientcredentials.Config{
+			ClientID:     id,
+			ClientSecret: "secret",
+			TokenURL:     ts.URL + "/oauth2/token",
+			Scopes:       scopes,
+		}).Token(context.Background())
+		require.NoError(t, err)
+		return tok.AccessToken
+	}
+
+	post := func(t *testing.T, path string, form url.Values) (int, map[string]interface{}) {
+		req, err := http.NewRequest("POST", ts.URL+path, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("gateway", "secret")
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		var body map[string]interface{}
+		if res.StatusCode != http.StatusOK || path != "/oauth2/revoke" {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		}
+		return res.StatusCode, body
+	}
+
+	subjectToken := token(t, "frontend", "foo", "bar", "baz")
+	exchange := func(extra url.Values) url.Values {
+		form := url.Values{
+			"grant_type":         {TokenExchangeGrantType},
+			"subject_token":      {subjectToken},
+			"subject_token_type": {AccessTokenType},
+			"audience":           {"https://downstream.example.com"},
+		}
+		for k, v := range extra {
+			form[k] = v
+		}
+		return form
+	}
+
+	for k, tc := range []struct {
+		d     string
+		form  url.Values
+		error string
+	}{
+		{
+			d:     "should fail because the subject token is unknown",
+			form:  exchange(url.Values{"subject_token": {"foo.bar"}}),
+			error: "invalid_request",
+		},
+		{
+			d:     "should fail because the subject token type is not supported",
+			form:  exchange(url.Values{"subject_token_type": {"urn:ietf:params:oauth:token-type:id_token"}}),
+			error: "invalid_request",
+		},
+		{
+			d:     "should fail because the audience is not allowed",
+			form:  exchange(url.Values{"audience": {"https://evil.example.com"}}),
+			error: "invalid_request",
+		},
+		{
+			d:     "should fail because the client may not request the scope",
+			form:  exchange(url.Values{"scope": {"baz"}}),
+			error: "invalid_scope",
+		},
+		{
+			d:     "should fail because the actor token was issued to another client",
+			form:  exchange(url.Values{"actor_token": {token(t, "frontend", "foo")}, "actor_token_type": {AccessTokenType}}),
+			error: "invalid_request",
+		},
+		{
+			d:     "should fail because the requested token type is not supported",
+			form:  exchange(url.Values{"requested_token_type": {"urn:ietf:params:oauth:token-type:refresh_token"}}),
+			error: "invalid_request",
+		},
+	} {
+		t.Run("case="+tc.d, func(t *testing.T) {
+			code, body := post(t, "/oauth2/token", tc.form)
+			assert.Equal(t, http.StatusBadRequest, code, "%d: %+v", k, body)
+			assert.Equal(t, tc.error, body["error"], "%d: %+v", k, body)
+		})
+	}
+
+	t.Run("case=should fail because the client is public", func(t *testing.T) {
+		form := exchange(url.Values{"client_id": {"public-gateway"}})
+		res, err := http.PostForm(ts.URL+"/oauth2/token", form)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		assert.Equal(t, "unauthorized_client", body["error"], "%+v", body)
+	})
+
+	t.Run("case=should fail because the scope was not granted to the subject token", func(t *testing.T) {
+		form := exchange(url.Values{"scope": {"bar"}})
+		form.Set("subject_token", token(t, "frontend", "foo"))
+		code, body := post(t, "/oauth2/token", form)
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "invalid_scope", body["error"])
+	})
+
+	t.Run("case=should impersonate the subject", func(t *testing.T) {
+		code, body := post(t, "/oauth2/token", exchange(url.Values{"scope": {"foo"}}))
+		require.Equal(t, http.StatusOK, code, "%+v", body)
+		assert.Equal(t, AccessTokenType, body["issued_token_type"])
+		assert.Nil(t, body["refresh_token"])
+
+		code, introspection := post(t, "/oauth2/introspect", url.Values{"token": {body["access_token"].(string)}})
+		require.Equal(t, http.StatusOK, code, "%+v", introspection)
+		assert.Equal(t, true, introspection["active"])
+		assert.Equal(t, "frontend", introspection["sub"])
+		assert.Equal(t, "gateway", introspection["client_id"])
+		assert.Equal(t, "foo", introspection["scope"])
+		assert.EqualValues(t, []interface{}{"https://downstream.example.com"}, introspection["aud"])
+		assert.Nil(t, introspection["act"])
+
+		code, _ = post(t, "/oauth2/revoke", url.Values{"token": {body["access_token"].(string)}})
+		require.Equal(t, http.StatusOK, code)
+
+		code, introspection = post(t, "/oauth2/introspect", url.Values{"token": {body["access_token"].(string)}})
+		require.Equal(t, http.StatusOK, code, "%+v", introspection)
+		assert.Equal(t, false, introspection["active"])
+	})
+
+	t.Run("case=should delegate to the actor", func(t *testing.T) {
+		code, body := post(t, "/oauth2/token", exchange(url.Values{
+			"actor_token":      {token(t, "gateway", "gateway")},
+			"actor_token_type": {AccessTokenType},
+		}))
+		require.Equal(t, http.StatusOK, code, "%+v", body)
+
+		code, introspection := post(t, "/oauth2/introspect", url.Values{"token": {body["access_token"].(string)}})
+		require.Equal(t, http.StatusOK, code, "%+v", introspection)
+		assert.Equal(t, true, introspection["active"])
+		assert.Equal(t, "frontend", introspection["sub"])
+		assert.Equal(t, "foo bar", introspection["scope"])
+		assert.EqualValues(t, map[string]interface{}{"sub": "gateway", "client_id": "gateway"}, introspection["act"])
+	})
+}
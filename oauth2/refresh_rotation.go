@@ -0,0 +1,217 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @Copyright 	2017-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2
+
+import (
+	"context"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/hydra/client"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrRefreshTokenRotated is returned by RotateRefreshTokenSession if the refresh token has already been rotated, for
+// example by a concurrent refresh.
+var ErrRefreshTokenRotated = errors.New("The refresh token has already been rotated")
+
+// RefreshTokenRotationStorage keeps refresh tokens after they have been rotated, so that their reuse can be detected.
+//
+// All refresh and access tokens issued from the same authorization share the request ID of that authorization, which
+// makes it the identifier of the refresh token family.
+type RefreshTokenRotationStorage interface {
+	foauth2.TokenRevocationStorage
+
+	// RotateRefreshTokenSession marks a refresh token as rotated. Afterwards, GetRefreshTokenSession returns
+	// fosite.ErrInactiveToken for it until the family is revoked. Only one of several concurrent rotations of the same
+	// refresh token succeeds, the others return ErrRefreshTokenRotated. fosite.ErrNotFound is returned if the refresh
+	// token does not exist.
+	RotateRefreshTokenSession(ctx context.Context, signature string, rotatedAt time.Time) error
+
+	// GetRotatedRefreshTokenSession returns a rotated refresh token and the time it was rotated at.
+	GetRotatedRefreshTokenSession(ctx context.Context, signature string, session fosite.Session) (fosite.Requester, time.Time, error)
+}
+
+// RefreshTokenGrantHandler implements the refresh token grant with refresh token rotation. Every refresh rotates the
+// refresh token. If a rotated refresh token is presented again, the whole refresh token family is revoked, because
+// either the client or an attacker holds a stolen token. Clients may configure a grace period during which a rotated
+// refresh token is still accepted, to support concurrent refreshes.
+type RefreshTokenGrantHandler struct {
+	*foauth2.HandleHelper
+
+	RefreshTokenStrategy foauth2.RefreshTokenStrategy
+	Storage              RefreshTokenRotationStorage
+	L                    logrus.FieldLogger
+}
+
+// NewRefreshTokenGrantFactory returns a factory which creates a RefreshTokenGrantHandler and is meant to be used with
+// compose.Compose in place of compose.OAuth2RefreshTokenGrantFactory. Reuse of rotated refresh tokens is logged to l.
+func NewRefreshTokenGrantFactory(l logrus.FieldLogger) compose.Factory {
+	return func(config *compose.Config, storage interface{}, strategy interface{}) interface{} {
+		return &RefreshTokenGrantHandler{
+			HandleHelper: &foauth2.HandleHelper{
+				AccessTokenStrategy: strategy.(foauth2.AccessTokenStrategy),
+				AccessTokenStorage:  storage.(foauth2.AccessTokenStorage),
+				AccessTokenLifespan: config.GetAccessTokenLifespan(),
+			},
+			RefreshTokenStrategy: strategy.(foauth2.RefreshTokenStrategy),
+			Storage:              storage.(RefreshTokenRotationStorage),
+			L:                    l,
+		}
+	}
+}
+
+// HandleTokenEndpointRequest validates the refresh token and revokes its family if it has been rotated before.
+func (h *RefreshTokenGrantHandler) HandleTokenEndpointRequest(ctx context.Context, request fosite.AccessRequester) error {
+	if !request.GetGrantTypes().Exact("refresh_token") {
+		return errors.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	if !request.GetClient().GetGrantTypes().Has("refresh_token") {
+		return errors.WithStack(fosite.ErrInvalidGrant.WithDebug("The client is not allowed to use grant type refresh_token"))
+	}
+
+	refresh := request.GetRequestForm().Get("refresh_token")
+	original, _, err := h.getRefreshTokenSession(ctx, request, refresh, request.GetSession())
+	if err != nil {
+		return err
+	}
+
+	if err := h.RefreshTokenStrategy.ValidateRefreshToken(ctx, original, refresh); err != nil {
+		return errors.WithStack(fosite.ErrInvalidRequest.WithDebug(err.Error()))
+	}
+
+	if !original.GetGrantedScopes().Has("offline") {
+		return errors.WithStack(fosite.ErrScopeNotGranted.WithDebug("The client is not allowed to use grant type refresh_token"))
+	}
+
+	if original.GetClient().GetID() != request.GetClient().GetID() {
+		return errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Client ID mismatch"))
+	}
+
+	request.SetID(original.GetID())
+	request.SetSession(original.GetSession().Clone())
+	request.SetRequestedScopes(original.GetRequestedScopes())
+	for _, scope := range original.GetGrantedScopes() {
+		request.GrantScope(scope)
+	}
+
+	request.GetSession().SetExpiresAt(fosite.AccessToken, time.Now().UTC().Add(h.AccessTokenLifespan))
+	return nil
+}
+
+// PopulateTokenEndpointResponse rotates the refresh token and issues new access and refresh tokens. Refreshes within
+// the grace period of an already rotated refresh token leave the tokens issued by the first refresh intact.
+func (h *RefreshTokenGrantHandler) PopulateTokenEndpointResponse(ctx context.Context, request fosite.AccessRequester, responder fosite.AccessResponder) error {
+	if !request.GetGrantTypes().Exact("refresh_token") {
+		return errors.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	refresh := request.GetRequestForm().Get("refresh_token")
+	original, rotated, err := h.getRefreshTokenSession(ctx, request, refresh, NewSession(""))
+	if err != nil {
+		return err
+	}
+
+	if !rotated {
+		err := h.Storage.RotateRefreshTokenSession(ctx, h.RefreshTokenStrategy.RefreshTokenSignature(refresh), time.Now().UTC())
+		if errors.Cause(err) == ErrRefreshTokenRotated {
+			// The refresh token was rotated by a concurrent refresh, which is handled like any other reuse of a rotated
+			// refresh token: it is only accepted within the grace period, otherwise its family is revoked.
+			if _, _, err := h.getRefreshTokenSession(ctx, request, refresh, NewSession("")); err != nil {
+				return err
+			}
+		} else if errors.Cause(err) == fosite.ErrNotFound {
+			return errors.WithStack(fosite.ErrInvalidGrant.WithDebug("The refresh token is unknown or has been revoked"))
+		} else if err != nil {
+			return errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+		} else if err := h.Storage.RevokeAccessToken(ctx, original.GetID()); err != nil {
+			return errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+		}
+	}
+
+	if err := h.IssueAccessToken(ctx, request, responder); err != nil {
+		return err
+	}
+
+	token, signature, err := h.RefreshTokenStrategy.GenerateRefreshToken(ctx, request)
+	if err != nil {
+		return errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+	}
+
+	if err := h.Storage.CreateRefreshTokenSession(ctx, signature, request.Sanitize([]string{})); err != nil {
+		return errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+	}
+
+	responder.SetExtra("refresh_token", token)
+	return nil
+}
+
+// getRefreshTokenSession returns the refresh token and whether it has already been rotated. A rotated refresh token
+// is only returned if it was rotated within the grace period of the client, otherwise its family is revoked.
+func (h *RefreshTokenGrantHandler) getRefreshTokenSession(ctx context.Context, request fosite.AccessRequester, refresh string, session fosite.Session) (fosite.Requester, bool, error) {
+	signature := h.RefreshTokenStrategy.RefreshTokenSignature(refresh)
+	original, err := h.Storage.GetRefreshTokenSession(ctx, signature, session)
+	if err == nil {
+		return original, false, nil
+	} else if errors.Cause(err) == fosite.ErrNotFound {
+		return nil, false, errors.WithStack(fosite.ErrInvalidGrant.WithDebug("The refresh token is unknown or has been revoked"))
+	} else if errors.Cause(err) != fosite.ErrInactiveToken {
+		return nil, false, errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+	}
+
+	original, rotatedAt, err := h.Storage.GetRotatedRefreshTokenSession(ctx, signature, session)
+	if errors.Cause(err) == fosite.ErrNotFound {
+		return nil, false, errors.WithStack(fosite.ErrInvalidGrant.WithDebug("The refresh token is unknown or has been revoked"))
+	} else if err != nil {
+		return nil, false, errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+	}
+
+	var gracePeriod time.Duration
+	if c, ok := original.GetClient().(*client.Client); ok {
+		gracePeriod = time.Second * time.Duration(c.RefreshTokenGracePeriod)
+	}
+
+	if original.GetClient().GetID() == request.GetClient().GetID() && rotatedAt.Add(gracePeriod).After(time.Now().UTC()) {
+		return original, true, nil
+	}
+
+	l := h.L.WithFields(logrus.Fields{
+		"event":      "refresh_token_reuse",
+		"request_id": original.GetID(),
+		"client_id":  original.GetClient().GetID(),
+		"subject":    original.GetSession().GetSubject(),
+		"rotated_at": rotatedAt,
+	})
+	l.Warn("A rotated refresh token was used again, revoking all tokens issued from the same authorization")
+
+	if err := h.Storage.RevokeRefreshToken(ctx, original.GetID()); err != nil {
+		l.WithError(err).Error("Unable to revoke the refresh tokens of the refresh token family")
+	}
+	if err := h.Storage.RevokeAccessToken(ctx, original.GetID()); err != nil {
+		l.WithError(err).Error("Unable to revoke the access tokens of the refresh token family")
+	}
+
+	return nil, false, errors.WithStack(fosite.ErrInvalidGrant.WithDebug("The refresh token has already been used, all tokens issued from the same authorization have been revoked"))
+}
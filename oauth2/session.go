@@ -31,6 +31,9 @@ type Session struct {
 	*openid.DefaultSession `json:"idToken"`
 	Audience               []string
 	Extra                  map[string]interface{} `json:"extra"`
+
+	// Actor is the act claim of tokens obtained through delegation using OAuth 2.0 Token Exchange.
+	Actor map[string]interface{} `json:"act,omitempty"`
 }
 
 func NewSession(subject string) *Session {
@@ -0,0 +1,108 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"math/big"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/ory/hydra/jwk"
+	"github.com/pkg/errors"
+	"github.com/square/go-jose"
+)
+
+// signingMethodSigner signs tokens using a crypto.Signer, such as a private key kept in an external key service,
+// instead of the private key material jwt-go expects. Tokens are verified by the wrapped signing method.
+type signingMethodSigner struct {
+	jwtgo.SigningMethod
+}
+
+// signingMethodForKey returns the signing method, or a signing method which signs through the key if it is kept in
+// an external key service.
+func signingMethodForKey(method jwtgo.SigningMethod, key *jose.JSONWebKey) jwtgo.SigningMethod {
+	if jwk.IsExternalKey(key) {
+		return &signingMethodSigner{SigningMethod: method}
+	}
+	return method
+}
+
+func (m *signingMethodSigner) Sign(signingString string, key interface{}) (string, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return "", jwtgo.ErrInvalidKeyType
+	}
+
+	var opts crypto.SignerOpts
+	switch m.Alg() {
+	case "RS256", "ES256":
+		opts = crypto.SHA256
+	case "PS256":
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	case "EdDSA":
+		// Ed25519 signs the message itself instead of its digest.
+		opts = crypto.Hash(0)
+	default:
+		return "", errors.Errorf("JWS algorithm %s is not supported for keys kept in an external key service", m.Alg())
+	}
+
+	digest := []byte(signingString)
+	if opts.HashFunc() != 0 {
+		hash := opts.HashFunc().New()
+		hash.Write(digest)
+		digest = hash.Sum(nil)
+	}
+
+	signature, err := signer.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if m.Alg() == "ES256" {
+		if signature, err = concatECDSASignature(signature, 32); err != nil {
+			return "", err
+		}
+	}
+
+	return jwtgo.EncodeSegment(signature), nil
+}
+
+// concatECDSASignature converts an ASN.1 encoded ECDSA signature, as returned by crypto.Signer implementations, to the
+// concatenation of r and s which JWS uses, each padded to size bytes.
+func concatECDSASignature(signature []byte, size int) ([]byte, error) {
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(signature, &sig); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	r, s := sig.R.Bytes(), sig.S.Bytes()
+	if len(r) > size || len(s) > size {
+		return nil, errors.New("ECDSA signature is too long for the curve")
+	}
+
+	concat := make([]byte, 2*size)
+	copy(concat[size-len(r):], r)
+	copy(concat[2*size-len(s):], s)
+	return concat, nil
+}
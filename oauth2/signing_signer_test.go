@@ -0,0 +1,99 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2_test
+
+import (
+	"context"
+	"crypto"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/hydra/jwk"
+	. "github.com/ory/hydra/oauth2"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// externalSigner hides the type of the wrapped private key, like keys kept in an external key service.
+type externalSigner struct {
+	crypto.Signer
+}
+
+func externalKey(t *testing.T, generator jwk.KeyGenerator) (*jose.JSONWebKey, *jose.JSONWebKey) {
+	keys, err := generator.Generate("")
+	require.NoError(t, err)
+	privateKey, err := jwk.FindKeyByPrefix(keys, "private")
+	require.NoError(t, err)
+	publicKey, err := jwk.FindKeyByPrefix(keys, "public")
+	require.NoError(t, err)
+
+	privateKey.Key = &externalSigner{Signer: privateKey.Key.(crypto.Signer)}
+	require.True(t, jwk.IsExternalKey(privateKey))
+	return privateKey, publicKey
+}
+
+func TestSigningWithExternalKeys(t *testing.T) {
+	for alg, generator := range map[string]jwk.KeyGenerator{
+		"RS256": &jwk.RS256Generator{KeyLength: 2048},
+		"PS256": &jwk.PS256Generator{},
+		"ES256": &jwk.ECDSA256Generator{},
+		"EdDSA": &jwk.EdDSAGenerator{},
+	} {
+		t.Run("alg="+alg, func(t *testing.T) {
+			privateKey, publicKey := externalKey(t, generator)
+			strategy := &OpenIDConnectStrategy{Keys: []SigningKey{{Algorithm: alg, PrivateKey: privateKey, PublicKeyID: publicKey.KeyID}}}
+
+			token, _, err := strategy.Generate(jwtgo.MapClaims{"sub": "alice"}, &jwt.Headers{})
+			require.NoError(t, err)
+
+			_, err = strategy.Decode(token)
+			require.NoError(t, err)
+
+			parsed, err := jwtgo.Parse(token, func(*jwtgo.Token) (interface{}, error) {
+				return publicKey.Key, nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, alg, parsed.Header["alg"])
+		})
+	}
+
+	t.Run("case=access token", func(t *testing.T) {
+		privateKey, publicKey := externalKey(t, &jwk.ECDSA256Generator{})
+		strategy := &JWTStrategy{
+			HMACSHAStrategy: compose.NewOAuth2HMACStrategy(&compose.Config{AccessTokenLifespan: time.Hour}, []byte("some super secret secret secret secret")),
+			Key:             SigningKey{Algorithm: "ES256", PrivateKey: privateKey, PublicKeyID: publicKey.KeyID},
+			Issuer:          "https://hydra.localhost",
+		}
+
+		request := fosite.NewRequest()
+		request.Client = &fosite.DefaultClient{ID: "my-client"}
+		request.Session = NewSession("alice")
+
+		token, _, err := strategy.GenerateAccessToken(context.Background(), request)
+		require.NoError(t, err)
+		require.NoError(t, strategy.ValidateAccessToken(context.Background(), request, token))
+	})
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @Copyright 	2017-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/ory/fosite"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/consent"
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// AccessTokenKeyName is the name of the JSON Web Key Set used to sign JWT access tokens.
+	AccessTokenKeyName = "hydra.jwt.access-token"
+
+	// AccessTokenStrategyOpaque issues opaque HMAC-SHA access tokens, which have to be introspected.
+	AccessTokenStrategyOpaque = "opaque"
+
+	// AccessTokenStrategyJWT issues signed JSON Web Tokens as access tokens, which can be verified using the
+	// public keys published at /.well-known/jwks.json .
+	AccessTokenStrategyJWT = "jwt"
+)
+
+// JWTStrategy issues access tokens as signed JSON Web Tokens. Refresh tokens and authorize codes are delegated to
+// the HMACSHAStrategy.
+//
+// Issued access tokens are stored just like opaque ones, so introspection and revocation keep working. The storage
+// signature of an access token is the SHA-256 hash of the token because RSA signatures exceed the length of the
+// signature column.
+type JWTStrategy struct {
+	*foauth2.HMACSHAStrategy
+
+	// Key signs the access tokens, its private key must be a RSA or ECDSA private key or a crypto.Signer of one kept
+	// in an external key service. The kid header of access tokens is set to its public key ID.
+	Key SigningKey
+
+	// PublishedKeys are the keys access tokens are verified with in addition to Key. They are keys which have been
+	// rotated but not retired yet, and keys which are not active yet.
+	PublishedKeys []SigningKey
+
+	Issuer string
+
+	// SubjectIdentifierAlgorithm derives the sub claim from the subject for the subject type of the client, so that
+	// clients with the pairwise subject type do not learn the original subject from their access tokens.
+	SubjectIdentifierAlgorithm map[string]consent.SubjectIdentifierAlgorithm
+
+	sync.RWMutex
+}
+
+// SetKeys replaces the signing keys, it is safe to call while tokens are issued. The first of keys becomes the
+// signing key.
+func (s *JWTStrategy) SetKeys(keys, published []SigningKey) {
+	if len(keys) == 0 {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.Key = keys[0]
+	s.PublishedKeys = append(append([]SigningKey{}, keys[1:]...), published...)
+}
+
+func (s *JWTStrategy) AccessTokenSignature(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
+
+func (s *JWTStrategy) GenerateAccessToken(_ context.Context, requester fosite.Requester) (string, string, error) {
+	s.RLock()
+	key := s.Key
+	s.RUnlock()
+
+	method, err := jwtSigningMethod(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	session, ok := requester.GetSession().(*Session)
+	if !ok {
+		return "", "", errors.Errorf("Session must be of type *oauth2.Session but got type %T", requester.GetSession())
+	}
+
+	subject := session.GetSubject()
+	if c, ok := requester.GetClient().(*client.Client); ok && subject != "" {
+		if subject, err = consent.ObfuscateSubjectIdentifier(s.SubjectIdentifierAlgorithm, c, subject); err != nil {
+			return "", "", err
+		}
+	}
+
+	now := time.Now().UTC()
+	expiresAt := session.GetExpiresAt(fosite.AccessToken)
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(s.AccessTokenLifespan)
+	}
+
+	claims := jwtgo.MapClaims{
+		"iss":       strings.TrimRight(s.Issuer, "/") + "/",
+		"sub":       subject,
+		"client_id": requester.GetClient().GetID(),
+		"scope":     strings.Join(requester.GetGrantedScopes(), " "),
+		"jti":       uuid.New(),
+		"iat":       now.Unix(),
+		"nbf":       now.Unix(),
+		"exp":       expiresAt.Unix(),
+	}
+
+	if len(session.Audience) > 0 {
+		claims["aud"] = session.Audience
+	}
+
+	if len(session.Extra) > 0 {
+		claims["ext"] = session.Extra
+	}
+
+	if session.Actor != nil {
+		claims["act"] = session.Actor
+	}
+
+	token := jwtgo.NewWithClaims(signingMethodForKey(method, key.PrivateKey), claims)
+	token.Header["kid"] = key.PublicKeyID
+
+	signed, err := token.SignedString(key.PrivateKey.Key)
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+
+	return signed, s.AccessTokenSignature(signed), nil
+}
+
+// ValidateAccessToken verifies the access token with the key referenced by its kid header, which may also be one of
+// the published keys, so that access tokens stay valid after the keys have been rotated.
+func (s *JWTStrategy) ValidateAccessToken(_ context.Context, _ fosite.Requester, token string) error {
+	parsed, err := jwtgo.Parse(token, func(t *jwtgo.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key := s.verificationKey(kid)
+
+		method, err := jwtSigningMethod(key)
+		if err != nil {
+			return nil, err
+		} else if t.Method.Alg() != method.Alg() {
+			return nil, errors.Errorf("Unexpected signing algorithm %s", t.Header["alg"])
+		}
+		return signingPublicKey(key.PrivateKey.Key), nil
+	})
+	if e, ok := err.(*jwtgo.ValidationError); ok && e.Errors&jwtgo.ValidationErrorExpired != 0 {
+		return errors.WithStack(fosite.ErrTokenExpired.WithDebug(err.Error()))
+	} else if err != nil {
+		return errors.WithStack(fosite.ErrTokenSignatureMismatch.WithDebug(err.Error()))
+	} else if !parsed.Valid {
+		return errors.WithStack(fosite.ErrTokenSignatureMismatch)
+	}
+
+	return nil
+}
+
+func (s *JWTStrategy) verificationKey(kid string) SigningKey {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, k := range s.PublishedKeys {
+		if k.PublicKeyID == kid {
+			return k
+		}
+	}
+	return s.Key
+}
+
+// jwtSigningMethod returns RS256 for RSA keys and ES256 for ECDSA keys using the P-256 curve.
+func jwtSigningMethod(key SigningKey) (jwtgo.SigningMethod, error) {
+	if key.PrivateKey == nil {
+		return nil, errors.New("No JWT access token signing key is configured")
+	}
+
+	switch k := signingPublicKey(key.PrivateKey.Key).(type) {
+	case *rsa.PublicKey:
+		return jwtgo.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		if k.Curve.Params().BitSize != 256 {
+			return nil, errors.Errorf("JSON Web Key %s must use the P-256 curve", key.PrivateKey.KeyID)
+		}
+		return jwtgo.SigningMethodES256, nil
+	default:
+		return nil, errors.Errorf("JSON Web Key %s must be a RSA or ECDSA private key", key.PrivateKey.KeyID)
+	}
+}
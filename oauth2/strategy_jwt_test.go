@@ -0,0 +1,175 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @Copyright 	2017-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	"github.com/ory/hydra/client"
+	"github.com/ory/hydra/consent"
+	"github.com/ory/hydra/jwk"
+	. "github.com/ory/hydra/oauth2"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTStrategy(t *testing.T) {
+	for _, tc := range []struct {
+		alg       string
+		generator jwk.KeyGenerator
+	}{
+		{alg: "RS256", generator: &jwk.RS256Generator{KeyLength: 2048}},
+		{alg: "ES256", generator: &jwk.ECDSA256Generator{}},
+	} {
+		t.Run("alg="+tc.alg, func(t *testing.T) {
+			keys, err := tc.generator.Generate("")
+			require.NoError(t, err)
+			privateKey, err := jwk.FindKeyByPrefix(keys, "private")
+			require.NoError(t, err)
+			publicKey, err := jwk.FindKeyByPrefix(keys, "public")
+			require.NoError(t, err)
+
+			strategy := &JWTStrategy{
+				HMACSHAStrategy: compose.NewOAuth2HMACStrategy(&compose.Config{AccessTokenLifespan: time.Hour}, []byte("some super secret secret secret secret")),
+				Key:             SigningKey{Algorithm: tc.alg, PrivateKey: privateKey, PublicKeyID: publicKey.KeyID},
+				Issuer:          "https://hydra.localhost",
+			}
+
+			session := NewSession("alice")
+			session.Audience = []string{"https://api.localhost"}
+			session.Extra = map[string]interface{}{"foo": "bar"}
+			session.SetExpiresAt(fosite.AccessToken, time.Now().UTC().Add(time.Hour))
+
+			request := fosite.NewRequest()
+			request.Client = &fosite.DefaultClient{ID: "my-client"}
+			request.Session = session
+			request.GrantScope("foo")
+			request.GrantScope("bar")
+
+			token, signature, err := strategy.GenerateAccessToken(context.Background(), request)
+			require.NoError(t, err)
+			assert.Equal(t, strategy.AccessTokenSignature(token), signature)
+			assert.True(t, len(signature) < 255)
+			require.NoError(t, strategy.ValidateAccessToken(context.Background(), request, token))
+
+			parsed, err := jwtgo.Parse(token, func(*jwtgo.Token) (interface{}, error) {
+				return publicKey.Key, nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.alg, parsed.Method.Alg())
+			assert.Equal(t, publicKey.KeyID, parsed.Header["kid"])
+
+			claims := parsed.Claims.(jwtgo.MapClaims)
+			assert.Equal(t, "https://hydra.localhost/", claims["iss"])
+			assert.Equal(t, "alice", claims["sub"])
+			assert.Equal(t, "my-client", claims["client_id"])
+			assert.Equal(t, "foo bar", claims["scope"])
+			assert.Equal(t, []interface{}{"https://api.localhost"}, claims["aud"])
+			assert.Equal(t, map[string]interface{}{"foo": "bar"}, claims["ext"])
+
+			assert.Error(t, strategy.ValidateAccessToken(context.Background(), request, token+"a"))
+			assert.Error(t, strategy.ValidateAccessToken(context.Background(), request, "not-a-jwt"))
+
+			session.SetExpiresAt(fosite.AccessToken, time.Now().UTC().Add(-time.Hour))
+			expired, _, err := strategy.GenerateAccessToken(context.Background(), request)
+			require.NoError(t, err)
+			assert.EqualError(t, errors.Cause(strategy.ValidateAccessToken(context.Background(), request, expired)), fosite.ErrTokenExpired.Error())
+
+			// Access tokens signed with a rotated key stay valid as long as the key is published.
+			session.SetExpiresAt(fosite.AccessToken, time.Now().UTC().Add(time.Hour))
+			next, err := tc.generator.Generate("")
+			require.NoError(t, err)
+			nextPrivateKey, err := jwk.FindKeyByPrefix(next, "private")
+			require.NoError(t, err)
+			nextPublicKey, err := jwk.FindKeyByPrefix(next, "public")
+			require.NoError(t, err)
+
+			previous := strategy.Key
+			strategy.SetKeys([]SigningKey{{Algorithm: tc.alg, PrivateKey: nextPrivateKey, PublicKeyID: nextPublicKey.KeyID}}, []SigningKey{previous})
+			require.NoError(t, strategy.ValidateAccessToken(context.Background(), request, token))
+
+			rotated, _, err := strategy.GenerateAccessToken(context.Background(), request)
+			require.NoError(t, err)
+			require.NoError(t, strategy.ValidateAccessToken(context.Background(), request, rotated))
+			parsed, _ = jwtgo.Parse(rotated, func(*jwtgo.Token) (interface{}, error) {
+				return nextPublicKey.Key, nil
+			})
+			assert.Equal(t, nextPublicKey.KeyID, parsed.Header["kid"])
+
+			strategy.SetKeys([]SigningKey{strategy.Key}, nil)
+			assert.Error(t, strategy.ValidateAccessToken(context.Background(), request, token))
+		})
+	}
+}
+
+func TestJWTStrategyPairwiseSubject(t *testing.T) {
+	keys, err := (&jwk.RS256Generator{KeyLength: 2048}).Generate("")
+	require.NoError(t, err)
+	privateKey, err := jwk.FindKeyByPrefix(keys, "private")
+	require.NoError(t, err)
+	publicKey, err := jwk.FindKeyByPrefix(keys, "public")
+	require.NoError(t, err)
+
+	algorithms := map[string]consent.SubjectIdentifierAlgorithm{
+		consent.SubjectTypePublic:   consent.NewSubjectIdentifierAlgorithmPublic(),
+		consent.SubjectTypePairwise: consent.NewSubjectIdentifierAlgorithmPairwise([]byte("76d5d2bf-747f-4592-9fbd-d2b895a54b3a")),
+	}
+	strategy := &JWTStrategy{
+		HMACSHAStrategy:            compose.NewOAuth2HMACStrategy(&compose.Config{AccessTokenLifespan: time.Hour}, []byte("some super secret secret secret secret")),
+		Key:                        SigningKey{Algorithm: "RS256", PrivateKey: privateKey, PublicKeyID: publicKey.KeyID},
+		Issuer:                     "https://hydra.localhost",
+		SubjectIdentifierAlgorithm: algorithms,
+	}
+
+	for _, c := range []*client.Client{
+		{ID: "public-client", RedirectURIs: []string{"https://public.localhost/cb"}},
+		{ID: "pairwise-client", RedirectURIs: []string{"https://pairwise.localhost/cb"}, SubjectType: consent.SubjectTypePairwise},
+	} {
+		t.Run("client="+c.ID, func(t *testing.T) {
+			request := fosite.NewRequest()
+			request.Client = c
+			request.Session = NewSession("alice")
+
+			token, _, err := strategy.GenerateAccessToken(context.Background(), request)
+			require.NoError(t, err)
+
+			parsed, err := jwtgo.Parse(token, func(*jwtgo.Token) (interface{}, error) {
+				return publicKey.Key, nil
+			})
+			require.NoError(t, err)
+
+			expected, err := consent.ObfuscateSubjectIdentifier(algorithms, c, "alice")
+			require.NoError(t, err)
+			assert.Equal(t, expected, parsed.Claims.(jwtgo.MapClaims)["sub"])
+			if c.SubjectType == consent.SubjectTypePairwise {
+				assert.NotEqual(t, "alice", parsed.Claims.(jwtgo.MapClaims)["sub"])
+			} else {
+				assert.Equal(t, "alice", parsed.Claims.(jwtgo.MapClaims)["sub"])
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/pkg/errors"
+	"github.com/square/go-jose"
+	"golang.org/x/crypto/ed25519"
+)
+
+// SigningKey is a private key the OpenID Connect provider signs JSON Web Tokens with.
+type SigningKey struct {
+	// Algorithm is the JWS algorithm of the key, one of RS256, PS256, ES256 or EdDSA.
+	Algorithm string
+
+	PrivateKey *jose.JSONWebKey
+
+	// PublicKeyID is the kid of the matching public key published at /.well-known/jwks.json.
+	PublicKeyID string
+}
+
+// OpenIDConnectStrategy signs ID Tokens and the other JSON Web Tokens issued by the OpenID Connect provider, such as
+// logout tokens and userinfo responses. Unlike fosite's default strategy it is not limited to RS256: it holds one
+// signing key per algorithm and signs each token with the key referenced by the kid header of the token, or else the
+// key of the algorithm requested by the alg header, falling back to the first key.
+type OpenIDConnectStrategy struct {
+	// Keys are the active signing keys, one per algorithm.
+	Keys []SigningKey
+
+	// PublishedKeys are the keys tokens are verified with in addition to Keys. They are keys which have been rotated
+	// but not retired yet, and keys which are not active yet.
+	PublishedKeys []SigningKey
+
+	// Expiry is the lifespan of ID Tokens whose session does not set an expiry, defaults to one hour.
+	Expiry time.Duration
+
+	sync.RWMutex
+
+	// hashed remembers the inputs of Hash by the at_hash or c_hash value fosite derives from them, so that
+	// GenerateIDToken can recompute the value with the hash function of the signing algorithm.
+	hashed     map[string]hashedValue
+	hashedLock sync.Mutex
+}
+
+type hashedValue struct {
+	in        []byte
+	expiresAt time.Time
+}
+
+// SetKeys replaces the signing keys, it is safe to call while tokens are issued.
+func (s *OpenIDConnectStrategy) SetKeys(keys, published []SigningKey) {
+	s.Lock()
+	defer s.Unlock()
+	s.Keys = keys
+	s.PublishedKeys = published
+}
+
+// Algorithms returns the JWS algorithms of the signing keys, the default algorithm first.
+func (s *OpenIDConnectStrategy) Algorithms() []string {
+	s.RLock()
+	defer s.RUnlock()
+
+	algorithms := make([]string, len(s.Keys))
+	for i, k := range s.Keys {
+		algorithms[i] = k.Algorithm
+	}
+	return algorithms
+}
+
+// GenerateIDToken validates the ID Token claims of the session against the authorization request and signs them.
+// The checks are the ones of fosite's openid.DefaultStrategy.
+func (s *OpenIDConnectStrategy) GenerateIDToken(_ context.Context, requester fosite.Requester) (string, error) {
+	sess, ok := requester.GetSession().(openid.Session)
+	if !ok {
+		return "", errors.New("Failed to generate id token because session must be of type fosite/handler/openid.Session")
+	}
+
+	claims := sess.IDTokenClaims()
+	if claims.Subject == "" {
+		return "", errors.New("Failed to generate id token because subject is an empty string")
+	}
+
+	now := time.Now().UTC()
+	form := requester.GetRequestForm()
+	if form.Get("grant_type") != "refresh_token" {
+		// Adds a bit of wiggle room for timing issues
+		if claims.AuthTime.After(now.Add(time.Second * 5)) {
+			return "", errors.WithStack(fosite.ErrServerError.WithDebug("Failed to validate OpenID Connect request because authentication time is in the future"))
+		}
+
+		if maxAge, err := strconv.ParseInt(form.Get("max_age"), 10, 64); err == nil && maxAge > 0 {
+			if claims.AuthTime.IsZero() {
+				return "", errors.WithStack(fosite.ErrServerError.WithDebug("Failed to generate id token because authentication time claim is required when max_age is set"))
+			} else if claims.RequestedAt.IsZero() {
+				return "", errors.WithStack(fosite.ErrServerError.WithDebug("Failed to generate id token because requested at claim is required when max_age is set"))
+			} else if claims.AuthTime.Add(time.Second * time.Duration(maxAge)).Before(claims.RequestedAt) {
+				return "", errors.WithStack(fosite.ErrServerError.WithDebug("Failed to generate id token because authentication time does not satisfy max_age time"))
+			}
+		}
+
+		prompt := form.Get("prompt")
+		if prompt != "" && claims.AuthTime.IsZero() {
+			return "", errors.WithStack(fosite.ErrServerError.WithDebug("Unable to determine validity of prompt parameter because auth_time is missing in id token claims"))
+		}
+
+		switch prompt {
+		case "none":
+			if claims.AuthTime.After(claims.RequestedAt) {
+				return "", errors.WithStack(fosite.ErrServerError.WithDebug("Failed to generate id token because prompt was set to \"none\" but auth_time happened after the authorization request was registered"))
+			}
+		case "login":
+			if claims.AuthTime.Before(claims.RequestedAt) {
+				return "", errors.WithStack(fosite.ErrServerError.WithDebug("Failed to generate id token because prompt was set to \"login\" but auth_time happened before the authorization request was registered"))
+			}
+		}
+
+		if hint := form.Get("id_token_hint"); hint != "" {
+			token, err := s.Decode(hint)
+			if ve, ok := errors.Cause(err).(*jwtgo.ValidationError); ok && ve.Errors == jwtgo.ValidationErrorExpired {
+				// Expired ID Tokens are allowed as values to id_token_hint
+			} else if err != nil {
+				return "", errors.WithStack(fosite.ErrServerError.WithDebug(fmt.Sprintf("Unable to decode id token from id_token_hint parameter because %s", err.Error())))
+			}
+
+			if hintClaims, ok := token.Claims.(jwtgo.MapClaims); !ok {
+				return "", errors.WithStack(fosite.ErrServerError.WithDebug("Unable to decode id token from id_token_hint to *jwt.StandardClaims"))
+			} else if hintSub, _ := hintClaims["sub"].(string); hintSub == "" {
+				return "", errors.WithStack(fosite.ErrServerError.WithDebug("Provided id token from id_token_hint does not have a subject"))
+			} else if hintSub != claims.Subject {
+				return "", errors.WithStack(fosite.ErrServerError.WithDebug("Subject from authorization mismatches id token subject from id_token_hint"))
+			}
+		}
+	}
+
+	if claims.ExpiresAt.IsZero() {
+		expiry := s.Expiry
+		if expiry == 0 {
+			expiry = time.Hour
+		}
+		claims.ExpiresAt = now.Add(expiry)
+	}
+
+	if claims.ExpiresAt.Before(now) {
+		return "", errors.WithStack(fosite.ErrServerError.WithDebug("Failed to generate id token because expiry claim can not be in the past"))
+	}
+
+	if claims.AuthTime.IsZero() {
+		claims.AuthTime = now
+	}
+
+	headers := sess.IDTokenHeaders().ToMap()
+	kid, _ := headers["kid"].(string)
+	alg, _ := headers["alg"].(string)
+	key, err := s.signingKey(kid, alg)
+	if err != nil {
+		return "", err
+	}
+	claims.AccessTokenHash = s.rehash(claims.AccessTokenHash, key.Algorithm)
+	claims.CodeHash = s.rehash(claims.CodeHash, key.Algorithm)
+
+	// OPTIONAL. String value used to associate a Client session with an ID Token, and to mitigate replay attacks.
+	if nonce := form.Get("nonce"); len(nonce) > 0 {
+		claims.Nonce = nonce
+	}
+	claims.IssuedAt = now
+
+	mapClaims := claims.ToMapClaims()
+	mapClaims["aud"] = []string{requester.GetClient().GetID()}
+
+	token, _, err := s.Generate(mapClaims, sess.IDTokenHeaders())
+	return token, err
+}
+
+// Generate signs the claims with the key referenced by the kid header, or else the key of the algorithm requested by
+// the alg header, or the first key if the headers do not reference one of the signing keys.
+func (s *OpenIDConnectStrategy) Generate(claims jwtgo.Claims, header jwt.Mapper) (string, string, error) {
+	if claims == nil || header == nil {
+		return "", "", errors.New("Either claims or header is nil")
+	}
+
+	headers := header.ToMap()
+	kid, _ := headers["kid"].(string)
+	alg, _ := headers["alg"].(string)
+	key, err := s.signingKey(kid, alg)
+	if err != nil {
+		return "", "", err
+	}
+
+	method := jwtgo.GetSigningMethod(key.Algorithm)
+	if method == nil {
+		return "", "", errors.Errorf("JWS algorithm %s of JSON Web Key %s is not supported", key.Algorithm, key.PrivateKey.KeyID)
+	}
+
+	token := jwtgo.NewWithClaims(signingMethodForKey(method, key.PrivateKey), claims)
+	for k, v := range headers {
+		token.Header[k] = v
+	}
+	token.Header["alg"] = method.Alg()
+	token.Header["kid"] = key.PublicKeyID
+
+	signed, err := token.SignedString(key.PrivateKey.Key)
+	if err != nil {
+		return "", "", errors.WithStack(err)
+	}
+
+	signature, err := s.GetSignature(signed)
+	if err != nil {
+		return "", "", err
+	}
+
+	return signed, signature, nil
+}
+
+// Validate verifies the token and returns its signature.
+func (s *OpenIDConnectStrategy) Validate(token string) (string, error) {
+	if _, err := s.Decode(token); err != nil {
+		return "", err
+	}
+	return s.GetSignature(token)
+}
+
+// Decode parses the token and verifies it using the key referenced by its kid header, which may also be one of the
+// published keys.
+func (s *OpenIDConnectStrategy) Decode(token string) (*jwtgo.Token, error) {
+	parsed, err := jwtgo.Parse(token, func(t *jwtgo.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := s.verificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		if t.Method.Alg() != key.Algorithm {
+			return nil, errors.Errorf("Unexpected signing algorithm %s", t.Header["alg"])
+		}
+		return signingPublicKey(key.PrivateKey.Key), nil
+	})
+	if err != nil {
+		return parsed, errors.WithStack(err)
+	} else if !parsed.Valid {
+		return parsed, errors.WithStack(fosite.ErrTokenSignatureMismatch)
+	}
+
+	return parsed, nil
+}
+
+func (s *OpenIDConnectStrategy) GetSignature(token string) (string, error) {
+	split := strings.Split(token, ".")
+	if len(split) != 3 {
+		return "", errors.New("Header, body and signature must all be set")
+	}
+	return split[2], nil
+}
+
+// Hash is used by fosite to compute at_hash and c_hash values. It always uses SHA-256 because the signing algorithm is
+// not known yet. GenerateIDToken replaces the values with ones computed by the hash function of the algorithm the ID
+// Token is signed with.
+func (s *OpenIDConnectStrategy) Hash(in []byte) ([]byte, error) {
+	hash := sha256.Sum256(in)
+
+	s.hashedLock.Lock()
+	defer s.hashedLock.Unlock()
+
+	now := time.Now()
+	if s.hashed == nil {
+		s.hashed = map[string]hashedValue{}
+	}
+	for k, v := range s.hashed {
+		if v.expiresAt.Before(now) {
+			delete(s.hashed, k)
+		}
+	}
+	s.hashed[base64.RawURLEncoding.EncodeToString(hash[:sha256.Size/2])] = hashedValue{in: in, expiresAt: now.Add(time.Minute)}
+
+	return hash[:], nil
+}
+
+func (s *OpenIDConnectStrategy) GetSigningMethodLength() int {
+	return sha256.Size
+}
+
+// rehash returns the at_hash or c_hash value computed by fosite with SHA-256 for an ID Token signed with alg. If alg
+// uses another hash function and the hashed value is unknown, which is the case for at_hash values of the token
+// endpoint, an empty string is returned because a wrong value is worse than the absent, optional claim.
+func (s *OpenIDConnectStrategy) rehash(value, alg string) string {
+	if value == "" {
+		return ""
+	}
+
+	s.hashedLock.Lock()
+	hashed, ok := s.hashed[value]
+	delete(s.hashed, value)
+	s.hashedLock.Unlock()
+
+	hash := hashFunction(alg)
+	if !ok {
+		if hash == crypto.SHA256 {
+			return value
+		}
+		return ""
+	}
+
+	h := hash.New()
+	h.Write(hashed.in)
+	sum := h.Sum(nil)
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+// hashFunction returns the hash function at_hash and c_hash values of ID Tokens signed with alg are computed with.
+// OpenID Connect uses the hash function of the JWS algorithm. EdDSA, whose Ed25519 curve is based on SHA-512, uses
+// SHA-512.
+func hashFunction(alg string) crypto.Hash {
+	switch {
+	case alg == "EdDSA" || strings.HasSuffix(alg, "512"):
+		return crypto.SHA512
+	case strings.HasSuffix(alg, "384"):
+		return crypto.SHA384
+	default:
+		return crypto.SHA256
+	}
+}
+
+func (s *OpenIDConnectStrategy) signingKey(kid, alg string) (SigningKey, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if len(s.Keys) == 0 {
+		return SigningKey{}, errors.New("No OpenID Connect signing key is configured")
+	}
+
+	for _, k := range s.Keys {
+		if kid != "" && k.PublicKeyID == kid {
+			return k, nil
+		}
+	}
+	for _, k := range s.Keys {
+		if alg != "" && k.Algorithm == alg {
+			return k, nil
+		}
+	}
+	return s.Keys[0], nil
+}
+
+func (s *OpenIDConnectStrategy) verificationKey(kid string) (SigningKey, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, keys := range [][]SigningKey{s.Keys, s.PublishedKeys} {
+		for _, k := range keys {
+			if k.PublicKeyID == kid {
+				return k, nil
+			}
+		}
+	}
+
+	if len(s.Keys) == 0 {
+		return SigningKey{}, errors.New("No OpenID Connect signing key is configured")
+	}
+	return s.Keys[0], nil
+}
+
+func signingPublicKey(key interface{}) interface{} {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey
+	case ed25519.PrivateKey:
+		return k.Public()
+	case crypto.Signer:
+		return k.Public()
+	default:
+		return nil
+	}
+}
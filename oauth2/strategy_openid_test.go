@@ -0,0 +1,183 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/hydra/jwk"
+	. "github.com/ory/hydra/oauth2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenIDConnectStrategy(t *testing.T) {
+	strategy := &OpenIDConnectStrategy{Expiry: time.Hour}
+	for alg, generator := range map[string]jwk.KeyGenerator{
+		"RS256": &jwk.RS256Generator{KeyLength: 2048},
+		"PS256": &jwk.PS256Generator{},
+		"ES256": &jwk.ECDSA256Generator{},
+		"EdDSA": &jwk.EdDSAGenerator{},
+	} {
+		keys, err := generator.Generate(alg)
+		require.NoError(t, err)
+		privateKey, err := jwk.FindKeyByPrefix(keys, "private")
+		require.NoError(t, err)
+
+		strategy.Keys = append(strategy.Keys, SigningKey{Algorithm: alg, PrivateKey: privateKey, PublicKeyID: "public:" + alg})
+	}
+
+	assert.Len(t, strategy.Algorithms(), 4)
+
+	for _, alg := range strategy.Algorithms() {
+		t.Run("alg="+alg, func(t *testing.T) {
+			session := NewSession("alice")
+			session.Claims.Subject = "alice"
+			session.Headers = &jwt.Headers{Extra: map[string]interface{}{"alg": alg}}
+
+			request := fosite.NewRequest()
+			request.Client = &fosite.DefaultClient{ID: "my-client"}
+			request.Session = session
+			request.Form.Set("nonce", "some-nonce")
+
+			token, err := strategy.GenerateIDToken(context.Background(), request)
+			require.NoError(t, err)
+
+			parsed, err := strategy.Decode(token)
+			require.NoError(t, err)
+			assert.Equal(t, alg, parsed.Header["alg"])
+			assert.Equal(t, "public:"+alg, parsed.Header["kid"])
+
+			claims := parsed.Claims.(jwtgo.MapClaims)
+			assert.Equal(t, "alice", claims["sub"])
+			assert.Equal(t, "some-nonce", claims["nonce"])
+			assert.EqualValues(t, []interface{}{"my-client"}, claims["aud"])
+
+			signature, err := strategy.Validate(token)
+			require.NoError(t, err)
+			assert.NotEmpty(t, signature)
+		})
+	}
+
+	t.Run("case=signs with the first key if the kid is unknown", func(t *testing.T) {
+		token, _, err := strategy.Generate(jwtgo.MapClaims{"sub": "alice"}, &jwt.Headers{Extra: map[string]interface{}{"kid": "foo", "typ": "logout+jwt"}})
+		require.NoError(t, err)
+
+		parsed, err := strategy.Decode(token)
+		require.NoError(t, err)
+		assert.Equal(t, strategy.Keys[0].Algorithm, parsed.Header["alg"])
+		assert.Equal(t, strategy.Keys[0].PublicKeyID, parsed.Header["kid"])
+		assert.Equal(t, "logout+jwt", parsed.Header["typ"])
+	})
+
+	t.Run("case=rejects tokens signed with a key of another algorithm", func(t *testing.T) {
+		token, _, err := strategy.Generate(jwtgo.MapClaims{"sub": "alice"}, &jwt.Headers{Extra: map[string]interface{}{"kid": strategy.Keys[1].PublicKeyID}})
+		require.NoError(t, err)
+
+		tampered := &OpenIDConnectStrategy{Keys: []SigningKey{strategy.Keys[0], {
+			Algorithm:   strategy.Keys[0].Algorithm,
+			PrivateKey:  strategy.Keys[0].PrivateKey,
+			PublicKeyID: strategy.Keys[1].PublicKeyID,
+		}}}
+		_, err = tampered.Decode(token)
+		require.Error(t, err)
+	})
+
+	t.Run("case=verifies tokens signed with published keys", func(t *testing.T) {
+		token, _, err := strategy.Generate(jwtgo.MapClaims{"sub": "alice"}, &jwt.Headers{Extra: map[string]interface{}{"alg": strategy.Keys[1].Algorithm}})
+		require.NoError(t, err)
+
+		rotated := &OpenIDConnectStrategy{}
+		rotated.SetKeys(strategy.Keys[:1], strategy.Keys[1:])
+		_, err = rotated.Decode(token)
+		require.NoError(t, err)
+
+		rotated.SetKeys(strategy.Keys[:1], nil)
+		_, err = rotated.Decode(token)
+		require.Error(t, err)
+	})
+
+	t.Run("case=computes at_hash with the hash function of the signing algorithm", func(t *testing.T) {
+		sha256Hash := func(in string) string {
+			sum := sha256.Sum256([]byte(in))
+			return base64.RawURLEncoding.EncodeToString(sum[:sha256.Size/2])
+		}
+		sha512Hash := func(in string) string {
+			sum := sha512.Sum512([]byte(in))
+			return base64.RawURLEncoding.EncodeToString(sum[:sha512.Size/2])
+		}
+
+		for k, tc := range []struct {
+			alg      string
+			hashed   bool
+			expected string
+		}{
+			{alg: "RS256", hashed: true, expected: sha256Hash("some-token")},
+			{alg: "RS256", hashed: false, expected: sha256Hash("some-token")},
+			{alg: "EdDSA", hashed: true, expected: sha512Hash("some-token")},
+			{alg: "EdDSA", hashed: false},
+		} {
+			if tc.hashed {
+				// This is what fosite does in the implicit and hybrid flows.
+				hash, err := strategy.Hash([]byte("some-token"))
+				require.NoError(t, err)
+				require.Equal(t, sha256Hash("some-token"), base64.RawURLEncoding.EncodeToString(hash[:strategy.GetSigningMethodLength()/2]))
+			}
+
+			session := NewSession("alice")
+			session.Claims.Subject = "alice"
+			session.Claims.AccessTokenHash = sha256Hash("some-token")
+			session.Headers = &jwt.Headers{Extra: map[string]interface{}{"alg": tc.alg}}
+
+			request := fosite.NewRequest()
+			request.Client = &fosite.DefaultClient{ID: "my-client"}
+			request.Session = session
+
+			token, err := strategy.GenerateIDToken(context.Background(), request)
+			require.NoError(t, err)
+
+			parsed, err := strategy.Decode(token)
+			require.NoError(t, err)
+			if tc.expected == "" {
+				assert.Nil(t, parsed.Claims.(jwtgo.MapClaims)["at_hash"], "%d", k)
+			} else {
+				assert.Equal(t, tc.expected, parsed.Claims.(jwtgo.MapClaims)["at_hash"], "%d", k)
+			}
+		}
+	})
+
+	t.Run("case=fails without a subject", func(t *testing.T) {
+		request := fosite.NewRequest()
+		request.Client = &fosite.DefaultClient{ID: "my-client"}
+		request.Session = NewSession("")
+
+		_, err := strategy.GenerateIDToken(context.Background(), request)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2
+
+import (
+	"context"
+
+	"github.com/ory/fosite"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+)
+
+// SecretRotationStrategy issues tokens using the strategy of the current system secret and validates them using the
+// strategies of rotated system secrets as well, so that tokens issued before the secret was rotated stay valid until
+// they expire.
+type SecretRotationStrategy struct {
+	foauth2.CoreStrategy
+
+	// Rotated are the strategies of the previous system secrets, they are only used to validate tokens.
+	Rotated []foauth2.CoreStrategy
+}
+
+func (s *SecretRotationStrategy) ValidateAccessToken(ctx context.Context, requester fosite.Requester, token string) error {
+	err := s.CoreStrategy.ValidateAccessToken(ctx, requester, token)
+	if err == nil {
+		return nil
+	}
+
+	for _, rotated := range s.Rotated {
+		if rotated.ValidateAccessToken(ctx, requester, token) == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (s *SecretRotationStrategy) ValidateRefreshToken(ctx context.Context, requester fosite.Requester, token string) error {
+	err := s.CoreStrategy.ValidateRefreshToken(ctx, requester, token)
+	if err == nil {
+		return nil
+	}
+
+	for _, rotated := range s.Rotated {
+		if rotated.ValidateRefreshToken(ctx, requester, token) == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (s *SecretRotationStrategy) ValidateAuthorizeCode(ctx context.Context, requester fosite.Requester, token string) error {
+	err := s.CoreStrategy.ValidateAuthorizeCode(ctx, requester, token)
+	if err == nil {
+		return nil
+	}
+
+	for _, rotated := range s.Rotated {
+		if rotated.ValidateAuthorizeCode(ctx, requester, token) == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	. "github.com/ory/hydra/oauth2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretRotationStrategy(t *testing.T) {
+	config := &compose.Config{AccessTokenLifespan: time.Hour, AuthorizeCodeLifespan: time.Minute}
+	current := compose.NewOAuth2HMACStrategy(config, []byte("some super secret secret secret secret"))
+	previous := compose.NewOAuth2HMACStrategy(config, []byte("some previous secret secret secret secret"))
+	unknown := compose.NewOAuth2HMACStrategy(config, []byte("some unknown secret secret secret secret"))
+
+	strategy := &SecretRotationStrategy{CoreStrategy: current, Rotated: []foauth2.CoreStrategy{previous}}
+
+	request := fosite.NewRequest()
+	request.Session = NewSession("alice")
+	ctx := context.Background()
+
+	for k, tc := range []struct {
+		issuer *foauth2.HMACSHAStrategy
+		valid  bool
+	}{
+		{issuer: current, valid: true},
+		{issuer: previous, valid: true},
+		{issuer: unknown, valid: false},
+	} {
+		accessToken, _, err := tc.issuer.GenerateAccessToken(ctx, request)
+		require.NoError(t, err)
+		refreshToken, _, err := tc.issuer.GenerateRefreshToken(ctx, request)
+		require.NoError(t, err)
+		code, _, err := tc.issuer.GenerateAuthorizeCode(ctx, request)
+		require.NoError(t, err)
+
+		assert.Equal(t, tc.valid, strategy.ValidateAccessToken(ctx, request, accessToken) == nil, "%d", k)
+		assert.Equal(t, tc.valid, strategy.ValidateRefreshToken(ctx, request, refreshToken) == nil, "%d", k)
+		assert.Equal(t, tc.valid, strategy.ValidateAuthorizeCode(ctx, request, code) == nil, "%d", k)
+	}
+
+	token, signature, err := strategy.GenerateAccessToken(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, current.AccessTokenSignature(token), signature)
+	require.NoError(t, current.ValidateAccessToken(ctx, request, token))
+}
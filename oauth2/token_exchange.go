@@ -0,0 +1,215 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @Copyright 	2017-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	foauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/go-convenience/stringslice"
+	"github.com/ory/hydra/client"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TokenExchangeGrantType is the grant type of OAuth 2.0 Token Exchange as defined in
+	// https://tools.ietf.org/html/rfc8693#section-2.1 .
+	TokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+	// AccessTokenType identifies access tokens in the subject_token_type, actor_token_type, requested_token_type and
+	// issued_token_type parameters. It is the only token type supported by this server.
+	AccessTokenType = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// TokenExchangeGrantHandler implements OAuth 2.0 Token Exchange. It exchanges an access token for a new access token
+// with a narrower scope and a different audience, either impersonating the subject of the original token or, if an
+// actor token is given, acting on its behalf.
+//
+// Which audiences and scopes a client may request is governed by the token_exchange_audiences and
+// token_exchange_scope fields of the client. Only confidential clients may exchange tokens, and an actor token must
+// have been issued to the client itself.
+type TokenExchangeGrantHandler struct {
+	*foauth2.HandleHelper
+
+	ScopeStrategy fosite.ScopeStrategy
+}
+
+// TokenExchangeGrantFactory creates a TokenExchangeGrantHandler and is meant to be used with compose.Compose.
+func TokenExchangeGrantFactory(config *compose.Config, storage interface{}, strategy interface{}) interface{} {
+	return &TokenExchangeGrantHandler{
+		HandleHelper: &foauth2.HandleHelper{
+			AccessTokenStrategy: strategy.(foauth2.AccessTokenStrategy),
+			AccessTokenStorage:  storage.(foauth2.AccessTokenStorage),
+			AccessTokenLifespan: config.GetAccessTokenLifespan(),
+		},
+		ScopeStrategy: config.GetScopeStrategy(),
+	}
+}
+
+// HandleTokenEndpointRequest validates the subject and actor tokens and checks the requested audiences and scopes
+// against the allow-lists of the client.
+func (h *TokenExchangeGrantHandler) HandleTokenEndpointRequest(ctx context.Context, request fosite.AccessRequester) error {
+	if !request.GetGrantTypes().Exact(TokenExchangeGrantType) {
+		return errors.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	c, ok := request.GetClient().(*client.Client)
+	if !ok {
+		return errors.WithStack(fosite.ErrServerError.WithDebug(fmt.Sprintf("Expected client to be of type *client.Client but got %T", request.GetClient())))
+	}
+
+	if !c.GetGrantTypes().Has(TokenExchangeGrantType) {
+		return errors.WithStack(fosite.ErrInvalidGrant.WithDebug(fmt.Sprintf("The client is not allowed to use grant type %s", TokenExchangeGrantType)))
+	}
+
+	// Token exchange lets the client act on behalf of other subjects, so the client must be authenticated.
+	if c.IsPublic() {
+		return errors.WithStack(fosite.ErrUnauthorizedClient.WithDebug("Public clients are not allowed to use token exchange"))
+	}
+
+	form := request.GetRequestForm()
+	if t := form.Get("requested_token_type"); t != "" && t != AccessTokenType {
+		return errors.WithStack(fosite.ErrInvalidRequest.WithDebug(fmt.Sprintf("Requested token type %s is not supported, only %s is", t, AccessTokenType)))
+	}
+
+	subject, err := h.validateToken(ctx, form.Get("subject_token"), form.Get("subject_token_type"), "subject_token")
+	if err != nil {
+		return err
+	}
+	subjectSession, ok := subject.GetSession().(*Session)
+	if !ok {
+		return errors.WithStack(fosite.ErrServerError.WithDebug(fmt.Sprintf("Expected session to be of type *oauth2.Session but got %T", subject.GetSession())))
+	}
+
+	audiences := fosite.RemoveEmpty(form["audience"])
+	for _, audience := range audiences {
+		if !stringslice.Has(c.TokenExchangeAudiences, audience) {
+			return errors.WithStack(fosite.ErrInvalidRequest.WithDebug(fmt.Sprintf("The client is not allowed to request a token for audience %s", audience)))
+		}
+	}
+
+	// Without an explicit scope, all scopes of the subject token which the client may request are granted.
+	scopes := request.GetRequestedScopes()
+	if len(scopes) == 0 {
+		for _, scope := range subject.GetGrantedScopes() {
+			if h.ScopeStrategy(c.GetTokenExchangeScopes(), scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	for _, scope := range scopes {
+		if !h.ScopeStrategy(c.GetTokenExchangeScopes(), scope) {
+			return errors.WithStack(fosite.ErrInvalidScope.WithDebug(fmt.Sprintf("The client is not allowed to request scope %s through token exchange", scope)))
+		} else if !h.ScopeStrategy(subject.GetGrantedScopes(), scope) {
+			return errors.WithStack(fosite.ErrInvalidScope.WithDebug(fmt.Sprintf("Scope %s was not granted to the subject token", scope)))
+		}
+		request.GrantScope(scope)
+	}
+
+	session, ok := request.GetSession().(*Session)
+	if !ok {
+		return errors.WithStack(fosite.ErrServerError.WithDebug(fmt.Sprintf("Expected session to be of type *oauth2.Session but got %T", request.GetSession())))
+	}
+
+	session.Subject = subjectSession.GetSubject()
+	session.Audience = audiences
+	for k, v := range subjectSession.Extra {
+		session.Extra[k] = v
+	}
+
+	// An actor token turns impersonation into delegation, which is recorded in the act claim. If the subject token
+	// was itself obtained through delegation, the previous actors are kept as nested act claims.
+	session.Actor = subjectSession.Actor
+	if form.Get("actor_token") != "" {
+		actor, err := h.validateToken(ctx, form.Get("actor_token"), form.Get("actor_token_type"), "actor_token")
+		if err != nil {
+			return err
+		}
+
+		// The actor is the party which is authenticated at the token endpoint, it can not present the token of
+		// another client.
+		if actor.GetClient().GetID() != c.GetID() {
+			return errors.WithStack(fosite.ErrInvalidRequest.WithDebug("The actor token was not issued to the client making the request"))
+		}
+
+		act := map[string]interface{}{
+			"sub":       actor.GetSession().GetSubject(),
+			"client_id": c.GetID(),
+		}
+		if subjectSession.Actor != nil {
+			act["act"] = subjectSession.Actor
+		}
+		session.Actor = act
+	} else if form.Get("actor_token_type") != "" {
+		return errors.WithStack(fosite.ErrInvalidRequest.WithDebug("Parameter actor_token_type must not be set without actor_token"))
+	}
+
+	// The exchanged token must not outlive the subject token.
+	expiresAt := time.Now().UTC().Add(h.AccessTokenLifespan)
+	if exp := subjectSession.GetExpiresAt(fosite.AccessToken); !exp.IsZero() && exp.Before(expiresAt) {
+		expiresAt = exp
+	}
+	session.SetExpiresAt(fosite.AccessToken, expiresAt)
+
+	return nil
+}
+
+// PopulateTokenEndpointResponse issues the exchanged access token.
+func (h *TokenExchangeGrantHandler) PopulateTokenEndpointResponse(ctx context.Context, request fosite.AccessRequester, responder fosite.AccessResponder) error {
+	if !request.GetGrantTypes().Exact(TokenExchangeGrantType) {
+		return errors.WithStack(fosite.ErrUnknownRequest)
+	}
+
+	if err := h.IssueAccessToken(ctx, request, responder); err != nil {
+		return err
+	}
+
+	responder.SetExtra("issued_token_type", AccessTokenType)
+	return nil
+}
+
+func (h *TokenExchangeGrantHandler) validateToken(ctx context.Context, token, tokenType, parameter string) (fosite.Requester, error) {
+	if token == "" {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug(fmt.Sprintf("Parameter %s is missing", parameter)))
+	} else if tokenType != AccessTokenType {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug(fmt.Sprintf("Parameter %s_type must be %s", parameter, AccessTokenType)))
+	}
+
+	signature := h.AccessTokenStrategy.AccessTokenSignature(token)
+	or, err := h.AccessTokenStorage.GetAccessTokenSession(ctx, signature, NewSession(""))
+	if errors.Cause(err) == fosite.ErrNotFound {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug(fmt.Sprintf("The %s is unknown or has been revoked", strings.Replace(parameter, "_", " ", -1))))
+	} else if err != nil {
+		return nil, errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+	}
+
+	if err := h.AccessTokenStrategy.ValidateAccessToken(ctx, or, token); err != nil {
+		return nil, errors.WithStack(fosite.ErrInvalidRequest.WithDebug(fmt.Sprintf("The %s is invalid or has expired", strings.Replace(parameter, "_", " ", -1))))
+	}
+
+	return or, nil
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+package oauth2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/ory/go-convenience/stringslice"
+	"github.com/ory/hydra/client"
+	"github.com/pkg/errors"
+	"github.com/square/go-jose"
+)
+
+// defaultUserinfoEncryptionEncoding is used if a client requests encrypted userinfo responses without setting
+// userinfo_encrypted_response_enc.
+const defaultUserinfoEncryptionEncoding = string(jose.A128CBC_HS256)
+
+// userinfoResponseJWT serializes the userinfo claims as requested by the client. If the client requested signed
+// responses, the claims are signed using the OpenID Connect key of the requested algorithm. If it requested encrypted
+// responses, the signed JWT, or the plain JSON object otherwise, is encrypted to a key of the client's JSON Web Key Set.
+func (h *Handler) userinfoResponseJWT(ctx context.Context, c *client.Client, claims map[string]interface{}) (string, error) {
+	claims["iss"] = strings.TrimRight(h.IssuerURL, "/") + "/"
+	claims["aud"] = []string{c.GetID()}
+
+	var payload []byte
+	if c.UserinfoSignedResponseAlg != "" {
+		if h.JWTStrategy == nil || !stringslice.Has(h.IDTokenSigningAlgorithms, c.UserinfoSignedResponseAlg) {
+			return "", errors.WithStack(fosite.ErrServerError.WithDebug("The userinfo_signed_response_alg of the client is not supported by the OpenID Connect signing keys"))
+		}
+
+		token, _, err := h.JWTStrategy.Generate(jwtgo.MapClaims(claims), &jwt.Headers{Extra: map[string]interface{}{"alg": c.UserinfoSignedResponseAlg}})
+		if err != nil {
+			return "", errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+		}
+
+		if c.UserinfoEncryptedResponseAlg == "" {
+			return token, nil
+		}
+		payload = []byte(token)
+	} else {
+		var err error
+		if payload, err = json.Marshal(claims); err != nil {
+			return "", errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+		}
+	}
+
+	return h.encryptUserinfoResponse(ctx, c, payload, c.UserinfoSignedResponseAlg != "")
+}
+
+// encryptUserinfoResponse encrypts payload to a key of the client's JSON Web Key Set. If nested is true, payload is a
+// signed JWT and the content type of the encrypted JWT is set to JWT as required by OpenID Connect Core 1.0 §16.14.
+func (h *Handler) encryptUserinfoResponse(ctx context.Context, c *client.Client, payload []byte, nested bool) (string, error) {
+	hc := h.HTTPClient
+	if hc == nil {
+		hc = &http.Client{Timeout: time.Second * 10}
+	}
+
+	set, err := clientJSONWebKeys(ctx, hc, c)
+	if err != nil {
+		return "", errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+	}
+
+	key, err := findEncryptionKey(set, c.UserinfoEncryptedResponseAlg)
+	if err != nil {
+		return "", errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+	}
+
+	enc := c.UserinfoEncryptedResponseEnc
+	if enc == "" {
+		enc = defaultUserinfoEncryptionEncoding
+	}
+
+	var opts *jose.EncrypterOptions
+	if nested {
+		opts = &jose.EncrypterOptions{ExtraHeaders: map[jose.HeaderKey]interface{}{jose.HeaderContentType: "JWT"}}
+	}
+
+	encrypter, err := jose.NewEncrypter(jose.ContentEncryption(enc), jose.Recipient{
+		Algorithm: jose.KeyAlgorithm(c.UserinfoEncryptedResponseAlg),
+		Key:       key,
+	}, opts)
+	if err != nil {
+		return "", errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+	}
+
+	encrypted, err := encrypter.Encrypt(payload)
+	if err != nil {
+		return "", errors.WithStack(fosite.ErrServerError.WithDebug(err.Error()))
+	}
+
+	return encrypted.CompactSerialize()
+}
+
+// findEncryptionKey returns the first key of the set which is meant for encryption and can be used with the key
+// management algorithm alg.
+func findEncryptionKey(set *jose.JSONWebKeySet, alg string) (*jose.JSONWebKey, error) {
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "enc" {
+			continue
+		} else if key.Algorithm != "" && key.Algorithm != alg {
+			continue
+		}
+
+		switch key.Key.(type) {
+		case *rsa.PublicKey:
+			if strings.HasPrefix(alg, "RSA") {
+				return &key, nil
+			}
+		case *ecdsa.PublicKey:
+			if strings.HasPrefix(alg, "ECDH-ES") {
+				return &key, nil
+			}
+		}
+	}
+
+	return nil, errors.Errorf("The client has no JSON Web Key which can be used with key management algorithm %s", alg)
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+package oauth2_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/julienschmidt/httprouter"
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	"github.com/ory/herodot"
+	hc "github.com/ory/hydra/client"
+	. "github.com/ory/hydra/oauth2"
+	"github.com/ory/hydra/pkg"
+	"github.com/pborman/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserinfoResponse(t *testing.T) {
+	router := httprouter.New()
+	l := logrus.New()
+	l.Level = logrus.DebugLevel
+	store := NewFositeMemoryStore(hc.NewMemoryManager(hasher), time.Hour)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	signingKey := pkg.MustINSECURELOWENTROPYRSAKEYFORTEST()
+	handler := &Handler{
+		OAuth2: compose.Compose(
+			fc,
+			store,
+			oauth2Strategy,
+			hasher,
+			compose.OAuth2TokenIntrospectionFactory,
+		),
+		ScopeStrategy:            fosite.HierarchicScopeStrategy,
+		H:                        herodot.NewJSONWriter(l),
+		L:                        l,
+		IssuerURL:                ts.URL,
+		IDTokenSigningAlgorithms: []string{"RS256"},
+		JWTStrategy:              compose.NewOpenIDConnectStrategy(signingKey),
+	}
+	handler.SetRoutes(router, router)
+
+	encryptionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwks := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: &signingKey.PublicKey, KeyID: "sig", Use: "sig"},
+		{Key: &encryptionKey.PublicKey, KeyID: "enc", Use: "enc"},
+	}}
+
+	for _, c := range []*hc.Client{
+		{ID: "plain"},
+		{ID: "signed", UserinfoSignedResponseAlg: "RS256"},
+		{ID: "encrypted", UserinfoEncryptedResponseAlg: "RSA-OAEP", JSONWebKeys: jwks},
+		{ID: "signed-encrypted", UserinfoSignedResponseAlg: "RS256", UserinfoEncryptedResponseAlg: "RSA-OAEP-256", UserinfoEncryptedResponseEnc: "A256GCM", JSONWebKeys: jwks},
+		{ID: "unsupported", UserinfoSignedResponseAlg: "ES256"},
+	} {
+		require.NoError(t, store.CreateClient(c))
+	}
+
+	userinfo := func(t *testing.T, id string) (*http.Response, []byte) {
+		ctx := context.Background()
+		c, err := store.GetClient(ctx, id)
+		require.NoError(t, err)
+
+		req := &fosite.Request{
+			ID:          uuid.New(),
+			RequestedAt: time.Now().UTC(),
+			Client:      c,
+			Form:        url.Values{},
+			Session:     NewSession("foo"),
+		}
+		req.Session.SetExpiresAt(fosite.AccessToken, time.Now().UTC().Add(time.Hour))
+
+		token, signature, err := oauth2Strategy.GenerateAccessToken(ctx, req)
+		require.NoError(t, err)
+		require.NoError(t, store.CreateAccessTokenSession(ctx, signature, req))
+
+		r, err := http.NewRequest("GET", ts.URL+"/userinfo", nil)
+		require.NoError(t, err)
+		r.Header.Set("Authorization", "Bearer "+token)
+
+		res, err := http.DefaultClient.Do(r)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		body, err := ioutil.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, body
+	}
+
+	verify := func(t *testing.T, token, id string) {
+		claims := jwtgo.MapClaims{}
+		_, err := jwtgo.ParseWithClaims(token, claims, func(t *jwtgo.Token) (interface{}, error) {
+			return &signingKey.PublicKey, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "foo", claims["sub"])
+		assert.Equal(t, ts.URL+"/", claims["iss"])
+		assert.EqualValues(t, []interface{}{id}, claims["aud"])
+	}
+
+	decrypt := func(t *testing.T, token string) []byte {
+		encrypted, err := jose.ParseEncrypted(token)
+		require.NoError(t, err)
+		payload, err := encrypted.Decrypt(encryptionKey)
+		require.NoError(t, err)
+		return payload
+	}
+
+	header := func(t *testing.T, token string) map[string]interface{} {
+		raw, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+		require.NoError(t, err)
+
+		var header map[string]interface{}
+		require.NoError(t, json.Unmarshal(raw, &header))
+		return header
+	}
+
+	t.Run("case=returns plain json by default", func(t *testing.T) {
+		res, body := userinfo(t, "plain")
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.Contains(t, res.Header.Get("Content-Type"), "application/json")
+
+		var claims map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &claims))
+		assert.Equal(t, "foo", claims["sub"])
+	})
+
+	t.Run("case=returns a signed jwt", func(t *testing.T) {
+		res, body := userinfo(t, "signed")
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.Equal(t, "application/jwt", res.Header.Get("Content-Type"))
+		verify(t, string(body), "signed")
+	})
+
+	t.Run("case=returns encrypted json", func(t *testing.T) {
+		res, body := userinfo(t, "encrypted")
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.Equal(t, "application/jwt", res.Header.Get("Content-Type"))
+
+		assert.Nil(t, header(t, string(body))["cty"])
+
+		var claims map[string]interface{}
+		require.NoError(t, json.Unmarshal(decrypt(t, string(body)), &claims))
+		assert.Equal(t, "foo", claims["sub"])
+	})
+
+	t.Run("case=returns a signed and encrypted jwt", func(t *testing.T) {
+		res, body := userinfo(t, "signed-encrypted")
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.Equal(t, "JWT", header(t, string(body))["cty"])
+		verify(t, string(decrypt(t, string(body))), "signed-encrypted")
+	})
+
+	t.Run("case=fails if the signing algorithm is not supported", func(t *testing.T) {
+		res, body := userinfo(t, "unsupported")
+		assert.Equal(t, http.StatusInternalServerError, res.StatusCode, "%s", body)
+	})
+}
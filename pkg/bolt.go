@@ -0,0 +1,96 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"time"
+
+	bolt "github.com/coreos/bbolt"
+	"github.com/pkg/errors"
+)
+
+// OpenBolt opens the BoltDB database file at path, creating it if it does not exist yet. Only one process can open the
+// file at a time, OpenBolt fails if another process holds it for longer than a few seconds.
+func OpenBolt(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not open BoltDB database %s", path)
+	}
+	return db, nil
+}
+
+// BoltGet decodes the JSON value stored under the key in the bucket into v. It returns ErrNotFound if the bucket or
+// the key does not exist.
+func BoltGet(tx *bolt.Tx, bucket, key string, v interface{}) error {
+	b := tx.Bucket([]byte(bucket))
+	if b == nil {
+		return errors.Wrap(ErrNotFound, "")
+	}
+
+	raw := b.Get([]byte(key))
+	if raw == nil {
+		return errors.Wrap(ErrNotFound, "")
+	}
+	return errors.WithStack(json.Unmarshal(raw, v))
+}
+
+// BoltPut stores the JSON encoding of v under the key in the bucket, creating the bucket if it does not exist yet.
+func BoltPut(tx *bolt.Tx, bucket, key string, v interface{}) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(b.Put([]byte(key), raw))
+}
+
+// BoltDelete removes the keys from the bucket. Keys which do not exist are ignored.
+func BoltDelete(tx *bolt.Tx, bucket string, keys ...string) error {
+	b := tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+
+	for _, key := range keys {
+		if err := b.Delete([]byte(key)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// BoltForEach calls fn with the key and the raw JSON value of every entry of the bucket, ordered by key. The bucket
+// must not be modified by fn, collect the keys and use BoltDelete afterwards instead.
+func BoltForEach(tx *bolt.Tx, bucket string, fn func(key string, raw []byte) error) error {
+	b := tx.Bucket([]byte(bucket))
+	if b == nil {
+		return nil
+	}
+
+	return b.ForEach(func(k, v []byte) error {
+		return fn(string(k), v)
+	})
+}
@@ -41,4 +41,15 @@ type FositeStorer interface {
 	RevokeAccessToken(ctx context.Context, requestID string) error
 
 	FlushInactiveAccessTokens(ctx context.Context, notAfter time.Time) error
+
+	// SetClientAssertionJWT marks the JWT ID of a client assertion as used until it expires. It returns an error if the
+	// JWT ID has been used before.
+	SetClientAssertionJWT(ctx context.Context, jti string, exp time.Time) error
+}
+
+// SubjectTokenRevoker is implemented by FositeStorers which can revoke all tokens of a subject.
+type SubjectTokenRevoker interface {
+	// RevokeSubjectTokens deletes all authorize codes, access and refresh tokens, and OpenID Connect and PKCE sessions
+	// of a subject. If client is not empty, only those issued to that client are deleted.
+	RevokeSubjectTokens(ctx context.Context, subject string, client string) error
 }
@@ -0,0 +1,172 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/ory/sqlcon"
+	"github.com/pkg/errors"
+)
+
+// OpenRedis connects to the Redis server of the DSN, for example "redis://:password@host:6379/0". Use the rediss://
+// scheme to connect using TLS.
+func OpenRedis(dsn string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(dsn)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	r := redis.NewClient(opts)
+	if err := r.Ping().Err(); err != nil {
+		r.Close()
+		return nil, errors.Wrapf(err, "Could not connect to Redis server %s", opts.Addr)
+	}
+	return r, nil
+}
+
+// RedisGet decodes the JSON value stored under the key into v. It returns ErrNotFound if the key does not exist or
+// has expired.
+func RedisGet(r redis.Cmdable, key string, v interface{}) error {
+	raw, err := r.Get(key).Bytes()
+	if err == redis.Nil {
+		return errors.Wrap(ErrNotFound, "")
+	} else if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(json.Unmarshal(raw, v))
+}
+
+// RedisSet stores the JSON encoding of v under the key, which expires after ttl.
+func RedisSet(r redis.Cmdable, key string, v interface{}, ttl time.Duration) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(r.Set(key, raw, ttl).Err())
+}
+
+// RedisCreate works like RedisSet but returns sqlcon.ErrUniqueViolation if the key exists already.
+func RedisCreate(r redis.Cmdable, key string, v interface{}, ttl time.Duration) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if ok, err := r.SetNX(key, raw, ttl).Result(); err != nil {
+		return errors.WithStack(err)
+	} else if !ok {
+		return errors.WithStack(sqlcon.ErrUniqueViolation)
+	}
+	return nil
+}
+
+// RedisUpdate decodes the JSON value stored under the key into v, calls fn to modify it and stores it again. The key
+// must have been stored with a time to live, which is kept. It returns ErrNotFound if the key does not exist or has expired, and fails if the
+// key is modified concurrently.
+func RedisUpdate(r *redis.Client, key string, v interface{}, fn func() error) error {
+	return r.Watch(func(tx *redis.Tx) error {
+		if err := RedisGet(tx, key, v); err != nil {
+			return err
+		}
+
+		ttl, err := tx.PTTL(key).Result()
+		if err != nil {
+			return errors.WithStack(err)
+		} else if ttl <= 0 {
+			// The key has expired in the meantime.
+			return errors.Wrap(ErrNotFound, "")
+		}
+
+		if err := fn(); err != nil {
+			return err
+		}
+
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+			return RedisSet(pipe, key, v, ttl)
+		})
+		return errors.WithStack(err)
+	}, key)
+}
+
+// RedisIndex adds the key to the index set, which expires after ttl. All keys of an index must be stored with the
+// same time to live, so that the index does not expire before them.
+func RedisIndex(r redis.Cmdable, index, key string, ttl time.Duration) error {
+	_, err := r.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.SAdd(index, key)
+		pipe.Expire(index, ttl)
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+// RedisDeleteIndexed calls fn with the key and the raw JSON value of every key of the index set and deletes the keys
+// fn returns, the key is removed from the index if fn returns any. Keys which have expired are removed from the index.
+// The index is watched, the deletion is retried if a key is added to it concurrently.
+func RedisDeleteIndexed(r *redis.Client, index string, fn func(key string, raw []byte) ([]string, error)) error {
+	for i := 0; i < 5; i++ {
+		err := r.Watch(func(tx *redis.Tx) error {
+			members, err := tx.SMembers(index).Result()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var keys []string
+			var removed []interface{}
+			for _, key := range members {
+				raw, err := tx.Get(key).Bytes()
+				if err == redis.Nil {
+					removed = append(removed, key)
+					continue
+				} else if err != nil {
+					return errors.WithStack(err)
+				}
+
+				related, err := fn(key, raw)
+				if err != nil {
+					return err
+				} else if len(related) > 0 {
+					keys = append(keys, related...)
+					removed = append(removed, key)
+				}
+			}
+
+			if len(removed) == 0 {
+				return nil
+			}
+
+			_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+				if len(keys) > 0 {
+					pipe.Del(keys...)
+				}
+				pipe.SRem(index, removed...)
+				return nil
+			})
+			return err
+		}, index)
+		if err != redis.TxFailedErr {
+			return errors.WithStack(err)
+		}
+	}
+	return errors.WithStack(redis.TxFailedErr)
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package pkg
+
+import (
+	"regexp"
+
+	"github.com/ory/sqlcon"
+	"github.com/pkg/errors"
+	"github.com/rubenv/sql-migrate"
+)
+
+// SQLiteDialect is the name of the SQLite database driver, which sql-migrate uses as name of the dialect as well.
+const SQLiteDialect = "sqlite3"
+
+var sqlNowDefault = regexp.MustCompile(`(?i)DEFAULT now\(\)`)
+
+// HandleSQLError works like sqlcon.HandleError but maps unique constraint violations reported by SQLite to
+// sqlcon.ErrUniqueViolation as well.
+func HandleSQLError(err error) error {
+	if isSQLiteUniqueViolation(err) {
+		return errors.Wrap(sqlcon.ErrUniqueViolation, err.Error())
+	}
+	return sqlcon.HandleError(err)
+}
+
+// SQLMigrations returns the migrations for the dialect, which is the driver name of the database. Migrations are
+// written for PostgreSQL and MySQL and are returned unchanged for them. For SQLite, column defaults using now() are
+// changed to CURRENT_TIMESTAMP, and the up statements of the migrations in sqlite, keyed by migration ID, replace
+// statements which SQLite can not run at all.
+//
+// Down migrations are not supported on SQLite. Many of them use ALTER TABLE ... DROP COLUMN, which the bundled SQLite
+// version can not run, and they are returned unchanged.
+func SQLMigrations(source *migrate.MemoryMigrationSource, dialect string, sqlite map[string][]string) *migrate.MemoryMigrationSource {
+	if dialect != SQLiteDialect {
+		return source
+	}
+
+	migrations := make([]*migrate.Migration, len(source.Migrations))
+	for k, m := range source.Migrations {
+		up := m.Up
+		if statements, ok := sqlite[m.Id]; ok {
+			up = statements
+		}
+
+		migration := *m
+		migration.Up = make([]string, len(up))
+		for i, statement := range up {
+			migration.Up[i] = sqlNowDefault.ReplaceAllString(statement, "DEFAULT CURRENT_TIMESTAMP")
+		}
+		migrations[k] = &migration
+	}
+
+	return &migrate.MemoryMigrationSource{Migrations: migrations}
+}
@@ -0,0 +1,64 @@
+// +build cgo
+
+/*
+ * Copyright © 2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * @author		Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @copyright 	2015-2018 Aeneas Rekkas <aeneas+oss@aeneas.io>
+ * @license 	Apache-2.0
+ */
+
+package pkg
+
+import (
+	"net/url"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+// OpenSQLite opens the SQLite database referenced by a DSN like "sqlite:///var/lib/hydra/hydra.sqlite", creating the
+// file if it does not exist yet. Query parameters are passed on to the driver.
+func OpenSQLite(dsn string) (*sqlx.DB, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	path := u.Host + u.Path
+	if path == "" {
+		return nil, errors.Errorf(`DSN "%s" does not contain the path of the SQLite database file`, dsn)
+	}
+
+	db, err := sqlx.Open(SQLiteDialect, "file:"+path+"?"+u.RawQuery)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not open SQLite database %s", path)
+	}
+
+	// SQLite locks the whole database while writing. Using a single connection queues concurrent writes instead of
+	// failing them with "database is locked".
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		return nil, errors.Wrapf(err, "Could not open SQLite database %s", path)
+	}
+	return db, nil
+}
+
+func isSQLiteUniqueViolation(err error) bool {
+	e, ok := errors.Cause(err).(sqlite3.Error)
+	return ok && (e.ExtendedCode == sqlite3.ErrConstraintUnique || e.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
@@ -21,8 +21,16 @@
 package pkg
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/alicebob/miniredis"
+	bolt "github.com/coreos/bbolt"
+	"github.com/go-redis/redis"
+	"github.com/jmoiron/sqlx"
 	"github.com/ory/fosite/handler/oauth2"
 	"github.com/ory/fosite/storage"
 	"github.com/ory/fosite/token/hmac"
@@ -46,6 +54,82 @@ func LadonWarden(ps map[string]ladon.Policy) ladon.Warden {
 	}
 }
 
+var (
+	testDatabaseCleanups     []func()
+	testDatabaseCleanupsLock sync.Mutex
+)
+
+func addTestDatabaseCleanup(cleanup func()) {
+	testDatabaseCleanupsLock.Lock()
+	defer testDatabaseCleanupsLock.Unlock()
+	testDatabaseCleanups = append(testDatabaseCleanups, cleanup)
+}
+
+// CleanupTestDatabases closes the test databases and removes their temporary directories. Call it from TestMain once
+// all tests ran.
+func CleanupTestDatabases() {
+	testDatabaseCleanupsLock.Lock()
+	defer testDatabaseCleanupsLock.Unlock()
+
+	for _, cleanup := range testDatabaseCleanups {
+		cleanup()
+	}
+	testDatabaseCleanups = nil
+}
+
+// BoltTestDatabase opens a BoltDB database in a new temporary directory, which is removed by CleanupTestDatabases.
+func BoltTestDatabase() *bolt.DB {
+	dir, err := ioutil.TempDir("", "hydra-bolt")
+	Must(err, "Could not create temporary directory: %s", err)
+
+	db, err := OpenBolt(filepath.Join(dir, "hydra.db"))
+	Must(err, "Could not open BoltDB database: %s", err)
+
+	addTestDatabaseCleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+	return db
+}
+
+// SQLiteTestDatabase opens a SQLite database in a new temporary directory, which is removed by CleanupTestDatabases.
+func SQLiteTestDatabase() *sqlx.DB {
+	dir, err := ioutil.TempDir("", "hydra-sqlite")
+	Must(err, "Could not create temporary directory: %s", err)
+
+	db, err := OpenSQLite("sqlite://" + filepath.Join(dir, "hydra.sqlite"))
+	Must(err, "Could not open SQLite database: %s", err)
+
+	addTestDatabaseCleanup(func() {
+		db.Close()
+		os.RemoveAll(dir)
+	})
+	return db
+}
+
+// RedisTestDatabase connects to a new in-memory miniredis server, which stands in for a Redis server and is closed by
+// CleanupTestDatabases.
+func RedisTestDatabase() *redis.Client {
+	_, r := RedisTestServer()
+	return r
+}
+
+// RedisTestServer works like RedisTestDatabase, but also returns the miniredis server. Keys never expire in miniredis
+// unless its clock is advanced with FastForward.
+func RedisTestServer() (*miniredis.Miniredis, *redis.Client) {
+	s, err := miniredis.Run()
+	Must(err, "Could not start miniredis server: %s", err)
+
+	r, err := OpenRedis("redis://" + s.Addr())
+	Must(err, "Could not connect to miniredis server: %s", err)
+
+	addTestDatabaseCleanup(func() {
+		r.Close()
+		s.Close()
+	})
+	return s, r
+}
+
 func FositeStore() *storage.MemoryStore {
 	return storage.NewMemoryStore()
 }
@@ -9,11 +9,12 @@ killall mock-lcp || true
 killall mock-cb || true
 
 export HYDRA_URL=http://127.0.0.1:4444/
+export HYDRA_ADMIN_URL=http://127.0.0.1:4445/
 export OAUTH2_CLIENT_ID=foobar
 export OAUTH2_CLIENT_SECRET=bazbar
 export OAUTH2_ISSUER_URL=http://127.0.0.1:4444/
 export LOG_LEVEL=debug
-export REDIRECT_URL=http://127.0.0.1:4445/callback
+export REDIRECT_URL=http://127.0.0.1:4446/callback
 export AUTH2_SCOPE=openid,offline
 
 go install .
@@ -28,23 +29,24 @@ DATABASE_URL=memory \
     OAUTH2_SHARE_ERROR_DEBUG=true \
     hydra serve --dangerous-force-http --disable-telemetry &
 
-PORT=3000 mock-lcp &
+PORT=3000 HYDRA_URL=$HYDRA_ADMIN_URL mock-lcp &
 
-PORT=4445 mock-cb &
+PORT=4446 mock-cb &
 
 while ! echo exit | nc 127.0.0.1 4444; do sleep 1; done
 while ! echo exit | nc 127.0.0.1 4445; do sleep 1; done
+while ! echo exit | nc 127.0.0.1 4446; do sleep 1; done
 while ! echo exit | nc 127.0.0.1 3000; do sleep 1; done
 
 
 hydra clients create \
-    --endpoint http://127.0.0.1:4444 \
+    --endpoint $HYDRA_ADMIN_URL \
     --id $OAUTH2_CLIENT_ID \
     --secret $OAUTH2_CLIENT_SECRET \
     --response-types token,code,id_token \
     --grant-types refresh_token,authorization_code,client_credentials \
     --scope openid,offline \
-    --callbacks http://127.0.0.1:4445/callback
+    --callbacks http://127.0.0.1:4446/callback
 
 token=$(hydra token client)
 
@@ -82,7 +84,7 @@ cookie=$(OAUTH2_EXTRA="&mockLogin=accept&mockConsent=accept&rememberConsent=yes"
 OAUTH2_EXTRA="&mockLogin=accept&mockConsent=accept&prompt=none" \
     mock-client
 
-hydra clients delete $OAUTH2_CLIENT_ID
+hydra clients delete --endpoint $HYDRA_ADMIN_URL $OAUTH2_CLIENT_ID
 
 kill %1
 kill %2
@@ -73,7 +73,7 @@ func NewSDK(c *Configuration) (*CodeGenSDK, error) {
 
 	if c.ClientSecret != "" && c.ClientID != "" {
 		if len(c.Scopes) == 0 {
-			c.Scopes = []string{}
+			c.Scopes = []string{"hydra.*"}
 		}
 
 		oAuth2ClientConfig := &clientcredentials.Config{
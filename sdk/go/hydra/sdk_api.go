@@ -36,6 +36,7 @@ type JWKApi interface {
 	DeleteJsonWebKeySet(set string) (*swagger.APIResponse, error)
 	GetJsonWebKey(kid string, set string) (*swagger.JsonWebKeySet, *swagger.APIResponse, error)
 	GetJsonWebKeySet(set string) (*swagger.JsonWebKeySet, *swagger.APIResponse, error)
+	RotateJsonWebKeySet(set string) ([]swagger.JsonWebKeyMetadata, *swagger.APIResponse, error)
 	UpdateJsonWebKey(kid string, set string, body swagger.JsonWebKey) (*swagger.JsonWebKey, *swagger.APIResponse, error)
 	UpdateJsonWebKeySet(set string, body swagger.JsonWebKeySet) (*swagger.JsonWebKeySet, *swagger.APIResponse, error)
 }
@@ -47,10 +48,14 @@ type OAuth2API interface {
 	RejectLoginRequest(challenge string, body swagger.RejectRequest) (*swagger.CompletedRequest, *swagger.APIResponse, error)
 	GetLoginRequest(challenge string) (*swagger.LoginRequest, *swagger.APIResponse, error)
 	GetConsentRequest(challenge string) (*swagger.ConsentRequest, *swagger.APIResponse, error)
+	ListUserConsentSessions(user string, limit int64, offset int64) ([]swagger.PreviousConsentSession, *swagger.APIResponse, error)
+	RevokeUserConsentSessions(user string, client string) (*swagger.APIResponse, error)
+	RevokeUserLoginSessions(user string) (*swagger.APIResponse, error)
 
 	CreateOAuth2Client(body swagger.OAuth2Client) (*swagger.OAuth2Client, *swagger.APIResponse, error)
 	DeleteOAuth2Client(id string) (*swagger.APIResponse, error)
 	GetOAuth2Client(id string) (*swagger.OAuth2Client, *swagger.APIResponse, error)
+	GetAuthorizationServerMetadata() (*swagger.AuthorizationServerMetadata, *swagger.APIResponse, error)
 	GetWellKnown() (*swagger.WellKnown, *swagger.APIResponse, error)
 	IntrospectOAuth2Token(token string, scope string) (*swagger.OAuth2TokenIntrospection, *swagger.APIResponse, error)
 	ListOAuth2Clients(limit int64, offset int64) ([]swagger.OAuth2Client, *swagger.APIResponse, error)
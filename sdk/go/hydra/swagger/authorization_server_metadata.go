@@ -0,0 +1,65 @@
+/*
+ * ORY Hydra - Cloud Native OAuth 2.0 and OpenID Connect Server
+ *
+ * Welcome to the ORY Hydra HTTP API documentation. You will find documentation for all HTTP APIs here. Keep in mind that this document reflects the latest branch, always. Support for versioned documentation is coming in the future.
+ *
+ * OpenAPI spec version: Latest
+ * Contact: hi@ory.am
+ * Generated by: https://github.com/swagger-api/swagger-codegen.git
+ */
+
+package swagger
+
+type AuthorizationServerMetadata struct {
+
+	// URL of the authorization server's authorization endpoint.
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+
+	// JSON array containing a list of PKCE code challenge methods supported by this authorization server.
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+
+	// URL of the authorization server's OAuth 2.0 Device Authorization Endpoint. Only set if the device authorization grant is enabled.
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
+
+	// JSON array containing a list of the OAuth 2.0 grant type values that this authorization server supports.
+	GrantTypesSupported []string `json:"grant_types_supported,omitempty"`
+
+	// URL of the authorization server's OAuth 2.0 introspection endpoint. Only set if token introspection is enabled.
+	IntrospectionEndpoint string `json:"introspection_endpoint,omitempty"`
+
+	// JSON array containing a list of client authentication methods supported by this introspection endpoint.
+	IntrospectionEndpointAuthMethodsSupported []string `json:"introspection_endpoint_auth_methods_supported,omitempty"`
+
+	// The authorization server's issuer identifier, which is a URL that uses the https scheme and has no query or fragment components.
+	Issuer string `json:"issuer"`
+
+	// URL of the authorization server's JWK Set document.
+	JwksUri string `json:"jwks_uri,omitempty"`
+
+	// URL of the authorization server's OAuth 2.0 Dynamic Client Registration endpoint. Only set if dynamic client registration is enabled.
+	RegistrationEndpoint string `json:"registration_endpoint,omitempty"`
+
+	// JSON array containing a list of the OAuth 2.0 response_mode values that this authorization server supports.
+	ResponseModesSupported []string `json:"response_modes_supported,omitempty"`
+
+	// JSON array containing a list of the OAuth 2.0 response_type values that this authorization server supports.
+	ResponseTypesSupported []string `json:"response_types_supported"`
+
+	// URL of the authorization server's OAuth 2.0 revocation endpoint. Only set if token revocation is enabled.
+	RevocationEndpoint string `json:"revocation_endpoint,omitempty"`
+
+	// JSON array containing a list of client authentication methods supported by this revocation endpoint.
+	RevocationEndpointAuthMethodsSupported []string `json:"revocation_endpoint_auth_methods_supported,omitempty"`
+
+	// JSON array containing a list of the OAuth 2.0 scope values that this authorization server supports.
+	ScopesSupported []string `json:"scopes_supported,omitempty"`
+
+	// URL of the authorization server's token endpoint.
+	TokenEndpoint string `json:"token_endpoint"`
+
+	// JSON array containing a list of client authentication methods supported by this token endpoint.
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+
+	// JSON array containing a list of the JWS signing algorithms supported by the token endpoint for the signature on the JWT used to authenticate the client at the token endpoint.
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported,omitempty"`
+}
@@ -0,0 +1,32 @@
+/*
+ * ORY Hydra - Cloud Native OAuth 2.0 and OpenID Connect Server
+ *
+ * Welcome to the ORY Hydra HTTP API documentation. You will find documentation for all HTTP APIs here. Keep in mind that this document reflects the latest branch, always. Support for versioned documentation is coming in the future.
+ *
+ * OpenAPI spec version: Latest
+ * Contact: hi@ory.am
+ * Generated by: https://github.com/swagger-api/swagger-codegen.git
+ */
+
+package swagger
+
+type DeviceAuthorizationResponse struct {
+
+	// The device verification code.
+	DeviceCode string `json:"device_code,omitempty"`
+
+	// The lifetime in seconds of the device_code and user_code.
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+
+	// The minimum amount of time in seconds that the client should wait between polling requests to the token endpoint.
+	Interval int64 `json:"interval,omitempty"`
+
+	// The end-user verification code.
+	UserCode string `json:"user_code,omitempty"`
+
+	// The end-user verification URI on the authorization server.
+	VerificationUri string `json:"verification_uri,omitempty"`
+
+	// A verification URI that includes the user_code, designed for non-textual transmission.
+	VerificationUriComplete string `json:"verification_uri_complete,omitempty"`
+}
@@ -0,0 +1,30 @@
+/*
+ * ORY Hydra - Cloud Native OAuth 2.0 and OpenID Connect Server
+ *
+ * Welcome to the ORY Hydra HTTP API documentation. You will find documentation for all HTTP APIs here. Keep in mind that this document reflects the latest branch, always. Support for versioned documentation is coming in the future.
+ *
+ * OpenAPI spec version: Latest
+ * Contact: hi@ory.am
+ * Generated by: https://github.com/swagger-api/swagger-codegen.git
+ */
+
+package swagger
+
+import (
+	"time"
+)
+
+type JsonWebKeyMetadata struct {
+
+	// ActivatesAt is the time the key starts being used for signing. Rotated keys are published before they become active so that relying parties can pick them up before the first token signed with them is issued.
+	ActivatesAt time.Time `json:"activates_at,omitempty"`
+
+	// CreatedAt is the time the key was added to its set.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+
+	// KeyID is the kid of the key.
+	Kid string `json:"kid,omitempty"`
+
+	// RetiresAt is the time the key is removed from its set. Keys without it never retire.
+	RetiresAt time.Time `json:"retires_at,omitempty"`
+}
@@ -12,6 +12,12 @@ package swagger
 
 type OAuth2Client struct {
 
+	// Boolean value specifying whether the RP requires that a sid (session ID) Claim be included in the Logout Token to identify the RP session with the OP when the backchannel_logout_uri is used.
+	BackchannelLogoutSessionRequired bool `json:"backchannel_logout_session_required,omitempty"`
+
+	// RP URL that will cause the RP to log itself out when sent a Logout Token by the OP.
+	BackchannelLogoutUri string `json:"backchannel_logout_uri,omitempty"`
+
 	// Name is the human-readable string name of the client to be presented to the end-user during authorization.
 	ClientName string `json:"client_name,omitempty"`
 
@@ -27,12 +33,27 @@ type OAuth2Client struct {
 	// Contacts is a array of strings representing ways to contact people responsible for this client, typically email addresses.
 	Contacts []string `json:"contacts,omitempty"`
 
+	// Boolean value specifying whether the RP requires that iss (issuer) and sid (session ID) query parameters be included to identify the RP session with the OP when the frontchannel_logout_uri is used.
+	FrontchannelLogoutSessionRequired bool `json:"frontchannel_logout_session_required,omitempty"`
+
+	// RP URL that will cause the RP to log itself out when rendered in an iframe by the OP.
+	FrontchannelLogoutUri string `json:"frontchannel_logout_uri,omitempty"`
+
 	// GrantTypes is an array of grant types the client is allowed to use.
 	GrantTypes []string `json:"grant_types,omitempty"`
 
 	// ID is the id for this client.
 	Id string `json:"id,omitempty"`
 
+	// JWS alg algorithm [JWA] REQUIRED for signing the ID Token issued to this Client. If omitted, ID Tokens are signed using the default algorithm of the server. The algorithm must be one of the id_token_signing_alg_values_supported advertised by OpenID Connect Discovery.
+	IdTokenSignedResponseAlg string `json:"id_token_signed_response_alg,omitempty"`
+
+	// Client's JSON Web Key Set [JWK] document, passed by value. The semantics of the jwks parameter are the same as the jwks_uri parameter, other than that the JWK Set is passed by value, rather than by reference. Use either jwks_uri or jwks, but not both.
+	Jwks *JsonWebKeySet `json:"jwks,omitempty"`
+
+	// URL for the Client's JSON Web Key Set [JWK] document. If the Client signs requests to the Server, it contains the signing key(s) the Server uses to validate signatures from the Client. The JWK Set MAY also contain the Client's encryption keys(s), which are used by the Server to encrypt responses to the Client. Use either jwks_uri or jwks, but not both.
+	JwksUri string `json:"jwks_uri,omitempty"`
+
 	// LogoURI is an URL string that references a logo for the client.
 	LogoUri string `json:"logo_uri,omitempty"`
 
@@ -42,18 +63,48 @@ type OAuth2Client struct {
 	// PolicyURI is a URL string that points to a human-readable privacy policy document that describes how the deployment organization collects, uses, retains, and discloses personal data.
 	PolicyUri string `json:"policy_uri,omitempty"`
 
+	// Array of URLs supplied by the RP to which it MAY request that the End-User's User Agent be redirected using the post_logout_redirect_uri parameter after a logout has been performed.
+	PostLogoutRedirectUris []string `json:"post_logout_redirect_uris,omitempty"`
+
 	// Public is a boolean that identifies this client as public, meaning that it does not have a secret. It will disable the client_credentials grant type for this client if set.
 	Public bool `json:"public,omitempty"`
 
 	// RedirectURIs is an array of allowed redirect urls for the client, for example http://mydomain/oauth/callback .
 	RedirectUris []string `json:"redirect_uris,omitempty"`
 
+	// RefreshTokenGracePeriod is the number of seconds during which a refresh token which has just been rotated may be used again, for example by concurrent requests of a mobile app. Outside of this window, using a rotated refresh token revokes all tokens issued from the same authorization. If 0, rotated refresh tokens can not be used again.
+	RefreshTokenGracePeriod int64 `json:"refresh_token_grace_period,omitempty"`
+
 	// ResponseTypes is an array of the OAuth 2.0 response type strings that the client can use at the authorization endpoint.
 	ResponseTypes []string `json:"response_types,omitempty"`
 
 	// Scope is a string containing a space-separated list of scope values (as described in Section 3.3 of OAuth 2.0 [RFC6749]) that the client can use when requesting access tokens.
 	Scope string `json:"scope,omitempty"`
 
+	// URL using the https scheme to be used in calculating Pseudonymous Identifiers by the OP. The URL references a file with a single JSON array of redirect_uri values.
+	SectorIdentifierUri string `json:"sector_identifier_uri,omitempty"`
+
+	// SubjectType requested for responses to this Client. The subject_types_supported Discovery parameter contains a list of the supported subject_type values for this server. Valid types include `pairwise` and `public`. If empty, `public` is used.
+	SubjectType string `json:"subject_type,omitempty"`
+
+	// Requested Client Authentication method for the Token Endpoint. The options are client_secret_post, client_secret_basic, client_secret_jwt, private_key_jwt, and none. If empty, client_secret_basic is used.
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method,omitempty"`
+
+	// TokenExchangeAudiences is an array of audiences the client may request tokens for using OAuth 2.0 Token Exchange.
+	TokenExchangeAudiences []string `json:"token_exchange_audiences,omitempty"`
+
+	// TokenExchangeScope is a string containing a space-separated list of scope values the client may request using OAuth 2.0 Token Exchange. Exchanged tokens never carry scopes which were not granted to the subject token.
+	TokenExchangeScope string `json:"token_exchange_scope,omitempty"`
+
 	// TermsOfServiceURI is a URL string that points to a human-readable terms of service document for the client that describes a contractual relationship between the end-user and the client that the end-user accepts when authorizing the client.
 	TosUri string `json:"tos_uri,omitempty"`
+
+	// JWE alg algorithm [JWA] REQUIRED for encrypting UserInfo Responses. If both signing and encryption are requested, the response will be signed then encrypted, with the result being a Nested JWT. The response is encrypted to a key of the client's JSON Web Key Set.
+	UserinfoEncryptedResponseAlg string `json:"userinfo_encrypted_response_alg,omitempty"`
+
+	// JWE enc algorithm [JWA] REQUIRED for encrypting UserInfo Responses. If userinfo_encrypted_response_alg is specified, the default for this value is A128CBC-HS256. When userinfo_encrypted_response_enc is included, userinfo_encrypted_response_alg MUST also be provided.
+	UserinfoEncryptedResponseEnc string `json:"userinfo_encrypted_response_enc,omitempty"`
+
+	// JWS alg algorithm [JWA] REQUIRED for signing UserInfo Responses. If this is specified, the response will be JWT [JWT] serialized, and signed using JWS. If omitted, the UserInfo Response will return the Claims as a UTF-8 encoded JSON object using the application/json content-type.
+	UserinfoSignedResponseAlg string `json:"userinfo_signed_response_alg,omitempty"`
 }
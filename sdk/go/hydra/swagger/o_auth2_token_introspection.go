@@ -16,6 +16,9 @@ type OAuth2TokenIntrospection struct {
 	// Active is a boolean indicator of whether or not the presented token is currently active.  The specifics of a token's \"active\" state will vary depending on the implementation of the authorization server and the information it keeps about its tokens, but a \"true\" value return for the \"active\" property will generally indicate that a given token has been issued by this authorization server, has not been revoked by the resource owner, and is within its given time window of validity (e.g., after its issuance time and before its expiration time).
 	Active bool `json:"active,omitempty"`
 
+	// Actor identifies the party acting on behalf of the subject, if the token was obtained through delegation using OAuth 2.0 Token Exchange.
+	Act map[string]interface{} `json:"act,omitempty"`
+
 	Aud []string `json:"aud,omitempty"`
 
 	// ClientID is aclient identifier for the OAuth 2.0 client that requested this token.
@@ -0,0 +1,28 @@
+/*
+ * ORY Hydra - Cloud Native OAuth 2.0 and OpenID Connect Server
+ *
+ * Welcome to the ORY Hydra HTTP API documentation. You will find documentation for all HTTP APIs here. Keep in mind that this document reflects the latest branch, always. Support for versioned documentation is coming in the future.
+ *
+ * OpenAPI spec version: Latest
+ * Contact: hi@ory.am
+ * Generated by: https://github.com/swagger-api/swagger-codegen.git
+ */
+
+package swagger
+
+import (
+	"time"
+)
+
+type PreviousConsentSession struct {
+	Client OAuth2Client `json:"client,omitempty"`
+
+	// ExpiresAt is the time the consent is remembered until. It is not set if the consent is remembered indefinitely.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+
+	// GrantedScope contains the scopes the user granted to the client.
+	GrantScope []string `json:"grant_scope,omitempty"`
+
+	// GrantedAt is the time the consent was granted at.
+	GrantedAt time.Time `json:"granted_at,omitempty"`
+}